@@ -0,0 +1,195 @@
+// 发布构建：为各平台交叉编译 AIG_repo、AIG_person，通过 -ldflags 注入版本、提交及构建时间，
+// 并生成 self-update 使用的版本清单 release.json 及其签名。用法: go run ./build --version v1.2.0 --sign-key release.key
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"AIStat/stat"
+)
+
+// 默认构建的平台
+const defaultTargets = "windows/amd64,windows/arm64,linux/amd64,linux/arm64,darwin/amd64,darwin/arm64"
+
+// 发布的工具及其源码目录
+var tools = []struct{ name, pkg string }{
+	{"AIG_repo", "./repo"},
+	{"AIG_person", "./person"},
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	version := fs.String("version", "", "版本号，默认为 git describe --tags --always --dirty")
+	out := fs.String("out", "dist", "输出目录，各平台的可执行文件写入 系统_架构 子目录")
+	targets := fs.String("targets", defaultTargets, "逗号分隔的 系统/架构")
+	updateURL := fs.String("update-url", "", "写入可执行文件的默认版本清单位置，供 self-update 使用")
+	signKey := fs.String("sign-key", "", "签名版本清单的发布私钥文件，其公钥写入可执行文件供 self-update 校验")
+	genKey := fs.String("gen-key", "", "生成发布私钥写入该文件后退出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *genKey != "" {
+		return generateKey(*genKey)
+	}
+	var key ed25519.PrivateKey
+	if *signKey != "" {
+		var err error
+		if key, err = readKey(*signKey); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, "警告：没有指定 --sign-key，版本清单不签名，self-update 不会校验清单")
+	}
+
+	commit, err := gitOutput("rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	if *version == "" {
+		if *version, err = gitOutput("describe", "--tags", "--always", "--dirty"); err != nil {
+			return err
+		}
+	}
+	// 设置 SOURCE_DATE_EPOCH 时使用该时间，便于重复构建得到相同的文件
+	built := time.Now().UTC()
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		sec, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return fmt.Errorf("错误：SOURCE_DATE_EPOCH '%s' 不是有效的时间戳", epoch)
+		}
+		built = time.Unix(sec, 0).UTC()
+	}
+	manifest := &stat.ReleaseManifest{Version: *version, Commit: commit, BuildDate: built.Format(time.RFC3339)}
+	ldflags := fmt.Sprintf("-s -w -X AIStat/stat.Version=%s -X AIStat/stat.Commit=%s -X AIStat/stat.BuildDate=%s",
+		manifest.Version, manifest.Commit, manifest.BuildDate)
+	if *updateURL != "" {
+		ldflags += " -X AIStat/stat.UpdateURL=" + *updateURL
+	}
+	if key != nil {
+		ldflags += " -X AIStat/stat.UpdatePublicKey=" + base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	}
+
+	for _, target := range strings.Split(*targets, ",") {
+		goos, goarch, ok := strings.Cut(strings.TrimSpace(target), "/")
+		if !ok {
+			return fmt.Errorf("错误：无效的平台 '%s'，格式为 系统/架构，如 windows/amd64", target)
+		}
+		for _, tool := range tools {
+			file := path.Join(goos+"_"+goarch, tool.name)
+			if goos == "windows" {
+				file += ".exe"
+			}
+			dest := filepath.Join(*out, filepath.FromSlash(file))
+			fmt.Fprintf(os.Stderr, "构建 %s\n", file)
+			// 关闭 cgo 以便交叉编译，发布版本不包含需要 cgo 的 SQLite 存储
+			cmd := exec.Command("go", "build", "-trimpath", "-ldflags", ldflags, "-o", dest, tool.pkg)
+			cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS="+goos, "GOARCH="+goarch)
+			cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("错误：构建 %s 失败: %v", file, err)
+			}
+			sum, size, err := fileDigest(dest)
+			if err != nil {
+				return err
+			}
+			manifest.Assets = append(manifest.Assets, stat.ReleaseAsset{Tool: tool.name, OS: goos, Arch: goarch, File: file, SHA256: sum, Size: size})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := stat.WriteJSON(&buf, manifest); err != nil {
+		return err
+	}
+	name := filepath.Join(*out, stat.ReleaseManifestFile)
+	if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "版本清单已写入: %s\n", name)
+	if key != nil {
+		if err := os.WriteFile(name+stat.ReleaseSignatureSuffix, stat.SignReleaseManifest(buf.Bytes(), key), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "版本清单签名已写入: %s\n", name+stat.ReleaseSignatureSuffix)
+	}
+	return nil
+}
+
+// 生成发布私钥，文件内容为 base64 编码的 ed25519 私钥种子
+func generateKey(name string) error {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("错误：创建私钥文件 '%s' 失败: %v", name, err)
+	}
+	if _, err := fmt.Fprintln(f, base64.StdEncoding.EncodeToString(key.Seed())); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "发布私钥已写入: %s，公钥: %s\n", name, base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)))
+	return nil
+}
+
+// 读取 --gen-key 生成的发布私钥
+func readKey(name string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取私钥文件 '%s' 失败: %v", name, err)
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("错误：私钥文件 '%s' 格式不正确，请用 --gen-key 生成", name)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// 运行 git 命令并返回去除首尾空白的输出
+func gitOutput(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("错误：执行 git %s 失败: %v", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// 文件的 SHA256 及大小
+func fileDigest(name string) (string, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
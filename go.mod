@@ -2,4 +2,9 @@ module AIStat
 
 go 1.20
 
-require github.com/gogf/gf v1.16.9
+require (
+	github.com/gogf/gf v1.16.9
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.32
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -1,395 +1,300 @@
 package main
 
 import (
-	"bytes"
+	"flag"
 	"fmt"
-	"math"
+	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
-)
 
-// 定义正则表达式模式常量，避免重复编译
-const (
-	aigPattern = `AIG:(\s*([0-9.]+))`
-	fixPattern = `^[0-9a-f]{40} '[^']+' \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} (fix)`
-	// 添加提交信息解析模式
-	commitPattern = `^([0-9a-f]{40}) '([^']+)' (\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) (.+)$`
-	// 添加文件扩展名常量
-	includeFileExts = ".html,.vue,.js,.ts,.tsx,.css,.scss,.cjs,.go,.php,.yaml,.proto"
-	excludeFileExts = ".pb.go,.pb.validate.go"
+	"AIStat/stat"
 )
 
-type CommitStats struct {
-	AddedLines   int
-	DeletedLines int
-	AIGRatio     float64
-	IsFix        bool
+// 命令行选项
+type Options struct {
+	stat.RunOptions
+	Author string
+	// CSV、JSON 报告输出逐提交明细
+	Detail bool
+	// 输出构建信息；只检查运行环境，不做统计
+	Version  bool
+	CheckEnv bool
 }
 
 // AI代码统计脚本
 func main() {
-	author, since, until, err := parseCommandLineArgs()
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	output, err := runGitCommand(author, since, until)
-	if err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		fmt.Println(err)
-		return
 	}
-
-	commits := splitCommits(output)
-	stats := analyzeCommits(commits)
-	printStatistics(author, since, until, stats)
 }
 
-// 解析命令行参数
-func parseCommandLineArgs() (string, string, string, error) {
-	var author, since, until string
-	if len(os.Args) > 1 {
-		author = os.Args[1]
-	}
-	if len(os.Args) > 2 {
-		since = os.Args[2]
-		if _, err := time.Parse("2006-01-02", since); err != nil {
-			return "", "", "", fmt.Errorf("错误：起始日期 '%s' 格式不正确，请使用 '2006-01-02' 格式", since)
-		}
+// 统计单个开发者并输出报告
+func run(args []string) error {
+	opts, err := parseCommandLineArgs(args)
+	if err != nil {
+		return err
 	}
-	if len(os.Args) > 3 {
-		until = os.Args[3]
-		if _, err := time.Parse("2006-01-02", until); err != nil {
-			return "", "", "", fmt.Errorf("错误：结束日期 '%s' 格式不正确，请使用 '2006-01-02' 格式", until)
-		}
+	if opts.Version {
+		fmt.Printf("AIG_person %s\n", stat.CurrentBuild())
+		return nil
 	}
 
-	since, until = getDefaultDateRange(since, until)
-	return author, since, until, nil
-}
-
-// 获取默认日期范围
-func getDefaultDateRange(since, until string) (string, string) {
-	if since != "" && until != "" {
-		return since, until
+	cfg, err := resolveOptions(opts)
+	if opts.CheckEnv {
+		checks := []stat.EnvCheck{stat.CheckGit(), stat.CheckConfig(cfg, err)}
+		repo := repoDir(opts, cfg)
+		return stat.PrintEnvChecks(os.Stdout, append(checks, stat.CheckRepo(opts.GitRunner(repo), repo)))
 	}
-
-	now := time.Now()
-	year, month, day := now.Date()
-	location := now.Location()
-
-	var periodStart, periodEnd time.Time
-
-	if day <= 15 {
-		// 当前在上半月，则统计上月16号到月底的数据
-		firstOfThisMonth := time.Date(year, month, 1, 0, 0, 0, 0, location)
-		lastMonth := firstOfThisMonth.AddDate(0, -1, 0)
-		lastMonthYear, lastMonthMonth, _ := lastMonth.Date()
-
-		periodStart = time.Date(lastMonthYear, lastMonthMonth, 16, 0, 0, 0, 0, location)
-		firstOfNextMonth := time.Date(lastMonthYear, lastMonthMonth+1, 1, 0, 0, 0, 0, location)
-		periodEnd = firstOfNextMonth.AddDate(0, 0, -1)
-	} else {
-		// 当前在下半月，则统计本月1号到15号的数据
-		periodStart = time.Date(year, month, 1, 0, 0, 0, 0, location)
-		periodEnd = time.Date(year, month, 15, 0, 0, 0, 0, location)
+	if err != nil {
+		return err
 	}
 
-	return periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02")
-}
-
-// 运行 Git 命令
-func runGitCommand(author, since, until string) (string, error) {
-	cmdArgs := []string{
-		"log",
-		"--all",
-		"--since=" + since,
-		"--until=" + until,
-		"--pretty=format:%H '%an' %ad %s %b",
-		"--numstat",
-		"--date=format:%Y-%m-%d %H:%M:%S",
-		"--no-merges",
+	ctx, stop := stat.SignalContext()
+	defer stop()
+	opts.Context = ctx
+	git := opts.GitRunner(opts.Repo)
+	audit := stat.NewAuditEntry(git, "AIG_person", args, &opts.RunOptions)
+	analyzer := opts.NewAnalyzer(git)
+	commits, err := analyzer.Analyze(stat.LogQuery{Since: opts.Since, Until: opts.Until, RevRange: opts.RevRange, Author: opts.Author, Grep: opts.Grep, InvertGrep: opts.InvertGrep})
+
+	stats := &stat.PeriodStats{}
+	var sumAIGRatio float64
+	for i := range commits {
+		stats.Add(&commits[i])
+		if commits[i].AIGPolicy == "" {
+			sumAIGRatio += commits[i].AIGRatio
+		}
 	}
-
-	if author != "" {
-		cmdArgs = append(cmdArgs, "--author="+author)
+	// 部分步骤失败时仍输出已有的结果，失败的步骤记录在运行信息中
+	partial := stat.IsPartial(err)
+	if err != nil {
+		audit.Error = err.Error()
 	}
-
-	cmd := exec.Command("git", cmdArgs...)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("执行 git 命令时出错: %v", err)
+	if err == nil || partial {
+		audit.Commits = len(commits)
+		audit.Digest = stat.ReportDigest(stats)
 	}
-	return out.String(), nil
-}
-
-// 分析提交信息
-func analyzeCommits(commits []string) map[string]int {
-	stats := make(map[string]int)
-	aigRegex := regexp.MustCompile(aigPattern)
-	fixRegex := regexp.MustCompile(fixPattern)
-
-	includeExts := strings.Split(includeFileExts, ",")
-	excludeExts := strings.Split(excludeFileExts, ",")
-
-	for _, commit := range commits {
-		if commit == "" {
-			continue
-		}
-
-		commitStats := processCommit(commit, aigRegex, fixRegex, includeExts, excludeExts)
-		updateStats(stats, commitStats)
+	if auditErr := stat.AppendAudit(opts.AuditLog, audit); auditErr != nil {
+		fmt.Fprintln(os.Stderr, auditErr)
 	}
-
-	return stats
-}
-
-// 处理单个提交
-func processCommit(commit string, aigRegex, fixRegex *regexp.Regexp, includeExts, excludeExts []string) CommitStats {
-	lines := strings.Split(commit, "\n")
-	if len(lines) == 0 {
-		return CommitStats{}
+	if err != nil && !partial {
+		return err
 	}
-
-	// 获取提交的第一行作为基本信息
-	firstLine := lines[0]
-
-	// 解析提交的基本信息（ID、作者、时间）
-	commitRegex := regexp.MustCompile(commitPattern)
-	matches := commitRegex.FindStringSubmatch(firstLine)
-	if len(matches) < 5 {
-		return CommitStats{}
+	for _, warning := range analyzer.Warnings {
+		fmt.Fprintf(os.Stderr, "警告：%s\n", warning)
+	}
+	if partial {
+		fmt.Fprintln(os.Stderr, err)
 	}
 
-	commitID := matches[1]
-	author := matches[2]
-	commitTime := matches[3]
-
-	// 查找文件变更列表的起始位置
-	fileChangeStartIdx := 1
-	var messageLines []string
-	messageLines = append(messageLines, matches[4])
-
-	// 遍历每一行，直到找到文件变更记录
-	for i := 1; i < len(lines); i++ {
-		line := lines[i]
-		if line == "" {
-			continue
+	meta := analyzer.Metadata()
+	meta.Filters.Author = opts.Author
+	meta.Filters.Grep = opts.Grep
+	meta.Filters.InvertGrep = opts.InvertGrep
+	meta.ConfigFiles = cfg.Sources
+	meta.Filters.MinSample = opts.MinSample
+	meta.Filters.MinSampleLines = opts.MinSampleLines
+	meta.Lang, meta.KLoC = opts.Lang, opts.KLoC
+	meta.UsageLogs = opts.UsageLogs
+
+	authorStats := stats.AuthorStats(opts.Author)
+	authorStats.SumAIGRatio = sumAIGRatio
+	authorStats.FixSeverities = stat.CountFixSeverities(commits)
+	if len(opts.UsageLogs) > 0 {
+		records, err := stat.LoadUsage(opts.UsageLogs)
+		if err != nil {
+			return err
 		}
-
-		// 检查是否是文件变更记录（通过判断行的格式）
-		if isFileChangeLine(line) {
-			fileChangeStartIdx = i
-			break
+		// 个人统计按作者过滤，以提交中出现的邮箱对应使用记录
+		if len(commits) > 0 {
+			authorStats.Email = commits[0].Email
 		}
-		messageLines = append(messageLines, line)
+		stat.ApplyUsage([]*stat.AuthorStats{authorStats}, records, opts.Since, opts.Until, opts.UsageThreshold)
 	}
 
-	// 合并提交消息
-	message := strings.Join(messageLines, "\n")
-
-	// 获取文件变更列表
-	fileChanges := lines[fileChangeStartIdx:]
-
-	// 打印提交信息
-	fmt.Printf("\n提交详情:\n")
-	fmt.Printf("  提交ID: %s\n", commitID)
-	fmt.Printf("  作者: %s\n", author)
-	fmt.Printf("  时间: %s\n", commitTime)
-	fmt.Printf("  消息:\n")
-	// 打印多行消息，每行前面加缩进
-	for _, line := range strings.Split(message, "\n") {
-		if strings.TrimSpace(line) != "" {
-			fmt.Printf("    %s\n", line)
-		}
+	report := &stat.Report{
+		Meta:     meta,
+		Since:    opts.Since,
+		Until:    opts.Until,
+		RevRange: opts.RevRange,
+		Authors:  []*stat.AuthorStats{authorStats},
+		Commits:  commits,
 	}
 
-	stats := CommitStats{
-		AIGRatio: extractAIGRatio(aigRegex, message),
-		IsFix:    fixRegex.MatchString(firstLine),
+	// 开发者报告的 JSON、YAML 只包含该开发者的数据，CSV 可以改为逐提交明细
+	personal := func() *personReport {
+		r := &personReport{meta, opts.Author, opts.Since, opts.Until, opts.RevRange, stats, nil, commits}
+		if opts.Detail {
+			r.Detail = stat.CommitDetails(commits)
+		}
+		return r
 	}
-
-	fmt.Printf("  AI贡献率: %.2f%%\n", stats.AIGRatio*100)
-	fmt.Printf("  是否修复提交: %v\n", stats.IsFix)
-	fmt.Printf("  变更文件:\n")
-
-	for _, change := range fileChanges {
-		if change == "" {
-			continue
+	stat.RegisterRenderer(stat.FormatJSON, func(w io.Writer, report *stat.Report, _ *stat.RunOptions) error {
+		return stat.WriteJSON(w, personal())
+	})
+	stat.RegisterRenderer(stat.FormatYAML, func(w io.Writer, report *stat.Report, _ *stat.RunOptions) error {
+		return stat.WriteYAML(w, personal())
+	})
+	stat.RegisterRenderer(stat.FormatCSV, func(w io.Writer, report *stat.Report, _ *stat.RunOptions) error {
+		if opts.Detail {
+			return stat.WriteDetailCSV(w, report)
 		}
-
-		added, deleted, fileName := parseFileChange(change)
-		if !isValidFile(fileName, includeExts, excludeExts) {
-			fmt.Printf("    [跳过] %s (不符合统计条件)\n", fileName)
-			continue
+		return stat.WriteCSV(w, report)
+	})
+	stat.RegisterRenderer(stat.FormatText, func(w io.Writer, report *stat.Report, _ *stat.RunOptions) error {
+		for i := range commits {
+			stat.PrintCommit(w, &commits[i])
 		}
-
-		fmt.Printf("    - %s (添加: %d, 删除: %d)\n", fileName, added, deleted)
-		stats.AddedLines += added
-		stats.DeletedLines += deleted
+		printStatistics(w, report, stats)
+		return nil
+	})
+	if err := opts.WriteSinks(os.Stdout, opts.Author, report); err != nil {
+		return err
 	}
-
-	aiAddedLines := int(math.Round(float64(stats.AddedLines) * stats.AIGRatio))
-	aiDeletedLines := int(math.Round(float64(stats.DeletedLines) * stats.AIGRatio))
-	fmt.Printf("  本次提交总计:\n")
-	fmt.Printf("    总添加行数: %d\n", stats.AddedLines)
-	fmt.Printf("    总删除行数: %d\n", stats.DeletedLines)
-	fmt.Printf("    AI贡献添加行数: %d\n", aiAddedLines)
-	fmt.Printf("    AI贡献删除行数: %d\n", aiDeletedLines)
-	fmt.Printf("  %s\n", strings.Repeat("-", 80))
-
-	return stats
-}
-
-// 判断是否为文件变更记录行
-func isFileChangeLine(line string) bool {
-	parts := strings.Fields(line)
-	if len(parts) < 2 {
-		return false
-	}
-
-	// 检查前两个字段是否都是数字或 "-"
-	for _, field := range parts[:2] {
-		if field != "-" {
-			_, err := strconv.Atoi(field)
-			if err != nil {
-				return false
-			}
-		}
+	if meta.Interrupted {
+		// 被信号中断时以 130 退出，便于脚本区分部分结果
+		os.Exit(130)
 	}
-	return true
+	return nil
 }
 
-// 解析文件变更信息
-func parseFileChange(change string) (added, deleted int, fileName string) {
-	parts := strings.Fields(change)
-	if len(parts) < 3 {
-		return 0, 0, ""
+// 要分析的仓库目录：命令行、配置文件或环境变量，默认当前目录
+func repoDir(opts *Options, cfg *stat.Config) string {
+	if opts.Repo != "" {
+		return opts.Repo
 	}
-
-	added, _ = strconv.Atoi(parts[0])
-	deleted, _ = strconv.Atoi(parts[1])
-	if len(parts) > 3 {
-		fileName = strings.Join(parts[2:], "")
-	} else {
-		fileName = parts[2]
+	if cfg != nil && cfg.Repo != "" {
+		return cfg.Repo
 	}
-
-	return added, deleted, fileName
+	return "."
 }
 
-// 检查文件是否应该被统计
-func isValidFile(fileName string, includeExts, excludeExts []string) bool {
-	ext := filepath.Ext(fileName)
-	for _, excludeExt := range excludeExts {
-		if ext == excludeExt {
-			return false
-		}
+// 加载配置文件、环境变量及仓库自己的配置，补全并校验选项
+func resolveOptions(opts *Options) (*stat.Config, error) {
+	cfg, err := stat.LoadConfig(opts.ConfigPath)
+	if err != nil {
+		return nil, err
 	}
-	for _, includeExt := range includeExts {
-		if ext == includeExt {
-			return true
-		}
+	cfg.ApplyEnv(os.LookupEnv)
+	if err := cfg.ApplyRepoConfig(repoDir(opts, cfg)); err != nil {
+		return nil, err
 	}
-	return false
-}
-
-// 更新统计信息
-func updateStats(stats map[string]int, commitStats CommitStats) {
-	stats["totalAddedLines"] += commitStats.AddedLines
-	stats["totalDeletedLines"] += commitStats.DeletedLines
-
-	aiAddedLines := int(math.Round(float64(commitStats.AddedLines) * commitStats.AIGRatio))
-	aiDeletedLines := int(math.Round(float64(commitStats.DeletedLines) * commitStats.AIGRatio))
-
-	stats["totalAIAddedLines"] += aiAddedLines
-	stats["totalAIDeletedLines"] += aiDeletedLines
-
-	if commitStats.IsFix {
-		stats["fixCount"]++
-		if commitStats.AIGRatio > 0 {
-			stats["fixAndAIGCount"]++
-		}
+	if err := opts.Resolve(cfg, time.Now()); err != nil {
+		return nil, err
 	}
+	return cfg, nil
 }
 
-// 提取 AIG 比例
-func extractAIGRatio(re *regexp.Regexp, commit string) float64 {
-	matches := re.FindStringSubmatch(commit)
-	if len(matches) > 2 {
-		ratio, err := strconv.ParseFloat(matches[2], 64)
-		if err != nil || ratio < 0 {
-			return 0
-		}
-		return ratio
-	}
-	return 0
+// 开发者报告的 JSON、YAML 结构
+type personReport struct {
+	Meta     *stat.Metadata      `json:"meta"`
+	Author   string              `json:"author"`
+	Since    string              `json:"since"`
+	Until    string              `json:"until"`
+	RevRange string              `json:"rev_range,omitempty"`
+	Stats    *stat.PeriodStats   `json:"stats"`
+	Detail   []stat.CommitDetail `json:"detail,omitempty"`
+	Commits  []stat.CommitStats  `json:"commits"`
 }
 
-// 分割提交信息
-func splitCommits(output string) []string {
-	var commits []string
-	lines := strings.Split(output, "\n")
-	var currentCommit strings.Builder
+// 解析命令行参数
+func parseCommandLineArgs(args []string) (*Options, error) {
+	opts := &Options{}
+	fs := flag.NewFlagSet("AIG_person", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	fs.BoolVar(&opts.Version, "version", false, "输出版本及构建信息")
+	fs.BoolVar(&opts.CheckEnv, "check-env", false, "只检查 git 是否可用、仓库是否有效及配置文件和参数是否正确，不做统计")
+	fs.BoolVar(&opts.Detail, "detail", false, "CSV 报告改为逐提交明细(哈希、时间、标题、文件、行数、AIG、修复标记，末行为合计)，JSON 报告增加 detail 字段")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_person.exe [选项] 作者 [开始日期] [结束日期]\n")
+		fs.PrintDefaults()
+	}
 
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		s := strings.Fields(line)
-		if len(s) > 0 && len(s[0]) == 40 && currentCommit.Len() > 0 {
-			commits = append(commits, currentCommit.String())
-			currentCommit.Reset()
-		}
-		if currentCommit.Len() > 0 {
-			currentCommit.WriteByte('\n')
-		}
-		currentCommit.WriteString(line)
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return nil, err
 	}
 
-	if currentCommit.Len() > 0 {
-		commits = append(commits, currentCommit.String())
+	if len(positional) > 0 {
+		opts.Author = positional[0]
+	}
+	if len(positional) > 1 {
+		opts.Since = positional[1]
 	}
-	return commits
+	if len(positional) > 2 {
+		opts.Until = positional[2]
+	}
+	return opts, nil
 }
 
 // 打印统计结果
-func printStatistics(author, since, until string, stats map[string]int) {
-	// 计算占比
-	var addedRatio, deletedRatio, aiBugContribution float64
+func printStatistics(w io.Writer, report *stat.Report, stats *stat.PeriodStats) {
+	// 计算占比，样本不足时显示样本量
+	minSample, minLines := report.Meta.Filters.MinSample, report.Meta.Filters.MinSampleLines
+	nf := report.Meta.NumberFormat()
+	// 策略标记(n/a、exempt)的提交不计入分母
+	addedLines := stats.TotalAddedLines - stats.PolicyAddedLines
+	deletedLines := stats.TotalDeletedLines - stats.PolicyDeletedLines
+	fixCount := stats.FixCount - stats.PolicyFixCount
+	addedRatio := stat.FormatSampleRatio(stats.TotalAIAddedLines, addedLines, minLines)
+	deletedRatio := stat.FormatSampleRatio(stats.TotalAIDeletedLines, deletedLines, minLines)
+	aiBugContribution := stat.FormatSampleRatio(stats.FixAndAIGCount, fixCount, minSample)
+	if _, ok := stat.SampleRatio(stats.FixAndAIGCount, fixCount, minSample); ok {
+		aiBugContribution += " (" + stat.FixRatioNote(stats.FixAndAIGCount, fixCount) + ")"
+	}
 
-	if stats["totalAddedLines"] > 0 {
-		addedRatio = float64(stats["totalAIAddedLines"]) / float64(stats["totalAddedLines"]) * 100
+	fmt.Fprintf(w, "\n%s\n", strings.Repeat("=", 80))
+	fmt.Fprintf(w, "统计结果汇总:\n")
+	fmt.Fprintf(w, "%s\n", strings.Repeat("-", 80))
+	fmt.Fprintf(w, "  分析范围:\n")
+	fmt.Fprintf(w, "    作者: %s\n", report.Authors[0].Name)
+	if report.Since != "" {
+		fmt.Fprintf(w, "    开始时间: %s\n", report.Since)
 	}
-	if stats["totalDeletedLines"] > 0 {
-		deletedRatio = float64(stats["totalAIDeletedLines"]) / float64(stats["totalDeletedLines"]) * 100
+	if report.Until != "" {
+		fmt.Fprintf(w, "    结束时间: %s\n", report.Until)
 	}
-	if stats["fixCount"] > 0 {
-		aiBugContribution = float64(stats["fixAndAIGCount"]) / float64(stats["fixCount"]) * 100
+	if report.RevRange != "" {
+		fmt.Fprintf(w, "    提交范围: %s\n", report.RevRange)
 	}
-
-	fmt.Printf("\n%s\n", strings.Repeat("=", 80))
-	fmt.Printf("统计结果汇总:\n")
-	fmt.Printf("%s\n", strings.Repeat("-", 80))
-	fmt.Printf("  分析范围:\n")
-	fmt.Printf("    作者: %s\n", author)
-	fmt.Printf("    开始时间: %s\n", since)
-	fmt.Printf("    结束时间: %s\n", until)
-	fmt.Printf("\n  代码变更统计:\n")
-	fmt.Printf("    总代码添加: %d 行\n", stats["totalAddedLines"])
-	fmt.Printf("    总代码删除: %d 行\n", stats["totalDeletedLines"])
-	fmt.Printf("    AI贡献添加: %d 行 (%.2f%%)\n", stats["totalAIAddedLines"], addedRatio)
-	fmt.Printf("    AI贡献删除: %d 行 (%.2f%%)\n", stats["totalAIDeletedLines"], deletedRatio)
-	fmt.Printf("\n  Bug修复统计:\n")
-	fmt.Printf("    总修复提交: %d 次\n", stats["fixCount"])
-	fmt.Printf("    AI参与修复: %d 次\n", stats["fixAndAIGCount"])
-	fmt.Printf("    AI修复贡献率: %.2f%%\n", aiBugContribution)
-	fmt.Printf("%s\n", strings.Repeat("=", 80))
+	fmt.Fprintf(w, "\n  运行信息:\n")
+	for _, line := range report.Meta.Lines() {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+	if stats.EstimatedCommits > 0 {
+		fmt.Fprintf(w, "    LLM 估算: %s 次提交\n", nf.Int(stats.EstimatedCommits))
+	}
+	fmt.Fprintf(w, "\n  代码变更统计:\n")
+	if !report.Meta.Fast {
+		fmt.Fprintf(w, "    总代码添加: %s\n", nf.Lines(stats.TotalAddedLines))
+		fmt.Fprintf(w, "    总代码删除: %s\n", nf.Lines(stats.TotalDeletedLines))
+		fmt.Fprintf(w, "    AI贡献添加: %s (%s)\n", nf.Lines(stats.TotalAIAddedLines), addedRatio)
+		fmt.Fprintf(w, "    AI贡献删除: %s (%s)\n", nf.Lines(stats.TotalAIDeletedLines), deletedRatio)
+	}
+	fmt.Fprintf(w, "    按提交平均 AI 占比: %s (不按行数加权)\n", stat.FormatSampleMean(report.Authors[0].CommitAvgRatio(), report.Authors[0].RatioCommits(), minSample))
+	if stats.NACommits+stats.ExemptCommits > 0 {
+		fmt.Fprintf(w, "    标记 n/a: %s 次，标记 exempt: %s 次 (共添加 %s，不计入各比例)\n", nf.Int(stats.NACommits), nf.Int(stats.ExemptCommits), nf.Lines(stats.PolicyAddedLines))
+	}
+	if report.Meta.Heuristic {
+		fmt.Fprintf(w, "    启发式估算AI添加: %s (%.2f%%，实验性)\n", nf.Lines(stats.HeuristicAILines), report.Authors[0].HeuristicRatio())
+	}
+	fmt.Fprintf(w, "\n  Bug修复统计:\n")
+	fmt.Fprintf(w, "    总修复提交: %s 次\n", nf.Int(stats.FixCount))
+	fmt.Fprintf(w, "    AI参与修复: %s 次\n", nf.Int(stats.FixAndAIGCount))
+	fmt.Fprintf(w, "    AI修复贡献率: %s\n", aiBugContribution)
+	if lines := stat.FixSeverityLines(report.Authors[0].FixSeverities, report.Meta.Filters.FixSeverities, nf); len(lines) > 0 {
+		fmt.Fprintf(w, "    按严重级别:\n")
+		for _, line := range lines {
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+	}
+	if author := report.Authors[0]; author.ToolUsage {
+		fmt.Fprintf(w, "\n  工具使用记录:\n")
+		fmt.Fprintf(w, "    工具采纳行数: %s (%.2f%%)\n", nf.Lines(author.ToolAcceptedLines), author.ToolRatio())
+		if author.UsageDiscrepancy {
+			fmt.Fprintf(w, "    [差异较大] 自报 AI 占比 %.2f%% 与工具采纳占比 %.2f%% 不符\n", author.AddedRatio(), author.ToolRatio())
+		}
+	}
+	fmt.Fprintf(w, "%s\n", strings.Repeat("=", 80))
 }
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+
+	"AIStat/stat"
+)
+
+// apiAuth 校验 serve API 的访问令牌，并确定请求可以访问的项目仓库
+// 未配置项目、管理员令牌、OIDC 和角色时不校验，可以访问全部仓库
+type apiAuth struct {
+	projects []stat.Project
+	admin    string
+	oidc     *stat.OIDCVerifier
+	// 认证代理设置的开发者邮箱请求头，为空时不读取
+	emailHeader string
+	// 认证代理的地址，只信任来自这些地址的邮箱请求头
+	proxies []netip.Prefix
+	// 开发者邮箱(小写)到角色的映射，为空时不按角色限制个人数据
+	roles map[string]stat.RoleBinding
+	// 名单中各团队的成员邮箱(小写)及各成员所属的团队
+	teams  map[string][]string
+	teamOf map[string]string
+}
+
+// apiAccess 一个请求可以访问的仓库及开发者
+type apiAccess struct {
+	all   bool
+	repos map[string]bool
+	// 管理员令牌、admin 角色或未启用校验，可以查看任何开发者的数据
+	admin bool
+	// 请求者本人的邮箱，按开发者身份(OIDC 令牌或认证代理的请求头)访问时才有
+	email string
+	// 启用角色后可以查看其提交的开发者邮箱(小写)，为 nil 时不限制
+	people map[string]bool
+	// 启用角色后项目令牌只能查询汇总数据，不能按开发者过滤或分组
+	aggregateOnly bool
+}
+
+type accessKey struct{}
+
+var (
+	errMissingToken = errors.New("错误：缺少访问令牌")
+	errInvalidToken = errors.New("错误：访问令牌无效")
+)
+
+func newAPIAuth(cfg *stat.Config) (*apiAuth, error) {
+	if err := stat.ValidateProjects(cfg.Projects, cfg.OIDC); err != nil {
+		return nil, err
+	}
+	if err := stat.ValidateRoles(cfg.Roles, cfg.Roster); err != nil {
+		return nil, err
+	}
+	auth := &apiAuth{projects: cfg.Projects, admin: os.Getenv(stat.EnvAdminToken), emailHeader: cfg.MeEmailHeader}
+	if auth.emailHeader != "" && len(cfg.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("错误：配置 me_email_header 时需要用 trusted_proxies 指定认证代理的地址")
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		prefix, err := parseProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		auth.proxies = append(auth.proxies, prefix)
+	}
+	if cfg.OIDC != nil {
+		auth.oidc = &stat.OIDCVerifier{Config: *cfg.OIDC}
+	}
+	if len(cfg.Roles) > 0 {
+		auth.roles = make(map[string]stat.RoleBinding)
+		for _, b := range cfg.Roles {
+			auth.roles[strings.ToLower(strings.TrimSpace(b.Email))] = b
+		}
+		auth.teams = make(map[string][]string)
+		auth.teamOf = make(map[string]string)
+		for _, m := range cfg.Roster {
+			if m.Team != "" {
+				email := strings.ToLower(m.Email)
+				auth.teams[m.Team] = append(auth.teams[m.Team], email)
+				auth.teamOf[email] = m.Team
+			}
+		}
+	}
+	return auth, nil
+}
+
+func (a *apiAuth) enabled() bool {
+	return len(a.projects) > 0 || a.admin != "" || a.oidc != nil || a.roles != nil
+}
+
+// 校验令牌后把可访问的仓库放入请求上下文，令牌无效时返回 401，没有任何项目权限时返回 403
+func (a *apiAuth) protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access, err := a.access(r)
+		if errors.Is(err, errMissingToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="aistat"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="aistat", error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !access.all && len(access.repos) == 0 {
+			http.Error(w, "错误：没有可以访问的项目", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), accessKey{}, access)))
+	})
+}
+
+// 确定请求的访问权限：未启用校验时可以访问全部数据；
+// 否则依次尝试认证代理设置的邮箱请求头和 Authorization 头中的令牌
+func (a *apiAuth) access(r *http.Request) (*apiAccess, error) {
+	email := a.proxyEmail(r)
+	if !a.enabled() {
+		return &apiAccess{all: true, admin: true, email: email}, nil
+	}
+	if email != "" {
+		// 未配置角色时请求头只用于 /me 识别本人，不能访问仓库数据
+		access := &apiAccess{repos: make(map[string]bool), email: email}
+		if a.roles != nil {
+			access.all = true
+			a.applyRole(access)
+		}
+		return access, nil
+	}
+	token := requestToken(r)
+	if token == "" {
+		return nil, errMissingToken
+	}
+	return a.authorize(token)
+}
+
+// 认证代理设置的开发者邮箱，请求不是来自可信的代理地址时忽略该请求头，
+// 避免客户端直接伪造请求头冒充其他开发者
+func (a *apiAuth) proxyEmail(r *http.Request) string {
+	if a.emailHeader == "" {
+		return ""
+	}
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return ""
+	}
+	addr := addrPort.Addr().Unmap()
+	for _, proxy := range a.proxies {
+		if proxy.Contains(addr) {
+			return strings.TrimSpace(r.Header.Get(a.emailHeader))
+		}
+	}
+	return ""
+}
+
+// 解析 trusted_proxies 中的一项，单个 IP 视为只包含该地址的网段
+func parseProxy(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("错误：trusted_proxies 中的 '%s' 不是有效的 IP 或 CIDR", s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// 按令牌确定可访问的仓库：管理员令牌、项目令牌，最后尝试作为 OIDC 令牌校验
+func (a *apiAuth) authorize(token string) (*apiAccess, error) {
+	if a.admin != "" && tokenEqual(token, a.admin) {
+		return &apiAccess{all: true, admin: true}, nil
+	}
+	access := &apiAccess{repos: make(map[string]bool)}
+	for i := range a.projects {
+		for _, t := range a.projects[i].Tokens() {
+			if tokenEqual(token, t) {
+				access.add(&a.projects[i])
+			}
+		}
+	}
+	if len(access.repos) > 0 {
+		// 项目令牌不代表个人，启用角色后只能查询汇总数据
+		access.aggregateOnly = a.roles != nil
+		return access, nil
+	}
+	if a.oidc == nil {
+		return nil, errInvalidToken
+	}
+
+	claims, err := a.oidc.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]bool)
+	for _, g := range claims.Groups {
+		groups[g] = true
+	}
+	for i := range a.projects {
+		for _, g := range a.projects[i].OIDCGroups {
+			if groups[g] {
+				access.add(&a.projects[i])
+				break
+			}
+		}
+	}
+	access.email = claims.Email
+	if a.roles != nil {
+		if access.email == "" {
+			return nil, errors.New("错误：令牌中没有邮箱，无法确定角色")
+		}
+		// 启用角色后按开发者限制数据，不再按项目限制仓库
+		access.all = true
+		a.applyRole(access)
+	}
+	return access, nil
+}
+
+// 按请求者的角色限制可以查看的开发者：admin 不限制，lead 为所负责团队的成员及本人，
+// 其他(含未配置角色)只有本人
+func (a *apiAuth) applyRole(access *apiAccess) {
+	email := strings.ToLower(access.email)
+	binding := a.roles[email]
+	switch binding.Role {
+	case stat.RoleAdmin:
+		access.all, access.admin = true, true
+		return
+	case stat.RoleLead:
+		teams := binding.Teams
+		if len(teams) == 0 {
+			teams = []string{a.teamOf[email]}
+		}
+		access.people = map[string]bool{email: true}
+		for _, team := range teams {
+			for _, member := range a.teams[team] {
+				access.people[member] = true
+			}
+		}
+	default:
+		access.people = map[string]bool{email: true}
+	}
+}
+
+// 允许访问项目的全部仓库
+func (a *apiAccess) add(p *stat.Project) {
+	for _, repo := range p.Repos {
+		a.repos[repo] = true
+	}
+}
+
+func (a *apiAccess) allows(repo string) bool {
+	return a.all || a.repos[repo]
+}
+
+// 是否可以查看该开发者的个人数据：本人，或按角色可以查看的开发者
+func (a *apiAccess) seesPerson(email string) bool {
+	email = strings.ToLower(email)
+	if a.admin || (a.email != "" && strings.ToLower(a.email) == email) {
+		return true
+	}
+	return a.people[email]
+}
+
+// 是否可以查看该提交，启用角色后只能查看可以查看的开发者的提交
+func (a *apiAccess) seesCommit(c *stat.CommitStats) bool {
+	return a.people == nil || a.people[strings.ToLower(c.Email)]
+}
+
+// 不校验令牌的接口，可以读取所有仓库
+func publicAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), accessKey{}, &apiAccess{all: true})))
+	})
+}
+
+// 请求上下文中的访问权限，未经过校验的请求没有任何权限
+func requestAccess(r *http.Request) *apiAccess {
+	if access, ok := r.Context().Value(accessKey{}).(*apiAccess); ok {
+		return access
+	}
+	return &apiAccess{}
+}
+
+// 从 Authorization 头读取令牌，支持 Bearer 和 Basic(密码为令牌，便于 Grafana 数据源配置)
+func requestToken(r *http.Request) string {
+	if _, password, ok := r.BasicAuth(); ok {
+		return password
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// 以固定时间比较令牌，避免通过响应时间猜测令牌
+func tokenEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"AIStat/stat"
+)
+
+// 生成统计周期内 AI 贡献占比的 SVG 徽章，供仓库 README 显示
+func runBadge(args []string) error {
+	opts := &Options{}
+	fs := flag.NewFlagSet("AIG_repo badge", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	metric := fs.String("metric", stat.DefaultBadgeMetric, "徽章指标: ai-ratio(AI 添加占比)、fix-ratio(AI 修复贡献率)、commit-ratio(按提交平均 AI 占比)、tagged-ratio(AIG 标记率)等")
+	label := fs.String("label", "", "徽章左侧的文字 (默认为指标名称)")
+	out := fs.String("out", "", "SVG 文件路径 (默认输出到标准输出)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe badge [选项] [开始日期] [结束日期]\n")
+		fs.PrintDefaults()
+	}
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		opts.Since = positional[0]
+	}
+	if len(positional) > 1 {
+		opts.Until = positional[1]
+	}
+	if _, err := resolveOptions(opts); err != nil {
+		return err
+	}
+	name, err := stat.BadgeMetric(*metric)
+	if err != nil {
+		return err
+	}
+
+	git := opts.GitRunner(opts.Repo)
+	report, err := auditReport("AIG_repo badge", args, git, opts, func() (*stat.Report, error) {
+		analyzer := opts.NewAnalyzer(git)
+		commits, err := analyzer.Analyze(stat.LogQuery{Since: opts.Since, Until: opts.Until, RevRange: opts.RevRange, Grep: opts.Grep, InvertGrep: opts.InvertGrep})
+		if err != nil && !stat.IsPartial(err) {
+			return nil, err
+		}
+		return &stat.Report{Meta: analyzer.Metadata(), Since: opts.Since, Until: opts.Until, RevRange: opts.RevRange, Commits: commits}, nil
+	})
+	if err != nil {
+		return err
+	}
+	value := stat.BadgeValue(name, report.Commits)
+	if *out == "" {
+		return stat.WriteBadge(os.Stdout, name, *label, value)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("错误：创建徽章文件 '%s' 失败: %v", *out, err)
+	}
+	if err := stat.WriteBadge(f, name, *label, value); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// 按已保存的运行记录生成最近 days 天(默认 30 天)的徽章，参数 repo 为空时汇总所有可以访问的仓库
+func (s *grafanaServer) handleBadge(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = stat.DefaultBadgeMetric
+	}
+	name, err := stat.BadgeMetric(metric)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	days := stat.DefaultBadgeDays
+	if v := query.Get("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days <= 0 {
+			http.Error(w, "错误：days 必须是正整数", http.StatusBadRequest)
+			return
+		}
+	}
+	repo := query.Get("repo")
+	access := requestAccess(r)
+	if repo != "" && !access.allows(repo) {
+		http.Error(w, "错误：没有访问该仓库的权限", http.StatusForbidden)
+		return
+	}
+
+	to := time.Now()
+	commits, _, err := s.loadCommits(access, repo, to.AddDate(0, 0, -days), to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// loadCommits 首尾多取一天，这里按日期精确筛选
+	from := to.AddDate(0, 0, -days).Format(stat.DateLayout)
+	inRange := commits[:0]
+	for _, c := range commits {
+		if c.Time >= from {
+			inRange = append(inRange, c)
+		}
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "max-age=300")
+	if err := stat.WriteBadge(w, name, query.Get("label"), stat.BadgeValue(name, inRange)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"AIStat/stat"
+)
+
+// 把运行记录存储导出为单个压缩的数据包，供隔离网络中的构建机把数据带到中心实例
+func runExportBundle(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo export-bundle", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径 (默认读取当前目录下的 "+stat.DefaultConfigFile+")")
+	storeDir := fs.String("store", "", "运行记录保存位置 (默认 ~/.aistat/runs)")
+	out := fs.String("out", "", "数据包路径 (默认 aistat-bundle-<日期>.json.gz)")
+	since := fs.String("since", "", "只导出该日期(YYYY-MM-DD)及之后的数据，用于增量导出")
+	var repos []string
+	fs.Func("name", "只导出该仓库(运行记录中的仓库名)，可多次指定 (默认全部仓库)", func(s string) error {
+		repos = append(repos, s)
+		return nil
+	})
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe export-bundle [选项]\n")
+		fs.PrintDefaults()
+	}
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+	if *since != "" {
+		if _, err := time.ParseInLocation(stat.DateLayout, *since, time.Local); err != nil {
+			return fmt.Errorf("错误：日期 '%s' 格式不正确，应为 YYYY-MM-DD", *since)
+		}
+	}
+	path := *out
+	if path == "" {
+		path = "aistat-bundle-" + time.Now().Format(stat.DateLayout) + ".json.gz"
+	}
+
+	store, err := openStore(*configPath, *storeDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	bundle, err := stat.ExportBundle(store, repos, *since)
+	if err != nil {
+		return err
+	}
+	if len(bundle.Repos) == 0 {
+		return fmt.Errorf("错误：%s 中没有可以导出的运行记录", store)
+	}
+	if err := stat.WriteBundle(path, bundle); err != nil {
+		return err
+	}
+	runs, commits, frozen := 0, 0, 0
+	names := make([]string, 0, len(bundle.Repos))
+	for _, r := range bundle.Repos {
+		runs, commits, frozen = runs+len(r.Runs), commits+len(r.Commits), frozen+len(r.Frozen)
+		names = append(names, r.Name)
+	}
+	fmt.Fprintf(os.Stderr, "已导出 %d 个仓库(%s)的运行记录 %d 条，提交 %d 个，冻结记录 %d 条\n",
+		len(names), strings.Join(names, ", "), runs, commits, frozen)
+	fmt.Println(path)
+	return nil
+}
+
+// 把数据包合并到运行记录存储，重复导入同一数据包不会产生重复的记录
+func runImportBundle(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo import-bundle", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径 (默认读取当前目录下的 "+stat.DefaultConfigFile+")")
+	storeDir := fs.String("store", "", "运行记录保存位置 (默认 ~/.aistat/runs)")
+	dryRun := fs.Bool("dry-run", false, "只显示将要导入的数量，不修改存储")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe import-bundle [选项] 数据包...\n")
+		fs.PrintDefaults()
+	}
+	paths, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		fs.Usage()
+		return fmt.Errorf("错误：请指定要导入的数据包")
+	}
+
+	store, err := openStore(*configPath, *storeDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	for _, path := range paths {
+		bundle, err := stat.ReadBundle(path)
+		if err != nil {
+			return err
+		}
+		result, err := stat.ImportBundle(store, bundle, *dryRun)
+		if err != nil {
+			return err
+		}
+		action := "已导入"
+		if *dryRun {
+			action = "将导入"
+		}
+		source := bundle.CreatedAt
+		if bundle.Host != "" {
+			source = bundle.Host + " " + source
+		}
+		fmt.Printf("%s (%s): %s%s\n", path, source, action, result)
+		for _, key := range result.FrozenConflicts {
+			fmt.Fprintf(os.Stderr, "警告：%s 已在 %s 中冻结且内容不同，未导入该冻结记录\n", key, store)
+		}
+	}
+	return nil
+}
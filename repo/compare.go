@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"AIStat/stat"
+)
+
+// 对比两个仓库同一统计周期的 AI 采用情况，如在一个团队试点 AI 工具后与其他团队对照
+func runCompareRepos(args []string) error {
+	opts := &Options{}
+	fs := flag.NewFlagSet("AIG_repo compare-repos", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	var repoA, repoB string
+	fs.StringVar(&repoA, "a", "", "仓库 A 的目录")
+	fs.StringVar(&repoB, "b", "", "仓库 B 的目录")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe compare-repos --a 仓库A --b 仓库B [选项] [开始日期] [结束日期]\n")
+		fs.PrintDefaults()
+	}
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if repoA == "" || repoB == "" {
+		fs.Usage()
+		return errors.New("错误：请通过 --a 和 --b 指定要对比的两个仓库")
+	}
+	if len(positional) > 0 {
+		opts.Since = positional[0]
+	}
+	if len(positional) > 1 {
+		opts.Until = positional[1]
+	}
+	// 每个仓库按各自的 .aistat.yaml 重新解析选项
+	base := *opts
+	if _, err := resolveOptions(opts); err != nil {
+		return err
+	}
+	for _, format := range opts.Formats {
+		if format != stat.FormatText && format != stat.FormatJSON && format != stat.FormatCSV {
+			return fmt.Errorf("错误：compare-repos 不支持输出格式 '%s'，请使用 text、json 或 csv", format)
+		}
+	}
+
+	// 一个仓库分析失败时仍输出另一个仓库的结果，两个都失败才返回错误
+	comparison := &stat.RepoComparison{Since: opts.Since, Until: opts.Until, RevRange: opts.RevRange}
+	failed := 0
+	for _, side := range []struct {
+		dir     string
+		summary *stat.RepoSummary
+	}{{repoA, &comparison.A}, {repoB, &comparison.B}} {
+		sideOpts := base
+		sideOpts.Repo = side.dir
+		cfg, err := resolveOptions(&sideOpts)
+		var report *stat.Report
+		if err == nil {
+			report, err = generateReport("AIG_repo compare-repos", args, sideOpts.GitRunner(side.dir), &sideOpts, cfg)
+		}
+		if err != nil {
+			failed++
+			*side.summary = stat.RepoSummary{Repo: side.dir, Error: strings.TrimPrefix(err.Error(), "错误："), Failed: true}
+			fmt.Fprintf(os.Stderr, "错误：分析仓库 '%s' 失败: %s\n", side.dir, side.summary.Error)
+			continue
+		}
+		*side.summary = stat.SummarizeRepo(report)
+	}
+	if failed == 2 {
+		return errors.New("错误：两个仓库均分析失败")
+	}
+	// 两个目录同名时用目录路径区分
+	if comparison.A.Repo == comparison.B.Repo {
+		comparison.A.Repo, comparison.B.Repo = repoA, repoB
+	}
+
+	paths, err := opts.WriteOutputs(os.Stdout, "compare", func(w io.Writer, format string) error {
+		switch format {
+		case stat.FormatJSON:
+			return stat.WriteJSON(w, comparison)
+		case stat.FormatCSV:
+			return writeComparisonCSV(w, comparison)
+		}
+		printComparison(w, comparison)
+		return nil
+	})
+	for _, path := range paths {
+		fmt.Fprintf(os.Stderr, "报告已写入: %s\n", path)
+	}
+	return err
+}
+
+// 对比表的一行：指标名称及两个仓库的值
+type comparisonRow struct {
+	Metric string
+	A, B   string
+}
+
+// 按显示顺序列出对比指标
+func comparisonRows(c *stat.RepoComparison) []comparisonRow {
+	var rows []comparisonRow
+	add := func(metric string, value func(s *stat.RepoSummary) string) {
+		rows = append(rows, comparisonRow{metric, failedCell(&c.A, value), failedCell(&c.B, value)})
+	}
+	add("贡献者", func(s *stat.RepoSummary) string { return fmt.Sprintf("%d 人", s.Contributors) })
+	add("使用 AI 的开发者", func(s *stat.RepoSummary) string {
+		return fmt.Sprintf("%d 人 (%.2f%%)", s.AIContributors, s.AIContributorRatio())
+	})
+	add("提交次数", func(s *stat.RepoSummary) string { return fmt.Sprintf("%d 次", s.Commits) })
+	add("带 AIG 标记的提交", func(s *stat.RepoSummary) string {
+		return fmt.Sprintf("%d 次 (%.2f%%)", s.TaggedCommits, s.TaggedRatio())
+	})
+	add("标记 AIG>0 的提交", func(s *stat.RepoSummary) string { return fmt.Sprintf("%d 次", s.AICommits) })
+	add("代码添加", func(s *stat.RepoSummary) string { return fmt.Sprintf("%d 行", s.AddedLines) })
+	add("AI 贡献添加", func(s *stat.RepoSummary) string { return fmt.Sprintf("%d 行", s.AIAddedLines) })
+	add("AI 添加占比", func(s *stat.RepoSummary) string { return fmt.Sprintf("%.2f%%", s.AddedRatio) })
+	add("按提交平均 AI 占比", func(s *stat.RepoSummary) string { return fmt.Sprintf("%.2f%%", s.CommitAvgRatio) })
+	add("Bug 修复", func(s *stat.RepoSummary) string { return fmt.Sprintf("%d 次", s.FixCount) })
+	add("AI 修复贡献率", func(s *stat.RepoSummary) string {
+		return fmt.Sprintf("%.2f%% (%d 次)", s.AIFixRatio, s.FixAndAIGCount)
+	})
+	return rows
+}
+
+// 以 Markdown 表格打印两个仓库的对比
+func printComparison(w io.Writer, c *stat.RepoComparison) {
+	fmt.Fprintf(w, "## 仓库对比\n\n")
+	if c.RevRange != "" {
+		fmt.Fprintf(w, "统计范围: %s\n\n", c.RevRange)
+	} else {
+		fmt.Fprintf(w, "统计周期: %s ~ %s\n\n", c.Since, c.Until)
+	}
+	fmt.Fprintf(w, "| 指标 | %s | %s |\n", escapeCell(c.A.Repo), escapeCell(c.B.Repo))
+	fmt.Fprintf(w, "| --- | ---: | ---: |\n")
+	for _, row := range comparisonRows(c) {
+		fmt.Fprintf(w, "| %s | %s | %s |\n", row.Metric, row.A, row.B)
+	}
+	for _, s := range []*stat.RepoSummary{&c.A, &c.B} {
+		if s.Error != "" {
+			fmt.Fprintf(w, "\n%s: %s\n", s.Repo, s.Error)
+		}
+	}
+}
+
+// 分析失败的仓库显示为 -，部分结果照常显示
+func failedCell(s *stat.RepoSummary, value func(s *stat.RepoSummary) string) string {
+	if s.Failed {
+		return "-"
+	}
+	return value(s)
+}
+
+// 以 CSV 输出对比，每个指标一行，数值不带单位
+func writeComparisonCSV(w io.Writer, c *stat.RepoComparison) error {
+	cw := csv.NewWriter(w)
+	ratio := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	records := [][]string{{"metric", c.A.Repo, c.B.Repo}}
+	add := func(metric string, value func(s *stat.RepoSummary) string) {
+		records = append(records, []string{metric, csvCell(&c.A, value), csvCell(&c.B, value)})
+	}
+	add("contributors", func(s *stat.RepoSummary) string { return strconv.Itoa(s.Contributors) })
+	add("ai_contributors", func(s *stat.RepoSummary) string { return strconv.Itoa(s.AIContributors) })
+	add("ai_contributor_ratio", func(s *stat.RepoSummary) string { return ratio(s.AIContributorRatio()) })
+	add("commits", func(s *stat.RepoSummary) string { return strconv.Itoa(s.Commits) })
+	add("tagged_commits", func(s *stat.RepoSummary) string { return strconv.Itoa(s.TaggedCommits) })
+	add("tagged_ratio", func(s *stat.RepoSummary) string { return ratio(s.TaggedRatio()) })
+	add("ai_commits", func(s *stat.RepoSummary) string { return strconv.Itoa(s.AICommits) })
+	add("added_lines", func(s *stat.RepoSummary) string { return strconv.Itoa(s.AddedLines) })
+	add("ai_added_lines", func(s *stat.RepoSummary) string { return strconv.Itoa(s.AIAddedLines) })
+	add("ai_added_ratio", func(s *stat.RepoSummary) string { return ratio(s.AddedRatio) })
+	add("ai_commit_avg_ratio", func(s *stat.RepoSummary) string { return ratio(s.CommitAvgRatio) })
+	add("fix_count", func(s *stat.RepoSummary) string { return strconv.Itoa(s.FixCount) })
+	add("fix_and_aig_count", func(s *stat.RepoSummary) string { return strconv.Itoa(s.FixAndAIGCount) })
+	add("ai_fix_ratio", func(s *stat.RepoSummary) string { return ratio(s.AIFixRatio) })
+	records = append(records, []string{"error", c.A.Error, c.B.Error})
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 分析失败的仓库在 CSV 中为空值
+func csvCell(s *stat.RepoSummary, value func(s *stat.RepoSummary) string) string {
+	if s.Failed {
+		return ""
+	}
+	return value(s)
+}
+
+// 转义 Markdown 表格单元格中的竖线
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"AIStat/stat"
+)
+
+// 立即向名单中的开发者发送个人统计周报，--dry-run 只输出周报内容，便于检查或由 cron 定时调用
+func runDigest(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo digest", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径 (默认读取当前目录下的 "+stat.DefaultConfigFile+")")
+	storeDir := fs.String("store", "", "运行记录保存位置 (默认 ~/.aistat/runs)")
+	email := fs.String("email", "", "只处理该开发者 (默认名单中的全部开发者)")
+	dryRun := fs.Bool("dry-run", false, "只输出周报内容，不发送")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe digest [选项]\n")
+		fs.PrintDefaults()
+	}
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+	cfg, err := stat.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.WeeklyDigest == nil {
+		if !*dryRun {
+			return errors.New("错误：配置文件中没有 weekly_digest，无法发送周报")
+		}
+		cfg.WeeklyDigest = &stat.DigestConfig{}
+	} else if err := cfg.WeeklyDigest.Validate(); err != nil && !*dryRun {
+		return err
+	}
+	members := cfg.Roster
+	if *email != "" {
+		members = nil
+		for _, m := range cfg.Roster {
+			if strings.EqualFold(m.Email, *email) {
+				members = append(members, m)
+			}
+		}
+		if len(members) == 0 {
+			return fmt.Errorf("错误：名单中没有开发者 '%s'", *email)
+		}
+	}
+
+	store, err := openStore(*configPath, *storeDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	var preview io.Writer
+	if *dryRun {
+		preview = os.Stdout
+	}
+	sent, err := sendDigests(store, members, cfg.WeeklyDigest, time.Now(), preview)
+	if err != nil {
+		return err
+	}
+	if !*dryRun {
+		fmt.Fprintf(os.Stderr, "已发送 %d 份周报\n", sent)
+	}
+	return nil
+}
+
+// 向名单中未退订、未离职且最近有提交的开发者发送周报，preview 不为 nil 时只写出周报内容；
+// 单个开发者发送失败时继续处理其他开发者，返回发送的份数及合并的错误
+func sendDigests(store stat.Store, roster []stat.RosterMember, cfg *stat.DigestConfig, now time.Time, preview io.Writer) (int, error) {
+	repos, err := store.Repos()
+	if err != nil {
+		return 0, err
+	}
+	sender := &stat.DigestSender{SMTP: cfg.SMTP, Password: os.Getenv(stat.EnvSMTPPassword), IMURL: cfg.IMURL}
+	today := now.Format(stat.DateLayout)
+	sent := 0
+	var errs []string
+	for _, member := range roster {
+		if member.Email == "" || member.DigestOptOut || (member.Left != "" && member.Left < today) {
+			continue
+		}
+		digest, err := stat.BuildDigest(store, repos, member, cfg, now)
+		if err != nil {
+			return sent, err
+		}
+		if digest == nil {
+			continue
+		}
+		if preview != nil {
+			fmt.Fprintf(preview, "To: %s\nSubject: %s\n\n%s\n", digest.Email, digest.Subject(), digest.Text())
+			sent++
+			continue
+		}
+		if err := sender.Send(digest); err != nil {
+			errs = append(errs, strings.TrimPrefix(err.Error(), "错误："))
+			continue
+		}
+		sent++
+	}
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("错误：%s", strings.Join(errs, "；"))
+	}
+	return sent, nil
+}
+
+// serve 运行期间每周在配置的时间发送周报，收到停止信号时结束
+func scheduleDigests(store stat.Store, roster []stat.RosterMember, cfg *stat.DigestConfig) {
+	for {
+		next := cfg.Next(time.Now())
+		select {
+		case <-interrupt.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		sent, err := sendDigests(store, roster, cfg, time.Now(), nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "警告：发送个人周报失败: %v\n", err)
+		}
+		fmt.Fprintf(os.Stderr, "已发送 %d 份个人周报\n", sent)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureRepo 测试用的 git 仓库，提交的作者、时间固定，哈希在不同机器上一致
+type fixtureRepo struct {
+	t   *testing.T
+	Dir string
+}
+
+// fixtureCommit 一次提交：作者、时间、提交信息，以及写入(内容为空时删除)或重命名的文件
+type fixtureCommit struct {
+	Author, Email string
+	// 带时区的时间，如 2024-05-02T10:00:00+00:00
+	Date    string
+	Message string
+	Files   map[string]string
+	// 旧路径 -> 新路径
+	Renames map[string]string
+}
+
+// 创建空仓库，git 的用户级、系统级配置不影响结果
+func newFixtureRepo(t *testing.T) *fixtureRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("没有安装 git")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("TZ", "UTC")
+	r := &fixtureRepo{t: t, Dir: filepath.Join(t.TempDir(), "fixture")}
+	if err := os.Mkdir(r.Dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r.git(nil, "init", "-q", "-b", "main")
+	return r
+}
+
+// 执行 git 命令，失败时终止测试
+func (r *fixtureRepo) git(env []string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// 写入文件并提交
+func (r *fixtureRepo) Commit(c fixtureCommit) {
+	r.t.Helper()
+	for from, to := range c.Renames {
+		r.git(nil, "mv", from, to)
+	}
+	for name, content := range c.Files {
+		path := filepath.Join(r.Dir, filepath.FromSlash(name))
+		if content == "" {
+			r.git(nil, "rm", "-q", name)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+		r.git(nil, "add", name)
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=" + c.Author, "GIT_AUTHOR_EMAIL=" + c.Email, "GIT_AUTHOR_DATE=" + c.Date,
+		"GIT_COMMITTER_NAME=" + c.Author, "GIT_COMMITTER_EMAIL=" + c.Email, "GIT_COMMITTER_DATE=" + c.Date,
+	}
+	r.git(env, "commit", "-q", "--allow-empty", "-m", c.Message)
+}
+
+// 生成 n 行代码
+func codeLines(prefix string, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString(prefix)
+		b.WriteString(strings.Repeat("x", i%7))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// 标准测试仓库：多个作者、各种 AIG 标记、修复提交、重命名、二进制文件及不统计的文件类型
+func buildStandardFixture(t *testing.T) *fixtureRepo {
+	r := newFixtureRepo(t)
+	r.Commit(fixtureCommit{
+		Author: "Alice", Email: "alice@example.com", Date: "2024-05-02T09:00:00+00:00",
+		Message: "feat(api): add client\n\nAIG: 0.8",
+		Files:   map[string]string{"api/client.go": codeLines("func a", 40), "README.md": "# demo\n"},
+	})
+	r.Commit(fixtureCommit{
+		Author: "Conan O'Brien", Email: "conan@example.com", Date: "2024-05-03T14:30:00+00:00",
+		Message: "fix: handle empty response #12 AIG: 0.5",
+		Files:   map[string]string{"api/client.go": codeLines("func a", 44), "api/errors.go": codeLines("var e", 6)},
+	})
+	r.Commit(fixtureCommit{
+		Author: "Bob", Email: "bob@example.com", Date: "2024-05-06T11:00:00+00:00",
+		Message: "add logo and styles AIG: 0",
+		Files:   map[string]string{"web/logo.png": "\x89PNG\r\n\x1a\n\x00\x00binary", "web/app.css": codeLines(".c", 12)},
+	})
+	r.Commit(fixtureCommit{
+		Author: "Bob", Email: "bob@example.com", Date: "2024-05-07T16:45:00+00:00",
+		Message: "refactor: move client",
+		Renames: map[string]string{"api/client.go": "api/http_client.go"},
+	})
+	r.Commit(fixtureCommit{
+		Author: "Zoë 🚀", Email: "zoe@example.com", Date: "2024-05-09T08:15:00+00:00",
+		Message: "hotfix: retry on timeout\n\nAIG: 1",
+		Files:   map[string]string{"api/retry.go": codeLines("func r", 20), "api/errors.go": ""},
+	})
+	r.Commit(fixtureCommit{
+		Author: "Alice", Email: "alice@example.com", Date: "2024-05-13T10:00:00+00:00",
+		Message: "docs: update proto AIG: n/a",
+		Files:   map[string]string{"proto/api.proto": codeLines("message M", 8)},
+	})
+	return r
+}
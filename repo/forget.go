@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"AIStat/stat"
+)
+
+// 删除或匿名化离职开发者在运行记录存储中的数据
+func runForget(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo forget", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径 (默认读取当前目录下的 "+stat.DefaultConfigFile+")")
+	storeDir := fs.String("store", "", "运行记录保存位置 (默认 ~/.aistat/runs)")
+	email := fs.String("email", "", "开发者邮箱 (必填)")
+	remove := fs.Bool("delete", false, "删除该开发者的提交和个人统计并重新生成汇总，组织汇总和目标按配置文件重新计算 (默认匿名化，汇总不变)")
+	dryRun := fs.Bool("dry-run", false, "只显示将要修改的数量，不修改")
+	format := fs.String("format", stat.FormatText, "输出格式: text, json")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe forget --email 邮箱 [选项]\n")
+		fs.PrintDefaults()
+	}
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+	if *format != stat.FormatText && *format != stat.FormatJSON {
+		return fmt.Errorf("错误：不支持的输出格式 '%s'", *format)
+	}
+	if *email == "" {
+		return fmt.Errorf("错误：请通过 --email 指定开发者邮箱")
+	}
+	forgetter, err := stat.NewForgetter(*email, *remove)
+	if err != nil {
+		return err
+	}
+	cfg, err := stat.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	forgetter.Roster, forgetter.Org, forgetter.Targets = cfg.Roster, cfg.Org, cfg.Targets
+
+	store, err := openStore(*configPath, *storeDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	result, err := store.Forget(forgetter, *dryRun)
+	if err != nil {
+		return err
+	}
+	if *format == stat.FormatJSON {
+		return stat.WriteJSON(os.Stdout, result)
+	}
+	action := "已匿名化"
+	if *remove {
+		action = "已删除"
+	}
+	if *dryRun {
+		action = "将" + action[len("已"):]
+	}
+	fmt.Printf("%s %s %s\n", store, action, result)
+	if result.Frozen > 0 && !*dryRun {
+		fmt.Fprintln(os.Stderr, "提示：冻结记录已改写，之后核对这些统计周期时该开发者的提交会显示为差异")
+	}
+	return nil
+}
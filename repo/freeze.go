@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"AIStat/stat"
+)
+
+// 冻结已发布的统计周期：保存结果及校验和，之后对同一周期的统计结果变化时给出警告
+func runFreeze(args []string) error {
+	opts := &Options{}
+	fs := flag.NewFlagSet("AIG_repo freeze", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	force := fs.Bool("force", false, "已冻结的统计周期结果变化时仍然覆盖冻结记录")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe freeze [选项] [开始日期] [结束日期]\n")
+		fs.PrintDefaults()
+	}
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		opts.Since = positional[0]
+	}
+	if len(positional) > 1 {
+		opts.Until = positional[1]
+	}
+	cfg, err := resolveOptions(opts)
+	if err != nil {
+		return err
+	}
+	if opts.Store == stat.StoreOff {
+		return errors.New("错误：冻结记录保存在运行记录中，请不要使用 --store off")
+	}
+
+	git := opts.GitRunner(opts.Repo)
+	report, err := generateReport("AIG_repo freeze", args, git, opts, cfg)
+	if err != nil {
+		return err
+	}
+	store, err := stat.OpenStore(opts.Store)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	frozen, err := store.FrozenPeriods(report.Meta.Repo)
+	if err != nil {
+		return err
+	}
+	if existing := stat.FindFrozen(report, frozen); existing != nil {
+		if len(existing.Diff(report)) == 0 {
+			fmt.Printf("统计周期 %s 已于 %s 冻结，结果一致 (校验和 %s)\n", report.PeriodLabel(), existing.FrozenAt, existing.Checksum)
+			return nil
+		}
+		if !*force {
+			return fmt.Errorf("错误：统计周期 %s 已于 %s 冻结且结果已变化，确认要以当前结果重新冻结时请使用 --force", report.PeriodLabel(), existing.FrozenAt)
+		}
+	}
+	period := stat.FreezeReport(report)
+	if err := store.Freeze(period); err != nil {
+		return err
+	}
+	fmt.Printf("已冻结统计周期 %s: %d 个提交，校验和 %s\n", report.PeriodLabel(), len(period.Commits), period.Checksum)
+	return nil
+}
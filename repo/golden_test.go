@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"AIStat/stat"
+)
+
+var update = flag.Bool("update", false, "用当前的输出重新生成 testdata/golden 下的期望报告")
+
+// 与期望报告比较，-update 时改为写入
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (用 go test ./repo -run TestGoldenReports -update 生成)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s 与期望报告不一致，确认改动符合预期后用 -update 重新生成\n--- got ---\n%s", path, got)
+	}
+}
+
+// 报告中的生成时间
+var generatedAt = regexp.MustCompile(`(生成时间: |generated_at"?: "?)[0-9][0-9T:.+-]*Z?`)
+
+// 去掉每次运行都不同的生成时间
+func normalizeOutput(data []byte) []byte {
+	return generatedAt.ReplaceAll(data, []byte("${1}2024-05-16T00:00:00Z"))
+}
+
+// 在 dir 目录下以 args 运行 main，返回标准输出
+func runMain(t *testing.T, dir string, args ...string) []byte {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+
+	args0 := os.Args
+	os.Args = append([]string{"AIG_repo"}, args...)
+	defer func() { os.Args = args0 }()
+	main()
+	w.Close()
+	return <-done
+}
+
+func TestGoldenReports(t *testing.T) {
+	fixture := buildStandardFixture(t)
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"report.txt", []string{"--format", stat.FormatText}},
+		{"report.json", []string{"--format", stat.FormatJSON}},
+		{"report.csv", []string{"--format", stat.FormatCSV}},
+		{"report.yaml", []string{"--format", stat.FormatYAML}},
+		{"report.html", []string{"--format", stat.FormatHTML}},
+		{"by_week.json", []string{"--format", stat.FormatJSON, "--by-week", "--by-tag", "--message-quality"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runMain(t, fixture.Dir, append(tt.args, "2024-05-01", "2024-05-15")...)
+			checkGolden(t, tt.name, normalizeOutput(got))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"AIStat/stat"
+)
+
+// 检查统计周期内提交信息中的 AIG 标记格式，可以生成修正提交信息的脚本
+func runLintCommits(args []string) error {
+	opts := &Options{}
+	fs := flag.NewFlagSet("AIG_repo lint-commits", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	script := fs.String("fix-script", "", "生成修正提交信息的脚本(使用 git filter-branch，会改写历史)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe lint-commits [选项] [开始日期] [结束日期]\n")
+		fs.PrintDefaults()
+	}
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		opts.Since = positional[0]
+	}
+	if len(positional) > 1 {
+		opts.Until = positional[1]
+	}
+	if _, err := resolveOptions(opts); err != nil {
+		return err
+	}
+	asJSON := false
+	for _, format := range opts.Formats {
+		if format != stat.FormatText && format != stat.FormatJSON {
+			return fmt.Errorf("错误：lint-commits 不支持输出格式 '%s'，请使用 text 或 json", format)
+		}
+		asJSON = asJSON || format == stat.FormatJSON
+	}
+
+	git := opts.GitRunner(opts.Repo)
+	analyzer := opts.NewAnalyzer(git)
+	commits, err := analyzer.Analyze(stat.LogQuery{Since: opts.Since, Until: opts.Until, RevRange: opts.RevRange, Grep: opts.Grep, InvertGrep: opts.InvertGrep})
+	if err != nil && !stat.IsPartial(err) {
+		return err
+	}
+	lints := stat.LintCommits(commits, opts.AIGPrecedence)
+	if asJSON {
+		if err := stat.WriteJSON(os.Stdout, lints); err != nil {
+			return err
+		}
+	} else {
+		stat.PrintLint(os.Stdout, lints, len(commits))
+	}
+
+	if *script != "" && len(lints) > 0 {
+		if err := writeFixupScript(git, *script, lints, commits, opts.RevRange, opts.AIGPrecedence); err != nil {
+			return err
+		}
+	}
+	if len(lints) > 0 {
+		return fmt.Errorf("错误：%d 个提交的 AIG 标记有问题", len(lints))
+	}
+	return nil
+}
+
+// 读取可以自动修正的提交的原始提交信息，生成修正脚本及修正后的提交信息文件
+func writeFixupScript(git stat.GitRunner, path string, lints []stat.CommitLint, commits []stat.CommitStats, revRange, precedence string) error {
+	// git log 按从新到旧的顺序输出，序号最大的为最早的提交
+	order := make(map[string]int, len(commits))
+	for i, c := range commits {
+		order[c.ID] = i
+	}
+	// 不指定提交范围时脚本改写当前分支(HEAD)，默认统计的是所有分支，其他分支上的提交无法修正
+	ranged := strings.Contains(revRange, "..")
+	messages := make(map[string]string)
+	var unreachable []string
+	oldest := ""
+	for _, l := range lints {
+		if !l.Fixable {
+			continue
+		}
+		if !ranged {
+			if _, err := git.Run([]string{"merge-base", "--is-ancestor", l.ID, "HEAD"}); err != nil {
+				unreachable = append(unreachable, l.ID)
+				continue
+			}
+		}
+		out, err := git.Run([]string{"log", "-1", "--format=%B", l.ID})
+		if err != nil {
+			return err
+		}
+		raw, err := io.ReadAll(out)
+		if err != nil {
+			return err
+		}
+		_, fixed, fixable := stat.LintTrailers(strings.TrimRight(string(raw), "\n"), precedence)
+		if !fixable {
+			continue
+		}
+		messages[l.ID] = fixed
+		if oldest == "" || order[l.ID] > order[oldest] {
+			oldest = l.ID
+		}
+	}
+	if len(unreachable) > 0 {
+		fmt.Fprintf(os.Stderr, "警告：%d 个提交不在当前分支(HEAD)上，修正脚本不会改写这些提交，请切换到所在的分支或用 --rev-range 指定后重新生成:\n", len(unreachable))
+		for _, id := range unreachable {
+			fmt.Fprintf(os.Stderr, "  %s\n", id)
+		}
+	}
+	if len(messages) == 0 {
+		return errors.New("错误：没有可以自动修正的提交，未生成修正脚本")
+	}
+
+	// 从最早需要修正的提交开始改写，根提交没有父提交时改写整个分支
+	base := "HEAD"
+	if ranged {
+		base = revRange
+	} else if _, err := git.Run([]string{"rev-parse", "--verify", "-q", oldest + "^"}); err == nil {
+		base = oldest + "^..HEAD"
+	}
+	// filter-branch 在临时目录中执行过滤命令，提交信息文件使用绝对路径
+	dir, err := filepath.Abs(stat.FixupMessageDir(path))
+	if err != nil {
+		return err
+	}
+	if err := stat.WriteFixupMessages(dir, messages); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return fmt.Errorf("错误：创建修正脚本 '%s' 失败: %v", path, err)
+	}
+	if err := stat.WriteFixupScript(f, dir, base); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "修正脚本已写入: %s，提交信息在 %s (%d 个提交，会改写历史，运行前请先备份分支)\n", path, dir, len(messages))
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"AIStat/stat"
+)
+
+// 提交信息中的文本不能被修正脚本当作命令执行
+func TestFixupScriptHostileMessage(t *testing.T) {
+	r := newFixtureRepo(t)
+	pwned := filepath.Join(t.TempDir(), "pwned")
+	message := "feat: retry\n\naig: 0.5\nAIG_EOF\ntouch " + pwned + "\n'; touch " + pwned + "; echo '\n$(touch " + pwned + ")"
+	r.Commit(fixtureCommit{
+		Author: "Mallory", Email: "mallory@example.com", Date: "2024-05-02T09:00:00+00:00",
+		Message: message,
+		Files:   map[string]string{"a.go": codeLines("func a", 3)},
+	})
+	id := strings.TrimSpace(r.git(nil, "rev-parse", "HEAD"))
+	commits := []stat.CommitStats{{ID: id, Author: "Mallory", Subject: "feat: retry", Message: message}}
+	lints := stat.LintCommits(commits, stat.AIGPrecedenceFirst)
+	if len(lints) != 1 || !lints[0].Fixable {
+		t.Fatalf("lints = %+v", lints)
+	}
+
+	script := filepath.Join(t.TempDir(), "fix-aig.sh")
+	if err := writeFixupScript(&stat.ExecGitRunner{Dir: r.Dir}, script, lints, commits, "'; touch "+pwned+"; echo '..HEAD", stat.AIGPrecedenceFirst); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "AIG_EOF") || strings.Contains(string(data), "feat: retry") {
+		t.Errorf("提交信息写入了脚本:\n%s", data)
+	}
+
+	// 提交范围在脚本中被引用为一个参数，git 找不到该范围而失败，但不会执行其中的命令
+	cmd := exec.Command("sh", script)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(), "FILTER_BRANCH_SQUELCH_WARNING=1")
+	cmd.CombinedOutput()
+	if _, err := os.Stat(pwned); err == nil {
+		t.Fatalf("提交信息或提交范围中的命令被执行了:\n%s", data)
+	}
+
+	if err := writeFixupScript(&stat.ExecGitRunner{Dir: r.Dir}, script, lints, commits, "", stat.AIGPrecedenceFirst); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command("sh", script)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(), "FILTER_BRANCH_SQUELCH_WARNING=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if _, err := os.Stat(pwned); err == nil {
+		t.Fatal("提交信息中的命令被执行了")
+	}
+	want := strings.Replace(message, "aig: 0.5", "AIG: 0.5", 1)
+	if got := strings.TrimRight(r.git(nil, "log", "-1", "--format=%B"), "\n"); got != want {
+		t.Errorf("修正后的提交信息 = %q, want %q", got, want)
+	}
+
+	// 不在当前分支上的提交不写入脚本
+	r.git(nil, "checkout", "-q", "-b", "other")
+	r.Commit(fixtureCommit{
+		Author: "Mallory", Email: "mallory@example.com", Date: "2024-05-03T09:00:00+00:00",
+		Message: "fix: b\n\naig: 1",
+	})
+	other := strings.TrimSpace(r.git(nil, "rev-parse", "HEAD"))
+	r.git(nil, "checkout", "-q", "main")
+	commits = []stat.CommitStats{{ID: other, Author: "Mallory", Subject: "fix: b", Message: "fix: b\n\naig: 1"}}
+	if err := writeFixupScript(&stat.ExecGitRunner{Dir: r.Dir}, script, stat.LintCommits(commits, stat.AIGPrecedenceFirst), commits, "", stat.AIGPrecedenceFirst); err == nil {
+		t.Error("为不在当前分支上的提交生成了修正脚本")
+	}
+}
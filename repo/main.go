@@ -1,414 +1,927 @@
 package main
 
 import (
-	"bytes"
+	"context"
+	"flag"
 	"fmt"
-	"math"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
-)
 
-// 定义正则表达式模式常量，避免重复编译
-const (
-	aigPattern = `AIG:(\s*([0-9.]+))`
-	fixPattern = `^[0-9a-f]{40} '[^']+' \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} (fix)`
-	// 添加提交信息解析模式
-	commitPattern = `^([0-9a-f]{40}) '([^']+)' ([^ ]+) (\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) (.+)$`
-	// 添加文件扩展名常量
-	includeFileExts = ".html,.vue,.js,.ts,.tsx,.css,.scss,.cjs,.go,.php,.yaml,.proto"
-	excludeFileExts = ".pb.go,.pb.validate.go"
+	"AIStat/stat"
 )
 
-type CommitStats struct {
-	AddedLines   int
-	DeletedLines int
-	AIGRatio     float64
-	IsFix        bool
+// 命令行选项
+type Options struct {
+	stat.RunOptions
+	// 低于阈值的开发者归入“其他”汇总行
+	MinCommits int
+	MinLines   int
+	// 仅统计这些邮箱域名的开发者，为空时不过滤
+	EmailDomains     []string
+	CollapseExternal bool
+	// 只统计作者姓名或邮箱匹配的提交(git log --author)
+	Author string
+	// 与 git shortstat 交叉核对统计结果
+	Validate bool
+	// 输出提交时间热力图
+	Heatmap bool
+	// 按 AIG 标记分组汇总
+	ByTag bool
+	// 按周汇总及周的编号方式: iso、monday、sunday
+	ByWeek bool
+	Week   string
+	// 汇总开发者统计的识别方式: email、name、name+email
+	Identity string
+	// AI 添加占比与上期相比变化超过该百分点时标注
+	AnomalyThreshold float64
+	// 文本报告中标注与上一统计周期相比的变化
+	DiffPrev bool
+	// SZZ 缺陷引入分析
+	SZZ bool
+	// 读取 GitHub/GitLab 的代码评审数据
+	Reviews bool
+	// 统计修复提交的修复耗时
+	TimeToFix bool
+	// 统计文件的新建、删除及有 AI 参与的净新增文件
+	Lifecycle bool
+	// 按新建文件和已有文件分别统计 AI 添加占比
+	ChangeType bool
+	// 统计提交信息质量
+	MessageQuality bool
+	// 按目录统计变更集中程度及目录的级数
+	Ownership      bool
+	OwnershipDepth int
+	// 按工作量模型估算 AI 节省的工时
+	Effort bool
+	// 按提交间隔估算编码时长
+	Duration bool
+	// AI 工具费用明细(CSV)
+	Costs string
+	// 导出提交图的文件路径，按扩展名选择 DOT 或 Mermaid
+	CommitGraph string
+	// 只检查运行环境(git、仓库、配置)，不做统计
+	CheckEnv bool
 }
 
-type AuthorStats struct {
-	Name                string
-	Email               string
-	TotalAddedLines     int
-	TotalDeletedLines   int
-	TotalAIAddedLines   int
-	TotalAIDeletedLines int
-	FixCount            int
-	FixAndAIGCount      int
+// 子命令，未匹配时执行默认的统计
+var commands = map[string]func(args []string) error{
+	"verify":        runVerify,
+	"release":       runRelease,
+	"squash-msg":    runSquashMsg,
+	"lint-commits":  runLintCommits,
+	"freeze":        runFreeze,
+	"reconcile":     runReconcile,
+	"serve":         runServe,
+	"site":          runSite,
+	"retention":     runRetention,
+	"compare-repos": runCompareRepos,
+	"badge":         runBadge,
+	"watch":         runWatch,
+	"purge":         runPurge,
+	"forget":        runForget,
+	"export-bundle": runExportBundle,
+	"import-bundle": runImportBundle,
+	"merge":         runMerge,
+	"serve-local":   runServeLocal,
+	"digest":        runDigest,
+	"tag":           runTag,
+	"version":       runVersion,
+	"self-update":   runSelfUpdate,
 }
 
+// 收到 SIGINT/SIGTERM 时取消，正在执行的 git 命令随之中止，见 stat.SignalContext
+var interrupt = context.Background()
+
 func main() {
-	since, until, err := parseCommandLineArgs()
-	if err != nil {
-		fmt.Println(err)
-		return
+	ctx, stop := stat.SignalContext()
+	defer stop()
+	interrupt = ctx
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			exitInterrupted()
+			return
+		}
 	}
 
-	output, err := runGitCommand(since, until)
-	if err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		fmt.Println(err)
-		return
 	}
+	exitInterrupted()
+}
 
-	commits := splitCommits(output)
-	authorStats := analyzeCommits(commits)
-	printStatistics(since, until, authorStats)
+// 被信号中断时以 130 退出，便于脚本区分部分结果
+func exitInterrupted() {
+	if interrupt.Err() != nil {
+		os.Exit(130)
+	}
 }
 
-// 解析命令行参数
-func parseCommandLineArgs() (string, string, error) {
-	var since, until string
-	if len(os.Args) > 1 {
-		since = os.Args[1]
-		if _, err := time.Parse("2006-01-02", since); err != nil {
-			return "", "", fmt.Errorf("错误：起始日期 '%s' 格式不正确，请使用 '2006-01-02' 格式", since)
-		}
+// 统计所有开发者并输出报告
+func run(args []string) error {
+	opts, err := parseCommandLineArgs(args)
+	if err != nil {
+		return err
 	}
-	if len(os.Args) > 2 {
-		until = os.Args[2]
-		if _, err := time.Parse("2006-01-02", until); err != nil {
-			return "", "", fmt.Errorf("错误：结束日期 '%s' 格式不正确，请使用 '2006-01-02' 格式", until)
+	if opts.CheckEnv {
+		return checkEnv(opts)
+	}
+	if len(args) == 0 && stat.IsTerminal(os.Stdin) && stat.IsTerminal(os.Stdout) {
+		if args, err = pickOptions(opts, time.Now()); err != nil {
+			return err
 		}
 	}
+	cfg, err := resolveOptions(opts)
+	if err != nil {
+		return err
+	}
 
-	since, until = getDefaultDateRange(since, until)
-	return since, until, nil
+	git := opts.GitRunner(opts.Repo)
+	report, err := generateReport("AIG_repo", args, git, opts, cfg)
+	if err != nil {
+		return err
+	}
+
+	stat.RegisterRenderer(stat.FormatText, renderText)
+	return opts.WriteSinks(os.Stdout, "", report)
 }
 
-// 获取默认日期范围
-func getDefaultDateRange(since, until string) (string, string) {
-	if since != "" && until != "" {
-		return since, until
+// 文本报告：逐提交明细及各开发者的统计
+func renderText(w io.Writer, report *stat.Report, opts *stat.RunOptions) error {
+	for i := range report.Commits {
+		stat.PrintCommit(w, &report.Commits[i])
 	}
+	printStatistics(w, report)
+	return nil
+}
 
-	now := time.Now()
-	year, month, day := now.Date()
-	location := now.Location()
-
-	var periodStart, periodEnd time.Time
+// 要分析的仓库目录：命令行、配置文件或环境变量，默认当前目录
+func repoDir(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return "."
+}
 
-	if day <= 15 {
-		// 当前在上半月，则统计上月16号到月底的数据
-		firstOfThisMonth := time.Date(year, month, 1, 0, 0, 0, 0, location)
-		lastMonth := firstOfThisMonth.AddDate(0, -1, 0)
-		lastMonthYear, lastMonthMonth, _ := lastMonth.Date()
+// 加载配置文件、环境变量及仓库自己的配置，补全并校验选项
+func resolveOptions(opts *Options) (*stat.Config, error) {
+	cfg, err := stat.LoadConfig(opts.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ApplyEnv(os.LookupEnv)
+	if err := cfg.ApplyRepoConfig(repoDir(opts.Repo, cfg.Repo)); err != nil {
+		return nil, err
+	}
+	if err := opts.Resolve(cfg, time.Now()); err != nil {
+		return nil, err
+	}
+	opts.Context = interrupt
+	if opts.Identity == "" {
+		opts.Identity = cfg.Identity
+	}
+	if opts.Identity == "" {
+		opts.Identity = stat.IdentityEmail
+	}
+	if !stat.ValidIdentity(opts.Identity) {
+		return nil, fmt.Errorf("错误：不支持的开发者识别方式 '%s'", opts.Identity)
+	}
+	if opts.Week == "" {
+		opts.Week = cfg.Week
+	}
+	if opts.Week == "" {
+		opts.Week = stat.WeekISO
+	}
+	if !stat.ValidWeekScheme(opts.Week) {
+		return nil, fmt.Errorf("错误：不支持的周编号方式 '%s'，请使用 iso、monday 或 sunday", opts.Week)
+	}
+	if err := stat.ValidateTargets(cfg.Targets, cfg.Roster); err != nil {
+		return nil, err
+	}
+	if err := stat.ValidateOrg(cfg.Org, cfg.Roster); err != nil {
+		return nil, err
+	}
+	if err := stat.ValidateAvailability(cfg.Roster); err != nil {
+		return nil, err
+	}
+	if opts.Costs == "" {
+		opts.Costs = cfg.Costs
+	}
+	if opts.Fast {
+		// 这些分析依赖行数或逐行的 diff
+		for flag, on := range map[string]bool{"--szz": opts.SZZ, "--lifecycle": opts.Lifecycle, "--change-type": opts.ChangeType, "--ownership": opts.Ownership, "--effort": opts.Effort, "--validate": opts.Validate} {
+			if on {
+				return nil, fmt.Errorf("错误：--fast 不能与 %s 同时使用", flag)
+			}
+		}
+	}
+	if opts.AnomalyThreshold == 0 {
+		opts.AnomalyThreshold = cfg.AnomalyThreshold
+	}
+	if opts.AnomalyThreshold == 0 {
+		opts.AnomalyThreshold = stat.DefaultAnomalyThreshold
+	}
+	if opts.AnomalyThreshold < 0 {
+		return nil, fmt.Errorf("错误：异常标注阈值不能为负数")
+	}
+	return cfg, nil
+}
 
-		periodStart = time.Date(lastMonthYear, lastMonthMonth, 16, 0, 0, 0, 0, location)
-		firstOfNextMonth := time.Date(lastMonthYear, lastMonthMonth+1, 1, 0, 0, 0, 0, location)
-		periodEnd = firstOfNextMonth.AddDate(0, 0, -1)
+// 调用 build 生成报告并记录审计日志，所有分析仓库的命令都经过这里
+func auditReport(command string, args []string, git stat.GitRunner, opts *Options, build func() (*stat.Report, error)) (*stat.Report, error) {
+	audit := stat.NewAuditEntry(git, command, args, &opts.RunOptions)
+	report, err := build()
+	if err != nil {
+		audit.Error = err.Error()
 	} else {
-		// 当前在下半月，则统计本月1号到15号的数据
-		periodStart = time.Date(year, month, 1, 0, 0, 0, 0, location)
-		periodEnd = time.Date(year, month, 15, 0, 0, 0, 0, location)
+		audit.Commits = len(report.Commits)
+		audit.Digest = report.Digest()
+		if report.Meta != nil && len(report.Meta.Errors) > 0 {
+			audit.Error = "部分分析失败: " + strings.Join(report.Meta.Errors, "；")
+		}
 	}
+	if auditErr := stat.AppendAudit(opts.AuditLog, audit); auditErr != nil {
+		fmt.Fprintln(os.Stderr, auditErr)
+	}
+	return report, err
+}
 
-	return periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02")
+// 分析仓库生成报告，见 runReport
+func generateReport(command string, args []string, git stat.GitRunner, opts *Options, cfg *stat.Config) (*stat.Report, error) {
+	return runReport(command, args, git, opts, func() (*stat.Report, error) {
+		return buildReport(git, opts, cfg)
+	})
 }
 
-// 运行 Git 命令
-func runGitCommand(since, until string) (string, error) {
-	cmdArgs := []string{
-		"log",
-		"--all",
-		"--since=" + since,
-		"--until=" + until,
-		"--pretty=format:%H '%an' %ae %ad %s %b",
-		"--numstat",
-		"--date=format:%Y-%m-%d %H:%M:%S",
-		"--no-merges",
+// 调用 build 生成报告，记录审计日志、补充滚动统计并保存运行记录
+func runReport(command string, args []string, git stat.GitRunner, opts *Options, build func() (*stat.Report, error)) (*stat.Report, error) {
+	report, err := auditReport(command, args, git, opts, build)
+	if err != nil {
+		return nil, err
 	}
-
-	cmd := exec.Command("git", cmdArgs...)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("执行 git 命令时出错: %v", err)
+	if opts.RollingDays > 0 {
+		if err := applyRolling(report, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "警告：%s\n", strings.TrimPrefix(err.Error(), "错误："))
+		}
 	}
-	return out.String(), nil
+	previous, err := loadPrevious(report, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告：%s\n", strings.TrimPrefix(err.Error(), "错误："))
+	}
+	report.Anomalies = stat.DetectAnomalies(report, previous, opts.AnomalyThreshold, opts.MinSampleLines, opts.Identity)
+	if opts.DiffPrev {
+		if previous == nil && err == nil {
+			fmt.Fprintf(os.Stderr, "警告：没有找到上一统计周期的运行记录，不标注变化\n")
+		}
+		report.Previous = previous
+	}
+	// 被中断的部分结果只输出，不保存为运行记录，以免影响之后的对比
+	if opts.Store != stat.StoreOff && !report.Meta.Interrupted {
+		if err := checkFrozen(report, opts.Store); err != nil {
+			fmt.Fprintf(os.Stderr, "警告：%s\n", strings.TrimPrefix(err.Error(), "错误："))
+		}
+		if err := saveRun(opts.Store, report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	for _, warning := range report.Meta.Warnings {
+		fmt.Fprintf(os.Stderr, "警告：%s\n", warning)
+	}
+	for _, e := range report.Meta.Errors {
+		fmt.Fprintf(os.Stderr, "错误：分析未完成: %s\n", e)
+	}
+	return report, nil
 }
 
-// 分析提交信息
-func analyzeCommits(commits []string) map[string]*AuthorStats {
-	authorStats := make(map[string]*AuthorStats)
-	aigRegex := regexp.MustCompile(aigPattern)
-	fixRegex := regexp.MustCompile(fixPattern)
+// 与同一统计周期的冻结记录比较，不一致时在运行信息中给出警告
+func checkFrozen(report *stat.Report, location string) error {
+	if report.Meta == nil {
+		return nil
+	}
+	store, err := stat.OpenStore(location)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	frozen, err := store.FrozenPeriods(report.Meta.Repo)
+	if err != nil {
+		return err
+	}
+	f := stat.FindFrozen(report, frozen)
+	if f == nil {
+		return nil
+	}
+	report.Meta.FrozenAt = f.FrozenAt
+	if notes := f.Diff(report); len(notes) > 0 {
+		report.Meta.FrozenChanged = true
+		report.Meta.Warnings = append(report.Meta.Warnings, fmt.Sprintf("统计周期已于 %s 冻结，当前结果与冻结记录不一致: %s", f.FrozenAt, strings.Join(notes, "；")))
+	}
+	return nil
+}
 
-	includeExts := strings.Split(includeFileExts, ",")
-	excludeExts := strings.Split(excludeFileExts, ",")
+// 保存本次运行的报告
+func saveRun(location string, report *stat.Report) error {
+	store, err := stat.OpenStore(location)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	_, err = store.SaveRun(report)
+	return err
+}
 
-	for _, commit := range commits {
-		if commit == "" {
-			continue
+// 读取上一统计周期的运行记录，用于异常标注和变化标注，未保存运行记录或没有上期记录时返回 nil
+func loadPrevious(report *stat.Report, opts *Options) (*stat.Report, error) {
+	if opts.Store == stat.StoreOff || report.Meta == nil {
+		if opts.DiffPrev {
+			return nil, fmt.Errorf("错误：未保存运行记录，无法与上一统计周期对比")
 		}
-
-		commitStats, author, email := processCommit(commit, aigRegex, fixRegex, includeExts, excludeExts)
-		updateAuthorStats(authorStats, author, email, commitStats)
+		return nil, nil
 	}
-
-	return authorStats
+	store, err := stat.OpenStore(opts.Store)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	runs, err := store.LoadRuns(report.Meta.Repo)
+	if err != nil {
+		return nil, err
+	}
+	return stat.PreviousPeriod(report, runs), nil
 }
 
-// 处理单个提交
-func processCommit(commit string, aigRegex, fixRegex *regexp.Regexp, includeExts, excludeExts []string) (CommitStats, string, string) {
-	lines := strings.Split(commit, "\n")
-	if len(lines) == 0 {
-		return CommitStats{}, "", ""
+// 根据已保存的运行记录补充各开发者统计周期之前的平均水平
+func applyRolling(report *stat.Report, opts *Options) error {
+	if opts.Store == stat.StoreOff {
+		return fmt.Errorf("错误：未保存运行记录，无法计算滚动平均")
 	}
-
-	// 获取提交的第一行作为基本信息
-	firstLine := lines[0]
-
-	// 解析提交的基本信息（ID、作者、邮箱、时间）
-	parts := strings.SplitN(firstLine, " ", 5)
-	if len(parts) < 5 {
-		return CommitStats{}, "", ""
+	from, to, err := stat.RollingWindow(report.Since, opts.RollingDays)
+	if err != nil {
+		return err
 	}
+	store, err := stat.OpenStore(opts.Store)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	commits, err := store.Commits(report.Meta.Repo, from, to)
+	if err != nil {
+		return err
+	}
+	// 迁移前保存的运行记录使用旧邮箱和旧哈希
+	opts.Migration.Apply(commits)
+	stat.ApplyRolling(report.Authors, commits, opts.RollingDays, stat.PeriodDays(report.Since, report.Until))
+	return nil
+}
 
-	commitID := parts[0]
-	author := strings.Trim(parts[1], "'")
-	email := parts[2]
-	commitTime := parts[3]
-	message := parts[4]
-
-	// 查找文件变更列表的起始位置
-	fileChangeStartIdx := 1
-	var messageLines []string
-	messageLines = append(messageLines, message)
-
-	// 遍历每一行，直到找到文件变更记录
-	for i := 1; i < len(lines); i++ {
-		line := lines[i]
-		if line == "" {
-			continue
-		}
-
-		// 检查是否是文件变更记录（通过判断行的格式）
-		if isFileChangeLine(line) {
-			fileChangeStartIdx = i
-			break
+// 分析提交并生成报告
+func buildReport(git stat.GitRunner, opts *Options, cfg *stat.Config) (*stat.Report, error) {
+	analyzer := opts.NewAnalyzer(git)
+	analyzer.SZZ = opts.SZZ
+	query := stat.LogQuery{Since: opts.Since, Until: opts.Until, RevRange: opts.RevRange, Author: opts.Author, Grep: opts.Grep, InvertGrep: opts.InvertGrep}
+	commits, err := analyzer.Analyze(query)
+	if err != nil && !stat.IsPartial(err) {
+		return nil, err
+	}
+	meta := analyzer.Metadata()
+	meta.Filters.Author = opts.Author
+	meta.ConfigFiles = cfg.Sources
+	report, err := assembleReport(commits, meta, opts, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Validate {
+		rows := append(append([]*stat.AuthorStats{}, report.Authors...), report.Groups...)
+		if report.Validation, err = analyzer.Validate(query, commits, rows); err != nil {
+			return nil, err
 		}
-		messageLines = append(messageLines, line)
 	}
-
-	// 合并提交消息
-	fullMessage := strings.Join(messageLines, "\n")
-
-	// 打印提交信息
-	fmt.Printf("\n提交详情:\n")
-	fmt.Printf("  提交ID: %s\n", commitID)
-	fmt.Printf("  作者: %s\n", author)
-	fmt.Printf("  邮箱: %s\n", email)
-	fmt.Printf("  时间: %s\n", commitTime)
-	fmt.Printf("  消息:\n")
-	// 打印多行消息，每行前面加缩进
-	for _, line := range strings.Split(fullMessage, "\n") {
-		if strings.TrimSpace(line) != "" {
-			fmt.Printf("    %s\n", line)
+	if opts.Reviews {
+		// 评审平台不可用时仍输出统计结果
+		if report.Reviews, err = stat.CollectReviews(git, cfg.Review, report); err != nil {
+			report.Meta.Warnings = append(report.Meta.Warnings, fmt.Sprintf("读取代码评审数据失败: %v", strings.TrimPrefix(err.Error(), "错误：")))
 		}
 	}
-
-	stats := CommitStats{
-		AIGRatio: extractAIGRatio(aigRegex, fullMessage),
-		IsFix:    fixRegex.MatchString(firstLine),
+	if opts.TimeToFix {
+		report.TimeToFix = stat.MeasureTimeToFix(git, cfg.Review, report.Commits)
 	}
-
-	fmt.Printf("  AI贡献率: %.2f%%\n", stats.AIGRatio*100)
-	fmt.Printf("  是否修复提交: %v\n", stats.IsFix)
-	fmt.Printf("  变更文件:\n")
-
-	// 获取文件变更列表
-	fileChanges := lines[fileChangeStartIdx:]
-
-	for _, change := range fileChanges {
-		if change == "" {
-			continue
+	if opts.CommitGraph != "" {
+		if report.CommitGraph, err = stat.BuildCommitGraph(git, query, report.Commits); err != nil {
+			return nil, err
 		}
-
-		added, deleted, fileName := parseFileChange(change)
-		if !isValidFile(fileName, includeExts, excludeExts) {
-			fmt.Printf("    [跳过] %s (不符合统计条件)\n", fileName)
-			continue
+		if err := writeCommitGraph(report.CommitGraph, opts.CommitGraph); err != nil {
+			return nil, err
 		}
-
-		fmt.Printf("    - %s (添加: %d, 删除: %d)\n", fileName, added, deleted)
-		stats.AddedLines += added
-		stats.DeletedLines += deleted
 	}
-
-	aiAddedLines := int(math.Round(float64(stats.AddedLines) * stats.AIGRatio))
-	aiDeletedLines := int(math.Round(float64(stats.DeletedLines) * stats.AIGRatio))
-	fmt.Printf("  本次提交总计:\n")
-	fmt.Printf("    总添加行数: %d\n", stats.AddedLines)
-	fmt.Printf("    总删除行数: %d\n", stats.DeletedLines)
-	fmt.Printf("    AI贡献添加行数: %d\n", aiAddedLines)
-	fmt.Printf("    AI贡献删除行数: %d\n", aiDeletedLines)
-	fmt.Printf("  %s\n", strings.Repeat("-", 80))
-
-	return stats, author, email
+	return report, nil
 }
 
-// 判断是否为文件变更记录行
-func isFileChangeLine(line string) bool {
-	parts := strings.Fields(line)
-	if len(parts) < 2 {
-		return false
+// 按扩展名写出 DOT 或 Mermaid 格式的提交图
+func writeCommitGraph(g *stat.CommitGraph, path string) error {
+	format := ""
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dot", ".gv":
+		format = stat.GraphDOT
+	case ".mmd", ".mermaid":
+		format = stat.GraphMermaid
+	default:
+		return fmt.Errorf("错误：提交图文件 '%s' 的扩展名应为 .dot、.gv、.mmd 或 .mermaid", path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("错误：创建提交图文件 '%s' 失败: %v", path, err)
+	}
+	if err := g.Write(f, format); err != nil {
+		f.Close()
+		return err
 	}
+	return f.Close()
+}
 
-	// 检查前两个字段是否都是数字或 "-"
-	for _, field := range parts[:2] {
-		if field != "-" {
-			_, err := strconv.Atoi(field)
-			if err != nil {
-				return false
-			}
+// 由已分析的提交汇总开发者统计，应用名单、过滤规则等生成报告
+func assembleReport(commits []stat.CommitStats, meta *stat.Metadata, opts *Options, cfg *stat.Config) (*stat.Report, error) {
+	// 外部开发者的提交不参与任何汇总，只在需要时合并为一行
+	commits, external := filterEmailDomains(commits, cfg.Roster, opts.EmailDomains, opts.CollapseExternal, opts.Identity)
+	authorStats := stat.AggregateByIdentity(commits, opts.Identity)
+	applyRoster(authorStats, cfg.Roster, opts.Identity)
+	if stat.HasAvailability(cfg.Roster) {
+		rows := make([]*stat.AuthorStats, 0, len(authorStats))
+		for _, stats := range authorStats {
+			rows = append(rows, stats)
+		}
+		stat.ApplyActiveDays(rows, cfg.Roster, opts.Since, opts.Until)
+	}
+	if len(opts.UsageLogs) > 0 {
+		records, err := stat.LoadUsage(opts.UsageLogs)
+		if err != nil {
+			return nil, err
+		}
+		all := make([]*stat.AuthorStats, 0, len(authorStats))
+		for _, stats := range authorStats {
+			all = append(all, stats)
+		}
+		stat.ApplyUsage(all, records, opts.Since, opts.Until, opts.UsageThreshold)
+	}
+	authors, others := filterMinActivity(authorStats, cfg.Roster, opts.MinCommits, opts.MinLines)
+
+	meta.Filters.EmailDomains = opts.EmailDomains
+	meta.Filters.MinCommits = opts.MinCommits
+	meta.Filters.MinLines = opts.MinLines
+	meta.Filters.Identity = opts.Identity
+	meta.Filters.Grep = opts.Grep
+	meta.Filters.InvertGrep = opts.InvertGrep
+	meta.Filters.MinSample = opts.MinSample
+	meta.Filters.MinSampleLines = opts.MinSampleLines
+	meta.Lang, meta.KLoC = opts.Lang, opts.KLoC
+	meta.UsageLogs = opts.UsageLogs
+
+	report := &stat.Report{
+		Meta:     meta,
+		Since:    opts.Since,
+		Until:    opts.Until,
+		RevRange: opts.RevRange,
+		Authors:  authors,
+		Commits:  commits,
+	}
+	for _, group := range []*stat.AuthorStats{others, external} {
+		if group != nil {
+			report.Groups = append(report.Groups, group)
+		}
+	}
+	if opts.ByTag {
+		report.Cohorts = stat.BuildCohorts(commits, opts.Identity)
+	}
+	if opts.ByWeek {
+		report.Weeks = stat.BuildWeeks(commits, opts.Since, opts.Until, opts.Week, opts.Identity)
+		report.WeekScheme = opts.Week
+	}
+	if meta.SZZ {
+		report.BugIntroduction = stat.SummarizeBugs(commits, meta.BugFixCommits)
+	}
+	if opts.Effort {
+		model, err := stat.NewEffortModel(cfg.Effort)
+		if err != nil {
+			return nil, err
+		}
+		report.Effort = stat.EstimateEffort(model, commits, opts.Identity)
+	}
+	if opts.Duration {
+		duration, err := stat.EstimateDuration(commits, cfg.Duration, opts.Identity)
+		if err != nil {
+			return nil, err
+		}
+		report.Duration = duration
+	}
+	if opts.Costs != "" {
+		records, err := stat.LoadCosts(opts.Costs)
+		if err != nil {
+			return nil, err
+		}
+		if report.Costs, err = stat.AccountCosts(records, commits, opts.Since, opts.Until); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Lifecycle {
+		meta.Lifecycle = true
+		report.Lifecycle = stat.BuildFileLifecycle(commits)
+	}
+	meta.ChangeType = opts.ChangeType
+	meta.MessageQuality = opts.MessageQuality
+	if opts.Ownership {
+		report.Ownership = stat.BuildOwnership(commits, opts.OwnershipDepth, opts.Identity)
+	}
+	if opts.Heatmap {
+		report.Heatmaps = stat.BuildHeatmaps(commits, report.Authors, opts.Identity)
+	}
+	if len(cfg.Org) > 0 {
+		rows := append(append([]*stat.AuthorStats{}, report.Authors...), report.Groups...)
+		report.Org = stat.RollupOrg(cfg.Org, cfg.Roster, rows)
+	}
+	if len(cfg.Targets) > 0 {
+		// 按统计周期的结束日期判断是否已过截止日期，按提交范围统计时使用当前日期
+		asOf := time.Now()
+		if until, err := time.ParseInLocation(stat.DateLayout, stat.PeriodDay(opts.Until), time.Local); err == nil {
+			asOf = until
 		}
+		rows := append(append([]*stat.AuthorStats{}, report.Authors...), report.Groups...)
+		report.Targets = stat.EvaluateTargets(cfg.Targets, rows, cfg.Roster, asOf)
 	}
-	return true
+	return report, nil
 }
 
-// 解析文件变更信息
-func parseFileChange(change string) (added, deleted int, fileName string) {
-	parts := strings.Fields(change)
-	if len(parts) < 3 {
-		return 0, 0, ""
+// 解析命令行参数
+func parseCommandLineArgs(args []string) (*Options, error) {
+	opts := &Options{}
+	fs := flag.NewFlagSet("AIG_repo", flag.ContinueOnError)
+	resolveFlags := bindReportFlags(fs, opts)
+	fs.StringVar(&opts.Author, "author", "", "只统计作者姓名或邮箱包含该文本的提交 (按 git log --author 匹配)")
+	fs.BoolVar(&opts.SZZ, "szz", false, "SZZ 缺陷引入分析：用 git blame 追溯本期开始至今的修复提交所修改的代码由哪些提交引入，比较 AI 与人工代码每千行引入的缺陷数(较慢)")
+	fs.BoolVar(&opts.Reviews, "reviews", false, "从 GitHub/GitLab 读取本期合并的 PR/MR 的批准数、评论数和合并耗时，比较有无 AI 参与的 PR (访问令牌从 AISTAT_REVIEW_TOKEN 读取)")
+	fs.BoolVar(&opts.TimeToFix, "time-to-fix", false, "统计修复提交从缺陷引入(提交信息引用的提交)或 issue 创建到修复的耗时，比较 AI 参与与人工修复的中位数")
+	fs.BoolVar(&opts.DiffPrev, "diff-prev", false, "文本报告中的各项指标标注与上一统计周期(已保存的运行记录)相比的变化，增加为 ▲，减少为 ▼")
+	fs.BoolVar(&opts.Validate, "validate", false, "用 git log/diff --shortstat 交叉核对统计结果，说明文件类型、开发者过滤等规则造成的差异")
+	fs.StringVar(&opts.CommitGraph, "commit-graph", "", "导出按 AIG 比例着色的提交图，扩展名为 .dot/.gv 时为 Graphviz DOT，.mmd/.mermaid 时为 Mermaid；HTML 报告中同时绘制")
+	fs.BoolVar(&opts.CheckEnv, "check-env", false, "只检查 git 是否可用、仓库是否有效及配置文件和参数是否正确，不做统计")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe [选项] [开始日期] [结束日期]\n")
+		fs.PrintDefaults()
 	}
 
-	added, _ = strconv.Atoi(parts[0])
-	deleted, _ = strconv.Atoi(parts[1])
-	if len(parts) > 3 {
-		fileName = strings.Join(parts[2:], "")
-	} else {
-		fileName = parts[2]
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return nil, err
 	}
+	resolveFlags()
 
-	return added, deleted, fileName
+	if len(positional) > 0 {
+		opts.Since = positional[0]
+	}
+	if len(positional) > 1 {
+		opts.Until = positional[1]
+	}
+	return opts, nil
 }
 
-// 检查文件是否应该被统计
-func isValidFile(fileName string, includeExts, excludeExts []string) bool {
-	ext := filepath.Ext(fileName)
-	for _, excludeExt := range excludeExts {
-		if ext == excludeExt {
-			return false
-		}
-	}
-	for _, includeExt := range includeExts {
-		if ext == includeExt {
-			return true
+// 注册由提交汇总报告的选项(过滤、分组、附加统计等)，merge 等不读取仓库的命令同样适用；
+// 返回的函数在解析参数后调用，整理需要拆分的选项
+func bindReportFlags(fs *flag.FlagSet, opts *Options) func() {
+	opts.BindFlags(fs)
+	fs.IntVar(&opts.MinCommits, "min-commits", 0, "提交次数低于该值的开发者归入“其他”汇总行")
+	fs.IntVar(&opts.MinLines, "min-lines", 0, "变更行数(添加+删除)低于该值的开发者归入“其他”汇总行")
+	var emailDomains string
+	fs.StringVar(&emailDomains, "email-domain", "", "仅统计指定邮箱域名的开发者，多个域名用逗号分隔")
+	fs.BoolVar(&opts.CollapseExternal, "collapse-external", false, "配合 --email-domain 使用，将外部开发者合并为“外部贡献者”汇总行而不是直接排除")
+	fs.BoolVar(&opts.ByTag, "by-tag", false, "按 AIG 标记分组汇总：标记 AIG>0、标记 AIG=0、未标记，区分“没有使用 AI”和“忘记标记”")
+	fs.BoolVar(&opts.ByWeek, "by-week", false, "按周汇总提交次数、行数、AI 添加占比和修复次数，没有提交的周也列出")
+	fs.StringVar(&opts.Week, "week", "", "按周汇总时周的编号方式: iso 为 ISO 周(周一开始)，monday、sunday 为周一或周日开始、包含 1 月 1 日的周为第 1 周 (默认 iso)")
+	fs.BoolVar(&opts.Heatmap, "heatmap", false, "输出全体及各开发者按星期、小时统计的提交时间热力图")
+	fs.BoolVar(&opts.Effort, "effort", false, "按配置项 effort 中的工作量模型(每小时行数或 COCOMO)把 AI 添加行数折算为节省的工时，结果为粗略估计")
+	fs.BoolVar(&opts.Duration, "duration", false, "按同一开发者相邻提交的时间间隔估算编码时长(间隔过长时视为新的工作时段)，比较有无 AI 参与的提交每小时添加的行数，结果为粗略估计")
+	fs.StringVar(&opts.Costs, "costs", "", "AI 工具授权/API 费用明细(CSV，列 email、month、cost，可选 currency)，按统计周期分摊后计算每行 AI 代码及每次 AI 参与修复的费用")
+	fs.BoolVar(&opts.Lifecycle, "lifecycle", false, "统计各开发者新建、删除、修改的文件数，列出本期净新增且新建时有 AI 参与的文件")
+	fs.BoolVar(&opts.ChangeType, "change-type", false, "按新建文件(新代码)和已有文件(修改已有代码)分别统计各开发者的 AI 添加占比")
+	fs.BoolVar(&opts.MessageQuality, "message-quality", false, "统计各开发者提交信息的平均长度、符合 Conventional Commits 格式及引用 issue 的提交占比，分别比较有无 AI 参与(AIG>0)的提交")
+	fs.BoolVar(&opts.Ownership, "ownership", false, "按目录统计变更在开发者之间的集中程度(巴士因子)，比较 AI 编写为主的目录与其他目录")
+	fs.IntVar(&opts.OwnershipDepth, "ownership-depth", stat.DefaultOwnershipDepth, "按目录统计集中程度时取前几级目录")
+	fs.StringVar(&opts.Identity, "identity", "", "识别同一开发者的依据: email 按邮箱(邮箱为空时按姓名), name 按姓名, name+email 按姓名和邮箱 (默认 email)")
+	fs.Float64Var(&opts.AnomalyThreshold, "anomaly-threshold", 0, fmt.Sprintf("开发者 AI 添加占比与上一统计周期相比变化超过该百分点时标注为异常 (默认 %d)", stat.DefaultAnomalyThreshold))
+	return func() {
+		for _, domain := range strings.Split(emailDomains, ",") {
+			domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+			if domain != "" {
+				opts.EmailDomains = append(opts.EmailDomains, domain)
+			}
 		}
 	}
-	return false
 }
 
-// 更新作者统计信息
-func updateAuthorStats(authorStats map[string]*AuthorStats, author, email string, commitStats CommitStats) {
-	stats, exists := authorStats[email]
-	if !exists {
-		stats = &AuthorStats{
-			Name:  author,
-			Email: email,
-		}
-		authorStats[email] = stats
+// 将名单中本期无提交的成员补充为全零统计，键按 identity 生成
+func applyRoster(authorStats map[string]*stat.AuthorStats, roster []stat.RosterMember, identity string) {
+	seen := make(map[string]bool, len(authorStats))
+	for _, stats := range authorStats {
+		seen[strings.ToLower(stats.Email)] = true
 	}
 
-	stats.TotalAddedLines += commitStats.AddedLines
-	stats.TotalDeletedLines += commitStats.DeletedLines
+	for _, member := range roster {
+		if member.Email == "" || seen[strings.ToLower(member.Email)] {
+			continue
+		}
+		authorStats[stat.IdentityKey(identity, member.Name, member.Email)] = &stat.AuthorStats{
+			Name:  member.Name,
+			Email: member.Email,
+		}
+		seen[strings.ToLower(member.Email)] = true
+	}
+}
 
-	aiAddedLines := int(math.Round(float64(commitStats.AddedLines) * commitStats.AIGRatio))
-	aiDeletedLines := int(math.Round(float64(commitStats.DeletedLines) * commitStats.AIGRatio))
+// 按邮箱域名过滤提交，返回内部开发者的提交；名单中的成员始终保留。
+// collapse 为 true 时返回外部开发者的汇总行，MemberCount 为按 identity 识别的外部开发者人数
+func filterEmailDomains(commits []stat.CommitStats, roster []stat.RosterMember, domains []string, collapse bool, identity string) ([]stat.CommitStats, *stat.AuthorStats) {
+	if len(domains) == 0 {
+		return commits, nil
+	}
 
-	stats.TotalAIAddedLines += aiAddedLines
-	stats.TotalAIDeletedLines += aiDeletedLines
+	inRoster := make(map[string]bool, len(roster))
+	for _, member := range roster {
+		inRoster[strings.ToLower(member.Email)] = true
+	}
 
-	if commitStats.IsFix {
-		stats.FixCount++
-		if commitStats.AIGRatio > 0 {
-			stats.FixAndAIGCount++
+	internal := make([]stat.CommitStats, 0, len(commits))
+	var outside []stat.CommitStats
+	for _, c := range commits {
+		if inRoster[strings.ToLower(c.Email)] || stat.MatchEmailDomain(c.Email, domains) {
+			internal = append(internal, c)
+		} else {
+			outside = append(outside, c)
 		}
 	}
+	if !collapse || len(outside) == 0 {
+		return internal, nil
+	}
+	external := &stat.AuthorStats{Name: stat.GroupExternal}
+	for _, stats := range stat.AggregateByIdentity(outside, identity) {
+		external.Merge(stats)
+	}
+	return internal, external
 }
 
-// 提取 AIG 比例
-func extractAIGRatio(re *regexp.Regexp, commit string) float64 {
-	matches := re.FindStringSubmatch(commit)
-	if len(matches) > 2 {
-		ratio, err := strconv.ParseFloat(matches[2], 64)
-		if err != nil || ratio < 0 {
-			return 0
+// 按最小活跃度筛选开发者，返回按邮箱排序的主列表和“其他”汇总行
+// 名单中的成员不参与筛选，始终单独列出
+func filterMinActivity(authorStats map[string]*stat.AuthorStats, roster []stat.RosterMember, minCommits, minLines int) ([]*stat.AuthorStats, *stat.AuthorStats) {
+	inRoster := make(map[string]bool, len(roster))
+	for _, member := range roster {
+		inRoster[strings.ToLower(member.Email)] = true
+	}
+
+	var authors []*stat.AuthorStats
+	var others *stat.AuthorStats
+	for _, stats := range authorStats {
+		lines := stats.TotalAddedLines + stats.TotalDeletedLines
+		if inRoster[strings.ToLower(stats.Email)] || (stats.CommitCount >= minCommits && lines >= minLines) {
+			authors = append(authors, stats)
+			continue
 		}
-		return ratio
+		if others == nil {
+			others = &stat.AuthorStats{Name: stat.GroupOthers}
+		}
+		others.Merge(stats)
 	}
-	return 0
-}
 
-// 分割提交信息
-func splitCommits(output string) []string {
-	var commits []string
-	lines := strings.Split(output, "\n")
-	var currentCommit strings.Builder
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].Email != authors[j].Email {
+			return authors[i].Email < authors[j].Email
+		}
+		return authors[i].Name < authors[j].Name
+	})
+	return authors, others
+}
 
-	for _, line := range lines {
-		if line == "" {
-			continue
+// 打印统计结果
+func printStatistics(w io.Writer, report *stat.Report) {
+	fmt.Fprintf(w, "\n%s\n", strings.Repeat("=", 80))
+	fmt.Fprintf(w, "统计结果汇总:\n")
+	fmt.Fprintf(w, "  分析范围:\n")
+	if report.Since != "" {
+		fmt.Fprintf(w, "    开始时间: %s\n", report.Since)
+	}
+	if report.Until != "" {
+		fmt.Fprintf(w, "    结束时间: %s\n", report.Until)
+	}
+	if report.RevRange != "" {
+		fmt.Fprintf(w, "    提交范围: %s\n", report.RevRange)
+	}
+	if report.Meta != nil {
+		fmt.Fprintf(w, "  运行信息:\n")
+		for _, line := range report.Meta.Lines() {
+			fmt.Fprintf(w, "    %s\n", line)
 		}
-		s := strings.Fields(line)
-		if len(s) > 0 && len(s[0]) == 40 && currentCommit.Len() > 0 {
-			commits = append(commits, currentCommit.String())
-			currentCommit.Reset()
+	}
+	if v := report.Validation; v != nil {
+		fmt.Fprintf(w, "  数据校验:\n")
+		fmt.Fprintf(w, "    git log --shortstat: %s 行\n", v.Shortstat)
+		fmt.Fprintf(w, "    计入统计: %s 行，报告合计: %s 行\n", v.Counted, v.Reported)
+		if v.Diff != nil {
+			fmt.Fprintf(w, "    git diff --shortstat: %s 行\n", v.Diff)
 		}
-		if currentCommit.Len() > 0 {
-			currentCommit.WriteByte('\n')
+		for _, note := range v.Notes {
+			fmt.Fprintf(w, "    - %s\n", note)
 		}
-		currentCommit.WriteString(line)
 	}
+	fmt.Fprintf(w, "%s\n", strings.Repeat("-", 80))
 
-	if currentCommit.Len() > 0 {
-		commits = append(commits, currentCommit.String())
+	var prevAuthors, prevGroups, prevCohorts []*stat.AuthorStats
+	if report.Previous != nil {
+		prevAuthors, prevGroups, prevCohorts = report.Previous.Authors, report.Previous.Groups, report.Previous.Cohorts
+		fmt.Fprintf(w, "  变化标注: 与上一统计周期 %s 相比\n", report.Previous.PeriodLabel())
+	}
+	if len(report.Org) > 0 {
+		stat.PrintOrg(w, report.Org, report.Meta.NumberFormat(), report.Meta.Filters.MinSample, report.Meta.Filters.MinSampleLines)
+	}
+	for _, stats := range report.Authors {
+		printAuthorStats(w, stats, previousStats(report, prevAuthors, stats), report.Meta, false)
+	}
+	for _, stats := range report.Groups {
+		printAuthorStats(w, stats, previousStats(report, prevGroups, stats), report.Meta, true)
+	}
+	if len(report.Cohorts) > 0 {
+		fmt.Fprintf(w, "\n  按 AIG 标记分组:\n")
+		for _, stats := range report.Cohorts {
+			printAuthorStats(w, stats, previousStats(report, prevCohorts, stats), report.Meta, true)
+		}
+	}
+	if len(report.Weeks) > 0 {
+		stat.PrintWeeks(w, report.Weeks, report.WeekScheme, report.Meta.NumberFormat(), report.Meta.Filters.MinSampleLines)
+	}
+	if report.BugIntroduction != nil {
+		stat.PrintBugIntroduction(w, report.BugIntroduction, report.Meta.NumberFormat())
 	}
-	return commits
+	if report.Reviews != nil {
+		stat.PrintReviews(w, report.Reviews, report.Meta.NumberFormat())
+	}
+	if report.TimeToFix != nil {
+		stat.PrintTimeToFix(w, report.TimeToFix, report.Meta.NumberFormat())
+	}
+	if report.Effort != nil {
+		stat.PrintEffort(w, report.Effort, report.Meta.NumberFormat())
+	}
+	if report.Duration != nil {
+		stat.PrintDuration(w, report.Duration, report.Meta.NumberFormat())
+	}
+	if report.Costs != nil {
+		stat.PrintCosts(w, report.Costs, report.Meta.NumberFormat())
+	}
+	if report.Lifecycle != nil {
+		stat.PrintFileLifecycle(w, report.Lifecycle, report.Meta.NumberFormat())
+	}
+	if report.Ownership != nil {
+		stat.PrintOwnership(w, report.Ownership, report.Meta.NumberFormat())
+	}
+	if len(report.Targets) > 0 {
+		stat.PrintTargets(w, report.Targets)
+	}
+	if len(report.Anomalies) > 0 {
+		stat.PrintAnomalies(w, report.Anomalies)
+	}
+	for _, heatmap := range report.Heatmaps {
+		stat.PrintHeatmap(w, heatmap)
+	}
+	fmt.Fprintf(w, "%s\n", strings.Repeat("=", 80))
 }
 
-// 打印统计结果
-func printStatistics(since, until string, authorStats map[string]*AuthorStats) {
-	fmt.Printf("\n%s\n", strings.Repeat("=", 80))
-	fmt.Printf("统计结果汇总:\n")
-	fmt.Printf("  分析范围:\n")
-	fmt.Printf("    开始时间: %s\n", since)
-	fmt.Printf("    结束时间: %s\n", until)
-	fmt.Printf("%s\n", strings.Repeat("-", 80))
-
-	for _, stats := range authorStats {
-		// 计算占比
-		var addedRatio, deletedRatio, aiBugContribution float64
+// 上一统计周期中与 stats 对应的开发者或汇总行，没有上期记录时返回 nil，上期没有该行时返回全零数据
+func previousStats(report *stat.Report, rows []*stat.AuthorStats, stats *stat.AuthorStats) *stat.AuthorStats {
+	if report.Previous == nil {
+		return nil
+	}
+	identity := report.Meta.Filters.Identity
+	for _, s := range rows {
+		if s.MemberCount > 0 || stats.MemberCount > 0 {
+			if s.Name == stats.Name {
+				return s
+			}
+		} else if stat.IdentityKey(identity, s.Name, s.Email) == stat.IdentityKey(identity, stats.Name, stats.Email) {
+			return s
+		}
+	}
+	return &stat.AuthorStats{}
+}
 
-		if stats.TotalAddedLines > 0 {
-			addedRatio = float64(stats.TotalAIAddedLines) / float64(stats.TotalAddedLines) * 100
+// 打印单个开发者或汇总行(group 为真)的统计，比例按运行信息中的最小样本设置显示
+// prev 不为 nil 时在各项指标后标注与上一统计周期相比的变化
+func printAuthorStats(w io.Writer, stats, prev *stat.AuthorStats, meta *stat.Metadata, group bool) {
+	minSample, minLines := meta.Filters.MinSample, meta.Filters.MinSampleLines
+	nf := meta.NumberFormat()
+	var diff *stat.Delta
+	if prev != nil {
+		diff = &stat.Delta{Color: stat.ColorEnabled(w), Format: nf}
+	} else {
+		prev = &stat.AuthorStats{}
+	}
+	note := ""
+	if diff != nil && prev.CommitCount == 0 {
+		note = " (上期无提交)"
+	}
+	if group {
+		fmt.Fprintf(w, "\n  %s (%s 人)%s:\n", stats.Name, nf.Int(stats.MemberCount), note)
+	} else {
+		fmt.Fprintf(w, "\n  开发者统计 (%s)%s:\n", stats.Name, note)
+		if stats.Email != "" {
+			fmt.Fprintf(w, "    邮箱: %s\n", stats.Email)
+		} else {
+			fmt.Fprintf(w, "    邮箱: (无)\n")
+		}
+	}
+	fmt.Fprintf(w, "    提交次数: %s 次%s\n", nf.Int(stats.CommitCount), diff.Count(prev.CommitCount, stats.CommitCount))
+	fmt.Fprintf(w, "      标记 AIG>0: %s 次，标记 AIG=0: %s 次，未标记: %s 次\n", nf.Int(stats.AITaggedCommits), nf.Int(stats.NoAITaggedCommits), nf.Int(stats.UntaggedCommits()))
+	if stats.NACommits+stats.ExemptCommits > 0 {
+		fmt.Fprintf(w, "      标记 n/a: %s 次，标记 exempt: %s 次 (不计入各比例)\n", nf.Int(stats.NACommits), nf.Int(stats.ExemptCommits))
+	}
+	if stats.EstimatedCommits > 0 {
+		fmt.Fprintf(w, "      其中 LLM 估算: %s 次\n", nf.Int(stats.EstimatedCommits))
+	}
+	fmt.Fprintf(w, "    代码变更统计:\n")
+	if !meta.Fast {
+		fmt.Fprintf(w, "      总代码添加: %s%s\n", nf.Lines(stats.TotalAddedLines), diff.Lines(prev.TotalAddedLines, stats.TotalAddedLines))
+		fmt.Fprintf(w, "      总代码删除: %s%s\n", nf.Lines(stats.TotalDeletedLines), diff.Lines(prev.TotalDeletedLines, stats.TotalDeletedLines))
+		fmt.Fprintf(w, "      AI贡献添加: %s%s (%s%s)\n", nf.Lines(stats.TotalAIAddedLines), diff.Lines(prev.TotalAIAddedLines, stats.TotalAIAddedLines),
+			stat.FormatSampleRatio(stats.TotalAIAddedLines, stats.RatioAddedLines(), minLines), diff.Ratio(prev.TotalAIAddedLines, prev.RatioAddedLines(), stats.TotalAIAddedLines, stats.RatioAddedLines(), minLines))
+		fmt.Fprintf(w, "      AI贡献删除: %s%s (%s%s)\n", nf.Lines(stats.TotalAIDeletedLines), diff.Lines(prev.TotalAIDeletedLines, stats.TotalAIDeletedLines),
+			stat.FormatSampleRatio(stats.TotalAIDeletedLines, stats.RatioDeletedLines(), minLines), diff.Ratio(prev.TotalAIDeletedLines, prev.RatioDeletedLines(), stats.TotalAIDeletedLines, stats.RatioDeletedLines(), minLines))
+	}
+	fmt.Fprintf(w, "      按提交平均 AI 占比: %s%s (不按行数加权)\n", stat.FormatSampleMean(stats.CommitAvgRatio(), stats.RatioCommits(), minSample),
+		diff.Mean(prev.CommitAvgRatio(), prev.RatioCommits(), stats.CommitAvgRatio(), stats.RatioCommits(), minSample))
+	if meta.Heuristic {
+		fmt.Fprintf(w, "      启发式估算AI添加: %s (%.2f%%，实验性)\n", nf.Lines(stats.HeuristicAILines), stats.HeuristicRatio())
+	}
+	fmt.Fprintf(w, "    Bug修复统计:\n")
+	fmt.Fprintf(w, "      总修复提交: %s 次%s\n", nf.Int(stats.FixCount), diff.Count(prev.FixCount, stats.FixCount))
+	fmt.Fprintf(w, "      AI参与修复: %s 次%s\n", nf.Int(stats.FixAndAIGCount), diff.Count(prev.FixAndAIGCount, stats.FixAndAIGCount))
+	fmt.Fprintf(w, "      AI修复贡献率: %s%s", stat.FormatSampleRatio(stats.FixAndAIGCount, stats.RatioFixCount(), minSample),
+		diff.Ratio(prev.FixAndAIGCount, prev.RatioFixCount(), stats.FixAndAIGCount, stats.RatioFixCount(), minSample))
+	if _, ok := stat.SampleRatio(stats.FixAndAIGCount, stats.RatioFixCount(), minSample); ok {
+		fmt.Fprintf(w, " (%s)", stat.FixRatioNote(stats.FixAndAIGCount, stats.RatioFixCount()))
+	}
+	fmt.Fprintln(w)
+	if meta.SZZ {
+		fmt.Fprintf(w, "      引入缺陷: %s 个 (代码被之后的修复提交修改)\n", nf.Int(stats.BugsIntroduced))
+	}
+	if lines := stat.FixSeverityLines(stats.FixSeverities, meta.Filters.FixSeverities, nf); len(lines) > 0 {
+		fmt.Fprintf(w, "      按严重级别:\n")
+		for _, line := range lines {
+			fmt.Fprintf(w, "        %s\n", line)
 		}
-		if stats.TotalDeletedLines > 0 {
-			deletedRatio = float64(stats.TotalAIDeletedLines) / float64(stats.TotalDeletedLines) * 100
+	}
+	if stats.ActiveDays > 0 && meta.Fast {
+		fmt.Fprintf(w, "    按出勤折算 (出勤 %s 天):\n", nf.Float(stats.ActiveDays, 1))
+		fmt.Fprintf(w, "      每天提交: %s 次\n", nf.Float(stats.PerActiveDay(stats.CommitCount), 2))
+	} else if stats.ActiveDays > 0 {
+		fmt.Fprintf(w, "    按出勤折算 (出勤 %s 天):\n", nf.Float(stats.ActiveDays, 1))
+		fmt.Fprintf(w, "      每天提交: %s 次，每天添加: %s 行，每天 AI 添加: %s 行\n", nf.Float(stats.PerActiveDay(stats.CommitCount), 2),
+			nf.Float(stats.PerActiveDay(stats.TotalAddedLines), 1), nf.Float(stats.PerActiveDay(stats.TotalAIAddedLines), 1))
+	}
+	if meta.Lifecycle {
+		fmt.Fprintf(w, "    文件: 新建 %s 个 (AI 参与 %s 个)，删除 %s 个，修改 %s 次\n", nf.Int(stats.FilesCreated), nf.Int(stats.AIFilesCreated), nf.Int(stats.FilesDeleted), nf.Int(stats.FilesModified))
+	}
+	if meta.ChangeType {
+		fmt.Fprintf(w, "    按变更类型: 新建文件 AI 添加占比 %s (%s)，已有文件 %s (%s)\n",
+			stat.FormatSampleRatio(stats.NewFileAIAddedLines, stats.NewFileAddedLines, minLines), nf.Lines(stats.NewFileAddedLines),
+			stat.FormatSampleRatio(stats.ExistingFileAIAddedLines, stats.ExistingFileAddedLines, minLines), nf.Lines(stats.ExistingFileAddedLines))
+	}
+	if meta.MessageQuality {
+		all := stats.AllMessageQuality()
+		fmt.Fprintf(w, "    提交信息: %s\n", messageQualityLine(all, nf, minSample))
+		if all.Commits > 0 {
+			ai, other := stats.MessageQuality(true), stats.MessageQuality(false)
+			fmt.Fprintf(w, "      AI 参与 (AIG>0，%s 次): %s\n", nf.Int(ai.Commits), messageQualityLine(ai, nf, minSample))
+			fmt.Fprintf(w, "      其他 (%s 次): %s\n", nf.Int(other.Commits), messageQualityLine(other, nf, minSample))
 		}
-		if stats.FixCount > 0 {
-			aiBugContribution = float64(stats.FixAndAIGCount) / float64(stats.FixCount) * 100
+	}
+	if r := stats.Rolling; r != nil {
+		fmt.Fprintf(w, "    近 %d 天平均:\n", r.Days)
+		fmt.Fprintf(w, "      每期代码添加: %s 行\n", nf.Float(r.AddedPerPeriod, 1))
+		fmt.Fprintf(w, "      AI贡献添加占比: %s\n", stat.FormatSampleRatio(r.TotalAIAddedLines, r.RatioAddedLines(), minLines))
+	}
+	if stats.ToolUsage {
+		fmt.Fprintf(w, "    工具使用记录:\n")
+		fmt.Fprintf(w, "      工具采纳行数: %s (%.2f%%)\n", nf.Lines(stats.ToolAcceptedLines), stats.ToolRatio())
+		if stats.UsageDiscrepancy {
+			fmt.Fprintf(w, "      [差异较大] 自报 AI 占比 %.2f%% 与工具采纳占比 %.2f%% 不符\n", stats.AddedRatio(), stats.ToolRatio())
 		}
-
-		fmt.Printf("\n  开发者统计 (%s):\n", stats.Name)
-		fmt.Printf("    邮箱: %s\n", stats.Email)
-		fmt.Printf("    代码变更统计:\n")
-		fmt.Printf("      总代码添加: %d 行\n", stats.TotalAddedLines)
-		fmt.Printf("      总代码删除: %d 行\n", stats.TotalDeletedLines)
-		fmt.Printf("      AI贡献添加: %d 行 (%.2f%%)\n", stats.TotalAIAddedLines, addedRatio)
-		fmt.Printf("      AI贡献删除: %d 行 (%.2f%%)\n", stats.TotalAIDeletedLines, deletedRatio)
-		fmt.Printf("    Bug修复统计:\n")
-		fmt.Printf("      总修复提交: %d 次\n", stats.FixCount)
-		fmt.Printf("      AI参与修复: %d 次\n", stats.FixAndAIGCount)
-		fmt.Printf("      AI修复贡献率: %.2f%%\n", aiBugContribution)
-		fmt.Printf("    %s\n", strings.Repeat("-", 80))
 	}
-	fmt.Printf("%s\n", strings.Repeat("=", 80))
+	fmt.Fprintf(w, "    %s\n", strings.Repeat("-", 80))
+}
+
+// 提交信息质量的一行说明，提交数低于 minSample 时比例显示为样本不足
+func messageQualityLine(q stat.MessageQuality, nf stat.NumberFormat, minSample int) string {
+	return fmt.Sprintf("平均 %s 字，Conventional Commits %s，引用 issue %s", nf.Float(q.AvgLength, 1),
+		stat.FormatSampleMean(q.ConventionalRatio, q.Commits, minSample), stat.FormatSampleMean(q.IssueRefRatio, q.Commits, minSample))
 }
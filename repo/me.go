@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AIStat/stat"
+)
+
+// /me 默认及最多返回的统计周期数
+const (
+	defaultMePeriods = 6
+	maxMePeriods     = 24
+)
+
+// meResponse 请求者本人在各统计周期的统计，不包含其他开发者的数据
+type meResponse struct {
+	Email string `json:"email"`
+	// 当前半月周期及之前的周期，从新到旧
+	Periods []mePeriod `json:"periods"`
+}
+
+// mePeriod 一个统计周期内本人在全部仓库的合计及按仓库的统计
+type mePeriod struct {
+	Since string              `json:"since"`
+	Until string              `json:"until"`
+	Stats *stat.AuthorStats   `json:"stats"`
+	Repos []*stat.AuthorStats `json:"repos,omitempty"`
+	// 按行数加权和按提交平均的 AI 添加占比，用于观察趋势
+	AddedRatio     float64 `json:"added_ratio"`
+	CommitAvgRatio float64 `json:"commit_avg_ratio"`
+}
+
+// 开发者自助查询：按认证得到的邮箱返回本人最近几个统计周期的统计和趋势，
+// 数据来自全部仓库的运行记录，按邮箱匹配提交。管理员和 lead 可以用 email 参数查询有权查看的开发者
+func (s *grafanaServer) handleMe(w http.ResponseWriter, r *http.Request) {
+	access, err := s.auth.access(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="aistat"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	// email 参数查询其他开发者，需要管理员权限或按角色可以查看该开发者
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	if email == "" {
+		email = access.email
+	}
+	if email == "" {
+		http.Error(w, "错误：无法确定开发者身份，请使用 OIDC 令牌访问或通过 email 参数指定", http.StatusBadRequest)
+		return
+	}
+	if !access.seesPerson(email) {
+		http.Error(w, "错误：没有查看该开发者数据的权限", http.StatusForbidden)
+		return
+	}
+	periods := defaultMePeriods
+	if v := r.URL.Query().Get("periods"); v != "" {
+		if periods, err = strconv.Atoi(v); err != nil || periods <= 0 || periods > maxMePeriods {
+			http.Error(w, "错误：periods 必须是 1~"+strconv.Itoa(maxMePeriods)+" 的整数", http.StatusBadRequest)
+			return
+		}
+	}
+	repos, err := s.store.Repos()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := meResponse{Email: email, Periods: []mePeriod{}}
+	now := time.Now()
+	for i := 0; i < periods; i++ {
+		since, until := stat.CurrentDateRange(now)
+		period, err := s.mePeriod(repos, email, since, until)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Periods = append(resp.Periods, period)
+		start, _ := time.ParseInLocation(stat.DateLayout, since, time.Local)
+		now = start.AddDate(0, 0, -1)
+	}
+	writeJSONResponse(w, resp)
+}
+
+// 统计周期 [since, until] 内本人在各仓库的提交
+func (s *grafanaServer) mePeriod(repos []string, email, since, until string) (mePeriod, error) {
+	stats, rows, err := stat.PersonalPeriod(s.store, repos, email, since, until)
+	if err != nil {
+		return mePeriod{}, err
+	}
+	return mePeriod{Since: since, Until: until, Stats: stats, Repos: rows,
+		AddedRatio: stats.AddedRatio(), CommitAvgRatio: stats.CommitAvgRatio()}, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"AIStat/stat"
+)
+
+// --out 的扩展名对应的输出格式
+var mergeOutFormats = map[string]string{
+	".txt":  stat.FormatText,
+	".json": stat.FormatJSON,
+	".csv":  stat.FormatCSV,
+	".html": stat.FormatHTML,
+	".htm":  stat.FormatHTML,
+	".pdf":  stat.FormatPDF,
+	".yaml": stat.FormatYAML,
+	".yml":  stat.FormatYAML,
+}
+
+// 把不同机器、不同仓库各自生成的 JSON 报告合并为一份报告，同一提交按哈希只计一次，
+// 开发者统计、分组等按合并后的提交重新汇总
+func runMerge(args []string) error {
+	opts := &Options{}
+	fs := flag.NewFlagSet("AIG_repo merge", flag.ContinueOnError)
+	resolveFlags := bindReportFlags(fs, opts)
+	out := fs.String("out", "", "合并报告的输出文件，按扩展名(.txt、.json、.csv、.html、.pdf、.yaml、.yml)选择格式")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe merge [选项] 报告.json...\n")
+		fs.PrintDefaults()
+	}
+	paths, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	resolveFlags()
+	if len(paths) < 2 {
+		fs.Usage()
+		return errors.New("错误：请指定至少两个 JSON 格式的报告")
+	}
+	outFormat := ""
+	if *out != "" {
+		var ok bool
+		if outFormat, ok = mergeOutFormats[strings.ToLower(filepath.Ext(*out))]; !ok {
+			return fmt.Errorf("错误：无法根据扩展名确定 '%s' 的输出格式，可用 .txt、.json、.csv、.html、.pdf、.yaml、.yml", *out)
+		}
+	}
+
+	reports := make([]*stat.Report, 0, len(paths))
+	for _, path := range paths {
+		report, err := stat.ReadReport(path)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+
+	// 未指定日期时使用各报告统计周期的并集，指定时只保留该日期范围内的提交
+	explicit := opts.Since != "" || opts.Until != "" || opts.RevRange != ""
+	if !explicit {
+		opts.Since, opts.Until = stat.MergePeriod(reports)
+		if opts.Since == "" {
+			opts.RevRange = mergedRevRange(reports)
+		}
+	}
+	// 指定 --out 且未指定 --format 时只写入该文件
+	onlyOut := *out != "" && opts.Format == ""
+	cfg, err := resolveOptions(opts)
+	if err != nil {
+		return err
+	}
+	if onlyOut {
+		opts.Formats = nil
+	}
+	if *out != "" {
+		opts.Sinks = append(opts.Sinks, outFormat+"="+*out)
+	}
+
+	commits, duplicates := stat.MergeCommits(reports)
+	if explicit && opts.Since != "" {
+		commits = stat.CommitsInPeriod(commits, opts.Since, opts.Until)
+	}
+	report, err := assembleReport(commits, stat.MergeMetadata(reports, paths), opts, cfg)
+	if err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		fmt.Fprintf(os.Stderr, "已合并 %d 份报告，%d 个提交，去除重复的提交 %d 个\n", len(reports), len(commits), duplicates)
+	} else {
+		fmt.Fprintf(os.Stderr, "已合并 %d 份报告，%d 个提交\n", len(reports), len(commits))
+	}
+	for _, warning := range report.Meta.Warnings {
+		fmt.Fprintf(os.Stderr, "警告：%s\n", warning)
+	}
+
+	stat.RegisterRenderer(stat.FormatText, renderText)
+	return opts.WriteSinks(os.Stdout, "merged", report)
+}
+
+// 按提交范围统计的报告合并后的范围说明，如 v1.0..v1.1, main~10..main
+func mergedRevRange(reports []*stat.Report) string {
+	var ranges []string
+	seen := make(map[string]bool)
+	for _, r := range reports {
+		label := r.PeriodLabel()
+		if !seen[label] {
+			seen[label] = true
+			ranges = append(ranges, label)
+		}
+	}
+	return strings.Join(ranges, ", ")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"AIStat/stat"
+)
+
+// 交互选择的输出格式，第一项为默认值
+var pickerFormats = []string{stat.FormatText, stat.FormatHTML, stat.FormatCSV, stat.FormatJSON}
+
+// 不带参数在终端中运行时逐项询问统计周期、作者过滤和输出格式，避免首次使用时直接按默认周期统计；
+// 配置文件或环境变量已指定的项不再询问。问题写到标准错误输出，返回等价的命令行参数
+func pickOptions(opts *Options, now time.Time) ([]string, error) {
+	// 配置文件有误时不询问，由 resolveOptions 报告错误
+	cfg, err := stat.LoadConfig(opts.ConfigPath)
+	if err != nil {
+		return nil, nil
+	}
+	cfg.ApplyEnv(os.LookupEnv)
+
+	p := stat.NewPrompter(os.Stdin, os.Stderr, interrupt.Done())
+	fmt.Fprintf(os.Stderr, "未指定参数，请选择统计范围 (直接回车使用默认值，使用 --help 查看全部选项):\n")
+	var args []string
+	if cfg.Since == "" && cfg.Until == "" {
+		if opts.Since, opts.Until, err = p.ChoosePeriod(now); err != nil {
+			return nil, err
+		}
+		args = append(args, "--since", opts.Since, "--until", opts.Until)
+	}
+	if opts.Author, err = p.Ask("作者过滤 (姓名或邮箱的一部分，直接回车统计全部开发者)", ""); err != nil {
+		return nil, err
+	}
+	if opts.Author != "" {
+		args = append(args, "--author", opts.Author)
+	}
+	if cfg.Format == "" {
+		i, err := p.Choose("输出格式:", []string{"text 文本，输出到终端", "html 写入当前目录", "csv 写入当前目录", "json 写入当前目录"}, 0)
+		if err != nil {
+			return nil, err
+		}
+		if format := pickerFormats[i]; format != stat.FormatText {
+			opts.Format = format
+			args = append(args, "--format", format)
+			if cfg.OutDir == "" {
+				opts.OutDir = "."
+				args = append(args, "--out-dir", ".")
+			}
+		}
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			quoted[i] = strconv.Quote(arg)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "等价的命令: AIG_repo.exe %s\n\n", strings.Join(quoted, " "))
+	return args, nil
+}
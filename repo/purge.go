@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"AIStat/stat"
+)
+
+// 按保留期限删除运行记录存储中过期的运行记录、提交和冻结记录
+func runPurge(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo purge", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径 (默认读取当前目录下的 "+stat.DefaultConfigFile+")")
+	storeDir := fs.String("store", "", "运行记录保存位置 (默认 ~/.aistat/runs)")
+	keep := fs.Int("keep-months", 0, "保留最近 N 个月的数据 (默认读取配置文件的 retention_months)")
+	before := fs.String("before", "", "删除该日期(YYYY-MM-DD)之前的数据，指定时忽略保留月数")
+	dryRun := fs.Bool("dry-run", false, "只显示将要删除的数量，不删除")
+	format := fs.String("format", stat.FormatText, "输出格式: text, json")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe purge [选项]\n")
+		fs.PrintDefaults()
+	}
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+	if *format != stat.FormatText && *format != stat.FormatJSON {
+		return fmt.Errorf("错误：不支持的输出格式 '%s'", *format)
+	}
+
+	cutoff := *before
+	if cutoff != "" {
+		if _, err := time.ParseInLocation(stat.DateLayout, cutoff, time.Local); err != nil {
+			return fmt.Errorf("错误：日期 '%s' 格式不正确，应为 YYYY-MM-DD", cutoff)
+		}
+	} else {
+		months := *keep
+		if months == 0 {
+			cfg, err := stat.LoadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			months = cfg.RetentionMonths
+		}
+		if months <= 0 {
+			return fmt.Errorf("错误：请通过 --keep-months、--before 或配置文件的 retention_months 指定保留期限")
+		}
+		cutoff = stat.RetentionCutoff(months, time.Now())
+	}
+
+	store, err := openStore(*configPath, *storeDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	result, err := store.Purge(cutoff, *dryRun)
+	if err != nil {
+		return err
+	}
+	if *format == stat.FormatJSON {
+		return stat.WriteJSON(os.Stdout, result)
+	}
+	action := "已删除"
+	if *dryRun {
+		action = "将删除"
+	}
+	fmt.Printf("%s %s %s\n", store, action, result)
+	return nil
+}
+
+// serve 定期按保留期限清理的间隔
+const purgeInterval = 24 * time.Hour
+
+// serve 启动时及之后每天按配置的保留月数清理存储，收到停止信号时结束
+func enforceRetention(store stat.Store, months int) {
+	for {
+		result, err := store.Purge(stat.RetentionCutoff(months, time.Now()), false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "警告：按保留期限清理运行记录失败: %v\n", err)
+		} else if !result.Empty() {
+			fmt.Fprintf(os.Stderr, "按保留期限(%d 个月)已删除 %s\n", months, result)
+		}
+		select {
+		case <-interrupt.Done():
+			return
+		case <-time.After(purgeInterval):
+		}
+	}
+}
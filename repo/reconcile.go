@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"AIStat/stat"
+)
+
+// 重新统计已冻结的统计周期，按开发者逐个列出冻结后晚推送、消失或被改写的提交
+func runReconcile(args []string) error {
+	opts := &Options{}
+	fs := flag.NewFlagSet("AIG_repo reconcile", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe reconcile [选项] [开始日期] [结束日期]\n")
+		fs.PrintDefaults()
+	}
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		opts.Since = positional[0]
+	}
+	if len(positional) > 1 {
+		opts.Until = positional[1]
+	}
+	cfg, err := resolveOptions(opts)
+	if err != nil {
+		return err
+	}
+	if opts.Store == stat.StoreOff {
+		return errors.New("错误：冻结记录保存在运行记录中，请不要使用 --store off")
+	}
+	asJSON := false
+	for _, format := range opts.Formats {
+		if format != stat.FormatText && format != stat.FormatJSON {
+			return fmt.Errorf("错误：reconcile 不支持输出格式 '%s'，请使用 text 或 json", format)
+		}
+		asJSON = asJSON || format == stat.FormatJSON
+	}
+
+	git := opts.GitRunner(opts.Repo)
+	report, err := generateReport("AIG_repo reconcile", args, git, opts, cfg)
+	if err != nil {
+		return err
+	}
+	store, err := stat.OpenStore(opts.Store)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	frozen, err := store.FrozenPeriods(report.Meta.Repo)
+	if err != nil {
+		return err
+	}
+	f := stat.FindFrozen(report, frozen)
+	if f == nil {
+		return fmt.Errorf("错误：统计周期 %s 没有冻结记录，请先用 freeze 子命令冻结", report.PeriodLabel())
+	}
+	reconciliation := stat.Reconcile(f, report)
+	if asJSON {
+		return stat.WriteJSON(os.Stdout, reconciliation)
+	}
+	stat.PrintReconciliation(os.Stdout, reconciliation, report.Meta.NumberFormat())
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"AIStat/stat"
+)
+
+// 统计两个发布标签之间的 AI 贡献，生成适合放入发布说明的摘要
+func runRelease(args []string) error {
+	opts := &Options{}
+	fs := flag.NewFlagSet("AIG_repo release", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	var tag, prevTag string
+	fs.StringVar(&tag, "tag", "", "发布标签，如 v2.3.0")
+	fs.StringVar(&prevTag, "prev-tag", "", "上一个发布标签 (默认自动查找)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe release --tag 标签 [选项]\n")
+		fs.PrintDefaults()
+	}
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+	if tag == "" {
+		fs.Usage()
+		return errors.New("错误：请通过 --tag 指定发布标签")
+	}
+
+	// 先以标签作为提交范围，避免补全默认日期范围
+	opts.RevRange = tag
+	cfg, err := resolveOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	git := opts.GitRunner(opts.Repo)
+	if prevTag == "" {
+		if prevTag, err = stat.PreviousTag(git, tag); err != nil {
+			return err
+		}
+	}
+	if prevTag != "" {
+		opts.RevRange = prevTag + ".." + tag
+	}
+
+	report, err := generateReport("AIG_repo release", args, git, opts, cfg)
+	if err != nil {
+		return err
+	}
+
+	paths, err := opts.WriteOutputs(os.Stdout, "release", func(w io.Writer, format string) error {
+		switch format {
+		case stat.FormatJSON:
+			return stat.WriteJSON(w, report)
+		case stat.FormatCSV:
+			return stat.WriteCSV(w, report)
+		case stat.FormatHTML:
+			return stat.WriteHTML(w, report)
+		case stat.FormatPDF:
+			return stat.WritePDF(w, report, opts.PDFTool)
+		}
+		printReleaseNotes(w, tag, prevTag, report)
+		return nil
+	})
+	for _, path := range paths {
+		fmt.Fprintf(os.Stderr, "报告已写入: %s\n", path)
+	}
+	return err
+}
+
+// 以 Markdown 格式打印发布摘要
+func printReleaseNotes(w io.Writer, tag, prevTag string, report *stat.Report) {
+	// 名单中本期无提交的成员不计入贡献者
+	rows := append(append([]*stat.AuthorStats{}, report.Authors...), report.Groups...)
+	total := &stat.AuthorStats{}
+	for _, stats := range rows {
+		if stats.CommitCount > 0 {
+			total.Merge(stats)
+		}
+	}
+
+	fmt.Fprintf(w, "## AI 贡献统计 %s\n\n", tag)
+	if prevTag != "" {
+		fmt.Fprintf(w, "统计范围: %s..%s\n\n", prevTag, tag)
+	} else {
+		fmt.Fprintf(w, "统计范围: %s 及之前的全部提交\n\n", tag)
+	}
+	fmt.Fprintf(w, "- 提交次数: %d 次\n", total.CommitCount)
+	fmt.Fprintf(w, "- 贡献者: %d 人\n", total.MemberCount)
+	fmt.Fprintf(w, "- 代码添加: %d 行，其中 AI 贡献 %d 行 (%.2f%%)\n", total.TotalAddedLines, total.TotalAIAddedLines, total.AddedRatio())
+	fmt.Fprintf(w, "- 代码删除: %d 行，其中 AI 贡献 %d 行 (%.2f%%)\n", total.TotalDeletedLines, total.TotalAIDeletedLines, total.DeletedRatio())
+	fmt.Fprintf(w, "- Bug 修复: %d 次，其中 AI 参与 %d 次 (%.2f%%)\n", total.FixCount, total.FixAndAIGCount, total.AIFixRatio())
+	for _, warning := range report.Meta.Warnings {
+		fmt.Fprintf(w, "- 警告: %s\n", warning)
+	}
+
+	if total.CommitCount == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n| 贡献者 | 提交次数 | 代码添加 | AI添加占比 | Bug修复 |\n")
+	fmt.Fprintf(w, "| --- | ---: | ---: | ---: | ---: |\n")
+	for _, stats := range rows {
+		if stats.CommitCount == 0 {
+			continue
+		}
+		name := strings.ReplaceAll(stats.Name, "|", "\\|")
+		if stats.MemberCount > 0 {
+			name = fmt.Sprintf("%s (%d 人)", name, stats.MemberCount)
+		}
+		fmt.Fprintf(w, "| %s | %d | %d | %.2f%% | %d |\n", name, stats.CommitCount, stats.TotalAddedLines, stats.AddedRatio(), stats.FixCount)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"AIStat/stat"
+)
+
+// 根据已保存的运行记录对比相邻统计周期的开发者变化及团队 AI 占比的变化
+func runRetention(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo retention", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径 (默认读取当前目录下的 "+stat.DefaultConfigFile+")")
+	storeDir := fs.String("store", "", "运行记录保存位置 (默认 ~/.aistat/runs)")
+	repoDir := fs.String("repo", "", "仓库目录，用于确定运行记录中的仓库名 (默认当前目录)")
+	name := fs.String("name", "", "运行记录中的仓库名，指定时不读取仓库目录")
+	format := fs.String("format", stat.FormatText, "输出格式: text, json")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe retention [选项]\n")
+		fs.PrintDefaults()
+	}
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+	if *format != stat.FormatText && *format != stat.FormatJSON {
+		return fmt.Errorf("错误：不支持的输出格式 '%s'", *format)
+	}
+
+	store, err := openStore(*configPath, *storeDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	repo := *name
+	if repo == "" {
+		info, err := stat.DetectRepo(&stat.ExecGitRunner{Dir: *repoDir})
+		if err != nil {
+			return err
+		}
+		repo = info.Name()
+	}
+	runs, err := store.LoadRuns(repo)
+	if err != nil {
+		return err
+	}
+	reports := stat.LatestByPeriod(runs)
+	if len(reports) < 2 {
+		return fmt.Errorf("错误：仓库 '%s' 的运行记录少于两个统计周期，无法对比", repo)
+	}
+
+	changes := stat.Retention(reports)
+	if *format == stat.FormatJSON {
+		return stat.WriteJSON(os.Stdout, changes)
+	}
+	printRetention(os.Stdout, repo, changes)
+	return nil
+}
+
+// 打印各统计周期之间的开发者变化
+func printRetention(w io.Writer, repo string, changes []stat.PeriodChange) {
+	fmt.Fprintf(w, "开发者变化 (%s):\n", repo)
+	for _, c := range changes {
+		fmt.Fprintf(w, "\n%s -> %s\n", c.From, c.To)
+		fmt.Fprintf(w, "  留存: %d 人，新增: %d 人，离开: %d 人\n", c.Retained, len(c.Joined), len(c.Left))
+		if len(c.Joined) > 0 {
+			fmt.Fprintf(w, "  新增: %s\n", authorNames(c.Joined))
+		}
+		if len(c.Left) > 0 {
+			fmt.Fprintf(w, "  离开: %s\n", authorNames(c.Left))
+		}
+		fmt.Fprintf(w, "  团队 AI 添加占比: %.2f%% -> %.2f%%\n", c.FromRatio, c.ToRatio)
+		if c.Retained > 0 {
+			fmt.Fprintf(w, "    留存开发者: %.2f%% -> %.2f%%\n", c.RetainedFromRatio, c.RetainedToRatio)
+		}
+		if len(c.Joined) > 0 {
+			fmt.Fprintf(w, "    新增开发者(本期): %.2f%%\n", c.JoinedRatio)
+		}
+		if len(c.Left) > 0 {
+			fmt.Fprintf(w, "    离开开发者(上期): %.2f%%\n", c.LeftRatio)
+		}
+	}
+}
+
+// 以 "姓名 <邮箱>" 形式列出开发者
+func authorNames(authors []*stat.AuthorStats) string {
+	names := make([]string, 0, len(authors))
+	for _, a := range authors {
+		names = append(names, fmt.Sprintf("%s <%s>", a.Name, a.Email))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"AIStat/stat"
+)
+
+// 以 Grafana JSON 数据源协议提供已保存运行记录的时间序列
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo serve", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径 (默认读取当前目录下的 "+stat.DefaultConfigFile+")")
+	storeDir := fs.String("store", "", "运行记录保存位置 (默认 ~/.aistat/runs)")
+	listen := fs.String("listen", "127.0.0.1:8080", "监听地址")
+	var webhookRepos, notifyURLs []string
+	fs.Func("webhook-repo", "接收推送 webhook 的仓库，格式 仓库全名=本地克隆目录，如 group/app=/srv/app，可多次指定 (密钥从环境变量 "+stat.EnvWebhookSecret+" 读取)", func(s string) error {
+		webhookRepos = append(webhookRepos, s)
+		return nil
+	})
+	fs.Func("notify-url", "推送触发的统计更新完成后以 JSON POST 通知的地址，可多次指定", func(s string) error {
+		notifyURLs = append(notifyURLs, s)
+		return nil
+	})
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe serve [选项]\n")
+		fs.PrintDefaults()
+	}
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+
+	location, err := storeLocation(*configPath, *storeDir)
+	if err != nil {
+		return err
+	}
+	store, err := stat.OpenStore(location)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	cfg, err := stat.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	auth, err := newAPIAuth(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.WeeklyDigest != nil {
+		if err := cfg.WeeklyDigest.Validate(); err != nil {
+			return err
+		}
+	}
+	repos, err := parseRepoMapping(webhookRepos)
+	if err != nil {
+		return err
+	}
+	if len(webhookRepos) == 0 {
+		repos = cfg.WebhookRepos
+	}
+	if repos == nil {
+		repos = make(map[string]string)
+	}
+	// 项目的 webhook 仓库使用项目自己的配置文件(名单、团队目标等)
+	configs := make(map[string]string)
+	for _, p := range cfg.Projects {
+		for name, dir := range p.WebhookRepos {
+			repos[name] = dir
+			if p.Config != "" {
+				configs[name] = p.Config
+			}
+		}
+	}
+	if len(notifyURLs) == 0 {
+		notifyURLs = cfg.NotifyURLs
+	}
+
+	if len(repos) > 0 && os.Getenv(stat.EnvWebhookSecret) == "" {
+		return fmt.Errorf("错误：接收推送 webhook 需要设置环境变量 %s", stat.EnvWebhookSecret)
+	}
+
+	var hook *webhookHandler
+	server := &grafanaServer{store: store, auth: auth, publicBadges: cfg.PublicBadges}
+	mux := server.routes()
+	fmt.Fprintf(os.Stderr, "Grafana 数据源已启动: http://%s (运行记录 %s)\n", *listen, store)
+	if auth.enabled() {
+		fmt.Fprintf(os.Stderr, "已启用访问令牌校验 (%d 个项目)\n", len(cfg.Projects))
+	}
+	if len(repos) > 0 {
+		hook = &webhookHandler{
+			configPath: *configPath,
+			configs:    configs,
+			store:      store,
+			location:   location,
+			secret:     os.Getenv(stat.EnvWebhookSecret),
+			repos:      repos,
+		}
+		if len(notifyURLs) > 0 {
+			hook.notifier = &stat.Notifier{URLs: notifyURLs}
+		}
+		mux.Handle("/webhook", hook)
+		fmt.Fprintf(os.Stderr, "推送 webhook 地址: http://%s/webhook (%d 个仓库)\n", *listen, len(repos))
+	}
+	if cfg.RetentionMonths > 0 {
+		fmt.Fprintf(os.Stderr, "运行记录保留 %d 个月，每天清理更早的数据\n", cfg.RetentionMonths)
+		go enforceRetention(store, cfg.RetentionMonths)
+	}
+	if cfg.WeeklyDigest != nil {
+		fmt.Fprintf(os.Stderr, "每周发送个人统计周报，下次发送时间 %s\n", cfg.WeeklyDigest.Next(time.Now()).Format("2006-01-02 15:04"))
+		go scheduleDigests(store, cfg.Roster, cfg.WeeklyDigest)
+	}
+
+	// 收到 SIGINT/SIGTERM 时停止接收请求，等待进行中的请求和推送分析结束后关闭存储
+	srv := &http.Server{Addr: *listen, Handler: mux}
+	go func() {
+		<-interrupt.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "警告：关闭服务失败: %v\n", err)
+		}
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if hook != nil {
+		hook.pending.Wait()
+	}
+	fmt.Fprintln(os.Stderr, "服务已停止")
+	return nil
+}
+
+// 停止服务时等待进行中的请求结束的最长时间
+const shutdownTimeout = 10 * time.Second
+
+// 运行记录的位置：命令行参数 > 配置文件 > 默认目录，配置为 off 时也读取默认目录
+func storeLocation(configPath, location string) (string, error) {
+	cfg, err := stat.LoadConfig(configPath)
+	if err != nil {
+		return "", err
+	}
+	if location == "" {
+		location = cfg.Store
+	}
+	if location == "" || location == stat.StoreOff {
+		location = stat.DefaultStoreDir()
+	}
+	return location, nil
+}
+
+// 打开运行记录存储，位置的确定方式同 storeLocation
+func openStore(configPath, location string) (stat.Store, error) {
+	location, err := storeLocation(configPath, location)
+	if err != nil {
+		return nil, err
+	}
+	return stat.OpenStore(location)
+}
+
+// grafanaServer 实现 Grafana JSON 数据源(simpod-json-datasource)的接口
+// 连通性检查不需要令牌，其他接口只能访问令牌所属项目的仓库
+type grafanaServer struct {
+	store stat.Store
+	auth  *apiAuth
+	// 徽章接口不校验令牌
+	publicBadges bool
+}
+
+func (s *grafanaServer) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleHealth)
+	mux.Handle("/search", s.auth.protect(http.HandlerFunc(s.handleSearch)))
+	mux.Handle("/metrics", s.auth.protect(http.HandlerFunc(s.handleMetrics)))
+	mux.Handle("/metric-payload-options", s.auth.protect(http.HandlerFunc(s.handlePayloadOptions)))
+	mux.Handle("/query", s.auth.protect(http.HandlerFunc(s.handleQuery)))
+	// 只返回请求者本人(或按角色可以查看的开发者)的数据，不需要项目权限
+	mux.HandleFunc("/me", s.handleMe)
+	if s.publicBadges {
+		mux.Handle("/badge", publicAccess(http.HandlerFunc(s.handleBadge)))
+	} else {
+		mux.Handle("/badge", s.auth.protect(http.HandlerFunc(s.handleBadge)))
+	}
+	return mux
+}
+
+// 数据源连通性检查
+func (s *grafanaServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprintln(w, "OK")
+}
+
+// 旧版协议：返回指标名称列表
+func (s *grafanaServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, stat.SeriesMetrics)
+}
+
+// 返回指标及其可选参数
+func (s *grafanaServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	type payload struct {
+		Label   string              `json:"label"`
+		Name    string              `json:"name"`
+		Type    string              `json:"type"`
+		Options []map[string]string `json:"options,omitempty"`
+	}
+	type metric struct {
+		Label    string    `json:"label"`
+		Value    string    `json:"value"`
+		Payloads []payload `json:"payloads"`
+	}
+
+	payloads := []payload{
+		{Label: "仓库", Name: "repo", Type: "select"},
+		{Label: "开发者邮箱", Name: "author", Type: "input"},
+		{Label: "分组", Name: "group_by", Type: "select", Options: []map[string]string{
+			{"label": "不分组", "value": ""},
+			{"label": "按开发者", "value": "author"},
+			{"label": "按仓库", "value": "repo"},
+		}},
+	}
+	metrics := make([]metric, 0, len(stat.SeriesMetrics))
+	for _, m := range stat.SeriesMetrics {
+		metrics = append(metrics, metric{Label: m, Value: m, Payloads: payloads})
+	}
+	writeJSONResponse(w, metrics)
+}
+
+// 返回参数的可选值，目前只有仓库列表
+func (s *grafanaServer) handlePayloadOptions(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options := []map[string]string{}
+	if req.Name == "repo" {
+		repos, err := s.visibleRepos(requestAccess(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		options = append(options, map[string]string{"label": "全部仓库", "value": ""})
+		for _, repo := range repos {
+			options = append(options, map[string]string{"label": repo, "value": repo})
+		}
+	}
+	writeJSONResponse(w, options)
+}
+
+// Grafana 查询请求
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs int64 `json:"intervalMs"`
+	Targets    []struct {
+		Target  string                 `json:"target"`
+		Hide    bool                   `json:"hide"`
+		Payload map[string]interface{} `json:"payload"`
+	} `json:"targets"`
+}
+
+// Grafana 时间序列响应，datapoints 为 [值, 毫秒时间戳]
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// 按查询的时间范围和间隔返回各指标的时间序列
+func (s *grafanaServer) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var query grafanaQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// 提交比较稀疏，间隔至少为 1 天
+	step := time.Duration(query.IntervalMs) * time.Millisecond
+	if step < 24*time.Hour {
+		step = 24 * time.Hour
+	}
+	from, to := query.Range.From.Local(), query.Range.To.Local()
+
+	access := requestAccess(r)
+	result := []grafanaSeries{}
+	for _, target := range query.Targets {
+		if target.Hide || target.Target == "" {
+			continue
+		}
+		repoFilter := payloadString(target.Payload, "repo")
+		author := strings.ToLower(payloadString(target.Payload, "author"))
+		groupBy := payloadString(target.Payload, "group_by")
+
+		if access.aggregateOnly && (author != "" || groupBy == "author") {
+			http.Error(w, "错误：启用角色后项目令牌只能查询汇总数据，不能按开发者过滤或分组", http.StatusForbidden)
+			return
+		}
+		if repoFilter != "" && !access.allows(repoFilter) {
+			http.Error(w, fmt.Sprintf("错误：没有仓库 '%s' 的访问权限", repoFilter), http.StatusForbidden)
+			return
+		}
+		commits, repoOf, err := s.loadCommits(access, repoFilter, from, to)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if author != "" {
+			filtered := commits[:0]
+			for _, c := range commits {
+				if strings.ToLower(c.Email) == author || strings.ToLower(c.Author) == author {
+					filtered = append(filtered, c)
+				}
+			}
+			commits = filtered
+		}
+
+		group := func(c *stat.CommitStats) string {
+			switch groupBy {
+			case "author":
+				return c.Author
+			case "repo":
+				return repoOf[c.ID]
+			}
+			return target.Target
+		}
+		series, err := stat.BuildSeries(commits, target.Target, from, to, step, group)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, ser := range series {
+			gs := grafanaSeries{Target: ser.Name, Datapoints: [][2]float64{}}
+			if groupBy != "" {
+				gs.Target = target.Target + " " + ser.Name
+			}
+			for _, p := range ser.Points {
+				gs.Datapoints = append(gs.Datapoints, [2]float64{p.Value, float64(p.Time.UnixMilli())})
+			}
+			result = append(result, gs)
+		}
+	}
+	writeJSONResponse(w, result)
+}
+
+// 运行记录中可以访问的仓库
+func (s *grafanaServer) visibleRepos(access *apiAccess) ([]string, error) {
+	repos, err := s.store.Repos()
+	if err != nil {
+		return nil, err
+	}
+	visible := repos[:0]
+	for _, repo := range repos {
+		if access.allows(repo) {
+			visible = append(visible, repo)
+		}
+	}
+	return visible, nil
+}
+
+// 读取时间范围内的提交，repo 为空时读取所有可以访问的仓库，同时返回提交所属的仓库
+func (s *grafanaServer) loadCommits(access *apiAccess, repo string, from, to time.Time) ([]stat.CommitStats, map[string]string, error) {
+	repos := []string{repo}
+	if repo == "" {
+		var err error
+		if repos, err = s.visibleRepos(access); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var commits []stat.CommitStats
+	repoOf := make(map[string]string)
+	// 按日期读取，首尾多取一天，精确的时间范围由 BuildSeries 筛选
+	fromDate := from.AddDate(0, 0, -1).Format(stat.DateLayout)
+	toDate := to.AddDate(0, 0, 1).Format(stat.DateLayout)
+	for _, name := range repos {
+		repoCommits, err := s.store.Commits(name, fromDate, toDate)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range repoCommits {
+			// 启用角色后只保留可以查看的开发者的提交
+			if access.seesCommit(&c) {
+				repoOf[c.ID] = name
+				commits = append(commits, c)
+			}
+		}
+	}
+	return commits, repoOf, nil
+}
+
+// 读取字符串类型的参数
+func payloadString(payload map[string]interface{}, name string) string {
+	if v, ok := payload[name].(string); ok {
+		return strings.TrimSpace(v)
+	}
+	return ""
+}
+
+// 以 JSON 格式写出响应
+func writeJSONResponse(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"AIStat/stat"
+)
+
+// localStatus 当前开发者本期至今的统计，供编辑器状态栏等本地工具显示
+type localStatus struct {
+	Email string            `json:"email"`
+	Repo  string            `json:"repo"`
+	Since string            `json:"since"`
+	Until string            `json:"until"`
+	Stats *stat.AuthorStats `json:"stats"`
+	// 按行数加权和按提交平均的 AI 添加占比
+	AddedRatio     float64 `json:"added_ratio"`
+	CommitAvgRatio float64 `json:"commit_avg_ratio"`
+	// 状态栏显示的短文本，如 "AI: 34%"，本期没有添加行时为 "AI: -"
+	Label     string   `json:"label"`
+	UpdatedAt string   `json:"updated_at"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// localServer 按需统计当前仓库中本人的提交，仓库引用和统计周期不变时返回上次的结果
+type localServer struct {
+	base  Options
+	email string
+	// 命令行参数，记录在审计日志中
+	args []string
+
+	mu     sync.Mutex
+	status *localStatus
+	// 上次统计时的仓库引用和统计周期
+	refs, period string
+}
+
+// 在本地提供当前开发者本期至今的 AI 统计，统计方式与正式报告相同
+func runServeLocal(args []string) error {
+	base := Options{}
+	fs := flag.NewFlagSet("AIG_repo serve-local", flag.ContinueOnError)
+	base.BindFlags(fs)
+	listen := fs.String("listen", "127.0.0.1:8765", "监听地址，只应监听本机地址")
+	email := fs.String("email", "", "开发者邮箱 (默认读取仓库的 git config user.email)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe serve-local [选项] [开始日期] [结束日期]\n")
+		fs.PrintDefaults()
+	}
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		base.Since = positional[0]
+	}
+	if len(positional) > 1 {
+		base.Until = positional[1]
+	}
+	host, _, err := net.SplitHostPort(*listen)
+	if err != nil {
+		return fmt.Errorf("错误：监听地址 '%s' 格式不正确: %v", *listen, err)
+	}
+	if !loopbackHost(host) {
+		fmt.Fprintf(os.Stderr, "警告：监听地址 %s 不是本机地址，其他机器可以读取你的统计数据\n", *listen)
+	}
+
+	s := &localServer{base: base, email: strings.TrimSpace(*email), args: args}
+	if s.email == "" {
+		s.email = stat.GitUserEmail(&stat.ExecGitRunner{Dir: base.Repo})
+	}
+	if s.email == "" {
+		return errors.New("错误：无法确定开发者邮箱，请配置 git config user.email 或通过 --email 指定")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleHealth)
+	mux.HandleFunc("/status", s.handleStatus)
+	srv := &http.Server{Addr: *listen, Handler: mux}
+	go func() {
+		<-interrupt.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "警告：关闭服务失败: %v\n", err)
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "本地统计服务已启动: http://%s/status (%s)\n", *listen, s.email)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *localServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprintln(w, "OK")
+}
+
+// 返回本期至今的统计，只接受 Host 为本机地址的请求，避免网页通过 DNS 重绑定读取数据
+func (s *localServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if !loopbackHost(host) {
+		http.Error(w, "错误：只接受本机地址的请求", http.StatusForbidden)
+		return
+	}
+	status, err := s.current()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSONResponse(w, status)
+}
+
+// 当前的统计：未指定日期时为当前所在的半月周期，随时间切换；仓库有新提交或切换分支时重新统计
+func (s *localServer) current() (*localStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	opts := s.base
+	if opts.Since == "" && opts.Until == "" && opts.RevRange == "" {
+		opts.Since, opts.Until = stat.CurrentDateRange(time.Now())
+	}
+	if _, err := resolveOptions(&opts); err != nil {
+		return nil, err
+	}
+	git := opts.GitRunner(opts.Repo)
+	refs := stat.RefsSignature(git)
+	period := opts.Since + "~" + opts.Until + "~" + opts.RevRange
+	if s.status != nil && refs == s.refs && period == s.period {
+		return s.status, nil
+	}
+
+	report, err := auditReport("AIG_repo serve-local", s.args, git, &opts, func() (*stat.Report, error) {
+		return s.analyze(git, &opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	meta, stats := report.Meta, report.Authors[0]
+	status := &localStatus{
+		Email:          s.email,
+		Repo:           meta.Repo,
+		Since:          opts.Since,
+		Until:          opts.Until,
+		Stats:          stats,
+		AddedRatio:     stats.AddedRatio(),
+		CommitAvgRatio: stats.CommitAvgRatio(),
+		UpdatedAt:      time.Now().Format(time.RFC3339),
+		Errors:         meta.Errors,
+	}
+	status.Label = localLabel(status, meta.Fast)
+	// 部分失败的结果照常返回，但下次请求时重新统计
+	if len(meta.Errors) == 0 {
+		s.status, s.refs, s.period = status, refs, period
+	}
+	return status, nil
+}
+
+// 统计本人的提交，报告中只有一个开发者
+func (s *localServer) analyze(git stat.GitRunner, opts *Options) (*stat.Report, error) {
+	analyzer := opts.NewAnalyzer(git)
+	commits, err := analyzer.Analyze(stat.LogQuery{Since: opts.Since, Until: opts.Until, RevRange: opts.RevRange, Author: s.email, Grep: opts.Grep, InvertGrep: opts.InvertGrep})
+	if err != nil && !stat.IsPartial(err) {
+		return nil, err
+	}
+	// --author 按子串匹配，这里只保留邮箱完全相同的提交
+	stats := &stat.AuthorStats{Email: s.email}
+	own := commits[:0]
+	for i := range commits {
+		if strings.EqualFold(commits[i].Email, s.email) {
+			stats.Add(&commits[i])
+			own = append(own, commits[i])
+			if stats.Name == "" {
+				stats.Name = commits[i].Author
+			}
+		}
+	}
+	return &stat.Report{Meta: analyzer.Metadata(), Since: opts.Since, Until: opts.Until, RevRange: opts.RevRange, Authors: []*stat.AuthorStats{stats}, Commits: own}, nil
+}
+
+// 状态栏文本：快速模式没有行数，使用按提交平均的占比
+func localLabel(status *localStatus, fast bool) string {
+	switch {
+	case fast && status.Stats.CommitCount > 0:
+		return fmt.Sprintf("AI: %.0f%%", status.CommitAvgRatio)
+	case !fast && status.Stats.TotalAddedLines > 0:
+		return fmt.Sprintf("AI: %.0f%%", status.AddedRatio)
+	}
+	return "AI: -"
+}
+
+// 是否为本机地址
+func loopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
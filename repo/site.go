@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"AIStat/stat"
+)
+
+// 将已保存的运行记录生成静态站点，可直接发布到 GitLab Pages 等静态托管
+func runSite(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo site", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径 (默认读取当前目录下的 "+stat.DefaultConfigFile+")")
+	storeDir := fs.String("store", "", "运行记录保存位置 (默认 ~/.aistat/runs)")
+	out := fs.String("out", "public", "站点输出目录")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe site [选项]\n")
+		fs.PrintDefaults()
+	}
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+
+	store, err := openStore(*configPath, *storeDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	repos, err := store.Repos()
+	if err != nil {
+		return err
+	}
+	var sites []*siteRepo
+	for _, name := range repos {
+		runs, err := store.LoadRuns(name)
+		if err != nil {
+			return err
+		}
+		repo := newSiteRepo(name, runs)
+		if err := repo.write(*out); err != nil {
+			return err
+		}
+		sites = append(sites, repo)
+	}
+
+	if err := writeSitePage(filepath.Join(*out, "index.html"), "index", sites); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "站点已生成: %s (%d 个仓库)\n", *out, len(sites))
+	return nil
+}
+
+// siteRepo 一个仓库的全部统计周期和开发者
+type siteRepo struct {
+	Name    string
+	Periods []*sitePeriod
+	Authors []*siteAuthor
+}
+
+// sitePeriod 一个统计周期，同一周期多次运行时取最新的一次
+type sitePeriod struct {
+	Label  string
+	Page   string
+	Report *stat.Report
+}
+
+// siteAuthor 开发者在各统计周期的数据
+type siteAuthor struct {
+	Name  string
+	Email string
+	Page  string
+	// 按时间先后排列，与 Periods 一一对应，没有提交的周期为 nil
+	Periods []*sitePeriod
+	Stats   []*stat.AuthorStats
+}
+
+// 整理仓库的运行记录，runs 按生成时间排序
+func newSiteRepo(name string, runs []*stat.Report) *siteRepo {
+	repo := &siteRepo{Name: name}
+	for _, report := range stat.LatestByPeriod(runs) {
+		period := []string{report.Since, report.Until}
+		if report.RevRange != "" {
+			period = []string{report.RevRange}
+		}
+		repo.Periods = append(repo.Periods, &sitePeriod{
+			Label:  report.PeriodLabel(),
+			Page:   stat.ReportFileName("", stat.FormatHTML, period...),
+			Report: report,
+		})
+	}
+
+	authors := make(map[string]*siteAuthor)
+	for i, p := range repo.Periods {
+		identity := stat.IdentityEmail
+		if p.Report.Meta != nil && p.Report.Meta.Filters.Identity != "" {
+			identity = p.Report.Meta.Filters.Identity
+		}
+		for _, stats := range p.Report.Authors {
+			key := strings.ToLower(stat.IdentityKey(identity, stats.Name, stats.Email))
+			a, ok := authors[key]
+			if !ok {
+				label := stats.Email
+				if label == "" {
+					label = stats.Name
+				}
+				a = &siteAuthor{
+					Name:    stats.Name,
+					Email:   stats.Email,
+					Page:    stat.ReportFileName(label, stat.FormatHTML),
+					Periods: repo.Periods,
+					Stats:   make([]*stat.AuthorStats, len(repo.Periods)),
+				}
+				authors[key] = a
+				repo.Authors = append(repo.Authors, a)
+			}
+			a.Stats[i] = stats
+		}
+	}
+	sort.Slice(repo.Authors, func(i, j int) bool {
+		return repo.Authors[i].Email < repo.Authors[j].Email
+	})
+	return repo
+}
+
+// 写出仓库的各周期页面和开发者页面
+func (r *siteRepo) write(out string) error {
+	dir := filepath.Join(out, r.Dir())
+	if err := os.MkdirAll(filepath.Join(dir, "authors"), 0o755); err != nil {
+		return fmt.Errorf("错误：创建站点目录 '%s' 失败: %v", dir, err)
+	}
+	for _, p := range r.Periods {
+		if err := writeFileWith(filepath.Join(dir, p.Page), func(w io.Writer) error {
+			return stat.WriteHTML(w, p.Report)
+		}); err != nil {
+			return err
+		}
+	}
+	for _, a := range r.Authors {
+		if err := writeSitePage(filepath.Join(dir, "authors", a.Page), "author", a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 仓库页面所在的子目录名
+func (r *siteRepo) Dir() string {
+	return stat.SafeFileName(r.Name)
+}
+
+// 开发者的趋势图：AI 添加占比折线
+func (a *siteAuthor) Chart() template.HTML {
+	values := make([]float64, len(a.Stats))
+	present := make([]bool, len(a.Stats))
+	for i, s := range a.Stats {
+		if s != nil && s.RatioAddedLines() > 0 {
+			values[i], present[i] = s.AddedRatio(), true
+		}
+	}
+	return svgLineChart(values, present, 100)
+}
+
+// 生成简单的 SVG 折线图，不依赖外部脚本；present 为假的点不绘制
+func svgLineChart(values []float64, present []bool, maxValue float64) template.HTML {
+	const width, height, pad = 600.0, 200.0, 20.0
+	var points []string
+	var dots strings.Builder
+	for i, v := range values {
+		if !present[i] {
+			continue
+		}
+		x := pad
+		if len(values) > 1 {
+			x += float64(i) * (width - 2*pad) / float64(len(values)-1)
+		}
+		y := height - pad - v/maxValue*(height-2*pad)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		fmt.Fprintf(&dots, `<circle cx="%.1f" cy="%.1f" r="3"><title>%.2f%%</title></circle>`, x, y, v)
+	}
+	return template.HTML(fmt.Sprintf(`<svg width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" xmlns="http://www.w3.org/2000/svg">`+
+		`<rect x="%.0f" y="%.0f" width="%.0f" height="%.0f" fill="none" stroke="#ccc"/>`+
+		`<polyline points="%s" fill="none" stroke="#1976d2" stroke-width="2"/><g fill="#1976d2">%s</g></svg>`,
+		width, height, width, height, pad, pad, width-2*pad, height-2*pad, strings.Join(points, " "), dots.String()))
+}
+
+// 创建文件并写入内容
+func writeFileWith(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("错误：创建文件 '%s' 失败: %v", path, err)
+	}
+	defer f.Close()
+	if err := write(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// 使用站点模板写出页面
+func writeSitePage(path, name string, data interface{}) error {
+	return writeFileWith(path, func(w io.Writer) error {
+		return siteTemplate.ExecuteTemplate(w, name, data)
+	})
+}
+
+var siteTemplate = template.Must(template.New("site").Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; margin: 24px; color: #222; }
+table { border-collapse: collapse; margin-top: 12px; }
+th, td { border: 1px solid #ccc; padding: 6px 10px; text-align: right; }
+th { background: #f3f3f3; }
+td.name { text-align: left; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "index"}}{{template "head" "AI 代码贡献统计"}}<h1>AI 代码贡献统计</h1>
+{{range .}}<h2>{{.Name}}</h2>
+<h3>统计周期</h3>
+<table>
+<tr><th>统计周期</th><th>提交次数</th><th>开发者</th></tr>
+{{$dir := .Dir}}{{range .Periods}}<tr><td class="name"><a href="{{$dir}}/{{.Page}}">{{.Label}}</a></td><td>{{len .Report.Commits}}</td><td>{{len .Report.Authors}}</td></tr>
+{{end}}</table>
+<h3>开发者</h3>
+<ul>
+{{range .Authors}}<li><a href="{{$dir}}/authors/{{.Page}}">{{.Name}}</a> ({{.Email}})</li>
+{{end}}</ul>
+{{else}}<p>没有运行记录</p>
+{{end}}</body>
+</html>
+{{end}}
+
+{{define "author"}}{{template "head" .Name}}<p><a href="../../index.html">返回首页</a></p>
+<h1>{{.Name}}</h1>
+<p>{{.Email}}</p>
+<h2>AI 添加占比趋势</h2>
+{{.Chart}}
+<table>
+<tr><th>统计周期</th><th>提交次数</th><th>总代码添加</th><th>AI贡献添加</th><th>AI添加占比</th><th>总修复提交</th><th>AI参与修复</th></tr>
+{{$stats := .Stats}}{{range $i, $p := .Periods}}{{with index $stats $i}}<tr><td class="name"><a href="../{{$p.Page}}">{{$p.Label}}</a></td><td>{{.CommitCount}}</td><td>{{.TotalAddedLines}}</td><td>{{.TotalAIAddedLines}}</td><td>{{printf "%.2f%%" .AddedRatio}}</td><td>{{.FixCount}}</td><td>{{.FixAndAIGCount}}</td></tr>
+{{end}}{{end}}</table>
+</body>
+</html>
+{{end}}
+`))
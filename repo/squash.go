@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"AIStat/stat"
+)
+
+// 合并提交范围内各提交的 AIG 比例，输出 squash 合并提交消息使用的汇总标记
+func runSquashMsg(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo squash-msg", flag.ContinueOnError)
+	repo := fs.String("repo", "", "要分析的仓库目录 (默认当前目录)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe squash-msg [选项] 提交范围\n")
+		fmt.Fprintf(fs.Output(), "示例: AIG_repo.exe squash-msg origin/main..HEAD\n")
+		fs.PrintDefaults()
+	}
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return errors.New("错误：请指定要合并的提交范围")
+	}
+
+	analyzer := stat.NewAnalyzer(&stat.ExecGitRunner{Dir: *repo})
+	commits, err := analyzer.Analyze(stat.LogQuery{RevRange: positional[0]})
+	if err != nil && !stat.IsPartial(err) {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("错误：提交范围 '%s' 中没有提交", positional[0])
+	}
+	fmt.Println(stat.SquashTrailerLine(commits))
+	return nil
+}
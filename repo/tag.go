@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"AIStat/stat"
+)
+
+// 在提交信息文件(默认当前仓库的 COMMIT_EDITMSG)中写入格式正确的 AIG 标记，
+// 供 prepare-commit-msg 钩子调用，从源头减少格式错误的标记
+func runTag(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo tag", flag.ContinueOnError)
+	ratio := fs.String("ratio", "", "AI 生成比例：0~1 的小数、带 % 的百分数，或 n/a、exempt")
+	tool := fs.String("tool", "", "使用的 AI 工具，如 cursor、copilot，写入 "+stat.ToolTrailer+" 标记")
+	repo := fs.String("repo", "", "仓库目录，未指定提交信息文件时使用 (默认当前目录)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe tag --ratio 比例 [--tool 工具] [提交信息文件]\n")
+		fs.PrintDefaults()
+	}
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	value, err := stat.NormalizeTagRatio(*ratio)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(*tool, "\r\n") {
+		return errors.New("错误：--tool 不能包含换行")
+	}
+
+	// prepare-commit-msg 钩子的第一个参数为提交信息文件，之后的参数(来源、提交)忽略
+	path := ""
+	if len(positional) > 0 {
+		path = positional[0]
+	} else {
+		git := &stat.ExecGitRunner{Dir: *repo}
+		if path = stat.GitPath(git, "COMMIT_EDITMSG"); path == "" {
+			return errors.New("错误：无法确定 COMMIT_EDITMSG 的位置，请在仓库目录中运行或指定提交信息文件")
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(*repo, path)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("错误：读取提交信息 '%s' 失败: %v", path, err)
+	}
+	message := stat.AddAIGTrailer(string(data), value, strings.TrimSpace(*tool))
+	if err := os.WriteFile(path, []byte(message), 0o644); err != nil {
+		return fmt.Errorf("错误：写入提交信息 '%s' 失败: %v", path, err)
+	}
+	return nil
+}
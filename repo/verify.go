@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"AIStat/stat"
+)
+
+// 校验报告中嵌入的摘要和签名，或审计日志的哈希链
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo verify", flag.ContinueOnError)
+	var opts stat.VerifyOptions
+	fs.StringVar(&opts.Require, "require", "", "要求的签名方式: gpg, minisign, hash (默认接受 gpg、minisign，只有摘要的报告需要显式指定 hash)")
+	fs.StringVar(&opts.Signer, "signer", "", "期望的 gpg 签名者：密钥指纹或 16 位的长 key ID")
+	fs.StringVar(&opts.PubKey, "pubkey", "", "minisign 公钥文件")
+	audit := fs.Bool("audit", false, "校验审计日志的哈希链，而不是报告")
+	auditLog := fs.String("audit-log", "", "配合 --audit 使用，审计日志路径 (默认 ~/.aistat/audit.log)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe verify [选项] 报告文件...\n")
+		fmt.Fprintf(fs.Output(), "      AIG_repo.exe verify --audit [--audit-log 审计日志]\n")
+		fs.PrintDefaults()
+	}
+	files, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *audit {
+		path := *auditLog
+		if path == "" {
+			path = stat.DefaultAuditLog()
+		}
+		count, err := stat.VerifyAudit(path)
+		if err != nil {
+			fmt.Printf("[失败] %s: %v\n", path, err)
+			return errors.New("错误：审计日志校验未通过")
+		}
+		fmt.Printf("[通过] %s (%d 条记录)\n", path, count)
+		return nil
+	}
+	if len(files) == 0 {
+		fs.Usage()
+		return errors.New("错误：请指定要校验的报告文件")
+	}
+	if !stat.ValidRequiredSign(opts.Require) {
+		return fmt.Errorf("错误：不支持的签名方式 '%s'，请使用 gpg、minisign 或 hash", opts.Require)
+	}
+
+	failed := false
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("错误：读取报告 '%s' 失败: %v", file, err)
+		}
+		content, sig, err := stat.ExtractSignature(data)
+		if err == nil {
+			err = stat.VerifySignature(content, sig, opts)
+		}
+		if err != nil {
+			failed = true
+			fmt.Printf("[失败] %s: %v\n", file, err)
+			continue
+		}
+		fmt.Printf("[通过] %s (%s, sha256 %s)\n", file, sig.Method, sig.SHA256)
+	}
+	if failed {
+		return errors.New("错误：部分报告校验未通过")
+	}
+	return nil
+}
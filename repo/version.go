@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"AIStat/stat"
+)
+
+// 发布包中的工具名称，与版本清单中的 tool 一致
+const (
+	toolRepo   = "AIG_repo"
+	toolPerson = "AIG_person"
+)
+
+// 输出构建信息
+func runVersion(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo version", flag.ContinueOnError)
+	format := fs.String("format", stat.FormatText, "输出格式: text 或 json")
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+	build := stat.CurrentBuild()
+	switch *format {
+	case stat.FormatText:
+		fmt.Printf("%s %s\n", toolRepo, build)
+		return nil
+	case stat.FormatJSON:
+		return stat.WriteJSON(os.Stdout, build)
+	}
+	return fmt.Errorf("错误：不支持的输出格式 '%s'", *format)
+}
+
+// 按版本清单检查并更新可执行文件，同一目录下的 AIG_person 一起更新
+func runSelfUpdate(args []string) error {
+	fs := flag.NewFlagSet("AIG_repo self-update", flag.ContinueOnError)
+	location := fs.String("url", "", "版本清单 release.json 的地址、路径或所在目录 (默认使用配置项 update_url 或构建时指定的位置)")
+	configPath := fs.String("config", "", "配置文件路径")
+	check := fs.Bool("check", false, "只检查是否有新版本，不更新")
+	force := fs.Bool("force", false, "当前已是最新版本时也重新下载替换")
+	if _, err := stat.ParseArgs(fs, args); err != nil {
+		return err
+	}
+	cfg, err := stat.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	loc := *location
+	if loc == "" {
+		loc = cfg.UpdateURL
+	}
+	if loc == "" {
+		loc = stat.UpdateURL
+	}
+	if loc == "" {
+		return errors.New("错误：请通过 --url 或配置项 update_url 指定版本清单的位置")
+	}
+
+	if stat.UpdatePublicKey == "" {
+		fmt.Fprintln(os.Stderr, "警告：当前版本构建时没有指定发布公钥，不校验版本清单的签名，请只使用可信的清单位置")
+	}
+	manifest, where, err := stat.LoadReleaseManifest(loc, stat.UpdatePublicKey)
+	if err != nil {
+		return err
+	}
+	build := stat.CurrentBuild()
+	fmt.Printf("当前版本: %s\n", build)
+	fmt.Printf("最新版本: %s (提交 %.8s，构建于 %s)\n", manifest.Version, manifest.Commit, manifest.BuildDate)
+	if stat.CompareVersions(manifest.Version, build.Version) <= 0 && !*force {
+		fmt.Println("已是最新版本")
+		return nil
+	}
+	if *check {
+		fmt.Println("有新版本，运行 AIG_repo.exe self-update 更新")
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("错误：无法确定可执行文件的位置: %v", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	targets := []updateTarget{{tool: toolRepo, path: exe}}
+	person := filepath.Join(filepath.Dir(exe), toolPerson+filepath.Ext(exe))
+	if _, err := os.Stat(person); err == nil {
+		targets = append(targets, updateTarget{tool: toolPerson, path: person})
+	}
+	if err := updateExecutables(manifest, where, targets, runtime.GOOS, runtime.GOARCH); err != nil {
+		return err
+	}
+	for _, t := range targets {
+		fmt.Printf("已更新: %s\n", t.path)
+	}
+	fmt.Printf("已更新到 %s\n", manifest.Version)
+	return nil
+}
+
+// 需要更新的工具及其可执行文件路径
+type updateTarget struct {
+	tool, path string
+}
+
+// 先下载并校验全部工具的新版本，都成功后再替换，任一替换失败时恢复已替换的文件，
+// 避免 AIG_repo 与 AIG_person 的版本不一致
+func updateExecutables(manifest *stat.ReleaseManifest, where string, targets []updateTarget, goos, goarch string) error {
+	var staged []string
+	removeStaged := func() {
+		for _, path := range staged {
+			os.Remove(path + ".new")
+		}
+	}
+	for _, t := range targets {
+		asset, ok := manifest.Asset(t.tool, goos, goarch)
+		if !ok {
+			removeStaged()
+			return fmt.Errorf("错误：版本 %s 中没有 %s 的 %s/%s 版本", manifest.Version, t.tool, goos, goarch)
+		}
+		if err := stat.FetchReleaseAsset(where, asset, t.path+".new"); err != nil {
+			removeStaged()
+			return err
+		}
+		staged = append(staged, t.path)
+	}
+	for i, path := range staged {
+		if err := replaceExecutable(path); err != nil {
+			for _, done := range staged[:i] {
+				restoreExecutable(done)
+			}
+			for _, rest := range staged[i:] {
+				os.Remove(rest + ".new")
+			}
+			return err
+		}
+	}
+	// 正在运行的文件在 Windows 上不能删除，留到下次更新时删除
+	for _, path := range staged {
+		os.Remove(path + ".old")
+	}
+	return nil
+}
+
+// 用下载好的 .new 文件替换可执行文件，原文件改名为 .old：正在运行的文件在 Windows 上不能覆盖，但可以改名
+func replaceExecutable(path string) error {
+	tmp, old := path+".new", path+".old"
+	os.Remove(old)
+	if err := os.Rename(path, old); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("错误：替换 '%s' 失败: %v", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Rename(old, path)
+		return fmt.Errorf("错误：替换 '%s' 失败: %v", path, err)
+	}
+	return nil
+}
+
+// 用 .old 恢复已替换的可执行文件
+func restoreExecutable(path string) {
+	os.Remove(path)
+	if err := os.Rename(path+".old", path); err != nil {
+		fmt.Fprintf(os.Stderr, "警告：恢复 '%s' 的旧版本失败: %v，请重新运行 self-update\n", path, err)
+	}
+}
+
+// 检查运行环境，不做统计
+func checkEnv(opts *Options) error {
+	checks := []stat.EnvCheck{stat.CheckGit()}
+	cfg, err := resolveOptions(opts)
+	checks = append(checks, stat.CheckConfig(cfg, err))
+	dir := repoDir(opts.Repo)
+	if cfg != nil {
+		dir = repoDir(opts.Repo, cfg.Repo)
+	}
+	checks = append(checks, stat.CheckRepo(opts.GitRunner(dir), dir))
+	return stat.PrintEnvChecks(os.Stdout, checks)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"AIStat/stat"
+)
+
+// 任一工具的新版本校验失败时不替换任何文件
+func TestUpdateExecutablesAllOrNothing(t *testing.T) {
+	release, install := t.TempDir(), t.TempDir()
+	digest := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+	manifest := &stat.ReleaseManifest{Version: "v1.2.0"}
+	var targets []updateTarget
+	for _, tool := range []string{toolRepo, toolPerson} {
+		if err := os.WriteFile(filepath.Join(release, tool), []byte(tool+" v1.2.0"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		manifest.Assets = append(manifest.Assets, stat.ReleaseAsset{Tool: tool, OS: "linux", Arch: "amd64", File: tool, SHA256: digest(tool + " v1.2.0")})
+		path := filepath.Join(install, tool)
+		if err := os.WriteFile(path, []byte(tool+" v1.1.0"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, updateTarget{tool: tool, path: path})
+	}
+	where := filepath.Join(release, stat.ReleaseManifestFile)
+	check := func(version string) {
+		t.Helper()
+		for _, target := range targets {
+			data, err := os.ReadFile(target.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := target.tool + " " + version; string(data) != want {
+				t.Errorf("%s = %q, want %q", target.path, data, want)
+			}
+		}
+		entries, err := os.ReadDir(install)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != len(targets) {
+			t.Errorf("安装目录中留有临时文件: %v", entries)
+		}
+	}
+
+	// AIG_person 的文件与清单不符，AIG_repo 也不更新
+	manifest.Assets[1].SHA256 = digest("other")
+	if err := updateExecutables(manifest, where, targets, "linux", "amd64"); err == nil {
+		t.Error("校验失败时没有返回错误")
+	}
+	check("v1.1.0")
+
+	manifest.Assets[1].SHA256 = digest(toolPerson + " v1.2.0")
+	if err := updateExecutables(manifest, where, targets, "linux", "amd64"); err != nil {
+		t.Fatal(err)
+	}
+	check("v1.2.0")
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"AIStat/stat"
+)
+
+// 监视仓库的引用变化，有新提交时重新统计本期数据并输出变化，适合大屏展示
+func runWatch(args []string) error {
+	base := Options{}
+	fs := flag.NewFlagSet("AIG_repo watch", flag.ContinueOnError)
+	base.BindFlags(fs)
+	interval := fs.Duration("interval", 30*time.Second, "检查新提交的间隔")
+	clear := fs.Bool("clear", false, "每次刷新时清屏并输出完整统计")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: AIG_repo.exe watch [选项] [开始日期] [结束日期]\n")
+		fs.PrintDefaults()
+	}
+	positional, err := stat.ParseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		base.Since = positional[0]
+	}
+	if len(positional) > 1 {
+		base.Until = positional[1]
+	}
+	if *interval < time.Second {
+		return fmt.Errorf("错误：检查间隔不能小于 1 秒")
+	}
+
+	var prev *stat.Report
+	var lastRefs, lastPeriod string
+	for {
+		// 每次重新解析选项，未指定日期时统计当前所在的半月周期，随时间切换
+		opts := base
+		if opts.Since == "" && opts.Until == "" && opts.RevRange == "" {
+			opts.Since, opts.Until = stat.CurrentDateRange(time.Now())
+		}
+		cfg, err := resolveOptions(&opts)
+		if err != nil {
+			return err
+		}
+		if len(opts.Formats) != 1 || opts.Formats[0] != stat.FormatText || opts.OutDir != "" {
+			return fmt.Errorf("错误：watch 只支持输出文本格式到标准输出")
+		}
+
+		git := opts.GitRunner(opts.Repo)
+		refs := stat.RefsSignature(git)
+		period := opts.Since + "~" + opts.Until + "~" + opts.RevRange
+		if refs != lastRefs || period != lastPeriod {
+			report, err := auditReport("AIG_repo watch", args, git, &opts, func() (*stat.Report, error) {
+				return buildReport(git, &opts, cfg)
+			})
+			if interrupt.Err() != nil {
+				// 被中断的统计不完整，不再输出
+				return nil
+			}
+			if err != nil {
+				if prev == nil {
+					return err
+				}
+				// 刷新失败(如 git 正在写入)时保留上次结果，下次再试
+				fmt.Fprintf(os.Stderr, "警告：%s\n", strings.TrimPrefix(err.Error(), "错误："))
+			} else {
+				if *clear {
+					fmt.Print("\033[H\033[2J")
+				}
+				if *clear || prev == nil || period != lastPeriod {
+					printStatistics(os.Stdout, report)
+				}
+				if prev != nil && period == lastPeriod {
+					printWatchDelta(os.Stdout, prev, report, opts.Identity)
+				}
+				prev, lastRefs, lastPeriod = report, refs, period
+			}
+		}
+		select {
+		case <-interrupt.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// 打印与上次统计相比的变化：新增提交及各开发者的增量
+func printWatchDelta(w io.Writer, prev, cur *stat.Report, identity string) {
+	before := make(map[string]*stat.AuthorStats)
+	for _, s := range prev.Authors {
+		before[stat.IdentityKey(identity, s.Name, s.Email)] = s
+	}
+
+	fmt.Fprintf(w, "\n[%s] 新提交: %d 次\n", time.Now().Format("15:04:05"), len(cur.Commits)-len(prev.Commits))
+	prevTotal, curTotal := watchTotal(prev), watchTotal(cur)
+	printDeltaLine(w, "全体", prevTotal, curTotal)
+	for _, s := range cur.Authors {
+		old := before[stat.IdentityKey(identity, s.Name, s.Email)]
+		if old == nil {
+			old = &stat.AuthorStats{}
+		}
+		if s.CommitCount != old.CommitCount {
+			printDeltaLine(w, s.Name, old, s)
+		}
+	}
+}
+
+// 报告中全部开发者及汇总行的合计
+func watchTotal(report *stat.Report) *stat.AuthorStats {
+	total := &stat.AuthorStats{}
+	for _, rows := range [][]*stat.AuthorStats{report.Authors, report.Groups} {
+		for _, s := range rows {
+			total.Merge(s)
+		}
+	}
+	return total
+}
+
+// 打印单行增量，如 "张三: +2 次提交，+120 行 (AI +80 行)，AI 添加占比 40.00% -> 45.00%"
+func printDeltaLine(w io.Writer, name string, old, cur *stat.AuthorStats) {
+	fmt.Fprintf(w, "  %s: %+d 次提交，%+d 行 (AI %+d 行)，AI 添加占比 %.2f%% -> %.2f%%\n", name,
+		cur.CommitCount-old.CommitCount, cur.TotalAddedLines-old.TotalAddedLines,
+		cur.TotalAIAddedLines-old.TotalAIAddedLines, old.AddedRatio(), cur.AddedRatio())
+}
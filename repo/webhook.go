@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"AIStat/stat"
+)
+
+// webhookHandler 接收 GitHub/GitLab 的推送 webhook，只分析推送的提交并合并到本期的运行记录
+type webhookHandler struct {
+	configPath string
+	store      stat.Store
+	// 运行记录的位置，分析时保存到同一存储
+	location string
+	secret   string
+	// 仓库全名(如 group/app)到本地克隆目录的映射
+	repos map[string]string
+	// 属于项目的仓库全名到项目配置文件的映射，不在其中的仓库使用 configPath
+	configs  map[string]string
+	notifier *stat.Notifier
+	// 依次处理推送，避免同一仓库并发 fetch 和写入运行记录
+	mu sync.Mutex
+	// 尚未处理完的推送，停止服务时等待
+	pending sync.WaitGroup
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "只支持 POST 请求", http.StatusMethodNotAllowed)
+		return
+	}
+	event, err := stat.ParsePushWebhook(r, h.secret)
+	switch {
+	case errors.Is(err, stat.ErrWebhookIgnored):
+		fmt.Fprintln(w, "ignored")
+		return
+	case errors.Is(err, stat.ErrWebhookUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dir, ok := h.repos[event.Repo]
+	if !ok {
+		http.Error(w, fmt.Sprintf("错误：未配置仓库 '%s' 的本地目录", event.Repo), http.StatusNotFound)
+		return
+	}
+	if event.Deleted() {
+		fmt.Fprintln(w, "ignored")
+		return
+	}
+
+	// 分析可能较慢，先返回，避免推送方超时重试
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "accepted")
+	h.pending.Add(1)
+	go func() {
+		defer h.pending.Done()
+		h.process(event, dir)
+	}()
+}
+
+// 处理一次推送：更新运行记录并发送通知，错误输出到标准错误
+func (h *webhookHandler) process(event *stat.PushEvent, dir string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if interrupt.Err() != nil {
+		fmt.Fprintf(os.Stderr, "服务正在停止，跳过推送 (%s %s)\n", event.Repo, event.Ref)
+		return
+	}
+
+	report, added, err := h.update(event, dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s (%s %s)\n", err, event.Repo, event.Ref)
+		return
+	}
+	if report.Meta != nil && report.Meta.Interrupted {
+		fmt.Fprintf(os.Stderr, "已中断，推送未处理完，未保存运行记录 (%s %s)\n", event.Repo, event.Ref)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "已更新 %s %s: 新统计 %d 次提交\n", event.Repo, report.PeriodLabel(), len(added))
+	if h.notifier == nil || len(added) == 0 {
+		return
+	}
+	errs := h.notifier.Notify(&stat.PushNotification{
+		Event:      event,
+		Period:     report.PeriodLabel(),
+		NewCommits: added,
+		Summary:    stat.SummarizeRepo(report),
+	})
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// 拉取仓库后分析推送的提交，与本期最近一次运行记录合并生成新的运行记录
+// 本期还没有运行记录时完整分析本期，返回新报告及本次新统计的提交
+func (h *webhookHandler) update(event *stat.PushEvent, dir string) (*stat.Report, []stat.CommitStats, error) {
+	opts := &Options{}
+	opts.ConfigPath = h.configPath
+	if path, ok := h.configs[event.Repo]; ok {
+		opts.ConfigPath = path
+	}
+	opts.Repo = dir
+	opts.Store = h.location
+	opts.Since, opts.Until = stat.CurrentDateRange(time.Now())
+	cfg, err := resolveOptions(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	git := opts.GitRunner(dir)
+	if _, err := git.Run([]string{"fetch", "--quiet", "--prune", "origin"}); err != nil {
+		return nil, nil, err
+	}
+	info, err := stat.DetectRepo(git)
+	if err != nil {
+		return nil, nil, err
+	}
+	previous, err := h.latestRun(info.Name(), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	command := "AIG_repo serve webhook"
+	args := []string{event.Repo, event.Ref, event.Before + ".." + event.After}
+	if previous == nil {
+		report, err := generateReport(command, args, git, opts, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return report, report.Commits, nil
+	}
+
+	// 新建分支没有起始提交，分析分支上本期的全部提交，已统计的提交在合并时去重
+	revRange := event.After
+	if !event.NewBranch() {
+		revRange = event.Before + ".." + event.After
+	}
+	var added []stat.CommitStats
+	report, err := runReport(command, args, git, opts, func() (*stat.Report, error) {
+		analyzer := opts.NewAnalyzer(git)
+		commits, err := analyzer.Analyze(stat.LogQuery{
+			Since: opts.Since, Until: opts.Until, RevRange: revRange,
+			Grep: opts.Grep, InvertGrep: opts.InvertGrep,
+		})
+		if err != nil && !stat.IsPartial(err) {
+			return nil, err
+		}
+		merged, fresh := mergeCommits(previous.Commits, commits)
+		added = fresh
+		meta := analyzer.Metadata()
+		if previous.Meta != nil {
+			meta.IgnoredCommits = mergeIgnored(previous.Meta.IgnoredCommits, meta.IgnoredCommits)
+			meta.MassMoves += previous.Meta.MassMoves
+		}
+		return assembleReport(merged, meta, opts, cfg)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return report, added, nil
+}
+
+// 本期(当前所在的半月周期)最近一次按日期统计的运行记录，没有时返回 nil
+func (h *webhookHandler) latestRun(repo string, opts *Options) (*stat.Report, error) {
+	runs, err := h.store.LoadRuns(repo)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if r := runs[i]; r.RevRange == "" && r.Since == opts.Since && r.Until == opts.Until {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// 合并已统计的提交和新分析的提交，同一提交只保留一次，按提交时间倒序排列
+// 返回合并结果及之前没有统计过的提交
+func mergeCommits(previous, commits []stat.CommitStats) ([]stat.CommitStats, []stat.CommitStats) {
+	seen := make(map[string]bool, len(previous))
+	merged := append([]stat.CommitStats{}, previous...)
+	for _, c := range previous {
+		seen[c.ID] = true
+	}
+	var added []stat.CommitStats
+	for _, c := range commits {
+		if seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		merged = append(merged, c)
+		added = append(added, c)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Time > merged[j].Time
+	})
+	return merged, added
+}
+
+// 合并忽略的提交列表，按提交 ID 去重
+func mergeIgnored(previous, ignored []stat.IgnoredCommit) []stat.IgnoredCommit {
+	seen := make(map[string]bool)
+	var merged []stat.IgnoredCommit
+	for _, c := range append(append([]stat.IgnoredCommit{}, previous...), ignored...) {
+		if !seen[c.ID] {
+			seen[c.ID] = true
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
+
+// 解析 "仓库全名=本地目录" 形式的映射
+func parseRepoMapping(values []string) (map[string]string, error) {
+	repos := make(map[string]string)
+	for _, v := range values {
+		name, dir, ok := strings.Cut(v, "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(dir) == "" {
+			return nil, fmt.Errorf("错误：仓库映射 '%s' 格式不正确，请使用 仓库全名=本地目录", v)
+		}
+		repos[strings.TrimSpace(name)] = strings.TrimSpace(dir)
+	}
+	return repos, nil
+}
@@ -0,0 +1,414 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+)
+
+// LogQuery 描述要分析的提交范围
+type LogQuery struct {
+	Since string
+	Until string
+	// git 提交范围，为空时统计所有分支
+	RevRange string
+	// 按作者过滤，为空时统计所有作者
+	Author string
+	// 同一作者的其他邮箱(如迁移前的旧邮箱)，与 Author 匹配任意一个即可
+	AuthorAliases []string
+	// 按提交信息过滤(扩展正则，匹配任意一个即可)，InvertGrep 为真时排除匹配的提交
+	Grep       []string
+	InvertGrep bool
+	// 只获取提交信息，不统计文件变更行数
+	NoNumstat bool
+	// 按提交时间(committer，默认)或作者时间(author)确定统计周期及提交时间，为空时使用 Analyzer.DateKind
+	DateKind string
+}
+
+// FileChange 单个文件的变更行数
+type FileChange struct {
+	Path string `json:"path" yaml:"path"`
+	// 重命名或移动前的路径
+	OldPath string `json:"old_path,omitempty" yaml:"old_path,omitempty"`
+	Added   int    `json:"added" yaml:"added"`
+	Deleted int    `json:"deleted" yaml:"deleted"`
+	// 不符合统计条件的文件，不计入提交的行数
+	Skipped bool `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+	// 不计入统计的原因：excluded_ext、unlisted_ext、binary、ai_manifest、linguist-generated、linguist-vendored、symlink、mode
+	SkipReason string `json:"skip_reason,omitempty" yaml:"skip_reason,omitempty"`
+	// 大规模移动中未被 git 识别为重命名的移动文件，不计入提交的行数
+	Moved bool `json:"moved,omitempty" yaml:"moved,omitempty"`
+	// 因 .gitattributes 中的该属性(linguist-generated 或 linguist-vendored)跳过
+	Attribute string `json:"attribute,omitempty" yaml:"attribute,omitempty"`
+	// 符号链接变更或只修改了文件权限，同样跳过
+	Change string `json:"change,omitempty" yaml:"change,omitempty"`
+	// 变更状态：A 新建、D 删除、M 修改、R 重命名、C 复制，部分克隆跳过行数统计时为空
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// 不计入统计的特殊文件变更
+const (
+	// 新增、修改或删除符号链接，numstat 中为链接目标的行数
+	FileSymlink = "symlink"
+	// 只修改文件权限(如 100644 => 100755)，numstat 中为 0 0
+	FileModeOnly = "mode"
+)
+
+// CommitStats 单个提交的解析结果
+type CommitStats struct {
+	ID      string `json:"id" yaml:"id"`
+	Author  string `json:"author" yaml:"author"`
+	Email   string `json:"email" yaml:"email"`
+	Time    string `json:"time" yaml:"time"`
+	Subject string `json:"subject" yaml:"subject"`
+	// 标题与正文合并后的完整提交消息
+	Message      string       `json:"message" yaml:"message"`
+	Files        []FileChange `json:"files" yaml:"files"`
+	AddedLines   int          `json:"added_lines" yaml:"added_lines"`
+	DeletedLines int          `json:"deleted_lines" yaml:"deleted_lines"`
+	AIGRatio     float64      `json:"aig_ratio" yaml:"aig_ratio"`
+	// AIG 比例的来源：tag、squash、manifest(AI 清单)或 estimate(LLM 估算)，没有标记时为空
+	AIGSource string `json:"aig_source,omitempty" yaml:"aig_source,omitempty"`
+	// 策略标记 n/a 或 exempt，这类提交不计入各比例的分母
+	AIGPolicy string `json:"aig_policy,omitempty" yaml:"aig_policy,omitempty"`
+	// 提交信息中有多个取值不同的 AIG 标记，按 AIG 标记的取值规则取值
+	AIGAmbiguous bool `json:"aig_ambiguous,omitempty" yaml:"aig_ambiguous,omitempty"`
+	// LLM 估算的提交类型
+	EstimatedType string `json:"estimated_type,omitempty" yaml:"estimated_type,omitempty"`
+	IsFix         bool   `json:"is_fix" yaml:"is_fix"`
+	// 修复提交的严重级别，如 critical、high、normal
+	FixSeverity string `json:"fix_severity,omitempty" yaml:"fix_severity,omitempty"`
+	// SZZ 分析中修改过本提交引入的代码的修复提交
+	BugFixes []string `json:"bug_fixes,omitempty" yaml:"bug_fixes,omitempty"`
+	// 未计入 AddedLines 的新增空行数
+	BlankLines int `json:"blank_lines,omitempty" yaml:"blank_lines,omitempty"`
+	// 启发式估算的 AI 生成可能性(0~1)，实验性，仅供参考
+	HeuristicScore float64 `json:"heuristic_score,omitempty" yaml:"heuristic_score,omitempty"`
+	// 来自子模块的提交记录子模块路径
+	Submodule string `json:"submodule,omitempty" yaml:"submodule,omitempty"`
+	// 统计所有分支时提交经由哪个分支或标签计入统计(git log --source)
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+}
+
+// AIG 比例的来源
+const (
+	AIGSourceTag      = "tag"
+	AIGSourceSquash   = "squash"
+	AIGSourceEstimate = "estimate"
+	// 没有 AIG 标记，按提交中的 AI 清单计算
+	AIGSourceManifest = "manifest"
+)
+
+// AIG 策略标记
+const (
+	// 无法使用 AI 工具
+	AIGPolicyNA = "n/a"
+	// 豁免，如生成代码
+	AIGPolicyExempt = "exempt"
+)
+
+// AI 贡献的添加行数
+func (c *CommitStats) AIAddedLines() int {
+	return int(math.Round(float64(c.AddedLines) * c.AIGRatio))
+}
+
+// 按启发式评分估算的 AI 添加行数
+func (c *CommitStats) HeuristicAILines() int {
+	return int(math.Round(float64(c.AddedLines) * c.HeuristicScore))
+}
+
+// AI 贡献的删除行数
+func (c *CommitStats) AIDeletedLines() int {
+	return int(math.Round(float64(c.DeletedLines) * c.AIGRatio))
+}
+
+// Analyzer 通过注入的 GitRunner 获取提交日志并解析
+type Analyzer struct {
+	Git GitRunner
+	// 文件类型预设的名称及其统计、排除的扩展名
+	Profile     string
+	IncludeExts []string
+	ExcludeExts []string
+	// 同时分析已初始化的子模块（含嵌套子模块）
+	RecurseSubmodules bool
+	// 子模块中的文件路径加上子模块路径前缀
+	SubmodulePrefix bool
+	// 部分克隆中允许 git 从远程获取缺失对象，否则缺少对象时跳过行数统计
+	FetchMissing bool
+	// 为没有 AIG 标记的提交估算 AI 参与度，为 nil 时不估算
+	Classifier *LLMClassifier
+	// 对 diff 做 AI 风格的启发式评分(实验性)
+	Heuristic bool
+	// 快速模式：不获取文件变更，只有提交信息
+	Fast bool
+	// 分片读取提交时显示进度，为 nil 时不显示
+	Progress io.Writer
+	// 不计入新增的空行，需要读取 diff
+	IgnoreBlankLines bool
+	// 不参与统计的提交，仓库根目录下的 .aistat-ignore 会追加到其后
+	Ignore []IgnoreRule
+	// 大规模移动的处理方式：discount、exclude、off
+	MassMove string
+	// 提交信息中有多个 AIG 标记时的取值规则：first、last、key、trailer
+	AIGPrecedence string
+	// 确定统计周期及提交时间的时间口径：committer、author
+	DateKind string
+	// AI 清单的路径(相对于仓库根目录)，为空或 off 时不读取
+	AIManifest string
+	// 统计所有分支时同一改动在多个分支上的副本的处理方式：first、main、keep，及 main 方式使用的主分支(为空时自动识别)
+	BranchCopies string
+	MainBranch   string
+	// 仓库迁移前后的邮箱和提交哈希映射，为 nil 时不改写
+	Migration *Migration
+	// 修复提交的识别规则及严重级别，为 nil 时使用默认规则
+	Fixes *FixRules
+	// SZZ 分析：追溯之后的修复提交修改的代码由本期哪些提交引入
+	SZZ bool
+	// 不按 .gitattributes 的 linguist-generated、linguist-vendored 属性排除文件
+	IgnoreAttributes bool
+	// 分析过程中产生的警告
+	Warnings []string
+
+	// 部分克隆的对象过滤规则
+	partialClone string
+	// LLM 估算的提交数
+	estimated int
+	// 按 AI 清单计算 AIG 比例的提交数
+	manifests int
+	// 本次分析跳过了行数统计
+	noNumstat bool
+	// git rev-list --count 预计的提交数及并行读取的分片数
+	commitCount, shards int
+	// 失败但不影响其余结果的步骤
+	errors []*AnalysisError
+	// 无法解析的提交数
+	malformed int
+	// 被忽略的提交及其全部文件的行数(不含子模块)
+	ignored      []IgnoredCommit
+	ignoredLines LineCounts
+	// 识别出的大规模移动提交数
+	massMoves int
+	// 含有多个取值不同的 AIG 标记的提交
+	ambiguous []AmbiguousTag
+	// 作为其他分支上同一改动的副本而不计入统计的提交
+	copies []BranchCopy
+	// 按作者时间统计时放宽后的查询及作者时间在统计周期内的提交，供校验使用
+	authorQuery *LogQuery
+	authorIDs   map[string]bool
+	// 按原因汇总的跳过的文件
+	skipped []SkippedFiles
+	// Run 设置的回调，主仓库及每个子模块的提交分析完成后调用
+	emit func([]CommitStats)
+	// Run 设置的回调，git log 的输出每解析完一批提交后调用
+	parsed func([]CommitStats)
+	// 排除的符号链接变更及只修改文件权限的变更数
+	symlinkChanges, modeChanges int
+	// SZZ 分析扫描的修复提交数
+	bugFixes int
+	// 被 .gitattributes 标记为生成或第三方代码的文件(路径到属性)及其文件数和行数
+	attrExcluded map[string]string
+	attrFiles    int
+	attrLines    LineCounts
+}
+
+// 创建使用默认文件扩展名规则的分析器
+func NewAnalyzer(git GitRunner) *Analyzer {
+	return &Analyzer{
+		Git:         git,
+		Profile:     DefaultProfile,
+		IncludeExts: builtinProfiles[DefaultProfile].IncludeExts,
+		ExcludeExts: builtinProfiles[DefaultProfile].ExcludeExts,
+		MassMove:    MassMoveDiscount,
+		// 与以往只取第一个标记的结果一致
+		AIGPrecedence: AIGPrecedenceFirst,
+		BranchCopies:  BranchCopiesFirst,
+		DateKind:      DateKindCommitter,
+		AIManifest:    DefaultAIManifest,
+	}
+}
+
+// 获取并解析查询范围内的提交。读取提交失败时返回 AnalysisError；
+// 之后的步骤(忽略列表、.gitattributes、diff 分析、SZZ、子模块)失败时继续分析，
+// 返回已有的结果及 PartialError。被中断(ErrInterrupted)时跳过剩余的步骤
+func (a *Analyzer) Analyze(q LogQuery) ([]CommitStats, error) {
+	a.errors = nil
+	a.commitCount, a.shards = 0, 0
+	if a.Fast || a.checkPartialClone(q) {
+		q.NoNumstat = true
+	}
+	a.noNumstat = q.NoNumstat
+	if q.Author != "" && q.AuthorAliases == nil {
+		q.AuthorAliases = a.Migration.OldEmails(q.Author)
+	}
+	if q.DateKind == "" {
+		q.DateKind = a.DateKind
+	}
+	// 子模块按原来的统计周期各自放宽
+	period := q
+	q, ids, err := a.authorDateQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("错误：%w", &AnalysisError{Stage: "git log", Err: err})
+	}
+	a.authorQuery, a.authorIDs = nil, ids
+	if ids != nil {
+		a.authorQuery = &q
+	}
+	commits, err := a.readLog(q)
+	if err != nil {
+		return nil, fmt.Errorf("错误：%w", &AnalysisError{Stage: "git log", Err: err})
+	}
+	if a.malformed > 0 {
+		a.fail("解析提交", fmt.Errorf("%w: %d 个提交的格式不正确，已跳过", ErrParse, a.malformed))
+	}
+	commits = keepIDs(commits, ids)
+	a.Migration.rewriteEmails(commits)
+	if commits, err = filterGrep(commits, q); err != nil {
+		return nil, err
+	}
+	if kept, err := a.dedupeBranchCopies(q, commits); err != nil {
+		a.fail("跨分支去重", err)
+	} else {
+		commits = kept
+	}
+	if kept, err := a.applyIgnore(commits); err != nil {
+		a.fail("忽略列表", err)
+	} else {
+		commits = kept
+	}
+	a.countSpecialChanges(commits)
+	a.collectAmbiguous(commits)
+	if err := a.applyAttributes(commits); err != nil {
+		a.fail(".gitattributes", err)
+	}
+	commits = a.detectMassMoves(commits)
+	a.applyManifests(commits)
+	if !a.interrupted() {
+		a.estimate(commits)
+	}
+	a.Fixes.apply(commits)
+	if !a.interrupted() {
+		if err := a.analyzePatches(q, commits); err != nil {
+			a.fail("diff 分析", err)
+		}
+	}
+	if !a.interrupted() {
+		if err := a.attributeBugs(commits); err != nil {
+			a.fail("SZZ 分析", err)
+		}
+	}
+	a.Migration.remapCommits(commits)
+	if a.emit != nil {
+		a.emit(commits)
+	}
+	if a.RecurseSubmodules && !a.interrupted() {
+		commits = append(commits, a.analyzeSubmodules(period)...)
+	}
+	a.skipped = summarizeSkipped(commits)
+	return commits, a.partialError()
+}
+
+// 统计被排除的符号链接变更和权限变更
+func (a *Analyzer) countSpecialChanges(commits []CommitStats) {
+	a.symlinkChanges, a.modeChanges = 0, 0
+	for _, c := range commits {
+		for _, f := range c.Files {
+			switch f.Change {
+			case FileSymlink:
+				a.symlinkChanges++
+			case FileModeOnly:
+				a.modeChanges++
+			}
+		}
+	}
+}
+
+// 解析 LogArgs 格式的 git log 输出
+func (a *Analyzer) ParseLog(r io.Reader) ([]CommitStats, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	a.malformed = 0
+	return a.parseOutput(string(data)), nil
+}
+
+// 解析一段 git log 输出，格式不正确的提交跳过并计入 malformed；
+// 设置了 parsed 回调时每解析 parsedBatch 个提交回调一次
+func (a *Analyzer) parseOutput(data string) []CommitStats {
+	var commits []CommitStats
+	batch := 0
+	p := &logParser{includeExts: a.IncludeExts, excludeExts: a.ExcludeExts, precedence: a.AIGPrecedence}
+	eachCommit(data, func(commit string) {
+		stats, ok := p.parse(commit)
+		if !ok {
+			a.malformed++
+			return
+		}
+		commits = append(commits, stats)
+		if a.parsed != nil && len(commits)-batch == parsedBatch {
+			a.parsed(commits[batch:])
+			batch = len(commits)
+		}
+	})
+	if a.parsed != nil && len(commits) > batch {
+		a.parsed(commits[batch:])
+	}
+	return commits
+}
+
+// 构造 git log 参数
+func LogArgs(q LogQuery) []string {
+	args := []string{
+		"log",
+		"--pretty=format:" + logFormat(q.DateKind),
+		"--date=format:%Y-%m-%d %H:%M:%S",
+	}
+	if !q.NoNumstat {
+		// 显式开启重命名检测，不受 diff.renames 配置影响；--raw 输出文件权限，用于识别符号链接和权限变更
+		args = append(args, "--raw", "--numstat", "-M")
+	}
+	return append(args, revisionArgs(q)...)
+}
+
+// 构造选择提交的参数：按日期和作者筛选，提交范围放在最后、--end-of-options 之后，
+// 以免来自 webhook 等外部输入的范围被 git 当作选项解析
+func revisionArgs(q LogQuery) []string {
+	var args []string
+	if q.RevRange != "" {
+		if q.Since != "" {
+			args = append(args, "--since="+gitDate(q.Since, false))
+		}
+		if q.Until != "" {
+			args = append(args, "--until="+gitDate(q.Until, true))
+		}
+	} else {
+		args = append(args, "--all", "--since="+gitDate(q.Since, false))
+		if q.Until != "" {
+			args = append(args, "--until="+gitDate(q.Until, true))
+		}
+	}
+	args = append(args, "--no-merges")
+
+	if q.Author != "" {
+		args = append(args, "--author="+q.Author)
+		for _, alias := range q.AuthorAliases {
+			args = append(args, "--author="+regexp.QuoteMeta(alias))
+		}
+	}
+	if len(q.Grep) > 0 {
+		// 使用扩展正则，与解析后按提交信息再次过滤的 Go 正则语法基本一致
+		args = append(args, "--extended-regexp")
+		for _, pattern := range q.Grep {
+			args = append(args, "--grep="+pattern)
+		}
+		if q.InvertGrep {
+			args = append(args, "--invert-grep")
+		}
+	}
+	if q.RevRange != "" {
+		args = append(args, "--end-of-options", q.RevRange)
+	}
+	return args
+}
@@ -0,0 +1,163 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// 开发者 AI 添加占比与上一统计周期相比变化超过该百分点时标注
+const DefaultAnomalyThreshold = 20
+
+// 异常的类型
+const (
+	// 开发者 AI 添加占比与上一统计周期相比变化较大
+	AnomalyRatioShift = "ratio_shift"
+	// 提交的 AIG 比例为 100%
+	AnomalyFullAI = "full_ai"
+	// 统计周期中间连续的工作日没有提交
+	AnomalyIdleDays = "idle_days"
+)
+
+// Anomaly 报告中值得在评审时关注的异常，只是提示，不影响统计结果
+type Anomaly struct {
+	Kind   string `json:"kind" yaml:"kind"`
+	Author string `json:"author,omitempty" yaml:"author,omitempty"`
+	Email  string `json:"email,omitempty" yaml:"email,omitempty"`
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	// 无提交的起止日期
+	From string `json:"from,omitempty" yaml:"from,omitempty"`
+	To   string `json:"to,omitempty" yaml:"to,omitempty"`
+	// 占比变化时上期和本期的 AI 添加占比
+	Previous float64 `json:"previous,omitempty" yaml:"previous,omitempty"`
+	Current  float64 `json:"current,omitempty" yaml:"current,omitempty"`
+	// 说明文字
+	Message string `json:"message" yaml:"message"`
+}
+
+// 检测报告中的异常：AIG 为 100% 的提交、周期中间无提交的工作日，
+// previous 不为空时还检测开发者 AI 添加占比相对上期的变化(超过 threshold 个百分点)
+// 添加行数低于 minLines 的开发者不比较占比，避免小样本造成的大幅波动
+func DetectAnomalies(report, previous *Report, threshold float64, minLines int, identity string) []Anomaly {
+	var anomalies []Anomaly
+	if previous != nil {
+		anomalies = append(anomalies, ratioShifts(report, previous, threshold, minLines, identity)...)
+	}
+	for _, c := range report.Commits {
+		if c.AIGPolicy == "" && c.AIGRatio == 1 {
+			anomalies = append(anomalies, Anomaly{
+				Kind: AnomalyFullAI, Author: c.Author, Email: c.Email, Commit: c.ID,
+				Message: fmt.Sprintf("提交 %s (%s) 的 AIG 比例为 100%%: %s", shortID(c.ID), c.Author, c.Subject),
+			})
+		}
+	}
+	return append(anomalies, idleDays(report.Commits)...)
+}
+
+// 与上期相比 AI 添加占比变化超过阈值的开发者
+func ratioShifts(report, previous *Report, threshold float64, minLines int, identity string) []Anomaly {
+	before := make(map[string]*AuthorStats)
+	for _, s := range previous.Authors {
+		before[IdentityKey(identity, s.Name, s.Email)] = s
+	}
+	var anomalies []Anomaly
+	for _, s := range report.Authors {
+		old := before[IdentityKey(identity, s.Name, s.Email)]
+		if old == nil || s.CommitCount == 0 || old.CommitCount == 0 ||
+			s.RatioAddedLines() < minLines || old.RatioAddedLines() < minLines {
+			continue
+		}
+		prev, cur := old.AddedRatio(), s.AddedRatio()
+		if math.Abs(cur-prev) <= threshold {
+			continue
+		}
+		anomalies = append(anomalies, Anomaly{
+			Kind: AnomalyRatioShift, Author: s.Name, Email: s.Email, Previous: prev, Current: cur,
+			Message: fmt.Sprintf("%s 的 AI 添加占比 %.2f%% -> %.2f%% (%+.2f 个百分点，上期 %s)",
+				s.Name, prev, cur, cur-prev, previous.PeriodLabel()),
+		})
+	}
+	return anomalies
+}
+
+// 第一次和最后一次提交之间连续没有提交的工作日，周末不计入
+func idleDays(commits []CommitStats) []Anomaly {
+	active := make(map[string]bool)
+	var first, last string
+	for _, c := range commits {
+		day := commitDay(c.Time)
+		active[day] = true
+		if first == "" || day < first {
+			first = day
+		}
+		if day > last {
+			last = day
+		}
+	}
+	start, err1 := time.Parse(DateLayout, first)
+	end, err2 := time.Parse(DateLayout, last)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+
+	var anomalies []Anomaly
+	var from, to string
+	count := 0
+	flush := func() {
+		if count > 0 {
+			anomalies = append(anomalies, Anomaly{
+				Kind: AnomalyIdleDays, From: from, To: to,
+				Message: fmt.Sprintf("%s ~ %s 连续 %d 个工作日没有提交", from, to, count),
+			})
+		}
+		count = 0
+	}
+	for d := start.AddDate(0, 0, 1); d.Before(end); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			continue
+		}
+		day := d.Format(DateLayout)
+		if active[day] {
+			flush()
+			continue
+		}
+		if count == 0 {
+			from = day
+		}
+		to = day
+		count++
+	}
+	flush()
+	return anomalies
+}
+
+// 报告中与该期最近的上一统计周期(按日期统计、结束日期不晚于本期开始日期)的最新运行记录
+func PreviousPeriod(report *Report, runs []*Report) *Report {
+	if report.RevRange != "" || report.Since == "" {
+		return nil
+	}
+	var previous *Report
+	for _, r := range LatestByPeriod(runs) {
+		if r.RevRange == "" && r.Until != "" && r.Until <= report.Since {
+			previous = r
+		}
+	}
+	return previous
+}
+
+// 打印异常标注
+func PrintAnomalies(w io.Writer, anomalies []Anomaly) {
+	fmt.Fprintf(w, "\n  异常标注 (供评审参考):\n")
+	for _, a := range anomalies {
+		fmt.Fprintf(w, "    - %s\n", a.Message)
+	}
+}
+
+// 提交 ID 的前 8 位
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,319 @@
+package stat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 单个响应体的大小上限
+const maxAPIResponse = 32 << 20
+
+// 跟随分页链接的最大页数，防止服务端返回循环链接
+const maxAPIPages = 1000
+
+// APIClient 调用外部 HTTP 接口(GitHub、GitLab、Jira、LLM 服务等)的通用客户端
+// 失败时按指数退避重试，遵守限流响应头，支持 Link 分页和 GET 响应的条件请求缓存
+type APIClient struct {
+	Client *http.Client
+	// 每个请求附加的请求头，如 Authorization
+	Header http.Header
+	// 网络错误、429 及 5xx 响应的最大重试次数，为 0 时使用默认值 3，小于 0 时不重试
+	MaxRetries int
+	// 首次重试前的等待时间，之后每次加倍，为 0 时使用默认值 1 秒
+	Backoff time.Duration
+	// 等待限流重置的最长时间，超过时直接返回错误，为 0 时使用默认值 5 分钟
+	MaxWait time.Duration
+	// GET 响应缓存目录，为空时不缓存；有缓存时按 ETag/Last-Modified 发送条件请求，未变化的响应不计入多数服务的限流
+	CacheDir string
+
+	mu sync.Mutex
+	// 服务端告知剩余请求数为 0 时，限流重置的时间
+	resetAt time.Time
+}
+
+// APIResponse 读取完毕的响应
+type APIResponse struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+	// 来自缓存(服务端返回 304)
+	Cached bool
+}
+
+// APIError 重试后仍然失败的非 2xx 响应
+type APIError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("错误：'%s' 返回 %s: %s", e.URL, e.Status, e.Body)
+}
+
+// 缓存文件内容
+type apiCacheEntry struct {
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// 发送请求，失败时重试，非 2xx 响应返回 *APIError
+func (c *APIClient) Do(method, url string, body []byte, header http.Header) (*APIResponse, error) {
+	var cached *apiCacheEntry
+	cachePath := ""
+	if method == http.MethodGet && c.CacheDir != "" {
+		cachePath = filepath.Join(c.CacheDir, c.cacheKey(url, header)+".json")
+		cached = readAPICache(cachePath)
+	}
+
+	retries := c.MaxRetries
+	if retries == 0 {
+		retries = 3
+	}
+	backoff := c.Backoff
+	if backoff == 0 {
+		backoff = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		if err := c.waitRateLimit(); err != nil {
+			return nil, err
+		}
+		resp, err := c.send(method, url, body, header, cached)
+		if err == nil && resp.StatusCode == http.StatusNotModified && cached != nil {
+			return &APIResponse{StatusCode: http.StatusOK, Status: "200 OK", Header: cached.Header, Body: cached.Body, Cached: true}, nil
+		}
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if cachePath != "" {
+				writeAPICache(cachePath, resp)
+			}
+			return resp, nil
+		}
+
+		retryable := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 ||
+			(resp.StatusCode == http.StatusForbidden && rateLimited(resp.Header))
+		if !retryable || attempt >= retries {
+			if err != nil {
+				return nil, err
+			}
+			return nil, &APIError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status,
+				Body: strings.TrimSpace(string(truncateBytes(resp.Body, 1024)))}
+		}
+
+		wait := backoff << attempt
+		if err == nil {
+			if after := retryAfter(resp.Header); after > 0 {
+				wait = after
+			}
+		}
+		if max := c.maxWait(); wait > max {
+			return nil, fmt.Errorf("错误：'%s' 限流，需要等待 %s 后重试", url, wait.Round(time.Second))
+		}
+		time.Sleep(wait)
+	}
+}
+
+// 发送 GET 请求并解析 JSON 响应
+func (c *APIClient) GetJSON(url string, v interface{}) error {
+	resp, err := c.Do(http.MethodGet, url, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resp.Body, v); err != nil {
+		return fmt.Errorf("错误：解析 '%s' 的响应失败: %v", url, err)
+	}
+	return nil
+}
+
+// 发送 JSON POST 请求，返回响应
+func (c *APIClient) PostJSON(url string, v interface{}) (*APIResponse, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(http.MethodPost, url, body, http.Header{"Content-Type": {"application/json"}})
+}
+
+// 按 Link 响应头(GitHub、GitLab 的分页方式)依次读取全部分页，每页调用一次 page
+func (c *APIClient) GetPages(url string, page func(body []byte) error) error {
+	for i := 0; url != "" && i < maxAPIPages; i++ {
+		resp, err := c.Do(http.MethodGet, url, nil, nil)
+		if err != nil {
+			return err
+		}
+		if err := page(resp.Body); err != nil {
+			return err
+		}
+		url = nextPageURL(resp.Header.Get("Link"))
+	}
+	return nil
+}
+
+// 发送一次请求并读取响应体
+func (c *APIClient) send(method, url string, body []byte, header http.Header, cached *apiCacheEntry) (*APIResponse, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("错误：接口地址 '%s' 无效: %v", url, err)
+	}
+	for _, h := range []http.Header{c.Header, header} {
+		for name, values := range h {
+			req.Header[name] = values
+		}
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("错误：请求 '%s' 失败: %v", url, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAPIResponse))
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取 '%s' 的响应失败: %v", url, err)
+	}
+	c.recordRateLimit(resp.Header)
+	return &APIResponse{StatusCode: resp.StatusCode, Status: resp.Status, Header: resp.Header, Body: data}, nil
+}
+
+// 剩余请求数为 0 时记录限流重置时间，之后的请求等待到重置后再发送
+func (c *APIClient) recordRateLimit(h http.Header) {
+	if !rateLimited(h) {
+		return
+	}
+	reset := firstNonEmpty(h.Get("X-RateLimit-Reset"), h.Get("RateLimit-Reset"))
+	sec, err := strconv.ParseInt(reset, 10, 64)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.resetAt = time.Unix(sec, 0)
+	c.mu.Unlock()
+}
+
+// 等待到限流重置，等待时间超过上限时返回错误
+func (c *APIClient) waitRateLimit() error {
+	c.mu.Lock()
+	wait := time.Until(c.resetAt)
+	c.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	if wait > c.maxWait() {
+		return fmt.Errorf("错误：接口限流，需要等待到 %s 后重试", c.resetAt.Format("15:04:05"))
+	}
+	time.Sleep(wait)
+	return nil
+}
+
+func (c *APIClient) maxWait() time.Duration {
+	if c.MaxWait == 0 {
+		return 5 * time.Minute
+	}
+	return c.MaxWait
+}
+
+// 缓存键：地址及认证信息，不同令牌的响应分开缓存
+func (c *APIClient) cacheKey(url string, header http.Header) string {
+	auth := firstNonEmpty(header.Get("Authorization"), c.Header.Get("Authorization"))
+	sum := sha256.Sum256([]byte(url + "\x00" + auth))
+	return hex.EncodeToString(sum[:])
+}
+
+// 响应头表明剩余请求数为 0(GitHub 为 X-RateLimit-Remaining，GitLab 为 RateLimit-Remaining)
+func rateLimited(h http.Header) bool {
+	return firstNonEmpty(h.Get("X-RateLimit-Remaining"), h.Get("RateLimit-Remaining")) == "0"
+}
+
+// 按 Retry-After 或限流重置时间计算的等待时间，没有时返回 0
+func retryAfter(h http.Header) time.Duration {
+	if v := h.Get("Retry-After"); v != "" {
+		if sec, err := strconv.Atoi(v); err == nil {
+			return time.Duration(sec) * time.Second
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return time.Until(t)
+		}
+	}
+	if rateLimited(h) {
+		reset := firstNonEmpty(h.Get("X-RateLimit-Reset"), h.Get("RateLimit-Reset"))
+		if sec, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			return time.Until(time.Unix(sec, 0))
+		}
+	}
+	return 0
+}
+
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// 从 Link 响应头中取出下一页的地址，没有下一页时返回空
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		if m := linkNextPattern.FindStringSubmatch(part); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+func readAPICache(path string) *apiCacheEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	entry := &apiCacheEntry{}
+	if json.Unmarshal(data, entry) != nil || (entry.ETag == "" && entry.LastModified == "") {
+		return nil
+	}
+	return entry
+}
+
+// 只缓存可以发送条件请求的响应，写入失败时忽略
+func writeAPICache(path string, resp *APIResponse) {
+	entry := apiCacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Header:       resp.Header,
+		Body:         resp.Body,
+	}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil || os.MkdirAll(filepath.Dir(path), 0o700) != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0o600)
+}
+
+func truncateBytes(b []byte, n int) []byte {
+	if len(b) > n {
+		return b[:n]
+	}
+	return b
+}
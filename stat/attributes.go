@@ -0,0 +1,93 @@
+package stat
+
+import (
+	"strings"
+)
+
+// 标记生成代码和第三方代码的 .gitattributes 属性
+const (
+	AttrGenerated = "linguist-generated"
+	AttrVendored  = "linguist-vendored"
+)
+
+// 每次 git check-attr 检查的路径数，避免命令行过长
+const checkAttrBatch = 500
+
+// 按 .gitattributes 中的 linguist-generated、linguist-vendored 属性排除文件，属性以当前工作区(及暂存区)为准
+func (a *Analyzer) applyAttributes(commits []CommitStats) error {
+	a.attrExcluded = nil
+	if a.IgnoreAttributes || len(commits) == 0 {
+		return nil
+	}
+	var paths []string
+	seen := make(map[string]bool)
+	for _, c := range commits {
+		for _, f := range c.Files {
+			if !f.Skipped && f.Path != "" && !seen[f.Path] {
+				seen[f.Path] = true
+				paths = append(paths, f.Path)
+			}
+		}
+	}
+	excluded := make(map[string]string)
+	for start := 0; start < len(paths); start += checkAttrBatch {
+		end := start + checkAttrBatch
+		if end > len(paths) {
+			end = len(paths)
+		}
+		args := append([]string{"check-attr", "-z", AttrGenerated, AttrVendored, "--"}, paths[start:end]...)
+		out, err := a.Git.Run(args)
+		if err != nil {
+			return err
+		}
+		parseCheckAttr(gitText(out), excluded)
+	}
+	if len(excluded) == 0 {
+		return nil
+	}
+	a.attrExcluded = excluded
+	a.attrFiles = len(excluded)
+
+	for i := range commits {
+		c := &commits[i]
+		for j := range c.Files {
+			f := &c.Files[j]
+			attr, ok := excluded[f.Path]
+			if !ok || f.Skipped {
+				continue
+			}
+			f.Skipped = true
+			f.Attribute = attr
+			f.SkipReason = attr
+			c.AddedLines -= f.Added
+			c.DeletedLines -= f.Deleted
+			a.attrLines.Added += f.Added
+			a.attrLines.Deleted += f.Deleted
+		}
+	}
+	return nil
+}
+
+// 解析 git check-attr -z 的输出(路径、属性、值依次以 NUL 分隔)，属性为 set 或 true 的文件记录到 excluded
+func parseCheckAttr(out string, excluded map[string]string) {
+	fields := strings.Split(out, "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		path, attr, value := fields[i], fields[i+1], fields[i+2]
+		if value != "set" && value != "true" {
+			continue
+		}
+		// 同时有两个属性时以 linguist-generated 为准
+		if _, ok := excluded[path]; !ok || attr == AttrGenerated {
+			excluded[path] = attr
+		}
+	}
+}
+
+// 文件是否计入统计：符合文件类型规则且没有被 .gitattributes 标记为生成或第三方代码
+func (a *Analyzer) countsFile(path string) bool {
+	if !isValidFile(path, a.IncludeExts, a.ExcludeExts) {
+		return false
+	}
+	_, excluded := a.attrExcluded[path]
+	return !excluded
+}
@@ -0,0 +1,190 @@
+package stat
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 关闭审计日志时使用的取值
+const AuditLogOff = "off"
+
+// AuditEntry 审计日志中的一次运行记录
+// 每条记录保存上一条记录的摘要，形成哈希链，日志被改写或删除中间记录时可以被发现
+type AuditEntry struct {
+	Time    string   `json:"time"`
+	User    string   `json:"user"`
+	GitUser string   `json:"git_user,omitempty"`
+	Host    string   `json:"host,omitempty"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Repo    string   `json:"repo"`
+	Head    string   `json:"head,omitempty"`
+	Since   string   `json:"since"`
+	Until   string   `json:"until"`
+	// 按提交范围统计时的范围
+	RevRange string `json:"rev_range,omitempty"`
+	Commits  int    `json:"commits"`
+	// 报告结果的摘要，用于核对争议数据
+	Digest string `json:"digest,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Prev   string `json:"prev,omitempty"`
+}
+
+// 默认审计日志路径 ~/.aistat/audit.log
+func DefaultAuditLog() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".aistat", "audit.log")
+	}
+	return filepath.Join(home, ".aistat", "audit.log")
+}
+
+// 创建包含运行环境信息的审计记录
+func NewAuditEntry(git GitRunner, command string, args []string, o *RunOptions) *AuditEntry {
+	entry := &AuditEntry{
+		Time:     time.Now().Format(time.RFC3339),
+		Command:  command,
+		Args:     args,
+		Repo:     o.Repo,
+		Since:    o.Since,
+		Until:    o.Until,
+		RevRange: o.RevRange,
+	}
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+	entry.Host, _ = os.Hostname()
+	if abs, err := filepath.Abs(firstNonEmpty(o.Repo, ".")); err == nil {
+		entry.Repo = abs
+	}
+	entry.GitUser = GitUserEmail(git)
+	entry.Head = gitOutput(git, "rev-parse", "HEAD")
+	return entry
+}
+
+// 计算报告结果的摘要
+func ReportDigest(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// 以追加方式写入审计日志。读取最后一条记录到写入期间对日志加排他锁，
+// 同时运行的多个进程(如 serve 与命令行)或同一进程中的多个请求不会写入相同的 prev，使哈希链分叉
+func AppendAudit(path string, entry *AuditEntry) error {
+	if path == AuditLogOff {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("错误：创建审计日志目录失败: %v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("错误：打开审计日志 '%s' 失败: %v", path, err)
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("错误：锁定审计日志 '%s' 失败: %v", path, err)
+	}
+
+	last, err := lastLine(f)
+	if err != nil {
+		return fmt.Errorf("错误：读取审计日志 '%s' 失败: %v", path, err)
+	}
+	if last != "" {
+		sum := sha256.Sum256([]byte(last))
+		entry.Prev = hex.EncodeToString(sum[:])
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("错误：写入审计日志 '%s' 失败: %v", path, err)
+	}
+	return f.Close()
+}
+
+// 校验审计日志的哈希链：每条记录的 prev 应为上一条记录的摘要，第一条记录没有 prev。
+// 返回记录数；记录被修改、删除或插入时返回出错的行号。
+// 哈希链无法发现删除末尾的记录，需要时对照报告或外部保存的最后一条记录的摘要
+func VerifyAudit(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("错误：打开审计日志 '%s' 失败: %v", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	count, prev := 0, ""
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return count, fmt.Errorf("错误：审计日志第 %d 行格式不正确: %v", number, err)
+		}
+		if entry.Prev != prev {
+			if prev == "" {
+				return count, fmt.Errorf("错误：审计日志第 %d 行是第一条记录却带有 prev，之前的记录可能已被删除", number)
+			}
+			return count, fmt.Errorf("错误：审计日志第 %d 行的 prev 与上一条记录的摘要不一致，日志可能已被改写", number)
+		}
+		sum := sha256.Sum256([]byte(line))
+		prev = hex.EncodeToString(sum[:])
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("错误：读取审计日志 '%s' 失败: %v", path, err)
+	}
+	return count, nil
+}
+
+// 读取文件最后一个非空行
+func lastLine(r io.Reader) (string, error) {
+	var last string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.TrimSpace(line) != "" {
+			last = line
+		}
+	}
+	return last, scanner.Err()
+}
+
+// 执行 git 命令并返回去除空白的输出，失败时返回空字符串
+func gitOutput(git GitRunner, args ...string) string {
+	out, err := git.Run(args)
+	if err != nil {
+		return ""
+	}
+	return gitText(out)
+}
+
+// 读取 git 输出并去除首尾空白
+func gitText(r io.Reader) string {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(buf.String())
+}
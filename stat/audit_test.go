@@ -0,0 +1,66 @@
+package stat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 同时写入的记录不会使哈希链分叉
+func TestAppendAuditConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	const writers = 50
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if err := AppendAudit(path, &AuditEntry{Command: "AIG_repo", Args: []string{fmt.Sprint(i)}}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	count, err := VerifyAudit(path)
+	if err != nil || count != writers {
+		t.Fatalf("VerifyAudit = %d, %v, want %d 条记录", count, err, writers)
+	}
+}
+
+func TestVerifyAuditDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for _, args := range []string{"2024-05-01", "2024-05-16", "2024-06-01"} {
+		if err := AppendAudit(path, &AuditEntry{Command: "AIG_repo", Args: []string{args}, Commits: 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	tests := map[string]string{
+		"修改记录":    strings.Replace(string(data), `"commits":3`, `"commits":30`, 1),
+		"删除中间的记录": lines[0] + lines[2],
+		"删除第一条记录": lines[1] + lines[2],
+		"调换记录的顺序": lines[1] + lines[0] + lines[2],
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			tampered := filepath.Join(t.TempDir(), "audit.log")
+			if err := os.WriteFile(tampered, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := VerifyAudit(tampered); err == nil {
+				t.Error("篡改后的审计日志通过了校验")
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package stat
+
+import "math"
+
+// 汇总行的名称
+const (
+	// 未达到最小活跃度的开发者
+	GroupOthers = "其他"
+	// --collapse-external 合并的外部开发者
+	GroupExternal = "外部贡献者"
+)
+
+// AuthorStats 单个开发者（或多个开发者汇总）的统计
+type AuthorStats struct {
+	Name                string `json:"name" yaml:"name"`
+	Email               string `json:"email,omitempty" yaml:"email,omitempty"`
+	CommitCount         int    `json:"commit_count" yaml:"commit_count"`
+	TotalAddedLines     int    `json:"total_added_lines" yaml:"total_added_lines"`
+	TotalDeletedLines   int    `json:"total_deleted_lines" yaml:"total_deleted_lines"`
+	TotalAIAddedLines   int    `json:"total_ai_added_lines" yaml:"total_ai_added_lines"`
+	TotalAIDeletedLines int    `json:"total_ai_deleted_lines" yaml:"total_ai_deleted_lines"`
+	FixCount            int    `json:"fix_count" yaml:"fix_count"`
+	FixAndAIGCount      int    `json:"fix_and_aig_count" yaml:"fix_and_aig_count"`
+	// 按严重级别统计的修复提交
+	FixSeverities map[string]FixCounts `json:"fix_severities,omitempty" yaml:"fix_severities,omitempty"`
+	// SZZ 分析中本期提交的代码被之后的修复提交修改的次数
+	BugsIntroduced int `json:"bugs_introduced,omitempty" yaml:"bugs_introduced,omitempty"`
+	// 汇总行包含的开发者人数，单个开发者为 0
+	MemberCount int `json:"member_count,omitempty" yaml:"member_count,omitempty"`
+	// 标记 AIG>0 和明确标记 AIG=0 的提交数，其余为未标记
+	AITaggedCommits   int `json:"ai_tagged_commits" yaml:"ai_tagged_commits"`
+	NoAITaggedCommits int `json:"no_ai_tagged_commits" yaml:"no_ai_tagged_commits"`
+	// 策略标记为 n/a、exempt 的提交数，及这些提交的行数和修复提交数，不计入各比例的分母
+	NACommits          int `json:"na_commits,omitempty" yaml:"na_commits,omitempty"`
+	ExemptCommits      int `json:"exempt_commits,omitempty" yaml:"exempt_commits,omitempty"`
+	PolicyAddedLines   int `json:"policy_added_lines,omitempty" yaml:"policy_added_lines,omitempty"`
+	PolicyDeletedLines int `json:"policy_deleted_lines,omitempty" yaml:"policy_deleted_lines,omitempty"`
+	PolicyFixCount     int `json:"policy_fix_count,omitempty" yaml:"policy_fix_count,omitempty"`
+	// 计入比例的提交的 AIG 比例之和，用于按提交平均(不按行数加权)
+	SumAIGRatio float64 `json:"sum_aig_ratio" yaml:"sum_aig_ratio"`
+	// 按启发式评分估算的 AI 添加行数(实验性)
+	HeuristicAILines int `json:"heuristic_ai_lines,omitempty" yaml:"heuristic_ai_lines,omitempty"`
+	// AIG 比例由 LLM 估算的提交数
+	EstimatedCommits int `json:"estimated_commits,omitempty" yaml:"estimated_commits,omitempty"`
+	// IDE/AI 工具记录的采纳行数，ToolUsage 为假表示没有该开发者的使用日志
+	ToolUsage         bool `json:"tool_usage,omitempty" yaml:"tool_usage,omitempty"`
+	ToolAcceptedLines int  `json:"tool_accepted_lines,omitempty" yaml:"tool_accepted_lines,omitempty"`
+	// 自报 AI 占比与工具采纳占比差异较大
+	UsageDiscrepancy bool `json:"usage_discrepancy,omitempty" yaml:"usage_discrepancy,omitempty"`
+	// 统计周期之前一段时间的平均水平，来自已保存的运行记录
+	Rolling *RollingStats `json:"rolling,omitempty" yaml:"rolling,omitempty"`
+	// 本期按 FTE、入职离职及休假折算的出勤天数，名单中配置了出勤信息时才有
+	ActiveDays float64 `json:"active_days,omitempty" yaml:"active_days,omitempty"`
+	// 计入统计的文件中新建、删除、修改(含重命名)的次数，及有 AI 参与(AIG>0)的提交新建的文件数
+	FilesCreated   int `json:"files_created,omitempty" yaml:"files_created,omitempty"`
+	FilesDeleted   int `json:"files_deleted,omitempty" yaml:"files_deleted,omitempty"`
+	FilesModified  int `json:"files_modified,omitempty" yaml:"files_modified,omitempty"`
+	AIFilesCreated int `json:"ai_files_created,omitempty" yaml:"ai_files_created,omitempty"`
+	// 计入比例的提交在新建文件(新代码)和已有文件(修改已有代码)中添加的行数及其中的 AI 添加行数
+	NewFileAddedLines        int `json:"new_file_added_lines,omitempty" yaml:"new_file_added_lines,omitempty"`
+	NewFileAIAddedLines      int `json:"new_file_ai_added_lines,omitempty" yaml:"new_file_ai_added_lines,omitempty"`
+	ExistingFileAddedLines   int `json:"existing_file_added_lines,omitempty" yaml:"existing_file_added_lines,omitempty"`
+	ExistingFileAIAddedLines int `json:"existing_file_ai_added_lines,omitempty" yaml:"existing_file_ai_added_lines,omitempty"`
+	// 提交信息的字符数之和(不含 AIG 标记)、标题符合 Conventional Commits 格式及引用了 issue 的提交数，AI 开头的为其中标记 AIG>0 的提交
+	MessageChars          int `json:"message_chars,omitempty" yaml:"message_chars,omitempty"`
+	ConventionalCommits   int `json:"conventional_commits,omitempty" yaml:"conventional_commits,omitempty"`
+	IssueRefCommits       int `json:"issue_ref_commits,omitempty" yaml:"issue_ref_commits,omitempty"`
+	AIMessageChars        int `json:"ai_message_chars,omitempty" yaml:"ai_message_chars,omitempty"`
+	AIConventionalCommits int `json:"ai_conventional_commits,omitempty" yaml:"ai_conventional_commits,omitempty"`
+	AIIssueRefCommits     int `json:"ai_issue_ref_commits,omitempty" yaml:"ai_issue_ref_commits,omitempty"`
+}
+
+// 累加单个提交的统计
+func (s *AuthorStats) Add(c *CommitStats) {
+	s.CommitCount++
+	s.TotalAddedLines += c.AddedLines
+	s.TotalDeletedLines += c.DeletedLines
+	s.TotalAIAddedLines += c.AIAddedLines()
+	s.TotalAIDeletedLines += c.AIDeletedLines()
+	s.HeuristicAILines += c.HeuristicAILines()
+	s.BugsIntroduced += len(c.BugFixes)
+	if c.AIGSource == AIGSourceEstimate {
+		s.EstimatedCommits++
+	}
+	switch CommitCohort(c) {
+	case CohortAI:
+		s.AITaggedCommits++
+	case CohortNoAI:
+		s.NoAITaggedCommits++
+	}
+	s.addMessage(c)
+	if c.AIGPolicy != "" {
+		if c.AIGPolicy == AIGPolicyExempt {
+			s.ExemptCommits++
+		} else {
+			s.NACommits++
+		}
+		s.PolicyAddedLines += c.AddedLines
+		s.PolicyDeletedLines += c.DeletedLines
+		if c.IsFix {
+			s.PolicyFixCount++
+		}
+	} else {
+		s.SumAIGRatio += c.AIGRatio
+	}
+
+	newLines, existingLines := 0, 0
+	for i := range c.Files {
+		f := &c.Files[i]
+		if !lifecycleFile(f) {
+			continue
+		}
+		if f.Status == FileAdded {
+			newLines += f.Added
+		} else {
+			existingLines += f.Added
+		}
+		switch f.Status {
+		case FileAdded, FileCopied:
+			s.FilesCreated++
+			if c.AIGRatio > 0 {
+				s.AIFilesCreated++
+			}
+		case FileDeleted:
+			s.FilesDeleted++
+		default:
+			s.FilesModified++
+		}
+	}
+	if c.AIGPolicy == "" {
+		s.NewFileAddedLines += newLines
+		s.NewFileAIAddedLines += int(math.Round(float64(newLines) * c.AIGRatio))
+		s.ExistingFileAddedLines += existingLines
+		s.ExistingFileAIAddedLines += int(math.Round(float64(existingLines) * c.AIGRatio))
+	}
+
+	if c.IsFix {
+		s.FixCount++
+		if c.AIGRatio > 0 {
+			s.FixAndAIGCount++
+		}
+		if c.FixSeverity != "" {
+			if s.FixSeverities == nil {
+				s.FixSeverities = make(map[string]FixCounts)
+			}
+			addFixSeverity(s.FixSeverities, c)
+		}
+	}
+}
+
+// 没有 AIG 标记的提交数
+func (s *AuthorStats) UntaggedCommits() int {
+	return s.CommitCount - s.AITaggedCommits - s.NoAITaggedCommits - s.NACommits - s.ExemptCommits
+}
+
+// 计算 AI 添加占比的分母，不含策略标记提交的行数
+func (s *AuthorStats) RatioAddedLines() int {
+	return s.TotalAddedLines - s.PolicyAddedLines
+}
+
+// 计算 AI 删除占比的分母
+func (s *AuthorStats) RatioDeletedLines() int {
+	return s.TotalDeletedLines - s.PolicyDeletedLines
+}
+
+// 计算 AI 修复贡献率的分母
+func (s *AuthorStats) RatioFixCount() int {
+	return s.FixCount - s.PolicyFixCount
+}
+
+// 计算按提交平均 AI 占比的提交数
+func (s *AuthorStats) RatioCommits() int {
+	return s.CommitCount - s.NACommits - s.ExemptCommits
+}
+
+// 按提交平均的 AIG 比例（百分比），每个提交权重相同，不受单个大提交影响
+func (s *AuthorStats) CommitAvgRatio() float64 {
+	if n := s.RatioCommits(); n > 0 {
+		return s.SumAIGRatio / float64(n) * 100
+	}
+	return 0
+}
+
+// AI 贡献添加行占比（百分比）
+func (s *AuthorStats) AddedRatio() float64 {
+	return percent(s.TotalAIAddedLines, s.RatioAddedLines())
+}
+
+// AI 贡献删除行占比（百分比）
+func (s *AuthorStats) DeletedRatio() float64 {
+	return percent(s.TotalAIDeletedLines, s.RatioDeletedLines())
+}
+
+// 启发式估算的 AI 添加行占比（百分比）
+func (s *AuthorStats) HeuristicRatio() float64 {
+	return percent(s.HeuristicAILines, s.TotalAddedLines)
+}
+
+// 工具采纳行数占总添加行的比例（百分比）
+func (s *AuthorStats) ToolRatio() float64 {
+	return percent(s.ToolAcceptedLines, s.TotalAddedLines)
+}
+
+// AI 参与修复的提交占比（百分比）
+func (s *AuthorStats) AIFixRatio() float64 {
+	return percent(s.FixAndAIGCount, s.RatioFixCount())
+}
+
+// 将开发者统计累加到汇总行
+func (s *AuthorStats) Merge(src *AuthorStats) {
+	s.CommitCount += src.CommitCount
+	s.TotalAddedLines += src.TotalAddedLines
+	s.TotalDeletedLines += src.TotalDeletedLines
+	s.TotalAIAddedLines += src.TotalAIAddedLines
+	s.TotalAIDeletedLines += src.TotalAIDeletedLines
+	s.FixCount += src.FixCount
+	s.FixAndAIGCount += src.FixAndAIGCount
+	s.BugsIntroduced += src.BugsIntroduced
+	for name, f := range src.FixSeverities {
+		if s.FixSeverities == nil {
+			s.FixSeverities = make(map[string]FixCounts)
+		}
+		total := s.FixSeverities[name]
+		total.Fixes += f.Fixes
+		total.AIFixes += f.AIFixes
+		total.PolicyFixes += f.PolicyFixes
+		s.FixSeverities[name] = total
+	}
+	s.EstimatedCommits += src.EstimatedCommits
+	s.AITaggedCommits += src.AITaggedCommits
+	s.NoAITaggedCommits += src.NoAITaggedCommits
+	s.NACommits += src.NACommits
+	s.ExemptCommits += src.ExemptCommits
+	s.PolicyAddedLines += src.PolicyAddedLines
+	s.PolicyDeletedLines += src.PolicyDeletedLines
+	s.PolicyFixCount += src.PolicyFixCount
+	s.SumAIGRatio += src.SumAIGRatio
+	s.HeuristicAILines += src.HeuristicAILines
+	s.ToolUsage = s.ToolUsage || src.ToolUsage
+	s.ToolAcceptedLines += src.ToolAcceptedLines
+	s.ActiveDays += src.ActiveDays
+	s.FilesCreated += src.FilesCreated
+	s.FilesDeleted += src.FilesDeleted
+	s.FilesModified += src.FilesModified
+	s.AIFilesCreated += src.AIFilesCreated
+	s.NewFileAddedLines += src.NewFileAddedLines
+	s.NewFileAIAddedLines += src.NewFileAIAddedLines
+	s.ExistingFileAddedLines += src.ExistingFileAddedLines
+	s.ExistingFileAIAddedLines += src.ExistingFileAIAddedLines
+	s.MessageChars += src.MessageChars
+	s.ConventionalCommits += src.ConventionalCommits
+	s.IssueRefCommits += src.IssueRefCommits
+	s.AIMessageChars += src.AIMessageChars
+	s.AIConventionalCommits += src.AIConventionalCommits
+	s.AIIssueRefCommits += src.AIIssueRefCommits
+	if src.MemberCount > 0 {
+		s.MemberCount += src.MemberCount
+	} else {
+		s.MemberCount++
+	}
+}
+
+// 按邮箱汇总每个开发者的统计
+func AggregateByAuthor(commits []CommitStats) map[string]*AuthorStats {
+	return AggregateByIdentity(commits, IdentityEmail)
+}
+
+// 按识别方式汇总每个开发者的统计，键为 IdentityKey，姓名和邮箱取第一次出现的值(邮箱为空时取之后出现的)
+func AggregateByIdentity(commits []CommitStats, mode string) map[string]*AuthorStats {
+	authorStats := make(map[string]*AuthorStats)
+	for i := range commits {
+		c := &commits[i]
+		key := IdentityKey(mode, c.Author, c.Email)
+		stats, exists := authorStats[key]
+		if !exists {
+			stats = &AuthorStats{
+				Name:  c.Author,
+				Email: c.Email,
+			}
+			authorStats[key] = stats
+		} else if stats.Email == "" {
+			stats.Email = c.Email
+		}
+		stats.Add(c)
+	}
+	return authorStats
+}
+
+// 计算百分比，分母为 0 时返回 0
+func percent(part, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
+
+// PeriodStats 一个开发者在统计周期内的汇总，即个人报告的 stats 字段，键名与以往的 stats 字段一致
+type PeriodStats struct {
+	CommitCount         int `json:"commitCount" yaml:"commitCount"`
+	TotalAddedLines     int `json:"totalAddedLines" yaml:"totalAddedLines"`
+	TotalDeletedLines   int `json:"totalDeletedLines" yaml:"totalDeletedLines"`
+	TotalAIAddedLines   int `json:"totalAIAddedLines" yaml:"totalAIAddedLines"`
+	TotalAIDeletedLines int `json:"totalAIDeletedLines" yaml:"totalAIDeletedLines"`
+	// 按启发式评分估算的 AI 添加行数(实验性)及 AIG 比例由 LLM 估算的提交数
+	HeuristicAILines int `json:"heuristicAILines" yaml:"heuristicAILines"`
+	EstimatedCommits int `json:"estimatedCommits" yaml:"estimatedCommits"`
+	// 策略标记为 n/a、exempt 的提交数，及这些提交的行数和修复提交数
+	NACommits          int `json:"naCommits" yaml:"naCommits"`
+	ExemptCommits      int `json:"exemptCommits" yaml:"exemptCommits"`
+	PolicyAddedLines   int `json:"policyAddedLines" yaml:"policyAddedLines"`
+	PolicyDeletedLines int `json:"policyDeletedLines" yaml:"policyDeletedLines"`
+	PolicyFixCount     int `json:"policyFixCount" yaml:"policyFixCount"`
+	// 修复提交数及其中 AIG>0 的提交数
+	FixCount       int `json:"fixCount" yaml:"fixCount"`
+	FixAndAIGCount int `json:"fixAndAIGCount" yaml:"fixAndAIGCount"`
+}
+
+// 累加单个提交的统计
+func (s *PeriodStats) Add(c *CommitStats) {
+	s.CommitCount++
+	s.TotalAddedLines += c.AddedLines
+	s.TotalDeletedLines += c.DeletedLines
+	s.TotalAIAddedLines += c.AIAddedLines()
+	s.TotalAIDeletedLines += c.AIDeletedLines()
+	s.HeuristicAILines += c.HeuristicAILines()
+	if c.AIGSource == AIGSourceEstimate {
+		s.EstimatedCommits++
+	}
+	if c.AIGPolicy != "" {
+		if c.AIGPolicy == AIGPolicyExempt {
+			s.ExemptCommits++
+		} else {
+			s.NACommits++
+		}
+		s.PolicyAddedLines += c.AddedLines
+		s.PolicyDeletedLines += c.DeletedLines
+		if c.IsFix {
+			s.PolicyFixCount++
+		}
+	}
+	if c.IsFix {
+		s.FixCount++
+		if c.AIGRatio > 0 {
+			s.FixAndAIGCount++
+		}
+	}
+}
+
+// 转换为报告中的开发者统计
+func (s *PeriodStats) AuthorStats(name string) *AuthorStats {
+	return &AuthorStats{
+		Name:                name,
+		CommitCount:         s.CommitCount,
+		TotalAddedLines:     s.TotalAddedLines,
+		TotalDeletedLines:   s.TotalDeletedLines,
+		TotalAIAddedLines:   s.TotalAIAddedLines,
+		TotalAIDeletedLines: s.TotalAIDeletedLines,
+		FixCount:            s.FixCount,
+		FixAndAIGCount:      s.FixAndAIGCount,
+		EstimatedCommits:    s.EstimatedCommits,
+		HeuristicAILines:    s.HeuristicAILines,
+		NACommits:           s.NACommits,
+		ExemptCommits:       s.ExemptCommits,
+		PolicyAddedLines:    s.PolicyAddedLines,
+		PolicyDeletedLines:  s.PolicyDeletedLines,
+		PolicyFixCount:      s.PolicyFixCount,
+	}
+}
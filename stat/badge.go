@@ -0,0 +1,93 @@
+package stat
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// 徽章的默认指标及统计天数(serve 模式)
+const (
+	DefaultBadgeMetric = "ai-ratio"
+	DefaultBadgeDays   = 30
+)
+
+// 徽章指标的简写，其余指标与目标的指标名相同，- 和 _ 均可
+var badgeAliases = map[string]string{
+	"ai_ratio":     "ai_added_ratio",
+	"fix_ratio":    "ai_fix_ratio",
+	"commit_ratio": "ai_commit_avg_ratio",
+}
+
+// 解析徽章指标，返回规范的指标名
+func BadgeMetric(name string) (string, error) {
+	metric := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+	if alias, ok := badgeAliases[metric]; ok {
+		metric = alias
+	}
+	if _, ok := targetMetrics[metric]; !ok {
+		return "", fmt.Errorf("错误：不支持的徽章指标 '%s'，可选: ai-ratio, fix-ratio, commit-ratio, %s", name, strings.Join(TargetMetrics(), ", "))
+	}
+	return metric, nil
+}
+
+// 汇总提交并计算徽章指标的值(百分比)
+func BadgeValue(metric string, commits []CommitStats) float64 {
+	total := &AuthorStats{}
+	for i := range commits {
+		total.Add(&commits[i])
+	}
+	return targetMetrics[metric].Value(total)
+}
+
+// 按占比选择徽章颜色
+func badgeColor(v float64) string {
+	switch {
+	case v >= 50:
+		return "#4c1"
+	case v >= 30:
+		return "#97ca00"
+	case v >= 10:
+		return "#dfb317"
+	case v > 0:
+		return "#fe7d37"
+	}
+	return "#9f9f9f"
+}
+
+// 估算文字宽度：ASCII 字符约 6.5 像素，其他字符(如中文)约 12 像素
+func badgeTextWidth(s string) int {
+	width := 0.0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			width += 6.5
+		} else {
+			width += 12
+		}
+	}
+	return int(width + 0.5)
+}
+
+// 输出 shields.io 平面风格的 SVG 徽章，label 为空时使用指标名称
+func WriteBadge(w io.Writer, metric, label string, value float64) error {
+	if label == "" {
+		label = TargetMetricLabel(metric)
+	}
+	text := fmt.Sprintf("%.1f%%", value)
+	lw, vw := badgeTextWidth(label)+10, badgeTextWidth(text)+10
+	label, text = html.EscapeString(label), html.EscapeString(text)
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, lw+vw, lw, vw, label, text, badgeColor(value), lw/2, lw+vw/2)
+	return err
+}
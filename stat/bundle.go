@@ -0,0 +1,218 @@
+package stat
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// 数据包格式版本，导入时拒绝更新的版本
+const bundleVersion = 1
+
+// Bundle 运行记录存储的导出数据包，gzip 压缩的单个 JSON 文件，
+// 用于把隔离网络中构建机上保存的运行记录合并到中心实例
+type Bundle struct {
+	Version     int    `json:"version"`
+	CreatedAt   string `json:"created_at"`
+	Host        string `json:"host,omitempty"`
+	ToolVersion string `json:"tool_version"`
+	// 只导出该日期之后的数据时的起始日期
+	Since string        `json:"since,omitempty"`
+	Repos []*BundleRepo `json:"repos"`
+}
+
+// BundleRepo 一个仓库的运行记录、提交(含单独保存的提交)和冻结记录
+type BundleRepo struct {
+	Name    string          `json:"name"`
+	Runs    []*Report       `json:"runs"`
+	Commits []CommitStats   `json:"commits,omitempty"`
+	Frozen  []*FrozenPeriod `json:"frozen,omitempty"`
+}
+
+// BundleResult 导入数据包的结果，预览时为将要导入的数量
+type BundleResult struct {
+	Runs    int `json:"runs"`
+	Commits int `json:"commits"`
+	Frozen  int `json:"frozen"`
+	// 已存在而跳过的运行记录，以及与目标存储中同一周期冻结内容不同而跳过的冻结记录
+	SkippedRuns     int      `json:"skipped_runs"`
+	FrozenConflicts []string `json:"frozen_conflicts,omitempty"`
+}
+
+func (r *BundleResult) String() string {
+	return fmt.Sprintf("运行记录 %d 条(已存在 %d 条)，提交 %d 个，冻结记录 %d 条", r.Runs, r.SkippedRuns, r.Commits, r.Frozen)
+}
+
+// 导出存储中的数据，repos 为空时导出全部仓库；since 不为空时只导出统计周期在该日期及之后结束的运行记录、
+// 提交时间在该日期及之后的提交和冻结记录
+func ExportBundle(store Store, repos []string, since string) (*Bundle, error) {
+	if len(repos) == 0 {
+		var err error
+		if repos, err = store.Repos(); err != nil {
+			return nil, err
+		}
+	}
+	host, _ := os.Hostname()
+	bundle := &Bundle{Version: bundleVersion, CreatedAt: time.Now().Format(time.RFC3339), Host: host, ToolVersion: Version, Since: since}
+	for _, name := range repos {
+		repo := &BundleRepo{Name: SafeFileName(name), Runs: []*Report{}}
+		runs, err := store.LoadRuns(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range runs {
+			if since == "" || !expired(runDay(r), since) {
+				repo.Runs = append(repo.Runs, r)
+			}
+		}
+		if repo.Commits, err = store.Commits(name, since, "9999-12-31"); err != nil {
+			return nil, err
+		}
+		frozen, err := store.FrozenPeriods(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range frozen {
+			if since == "" || !expired(frozenDay(f), since) {
+				repo.Frozen = append(repo.Frozen, f)
+			}
+		}
+		if len(repo.Runs) > 0 || len(repo.Commits) > 0 || len(repo.Frozen) > 0 {
+			bundle.Repos = append(bundle.Repos, repo)
+		}
+	}
+	return bundle, nil
+}
+
+// 写入 gzip 压缩的数据包
+func WriteBundle(path string, bundle *Bundle) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("错误：创建数据包 '%s' 失败: %v", path, err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	if err := json.NewEncoder(zw).Encode(bundle); err != nil {
+		return fmt.Errorf("错误：写入数据包 '%s' 失败: %v", path, err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("错误：写入数据包 '%s' 失败: %v", path, err)
+	}
+	return f.Close()
+}
+
+// 读取数据包
+func ReadBundle(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取数据包 '%s' 失败: %v", path, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("错误：'%s' 不是有效的数据包: %v", path, err)
+	}
+	bundle := &Bundle{}
+	if err := json.NewDecoder(zr).Decode(bundle); err != nil {
+		return nil, fmt.Errorf("错误：解析数据包 '%s' 失败: %v", path, err)
+	}
+	if bundle.Version > bundleVersion {
+		return nil, fmt.Errorf("错误：数据包 '%s' 的格式版本 %d 高于当前支持的版本 %d，请升级工具", path, bundle.Version, bundleVersion)
+	}
+	return bundle, nil
+}
+
+// 把数据包合并到存储：已存在的运行记录(生成时间、统计周期和结果都相同)不重复保存，
+// 提交只保存目标存储中没有的，冻结记录只导入目标存储中没有的统计周期
+func ImportBundle(store Store, bundle *Bundle, dryRun bool) (*BundleResult, error) {
+	result := &BundleResult{}
+	for _, repo := range bundle.Repos {
+		existing, err := store.LoadRuns(repo.Name)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, r := range existing {
+			seen[bundleRunKey(r)] = true
+		}
+		for _, r := range repo.Runs {
+			key := bundleRunKey(r)
+			if seen[key] {
+				result.SkippedRuns++
+				continue
+			}
+			seen[key] = true
+			result.Runs++
+			if !dryRun {
+				if _, err := store.SaveRun(r); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// 运行记录中的提交已随运行记录保存，这里只补充目标存储中还没有的
+		have, err := store.Commits(repo.Name, "", "9999-12-31")
+		if err != nil {
+			return nil, err
+		}
+		ids := make(map[string]bool, len(have))
+		for _, c := range have {
+			ids[c.ID] = true
+		}
+		if dryRun {
+			for _, r := range repo.Runs {
+				for _, c := range r.Commits {
+					ids[c.ID] = true
+				}
+			}
+		}
+		var commits []CommitStats
+		for _, c := range repo.Commits {
+			if !ids[c.ID] {
+				ids[c.ID] = true
+				commits = append(commits, c)
+			}
+		}
+		result.Commits += len(commits)
+		if len(commits) > 0 && !dryRun {
+			if err := store.SaveCommits(repo.Name, commits); err != nil {
+				return nil, err
+			}
+		}
+
+		frozen, err := store.FrozenPeriods(repo.Name)
+		if err != nil {
+			return nil, err
+		}
+		frozenKeys := make(map[string]string)
+		for _, f := range frozen {
+			frozenKeys[f.Key()] = f.Checksum
+		}
+		for _, f := range repo.Frozen {
+			if checksum, ok := frozenKeys[f.Key()]; ok {
+				if checksum != f.Checksum {
+					result.FrozenConflicts = append(result.FrozenConflicts, repo.Name+" "+f.Key())
+				}
+				continue
+			}
+			result.Frozen++
+			if !dryRun {
+				if err := store.Freeze(f); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// 判断运行记录是否相同：生成时间、统计周期及统计结果的摘要
+func bundleRunKey(r *Report) string {
+	generated := ""
+	if r.Meta != nil {
+		generated = r.Meta.GeneratedAt
+	}
+	return generated + "|" + r.PeriodLabel() + "|" + r.Digest()
+}
@@ -0,0 +1,81 @@
+package stat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// EnvCheck --check-env 的一项检查结果，Err 为 nil 时通过
+type EnvCheck struct {
+	Item   string
+	Detail string
+	Err    error
+}
+
+// 检查 git 是否已安装
+func CheckGit() EnvCheck {
+	c := EnvCheck{Item: "git"}
+	path, err := exec.LookPath("git")
+	if err != nil {
+		c.Err = errors.New("没有找到 git，请安装 git 并加入 PATH")
+		return c
+	}
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		c.Err = fmt.Errorf("无法运行 '%s': %v", path, err)
+		return c
+	}
+	c.Detail = strings.TrimSpace(string(out)) + " (" + path + ")"
+	return c
+}
+
+// 检查 dir 是否为可以统计的 git 仓库
+func CheckRepo(git GitRunner, dir string) EnvCheck {
+	c := EnvCheck{Item: "仓库"}
+	out, err := git.Run([]string{"rev-parse", "--show-toplevel"})
+	if err != nil {
+		c.Err = fmt.Errorf("'%s' 不是有效的 git 仓库: %s", dir, strings.TrimPrefix(err.Error(), "错误："))
+		return c
+	}
+	c.Detail = gitText(out)
+	if gitOutput(git, "rev-parse", "--verify", "--quiet", "HEAD") == "" {
+		c.Detail += "，还没有提交"
+	} else if shallow := gitOutput(git, "rev-parse", "--is-shallow-repository"); shallow == "true" {
+		c.Detail += "，浅克隆(统计周期早于克隆深度的提交不会被统计)"
+	}
+	return c
+}
+
+// 检查配置文件及参数，err 为加载和解析选项时的错误
+func CheckConfig(cfg *Config, err error) EnvCheck {
+	c := EnvCheck{Item: "配置"}
+	if err != nil {
+		c.Err = errors.New(strings.TrimPrefix(err.Error(), "错误："))
+		return c
+	}
+	c.Detail = "未使用配置文件"
+	if cfg != nil && len(cfg.Sources) > 0 {
+		c.Detail = strings.Join(cfg.Sources, ", ")
+	}
+	return c
+}
+
+// 逐项输出检查结果，有未通过的项时返回错误
+func PrintEnvChecks(w io.Writer, checks []EnvCheck) error {
+	failed := 0
+	for _, c := range checks {
+		if c.Err != nil {
+			failed++
+			fmt.Fprintf(w, "[失败] %s: %v\n", c.Item, c.Err)
+			continue
+		}
+		fmt.Fprintf(w, "[通过] %s: %s\n", c.Item, c.Detail)
+	}
+	if failed > 0 {
+		return fmt.Errorf("错误：运行环境检查有 %d 项未通过", failed)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package stat
+
+// 按 AIG 标记划分的提交分组
+const (
+	// 标记了 AIG 且大于 0
+	CohortAI = "ai"
+	// 明确标记 AIG: 0
+	CohortNoAI = "no_ai"
+	// 没有标记(含 LLM 估算的提交)
+	CohortUntagged = "untagged"
+	// 策略标记 n/a、exempt
+	CohortPolicy = "policy"
+)
+
+// 分组的显示名称，按输出顺序排列
+var cohortLabels = []struct{ Cohort, Label string }{
+	{CohortAI, "标记 AIG>0"},
+	{CohortNoAI, "标记 AIG=0"},
+	{CohortUntagged, "未标记"},
+	{CohortPolicy, "标记 n/a 或 exempt"},
+}
+
+// 提交所属的分组，区分“没有使用 AI”和“忘记标记”
+func CommitCohort(c *CommitStats) string {
+	if c.AIGPolicy != "" {
+		return CohortPolicy
+	}
+	switch c.AIGSource {
+	case AIGSourceTag, AIGSourceSquash, AIGSourceManifest:
+		if c.AIGRatio > 0 {
+			return CohortAI
+		}
+		return CohortNoAI
+	}
+	return CohortUntagged
+}
+
+// 按 AIG 标记分组汇总全部指标，每组一行，MemberCount 为组内按 identity 识别的开发者人数
+func BuildCohorts(commits []CommitStats, identity string) []*AuthorStats {
+	rows := make(map[string]*AuthorStats)
+	members := make(map[string]map[string]bool)
+	var result []*AuthorStats
+	for _, l := range cohortLabels {
+		rows[l.Cohort] = &AuthorStats{Name: l.Label}
+		members[l.Cohort] = make(map[string]bool)
+		result = append(result, rows[l.Cohort])
+	}
+	for i := range commits {
+		cohort := CommitCohort(&commits[i])
+		rows[cohort].Add(&commits[i])
+		members[cohort][IdentityKey(identity, commits[i].Author, commits[i].Email)] = true
+	}
+	for cohort, row := range rows {
+		row.MemberCount = len(members[cohort])
+	}
+	return result
+}
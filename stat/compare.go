@@ -0,0 +1,82 @@
+package stat
+
+import "strings"
+
+// RepoSummary 单个仓库在统计周期内的 AI 采用情况
+type RepoSummary struct {
+	Repo string `json:"repo"`
+	// 有提交的开发者人数(含汇总行中的人数)
+	Contributors int `json:"contributors"`
+	// 单独列出的开发者中有提交标记 AIG>0 的人数
+	AIContributors int `json:"ai_contributors"`
+	Commits        int `json:"commits"`
+	// 标记 AIG>0 的提交数、带有任意 AIG 标记的提交数
+	AICommits     int `json:"ai_commits"`
+	TaggedCommits int `json:"tagged_commits"`
+	AddedLines    int `json:"added_lines"`
+	AIAddedLines  int `json:"ai_added_lines"`
+	// 按行数加权和按提交平均的 AI 添加占比
+	AddedRatio     float64 `json:"added_ratio"`
+	CommitAvgRatio float64 `json:"commit_avg_ratio"`
+	FixCount       int     `json:"fix_count"`
+	FixAndAIGCount int     `json:"fix_and_aig_count"`
+	AIFixRatio     float64 `json:"ai_fix_ratio"`
+	// 分析失败的原因，部分步骤失败时为失败的步骤
+	Error string `json:"error,omitempty"`
+	// 分析失败，没有统计结果
+	Failed bool `json:"failed,omitempty"`
+}
+
+// RepoComparison 两个仓库同一统计周期的对比
+type RepoComparison struct {
+	Since    string      `json:"since,omitempty"`
+	Until    string      `json:"until,omitempty"`
+	RevRange string      `json:"rev_range,omitempty"`
+	A        RepoSummary `json:"a"`
+	B        RepoSummary `json:"b"`
+}
+
+// 汇总报告中有提交的开发者及汇总行，名单中本期无提交的成员不计入
+func SummarizeRepo(report *Report) RepoSummary {
+	total := &AuthorStats{}
+	summary := RepoSummary{}
+	if report.Meta != nil {
+		summary.Repo = report.Meta.Repo
+		if len(report.Meta.Errors) > 0 {
+			summary.Error = "部分结果: " + strings.Join(report.Meta.Errors, "；")
+		}
+	}
+	rows := append(append([]*AuthorStats{}, report.Authors...), report.Groups...)
+	for _, s := range rows {
+		if s.CommitCount == 0 {
+			continue
+		}
+		total.Merge(s)
+		if s.MemberCount == 0 && s.AITaggedCommits > 0 {
+			summary.AIContributors++
+		}
+	}
+
+	summary.Contributors = total.MemberCount
+	summary.Commits = total.CommitCount
+	summary.AICommits = total.AITaggedCommits
+	summary.TaggedCommits = total.AITaggedCommits + total.NoAITaggedCommits
+	summary.AddedLines = total.TotalAddedLines
+	summary.AIAddedLines = total.TotalAIAddedLines
+	summary.AddedRatio = total.AddedRatio()
+	summary.CommitAvgRatio = total.CommitAvgRatio()
+	summary.FixCount = total.FixCount
+	summary.FixAndAIGCount = total.FixAndAIGCount
+	summary.AIFixRatio = total.AIFixRatio()
+	return summary
+}
+
+// 使用 AI 的开发者占比（百分比）
+func (s *RepoSummary) AIContributorRatio() float64 {
+	return percent(s.AIContributors, s.Contributors)
+}
+
+// 带有 AIG 标记的提交占比（百分比），反映标记习惯的普及程度
+func (s *RepoSummary) TaggedRatio() float64 {
+	return percent(s.TaggedCommits, s.Commits)
+}
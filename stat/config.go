@@ -0,0 +1,280 @@
+package stat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 默认配置文件路径
+const DefaultConfigFile = ".aistat.yaml"
+
+// 可覆盖配置文件的环境变量
+const (
+	EnvSince  = "AISTAT_SINCE"
+	EnvUntil  = "AISTAT_UNTIL"
+	EnvFormat = "AISTAT_FORMAT"
+	EnvRepo   = "AISTAT_REPO"
+)
+
+// Config 配置文件内容，优先级低于环境变量和命令行参数
+type Config struct {
+	Since  string `yaml:"since"`
+	Until  string `yaml:"until"`
+	Format string `yaml:"format"`
+	// 要分析的仓库目录，为空时使用当前目录
+	Repo string `yaml:"repo"`
+	// 报告输出目录
+	OutDir string `yaml:"out_dir"`
+	// 额外的输出目标，格式同 --sink
+	Sinks []string `yaml:"sinks"`
+	// 报告签名方式及密钥
+	Sign    string `yaml:"sign"`
+	SignKey string `yaml:"sign_key"`
+	// 生成 PDF 使用的转换工具
+	PDFTool string `yaml:"pdf_tool"`
+	// self-update 读取的版本清单(release.json)的地址、路径或所在目录
+	UpdateURL string `yaml:"update_url"`
+	// 审计日志路径，off 表示不记录
+	AuditLog string `yaml:"audit_log"`
+	// 运行记录保存目录，off 表示不保存
+	Store string `yaml:"store"`
+	// 运行记录的保留月数，purge 及 serve 按此删除更早的数据，0 表示不限制
+	RetentionMonths int `yaml:"retention_months"`
+	// 每周发送给名单中开发者本人的统计周报，serve 运行时按时发送
+	WeeklyDigest *DigestConfig `yaml:"weekly_digest"`
+	// 显示统计周期之前该天数内的平均水平
+	RollingDays int `yaml:"rolling_days"`
+	// 同时分析子模块
+	RecurseSubmodules bool `yaml:"recurse_submodules"`
+	SubmodulePrefix   bool `yaml:"submodule_prefix"`
+	// 部分克隆中从远程获取缺失对象
+	FetchMissing bool `yaml:"fetch_missing"`
+	// IDE/AI 工具的使用日志及差异标记阈值(百分点)
+	UsageLogs      []string `yaml:"usage_logs"`
+	UsageThreshold float64  `yaml:"usage_threshold"`
+	// LLM 估算设置，密钥只从环境变量读取
+	LLMEndpoint string `yaml:"llm_endpoint"`
+	LLMModel    string `yaml:"llm_model"`
+	LLMDiff     bool   `yaml:"llm_diff"`
+	LLMCacheDir string `yaml:"llm_cache_dir"`
+	// 启发式 AI 风格检测(实验性)
+	Heuristic bool `yaml:"heuristic"`
+	// 快速模式：不统计行数，只统计提交数、修复提交数和平均 AIG 比例
+	Fast bool `yaml:"fast"`
+	// 单个 git 命令的超时时间，如 10m
+	GitTimeout time.Duration `yaml:"git_timeout"`
+	// 比例的最小分母(修复提交数、变更行数)
+	MinSample      int `yaml:"min_sample"`
+	MinSampleLines int `yaml:"min_sample_lines"`
+	// 报告中数字的语言格式(如 zh-CN、en-US、de-DE)及是否以 KLoC 显示行数
+	Lang string `yaml:"lang"`
+	KLoC bool   `yaml:"kloc"`
+	// 不计入新增的空行
+	IgnoreBlankLines bool `yaml:"ignore_blank_lines"`
+	// 不按 .gitattributes 的 linguist-generated、linguist-vendored 属性排除文件
+	IgnoreGitattributes bool `yaml:"ignore_gitattributes"`
+	// 文件类型预设：default、frontend、backend-go、mobile、data 或 profiles 中新增的预设
+	Profile string `yaml:"profile"`
+	// 覆盖内置预设或新增预设
+	Profiles map[string]LanguageProfile `yaml:"profiles"`
+	// 统计的文件扩展名(代替预设的列表，"*" 为全部非二进制文件)及追加排除的扩展名
+	IncludeExts []string `yaml:"include_exts"`
+	ExcludeExts []string `yaml:"exclude_exts"`
+	// 统计全部非二进制、未被排除的文件，同 include_exts: ["*"]
+	AllText bool `yaml:"all_text"`
+	// 大规模移动的处理方式：discount、exclude、off
+	MassMove string `yaml:"mass_move"`
+	// 提交信息中有多个 AIG 标记时的取值规则：first、last、key、trailer
+	AIGPrecedence string `yaml:"aig_precedence"`
+	// 确定统计周期的时间口径：committer、author
+	DateKind string `yaml:"date_kind"`
+	// AI 清单的路径，off 表示不读取
+	AIManifest string `yaml:"ai_manifest"`
+	// 统计所有分支时同一改动在多个分支上的副本的处理方式：first、main、keep，及 main 方式使用的主分支
+	BranchCopies string `yaml:"branch_copies"`
+	MainBranch   string `yaml:"main_branch"`
+	// 不参与统计的提交，格式同 .aistat-ignore 的每一行："<哈希> [原因]"
+	IgnoreCommits []string `yaml:"ignore_commits"`
+	// 只统计(invert_grep 为真时排除)提交信息匹配这些正则的提交
+	Grep       []string `yaml:"grep"`
+	InvertGrep bool     `yaml:"invert_grep"`
+	// 识别修复提交的提交标题正则(默认标题以 fix 或 hotfix 开头)及修复的严重级别
+	FixPattern    string        `yaml:"fix_pattern"`
+	FixSeverities []FixSeverity `yaml:"fix_severities"`
+	// 团队成员名单，本期无提交的成员也会以全零数据出现在报告中
+	Roster []RosterMember `yaml:"roster"`
+	// 组织架构(部门、小组、团队)，报告中逐级汇总
+	Org []OrgUnit `yaml:"org"`
+	// 仓库迁移的邮箱映射文件、提交哈希映射文件及直接配置的旧邮箱到新邮箱映射
+	EmailMap      string            `yaml:"email_map"`
+	CommitMap     string            `yaml:"commit_map"`
+	EmailRewrites map[string]string `yaml:"email_rewrites"`
+	// 汇总开发者统计的识别方式: email、name、name+email
+	Identity string `yaml:"identity"`
+	// 按周汇总时周的起始日及编号方式: iso、monday、sunday
+	Week string `yaml:"week"`
+	// 团队目标，报告中显示进度
+	Targets []Target `yaml:"targets"`
+	// 开发者 AI 添加占比与上期相比变化超过该百分点时标注为异常
+	AnomalyThreshold float64 `yaml:"anomaly_threshold"`
+	// serve 接收推送 webhook 时，仓库全名(如 group/app)到本地克隆目录的映射
+	WebhookRepos map[string]string `yaml:"webhook_repos"`
+	// 推送触发的统计更新完成后通知的地址
+	NotifyURLs []string `yaml:"notify_urls"`
+	// serve 模式下的多个项目，各自使用独立的仓库、配置和访问令牌
+	Projects []Project `yaml:"projects"`
+	// 使用 OIDC 令牌访问 serve 的 API
+	OIDC *OIDCConfig `yaml:"oidc"`
+	// serve 的徽章接口不需要访问令牌，便于在 README 中引用
+	PublicBadges bool `yaml:"public_badges"`
+	// 已读取的配置文件，依次为用户级配置、指定的配置及仓库自己的配置，不对应配置项
+	Sources []string `yaml:"-"`
+	// 认证代理设置的开发者邮箱请求头(如 X-Forwarded-Email)，serve 以此识别开发者身份
+	MeEmailHeader string `yaml:"me_email_header"`
+	// 认证代理的地址(IP 或 CIDR)，只信任来自这些地址的 me_email_header 请求头
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// 开发者的角色(admin、lead、developer)，配置后 serve 的个人数据按角色限制
+	Roles []RoleBinding `yaml:"roles"`
+	// 代码评审数据(PR/MR)的来源，为空时根据远程地址推断
+	Review *ReviewConfig `yaml:"review"`
+	// 估算 AI 节省工时的工作量模型
+	Effort *EffortConfig `yaml:"effort"`
+	// 按提交间隔估算编码时长的参数
+	Duration *DurationConfig `yaml:"duration"`
+	// 每个用户每月的 AI 工具授权或 API 费用明细(CSV)
+	Costs string `yaml:"costs"`
+}
+
+type RosterMember struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+	// 所属团队，用于按团队设定目标
+	Team string `yaml:"team"`
+	// 出勤信息：FTE 比例(兼职，默认 1)、入职和离职日期、休假日期(单日或 2006-01-02~2006-01-05)
+	FTE    float64  `yaml:"fte"`
+	Joined string   `yaml:"joined"`
+	Left   string   `yaml:"left"`
+	Leave  []string `yaml:"leave"`
+	// 不接收每周的个人统计周报
+	DigestOptOut bool `yaml:"digest_opt_out"`
+}
+
+// 用户级配置文件，作为所有仓库的默认值
+func UserConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aistat", "config.yaml")
+}
+
+// 加载配置文件：先读取用户级配置 ~/.aistat/config.yaml 作为默认值，再读取 path 覆盖其中出现的配置项；
+// 未指定路径时读取当前目录下的默认文件，文件不存在时使用空配置
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if user := UserConfigPath(); user != "" {
+		if _, err := cfg.readFile(user, false); err != nil {
+			return nil, err
+		}
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultConfigFile
+	}
+	if _, err := cfg.readFile(path, explicit); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// 读取配置文件，文件中出现的配置项覆盖已有的值；文件不存在且 required 为假时返回 false
+func (c *Config) readFile(path string, required bool) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return false, nil
+		}
+		return false, fmt.Errorf("错误：读取配置文件 '%s' 失败: %v", path, err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return false, fmt.Errorf("错误：解析配置文件 '%s' 失败: %v", path, err)
+	}
+	c.Sources = append(c.Sources, path)
+	return true, nil
+}
+
+// 读取仓库目录下的 .aistat.yaml(与已读取的配置文件相同时跳过)，其中的统计规则覆盖已有配置，
+// 同一份全局配置分析多个仓库时各仓库可以有自己的规则。存储位置、名单、访问控制等其他配置项不受仓库配置影响
+func (c *Config) ApplyRepoConfig(dir string) error {
+	path := filepath.Join(dir, DefaultConfigFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	for _, source := range c.Sources {
+		if loaded, err := os.Stat(source); err == nil && os.SameFile(info, loaded) {
+			return nil
+		}
+	}
+	repo := &Config{}
+	if _, err := repo.readFile(path, true); err != nil {
+		return err
+	}
+	c.Sources = append(c.Sources, path)
+
+	if repo.Profile != "" {
+		c.Profile = repo.Profile
+	}
+	for name, profile := range repo.Profiles {
+		if c.Profiles == nil {
+			c.Profiles = make(map[string]LanguageProfile)
+		}
+		c.Profiles[name] = profile
+	}
+	if repo.IncludeExts != nil {
+		c.IncludeExts = repo.IncludeExts
+	}
+	c.ExcludeExts = append(c.ExcludeExts, repo.ExcludeExts...)
+	c.AllText = c.AllText || repo.AllText
+	c.IgnoreBlankLines = c.IgnoreBlankLines || repo.IgnoreBlankLines
+	c.IgnoreGitattributes = c.IgnoreGitattributes || repo.IgnoreGitattributes
+	c.RecurseSubmodules = c.RecurseSubmodules || repo.RecurseSubmodules
+	c.SubmodulePrefix = c.SubmodulePrefix || repo.SubmodulePrefix
+	c.MassMove = firstNonEmpty(repo.MassMove, c.MassMove)
+	c.AIGPrecedence = firstNonEmpty(repo.AIGPrecedence, c.AIGPrecedence)
+	c.DateKind = firstNonEmpty(repo.DateKind, c.DateKind)
+	c.AIManifest = firstNonEmpty(repo.AIManifest, c.AIManifest)
+	c.BranchCopies = firstNonEmpty(repo.BranchCopies, c.BranchCopies)
+	c.MainBranch = firstNonEmpty(repo.MainBranch, c.MainBranch)
+	c.IgnoreCommits = append(c.IgnoreCommits, repo.IgnoreCommits...)
+	if repo.Grep != nil {
+		c.Grep, c.InvertGrep = repo.Grep, repo.InvertGrep
+	}
+	c.FixPattern = firstNonEmpty(repo.FixPattern, c.FixPattern)
+	if repo.FixSeverities != nil {
+		c.FixSeverities = repo.FixSeverities
+	}
+	return nil
+}
+
+// 使用环境变量覆盖配置文件中的值
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) {
+	overrides := []struct {
+		name   string
+		target *string
+	}{
+		{EnvSince, &c.Since},
+		{EnvUntil, &c.Until},
+		{EnvFormat, &c.Format},
+		{EnvRepo, &c.Repo},
+	}
+	for _, o := range overrides {
+		if value, ok := lookup(o.name); ok && value != "" {
+			*o.target = value
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package stat
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 统计所有分支(--all)时，同一改动在多个分支上的副本(cherry-pick、rebase 后仍保留的旧分支)的处理方式
+const (
+	// 只统计提交时间(committer date)最早的一份，即改动第一次出现的提交(默认)
+	BranchCopiesFirst = "first"
+	// 优先统计主分支上的一份，主分支上没有时取提交时间最早的一份
+	BranchCopiesMain = "main"
+	// 每一份都统计(以往的行为)
+	BranchCopiesKeep = "keep"
+)
+
+// 运行信息中列出的重复提交数
+const branchCopyListLimit = 10
+
+// 检查副本的处理方式是否有效
+func ValidBranchCopies(mode string) bool {
+	switch mode {
+	case BranchCopiesFirst, BranchCopiesMain, BranchCopiesKeep:
+		return true
+	}
+	return false
+}
+
+// BranchCopy 因与另一个提交是同一改动而不计入统计的提交
+type BranchCopy struct {
+	ID      string `json:"id" yaml:"id"`
+	Ref     string `json:"ref" yaml:"ref"`
+	Author  string `json:"author" yaml:"author"`
+	Subject string `json:"subject" yaml:"subject"`
+	// 计入统计的那一份及其所在的引用
+	KeptID  string `json:"kept_id" yaml:"kept_id"`
+	KeptRef string `json:"kept_ref" yaml:"kept_ref"`
+}
+
+// 提交所在的引用、作者时间及提交时间，来自 git log --source
+type commitSource struct {
+	ref       string
+	authored  int64
+	committed int64
+}
+
+// 读取查询范围内各提交最先经由哪个引用到达及其作者时间、提交时间
+func (a *Analyzer) commitSources(q LogQuery) (map[string]commitSource, error) {
+	args := append([]string{"log", "--source", "--format=%H" + fieldSep + "%S" + fieldSep + "%at" + fieldSep + "%ct"}, revisionArgs(q)...)
+	out, err := a.Git.Run(args)
+	if err != nil {
+		return nil, err
+	}
+	sources := make(map[string]commitSource)
+	for _, line := range strings.Split(gitText(out), "\n") {
+		fields := strings.Split(line, fieldSep)
+		if len(fields) != 4 {
+			continue
+		}
+		authored, _ := strconv.ParseInt(fields[2], 10, 64)
+		committed, _ := strconv.ParseInt(fields[3], 10, 64)
+		sources[fields[0]] = commitSource{ref: shortRef(fields[1]), authored: authored, committed: committed}
+	}
+	return sources, nil
+}
+
+// 去掉引用名称的 refs/heads/、refs/remotes/、refs/tags/ 前缀
+func shortRef(ref string) string {
+	for _, prefix := range []string{"refs/heads/", "refs/remotes/", "refs/tags/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return strings.TrimPrefix(ref, prefix)
+		}
+	}
+	return ref
+}
+
+// 主分支：MainBranch 或 origin/HEAD 指向的分支，其次为 main、master，找不到时为空
+func (a *Analyzer) mainBranch() string {
+	if a.MainBranch != "" {
+		return a.MainBranch
+	}
+	if ref := gitOutput(a.Git, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); ref != "" {
+		return ref
+	}
+	for _, name := range []string{"main", "master"} {
+		if gitOutput(a.Git, "rev-parse", "--verify", "--quiet", "refs/heads/"+name) != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// 提交改动内容的标识，与 git patch-id 一样忽略空白、行号及 index 行，
+// cherry-pick、rebase 后内容不变的副本相同。读取失败时返回空
+func (a *Analyzer) patchID(id string) string {
+	out, err := a.Git.Run([]string{"diff-tree", "-p", "--root", "--no-commit-id", "--no-color", "--no-ext-diff", id})
+	if err != nil {
+		return ""
+	}
+	h := sha1.New()
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "index ") || strings.HasPrefix(line, "@@") {
+			continue
+		}
+		h.Write([]byte(strings.Join(strings.Fields(line), "")))
+		h.Write([]byte{'\n'})
+	}
+	if scanner.Err() != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 统计所有分支时记录各提交所在的引用，同一改动(作者邮箱、作者时间和标题相同，且改动内容相同)有多份时只保留一份。
+// 按提交范围统计或处理方式为 keep 时不处理
+func (a *Analyzer) dedupeBranchCopies(q LogQuery, commits []CommitStats) ([]CommitStats, error) {
+	a.copies = nil
+	if q.RevRange != "" || a.BranchCopies == BranchCopiesKeep || len(commits) < 2 {
+		return commits, nil
+	}
+	sources, err := a.commitSources(q)
+	if err != nil {
+		return commits, err
+	}
+	groups := make(map[string][]int)
+	for i := range commits {
+		c := &commits[i]
+		src := sources[c.ID]
+		c.Ref = src.ref
+		// 按提交时间统计时 c.Time 是各副本各自的提交时间，以作者时间作为判断依据
+		key := c.Email + fieldSep + strconv.FormatInt(src.authored, 10) + fieldSep + c.Subject
+		groups[key] = append(groups[key], i)
+	}
+	// 邮箱、作者时间和标题相同但改动内容不同的是不同的提交(如同一秒内用相同标题提交的两次改动)，
+	// 按改动内容再分组；无法读取改动内容的提交不与其他提交合并
+	copies := make(map[string][]int)
+	for key, idx := range groups {
+		if len(idx) < 2 {
+			continue
+		}
+		for _, i := range idx {
+			patch := a.patchID(commits[i].ID)
+			if patch == "" {
+				patch = commits[i].ID
+			}
+			copies[key+fieldSep+patch] = append(copies[key+fieldSep+patch], i)
+		}
+	}
+
+	main := ""
+	if a.BranchCopies == BranchCopiesMain {
+		main = a.mainBranch()
+	}
+	drop := make(map[int]bool)
+	for _, idx := range copies {
+		if len(idx) < 2 {
+			continue
+		}
+		// 提交时间最早的排在前面，相同时按哈希排序使结果稳定
+		sort.Slice(idx, func(i, j int) bool {
+			ci, cj := sources[commits[idx[i]].ID].committed, sources[commits[idx[j]].ID].committed
+			if ci != cj {
+				return ci < cj
+			}
+			return commits[idx[i]].ID < commits[idx[j]].ID
+		})
+		kept := idx[0]
+		if main != "" {
+			for _, i := range idx {
+				if _, err := a.Git.Run([]string{"merge-base", "--is-ancestor", commits[i].ID, main}); err == nil {
+					kept = i
+					break
+				}
+			}
+		}
+		for _, i := range idx {
+			if i == kept {
+				continue
+			}
+			drop[i] = true
+			c := &commits[i]
+			a.copies = append(a.copies, BranchCopy{ID: c.ID, Ref: c.Ref, Author: c.Author, Subject: c.Subject, KeptID: commits[kept].ID, KeptRef: commits[kept].Ref})
+		}
+	}
+	if len(drop) == 0 {
+		return commits, nil
+	}
+	sort.Slice(a.copies, func(i, j int) bool { return a.copies[i].ID < a.copies[j].ID })
+	out := commits[:0]
+	for i, c := range commits {
+		if !drop[i] {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// 运行信息中不计入统计的副本的说明
+func branchCopyLines(copies []BranchCopy, mode string) []string {
+	if len(copies) == 0 {
+		return nil
+	}
+	rule := "提交时间最早的一份"
+	if mode == BranchCopiesMain {
+		rule = "主分支上的一份"
+	}
+	lines := []string{fmt.Sprintf("跨分支重复的提交: %d 次提交与其他分支上的提交是同一改动，只统计%s (可通过 branch_copies 调整)", len(copies), rule)}
+	for i, c := range copies {
+		if i == branchCopyListLimit {
+			lines = append(lines, fmt.Sprintf("  ... 另有 %d 次提交", len(copies)-branchCopyListLimit))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %.10s (%s) %s <%s>，已按 %.10s (%s) 统计", c.ID, c.Ref, c.Subject, c.Author, c.KeptID, c.KeptRef))
+	}
+	return lines
+}
@@ -0,0 +1,55 @@
+package stat
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// 按提交返回不同改动内容的 git：log 输出提交来源，diff-tree 输出 patches 中该提交的改动
+type copiesGit struct {
+	sources string
+	patches map[string]string
+}
+
+func (g *copiesGit) Run(args []string) (io.Reader, error) {
+	if args[0] == "diff-tree" {
+		return strings.NewReader(g.patches[args[len(args)-1]]), nil
+	}
+	return strings.NewReader(g.sources), nil
+}
+
+func TestDedupeBranchCopiesComparesPatches(t *testing.T) {
+	source := func(id, ref, committed string) string {
+		return strings.Join([]string{id, "refs/heads/" + ref, "1714730400", committed}, fieldSep) + "\n"
+	}
+	patch := func(line string) string {
+		return "diff --git a/b.go b/b.go\nindex 0000000..1111111 100644\n--- a/b.go\n+++ b/b.go\n@@ -1,0 +2,1 @@\n+" + line + "\n"
+	}
+	git := &copiesGit{
+		sources: source("c1", "dev", "1714730400") + source("c2", "main", "1714816800") + source("c3", "other", "1714903200"),
+		patches: map[string]string{
+			// c2 是 c1 cherry-pick 到主分支后的副本，行号和空白不同
+			"c1": patch("func b() {}"),
+			"c2": strings.Replace(patch("func  b() {}"), "@@ -1,0 +2,1 @@", "@@ -7,0 +8,1 @@", 1),
+			// c3 的邮箱、作者时间和标题都相同，但改动不同
+			"c3": patch("func c() {}"),
+		},
+	}
+	a := NewAnalyzer(git)
+	a.BranchCopies = BranchCopiesFirst
+	var commits []CommitStats
+	for _, id := range []string{"c1", "c2", "c3"} {
+		commits = append(commits, CommitStats{ID: id, Email: "dev@example.com", Subject: "feat: b"})
+	}
+	kept, err := a.dedupeBranchCopies(LogQuery{}, commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[0].ID != "c1" || kept[1].ID != "c3" {
+		t.Errorf("保留的提交 = %+v", kept)
+	}
+	if len(a.copies) != 1 || a.copies[0].ID != "c2" || a.copies[0].KeptID != "c1" {
+		t.Errorf("副本 = %+v", a.copies)
+	}
+}
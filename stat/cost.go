@@ -0,0 +1,213 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 费用明细中各字段可能的列名(统一转为小写下划线形式后比较)
+var costColumns = map[string][]string{
+	"email":    {"email", "user_email", "user", "login"},
+	"month":    {"month", "period", "billing_month"},
+	"cost":     {"cost", "amount", "total", "cost_usd"},
+	"currency": {"currency"},
+}
+
+// CostRecord AI 工具的授权或 API 费用，每条为某个用户某月的费用
+type CostRecord struct {
+	Email    string
+	Month    string
+	Cost     float64
+	Currency string
+}
+
+// 读取费用明细 CSV，首行为表头，需要 email、month(2006-01)、cost 列，currency 列可选
+func LoadCosts(path string) ([]CostRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取费用明细 '%s' 失败: %v", path, err)
+	}
+	rows, err := usageCSVRows(data)
+	if err != nil {
+		return nil, fmt.Errorf("错误：解析费用明细 '%s' 失败: %v", path, err)
+	}
+	records := make([]CostRecord, 0, len(rows))
+	for i, row := range rows {
+		field := func(name string) string {
+			for _, column := range costColumns[name] {
+				if v, ok := row[column]; ok {
+					return strings.TrimSpace(v)
+				}
+			}
+			return ""
+		}
+		r := CostRecord{Email: strings.ToLower(field("email")), Month: field("month"), Currency: strings.ToUpper(field("currency"))}
+		if r.Email == "" {
+			return nil, fmt.Errorf("错误：费用明细 '%s' 第 %d 条记录缺少用户邮箱", path, i+1)
+		}
+		if _, err := time.Parse("2006-01", r.Month); err != nil {
+			return nil, fmt.Errorf("错误：费用明细 '%s' 第 %d 条记录的月份 '%s' 格式不正确，请使用 2006-01 格式", path, i+1, r.Month)
+		}
+		if r.Cost, err = strconv.ParseFloat(field("cost"), 64); err != nil || r.Cost < 0 {
+			return nil, fmt.Errorf("错误：费用明细 '%s' 第 %d 条记录的费用 '%s' 不是有效的金额", path, i+1, field("cost"))
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// UserCost 一个用户在统计周期内分摊的费用及产出
+type UserCost struct {
+	Email   string  `json:"email" yaml:"email"`
+	Name    string  `json:"name,omitempty" yaml:"name,omitempty"`
+	Cost    float64 `json:"cost" yaml:"cost"`
+	AILines int     `json:"ai_lines" yaml:"ai_lines"`
+	AIFixes int     `json:"ai_fixes" yaml:"ai_fixes"`
+	// 每行 AI 代码、每次 AI 参与修复的费用，没有产出时为 0
+	PerAILine float64 `json:"per_ai_line" yaml:"per_ai_line"`
+	PerAIFix  float64 `json:"per_ai_fix" yaml:"per_ai_fix"`
+}
+
+// CostReport 统计周期内的 AI 工具费用核算
+type CostReport struct {
+	Currency string `json:"currency,omitempty" yaml:"currency,omitempty"`
+	// 费用按统计周期与各月重叠的天数分摊
+	From      string     `json:"from" yaml:"from"`
+	To        string     `json:"to" yaml:"to"`
+	Cost      float64    `json:"cost" yaml:"cost"`
+	AILines   int        `json:"ai_lines" yaml:"ai_lines"`
+	AIFixes   int        `json:"ai_fixes" yaml:"ai_fixes"`
+	PerAILine float64    `json:"per_ai_line" yaml:"per_ai_line"`
+	PerAIFix  float64    `json:"per_ai_fix" yaml:"per_ai_fix"`
+	Users     []UserCost `json:"users" yaml:"users"`
+}
+
+// 按统计周期分摊各月费用，计算每行 AI 代码和每次 AI 参与修复的费用；
+// 按提交范围统计时以最早和最晚的提交日期作为周期
+func AccountCosts(records []CostRecord, commits []CommitStats, since, until string) (*CostReport, error) {
+	from, errFrom := time.ParseInLocation(DateLayout, PeriodDay(since), time.Local)
+	to, errTo := time.ParseInLocation(DateLayout, PeriodDay(until), time.Local)
+	if errFrom != nil || errTo != nil {
+		first, last := "", ""
+		for _, c := range commits {
+			if day := strings.SplitN(c.Time, " ", 2)[0]; first == "" || day < first {
+				first = day
+			}
+			if day := strings.SplitN(c.Time, " ", 2)[0]; day > last {
+				last = day
+			}
+		}
+		from, errFrom = time.ParseInLocation(DateLayout, first, time.Local)
+		to, errTo = time.ParseInLocation(DateLayout, last, time.Local)
+		if errFrom != nil || errTo != nil {
+			return nil, fmt.Errorf("错误：没有统计周期，无法分摊费用")
+		}
+	}
+	r := &CostReport{From: from.Format(DateLayout), To: to.Format(DateLayout), Users: []UserCost{}}
+
+	users := make(map[string]*UserCost)
+	user := func(email string) *UserCost {
+		u, ok := users[email]
+		if !ok {
+			u = &UserCost{Email: email}
+			users[email] = u
+		}
+		return u
+	}
+	for _, rec := range records {
+		if r.Currency == "" {
+			r.Currency = rec.Currency
+		} else if rec.Currency != "" && rec.Currency != r.Currency {
+			return nil, fmt.Errorf("错误：费用明细中有多种货币 (%s、%s)，请先换算为同一货币", r.Currency, rec.Currency)
+		}
+		month, _ := time.ParseInLocation("2006-01", rec.Month, time.Local)
+		share := monthOverlap(month, from, to)
+		if share == 0 {
+			continue
+		}
+		u := user(rec.Email)
+		u.Cost += rec.Cost * share
+	}
+	for i := range commits {
+		c := &commits[i]
+		ai, fix := c.AIAddedLines(), c.IsFix && c.AIGRatio > 0
+		r.AILines += ai
+		if fix {
+			r.AIFixes++
+		}
+		u, ok := users[strings.ToLower(c.Email)]
+		if !ok {
+			continue
+		}
+		u.Name = c.Author
+		u.AILines += ai
+		if fix {
+			u.AIFixes++
+		}
+	}
+
+	for _, u := range users {
+		r.Cost += u.Cost
+		u.PerAILine, u.PerAIFix = costPer(u.Cost, u.AILines), costPer(u.Cost, u.AIFixes)
+		r.Users = append(r.Users, *u)
+	}
+	r.PerAILine, r.PerAIFix = costPer(r.Cost, r.AILines), costPer(r.Cost, r.AIFixes)
+	sort.Slice(r.Users, func(i, j int) bool { return r.Users[i].Email < r.Users[j].Email })
+	return r, nil
+}
+
+// 统计周期与某月重叠的天数占该月天数的比例
+func monthOverlap(month, from, to time.Time) float64 {
+	end := month.AddDate(0, 1, -1)
+	start := month
+	if from.After(start) {
+		start = from
+	}
+	if to.Before(end) {
+		end = to
+	}
+	if end.Before(start) {
+		return 0
+	}
+	days := int(end.Sub(start).Hours()/24+0.5) + 1
+	return float64(days) / float64(month.AddDate(0, 1, -1).Day())
+}
+
+func costPer(cost float64, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return cost / float64(n)
+}
+
+// 打印费用核算
+func PrintCosts(w io.Writer, r *CostReport, nf NumberFormat) {
+	currency := ""
+	if r.Currency != "" {
+		currency = " " + r.Currency
+	}
+	fmt.Fprintf(w, "\n  AI 工具费用 (%s ~ %s，按天数分摊):\n", r.From, r.To)
+	fmt.Fprintf(w, "    合计: %s%s，AI 添加 %s，AI 参与修复 %s 次\n", nf.Float(r.Cost, 2), currency, nf.Lines(r.AILines), nf.Int(r.AIFixes))
+	fmt.Fprintf(w, "    每行 AI 代码: %s，每次 AI 参与修复: %s\n", formatCost(r.PerAILine, r.AILines, nf, currency), formatCost(r.PerAIFix, r.AIFixes, nf, currency))
+	for _, u := range r.Users {
+		name := u.Email
+		if u.Name != "" {
+			name = u.Name + " <" + u.Email + ">"
+		}
+		fmt.Fprintf(w, "    %s: %s%s，每行 AI 代码 %s，每次 AI 参与修复 %s\n", name, nf.Float(u.Cost, 2), currency,
+			formatCost(u.PerAILine, u.AILines, nf, currency), formatCost(u.PerAIFix, u.AIFixes, nf, currency))
+	}
+}
+
+// 没有产出时显示为 -
+func formatCost(v float64, n int, nf NumberFormat, currency string) string {
+	if n == 0 {
+		return "-"
+	}
+	return nf.Float(v, 4) + currency
+}
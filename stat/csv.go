@@ -0,0 +1,130 @@
+package stat
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// 以 CSV 格式输出每个开发者及汇总行的统计
+// 运行信息以 # 开头的注释行写在表头之前
+func WriteCSV(w io.Writer, report *Report) error {
+	if report.Meta != nil {
+		for _, line := range report.Meta.Lines() {
+			if _, err := io.WriteString(w, "# "+line+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	header := []string{
+		"since", "until", "name", "email", "member_count", "commit_count",
+		"total_added_lines", "total_deleted_lines",
+		"total_ai_added_lines", "ai_added_ratio",
+		"total_ai_deleted_lines", "ai_deleted_ratio",
+		"fix_count", "fix_and_aig_count", "ai_fix_ratio", "rev_range",
+		"tool_accepted_lines", "tool_accepted_ratio", "usage_discrepancy", "estimated_commits",
+		"heuristic_ai_lines", "heuristic_ratio",
+		"ai_fix_ratio_ci_low", "ai_fix_ratio_ci_high",
+		"rolling_days", "rolling_added_per_period", "rolling_ai_added_ratio",
+		"ai_tagged_commits", "no_ai_tagged_commits", "untagged_commits",
+		"na_commits", "exempt_commits", "ai_commit_avg_ratio", "bugs_introduced",
+		"active_days", "added_per_active_day", "ai_added_per_active_day",
+		"files_created", "files_deleted", "files_modified", "ai_files_created",
+		"new_file_added_lines", "new_file_ai_added_lines", "new_file_ai_ratio",
+		"existing_file_added_lines", "existing_file_ai_added_lines", "existing_file_ai_ratio",
+		"message_avg_length", "conventional_ratio", "issue_ref_ratio",
+		"ai_message_avg_length", "ai_conventional_ratio", "ai_issue_ref_ratio",
+		"other_message_avg_length", "other_conventional_ratio", "other_issue_ref_ratio",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	// 样本不足的比例留空
+	var minSample, minLines int
+	if report.Meta != nil {
+		minSample, minLines = report.Meta.Filters.MinSample, report.Meta.Filters.MinSampleLines
+	}
+	ratio := func(part, total, min int) string {
+		if v, ok := SampleRatio(part, total, min); ok || total == 0 {
+			return formatRatio(v)
+		}
+		return ""
+	}
+
+	rows := append(append(append([]*AuthorStats{}, report.Authors...), report.Groups...), report.Cohorts...)
+	for _, s := range rows {
+		record := []string{
+			report.Since, report.Until, s.Name, s.Email,
+			strconv.Itoa(s.MemberCount), strconv.Itoa(s.CommitCount),
+			strconv.Itoa(s.TotalAddedLines), strconv.Itoa(s.TotalDeletedLines),
+			strconv.Itoa(s.TotalAIAddedLines), ratio(s.TotalAIAddedLines, s.RatioAddedLines(), minLines),
+			strconv.Itoa(s.TotalAIDeletedLines), ratio(s.TotalAIDeletedLines, s.RatioDeletedLines(), minLines),
+			strconv.Itoa(s.FixCount), strconv.Itoa(s.FixAndAIGCount), ratio(s.FixAndAIGCount, s.RatioFixCount(), minSample),
+			report.RevRange,
+		}
+		if s.ToolUsage {
+			record = append(record, strconv.Itoa(s.ToolAcceptedLines), formatRatio(s.ToolRatio()), strconv.FormatBool(s.UsageDiscrepancy))
+		} else {
+			record = append(record, "", "", "")
+		}
+		record = append(record, strconv.Itoa(s.EstimatedCommits))
+		if report.Meta != nil && report.Meta.Heuristic {
+			record = append(record, strconv.Itoa(s.HeuristicAILines), formatRatio(s.HeuristicRatio()))
+		} else {
+			record = append(record, "", "")
+		}
+		if s.RatioFixCount() > 0 {
+			lo, hi := WilsonInterval(s.FixAndAIGCount, s.RatioFixCount())
+			record = append(record, formatRatio(lo), formatRatio(hi))
+		} else {
+			record = append(record, "", "")
+		}
+		if r := s.Rolling; r != nil {
+			record = append(record, strconv.Itoa(r.Days), strconv.FormatFloat(r.AddedPerPeriod, 'f', 1, 64), ratio(r.TotalAIAddedLines, r.RatioAddedLines(), minLines))
+		} else {
+			record = append(record, "", "", "")
+		}
+		record = append(record, strconv.Itoa(s.AITaggedCommits), strconv.Itoa(s.NoAITaggedCommits), strconv.Itoa(s.UntaggedCommits()),
+			strconv.Itoa(s.NACommits), strconv.Itoa(s.ExemptCommits))
+		if n := s.RatioCommits(); n > 0 && n >= minSample {
+			record = append(record, formatRatio(s.CommitAvgRatio()))
+		} else {
+			record = append(record, "")
+		}
+		if report.Meta != nil && report.Meta.SZZ {
+			record = append(record, strconv.Itoa(s.BugsIntroduced))
+		} else {
+			record = append(record, "")
+		}
+		if s.ActiveDays > 0 {
+			record = append(record, strconv.FormatFloat(s.ActiveDays, 'f', -1, 64),
+				strconv.FormatFloat(s.PerActiveDay(s.TotalAddedLines), 'f', 1, 64), strconv.FormatFloat(s.PerActiveDay(s.TotalAIAddedLines), 'f', 1, 64))
+		} else {
+			record = append(record, "", "", "")
+		}
+		record = append(record, strconv.Itoa(s.FilesCreated), strconv.Itoa(s.FilesDeleted), strconv.Itoa(s.FilesModified), strconv.Itoa(s.AIFilesCreated))
+		record = append(record, strconv.Itoa(s.NewFileAddedLines), strconv.Itoa(s.NewFileAIAddedLines), ratio(s.NewFileAIAddedLines, s.NewFileAddedLines, minLines),
+			strconv.Itoa(s.ExistingFileAddedLines), strconv.Itoa(s.ExistingFileAIAddedLines), ratio(s.ExistingFileAIAddedLines, s.ExistingFileAddedLines, minLines))
+		for _, q := range []MessageQuality{s.AllMessageQuality(), s.MessageQuality(true), s.MessageQuality(false)} {
+			if q.Commits == 0 || q.Commits < minSample {
+				record = append(record, "", "", "")
+				continue
+			}
+			record = append(record, strconv.FormatFloat(q.AvgLength, 'f', 1, 64), formatRatio(q.ConventionalRatio), formatRatio(q.IssueRefRatio))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// 百分比保留两位小数
+func formatRatio(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
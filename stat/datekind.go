@@ -0,0 +1,138 @@
+package stat
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 确定提交属于哪个统计周期、报告中显示的提交时间
+const (
+	// 提交时间(committer date)，与 git log --since/--until 的筛选一致(默认)；rebase、cherry-pick 会改变提交时间
+	DateKindCommitter = "committer"
+	// 作者时间(author date)，即最初编写提交的时间，不受 rebase 影响
+	DateKindAuthor = "author"
+)
+
+// 检查时间口径是否有效
+func ValidDateKind(kind string) bool {
+	return kind == DateKindCommitter || kind == DateKindAuthor
+}
+
+// 报告中的时间口径名称
+func dateKindLabel(kind string) string {
+	if kind == DateKindAuthor {
+		return "作者时间 (author date)"
+	}
+	return "提交时间 (committer date)"
+}
+
+// git log 的输出格式，提交时间按时间口径取 %ad 或 %cd
+func logFormat(kind string) string {
+	if kind == DateKindAuthor {
+		return prettyFormat
+	}
+	return strings.Replace(prettyFormat, "%ad", "%cd", 1)
+}
+
+// 按作者时间统计时的查询范围。git 只能按提交时间筛选，而作者时间不晚于提交时间，
+// 因此先不限结束时间列出提交时间晚于起始日期的提交，找出作者时间在统计周期内的提交，
+// 再把 git log 的结束时间放宽到其中最晚的提交时间；返回放宽后的查询及应保留的提交。
+// 按提交范围统计且给出了日期时由 authorDateRange 处理。
+// 按提交范围统计但没有日期或日期无法解析时原样返回，保留的提交为 nil
+func (a *Analyzer) authorDateQuery(q LogQuery) (LogQuery, map[string]bool, error) {
+	if q.DateKind != DateKindAuthor || q.RevRange != "" && q.Since == "" && q.Until == "" {
+		return q, nil, nil
+	}
+	if q.RevRange != "" {
+		return a.authorDateRange(q)
+	}
+	since, err1 := time.ParseInLocation(DateTimeLayout, gitDate(q.Since, false), time.Local)
+	until, err2 := time.ParseInLocation(DateTimeLayout, gitDate(q.Until, true), time.Local)
+	if err1 != nil || err2 != nil {
+		a.warnf("无法解析统计周期 %s ~ %s，按提交时间筛选提交", q.Since, q.Until)
+		return q, nil, nil
+	}
+
+	open := q
+	open.Until = ""
+	args := append([]string{"log", "--format=%H" + fieldSep + "%at" + fieldSep + "%ct"}, revisionArgs(open)...)
+	out, err := a.Git.Run(args)
+	if err != nil {
+		return q, nil, err
+	}
+	ids := make(map[string]bool)
+	latest := since.Unix()
+	for _, line := range strings.Split(gitText(out), "\n") {
+		fields := strings.Split(line, fieldSep)
+		if len(fields) != 3 {
+			continue
+		}
+		authored, _ := strconv.ParseInt(fields[1], 10, 64)
+		committed, _ := strconv.ParseInt(fields[2], 10, 64)
+		if authored < since.Unix() || authored > until.Unix() {
+			continue
+		}
+		ids[fields[0]] = true
+		if committed > latest {
+			latest = committed
+		}
+	}
+	q.Until = time.Unix(latest, 0).In(time.Local).Format(DateTimeLayout)
+	return q, ids, nil
+}
+
+// 按提交范围统计且给出了日期(如推送 webhook 的增量统计)时，不按提交时间筛选，
+// 列出范围内的全部提交，只保留作者时间在日期范围内的，与按日期统计同一周期的结果一致；
+// 返回去掉日期的查询及应保留的提交
+func (a *Analyzer) authorDateRange(q LogQuery) (LogQuery, map[string]bool, error) {
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+	if q.Since != "" {
+		since, err := time.ParseInLocation(DateTimeLayout, gitDate(q.Since, false), time.Local)
+		if err != nil {
+			a.warnf("无法解析起始日期 %s，按提交时间筛选提交", q.Since)
+			return q, nil, nil
+		}
+		from = since.Unix()
+	}
+	if q.Until != "" {
+		until, err := time.ParseInLocation(DateTimeLayout, gitDate(q.Until, true), time.Local)
+		if err != nil {
+			a.warnf("无法解析结束日期 %s，按提交时间筛选提交", q.Until)
+			return q, nil, nil
+		}
+		to = until.Unix()
+	}
+
+	q.Since, q.Until = "", ""
+	out, err := a.Git.Run(append([]string{"log", "--format=%H" + fieldSep + "%at"}, revisionArgs(q)...))
+	if err != nil {
+		return q, nil, err
+	}
+	ids := make(map[string]bool)
+	for _, line := range strings.Split(gitText(out), "\n") {
+		id, at, ok := strings.Cut(line, fieldSep)
+		if !ok {
+			continue
+		}
+		if authored, _ := strconv.ParseInt(at, 10, 64); authored >= from && authored <= to {
+			ids[id] = true
+		}
+	}
+	return q, ids, nil
+}
+
+// 只保留 ids 中的提交，ids 为 nil 时不过滤
+func keepIDs(commits []CommitStats, ids map[string]bool) []CommitStats {
+	if ids == nil {
+		return commits
+	}
+	kept := commits[:0]
+	for _, c := range commits {
+		if ids[c.ID] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
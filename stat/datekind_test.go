@@ -0,0 +1,63 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 记录 git log 参数的 git：列出作者时间的查询返回 authored，其余返回 log
+type dateKindGit struct {
+	authored, log string
+	calls         [][]string
+}
+
+func (g *dateKindGit) Run(args []string) (io.Reader, error) {
+	g.calls = append(g.calls, args)
+	if strings.HasPrefix(args[1], "--format=%H"+fieldSep+"%at") {
+		return strings.NewReader(g.authored), nil
+	}
+	return strings.NewReader(g.log), nil
+}
+
+func TestAuthorDateRevRangeWithDates(t *testing.T) {
+	const (
+		inPeriod = "1111111111111111111111111111111111111111"
+		// rebase 后提交时间在本期，作者时间在上一期
+		rebased = "2222222222222222222222222222222222222222"
+	)
+	at := func(date string) int64 {
+		ts, err := time.ParseInLocation(DateTimeLayout, date, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts.Unix()
+	}
+	git := &dateKindGit{
+		authored: fmt.Sprintf("%s%s%d\n%s%s%d\n", inPeriod, fieldSep, at("2024-05-03 10:00:00"), rebased, fieldSep, at("2024-04-20 10:00:00")),
+		log: logEntry(inPeriod, "Dev", "dev@example.com", "feat: a", "", "1\t0\ta.go") +
+			logEntry(rebased, "Dev", "dev@example.com", "feat: b", "", "1\t0\tb.go"),
+	}
+	a := NewAnalyzer(git)
+	a.IncludeExts = []string{".go"}
+	commits, err := a.Analyze(LogQuery{Since: "2024-05-01", Until: "2024-05-15", RevRange: "aaaa..bbbb", DateKind: DateKindAuthor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].ID != inPeriod {
+		t.Errorf("commits = %+v, want 只有 %s", commits, inPeriod)
+	}
+	// 读取提交时不再按提交时间筛选
+	for _, args := range git.calls {
+		if args[0] != "log" {
+			continue
+		}
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "--since") || strings.HasPrefix(arg, "--until") {
+				t.Errorf("git %s 按提交时间筛选", strings.Join(args, " "))
+			}
+		}
+	}
+}
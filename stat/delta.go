@@ -0,0 +1,102 @@
+package stat
+
+import (
+	"io"
+	"math"
+	"os"
+)
+
+// 终端颜色的 ANSI 转义序列
+const (
+	ansiGreen = "\033[32m"
+	ansiRed   = "\033[31m"
+	ansiReset = "\033[0m"
+)
+
+// 输出到终端且没有设置 NO_COLOR 环境变量时使用颜色
+func ColorEnabled(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Delta 文本报告中与上一统计周期相比的变化标注，如 ▲120、▼3.50 个百分点
+// 为 nil 时不标注，各方法返回空字符串
+type Delta struct {
+	Color  bool
+	Format NumberFormat
+}
+
+// 次数的变化，没有变化时为空
+func (d *Delta) Count(prev, cur int) string {
+	if d == nil || prev == cur {
+		return ""
+	}
+	return d.mark(float64(cur-prev), d.Format.Int(absInt(cur-prev)))
+}
+
+// 行数的变化，单位与报告中的行数一致
+func (d *Delta) Lines(prev, cur int) string {
+	if d == nil || prev == cur {
+		return ""
+	}
+	return d.mark(float64(cur-prev), d.Format.LineValue(absInt(cur-prev)))
+}
+
+// 比例的变化(百分点)，任一期样本不足时不标注
+func (d *Delta) Ratio(prevPart, prevTotal, part, total, minSample int) string {
+	if d == nil {
+		return ""
+	}
+	prev, ok := SampleRatio(prevPart, prevTotal, minSample)
+	if !ok {
+		return ""
+	}
+	cur, ok := SampleRatio(part, total, minSample)
+	if !ok {
+		return ""
+	}
+	return d.Points(prev, cur)
+}
+
+// 平均值(百分比)的变化，任一期样本不足时不标注
+func (d *Delta) Mean(prevMean float64, prevN int, mean float64, n, minSample int) string {
+	if prevN == 0 || prevN < minSample || n == 0 || n < minSample {
+		return ""
+	}
+	return d.Points(prevMean, mean)
+}
+
+// 百分比数值的变化(百分点)，变化不足 0.01 个百分点时为空
+func (d *Delta) Points(prev, cur float64) string {
+	diff := cur - prev
+	if d == nil || math.Abs(diff) < 0.005 {
+		return ""
+	}
+	return d.mark(diff, d.Format.Float(math.Abs(diff), 2)+" 个百分点")
+}
+
+// 加上方向符号和颜色，增加为绿色 ▲，减少为红色 ▼
+func (d *Delta) mark(diff float64, value string) string {
+	arrow, color := "▲", ansiGreen
+	if diff < 0 {
+		arrow, color = "▼", ansiRed
+	}
+	if !d.Color {
+		return " " + arrow + value
+	}
+	return " " + color + arrow + value + ansiReset
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
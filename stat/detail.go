@@ -0,0 +1,98 @@
+package stat
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CommitDetail 开发者明细导出中的一个提交，文件只列出计入统计的文件
+type CommitDetail struct {
+	ID        string   `json:"id"`
+	Time      string   `json:"time"`
+	Subject   string   `json:"subject"`
+	Files     []string `json:"files"`
+	Added     int      `json:"added"`
+	Deleted   int      `json:"deleted"`
+	AIGRatio  float64  `json:"aig_ratio"`
+	AIGPolicy string   `json:"aig_policy,omitempty"`
+	AIAdded   int      `json:"ai_added"`
+	AIDeleted int      `json:"ai_deleted"`
+	IsFix     bool     `json:"is_fix"`
+	// 修复提交的严重级别
+	FixSeverity string `json:"fix_severity,omitempty"`
+}
+
+// 生成逐提交明细
+func CommitDetails(commits []CommitStats) []CommitDetail {
+	details := make([]CommitDetail, 0, len(commits))
+	for i := range commits {
+		c := &commits[i]
+		files := []string{}
+		for _, f := range c.Files {
+			if !f.Skipped && !f.Moved {
+				files = append(files, f.Path)
+			}
+		}
+		details = append(details, CommitDetail{
+			ID: c.ID, Time: c.Time, Subject: c.Subject, Files: files,
+			Added: c.AddedLines, Deleted: c.DeletedLines,
+			AIGRatio: c.AIGRatio, AIGPolicy: c.AIGPolicy, AIAdded: c.AIAddedLines(), AIDeleted: c.AIDeletedLines(),
+			IsFix: c.IsFix, FixSeverity: c.FixSeverity,
+		})
+	}
+	return details
+}
+
+// 以 CSV 格式输出逐提交明细，最后一行为合计；运行信息以 # 开头的注释行写在表头之前
+func WriteDetailCSV(w io.Writer, report *Report) error {
+	if report.Meta != nil {
+		for _, line := range report.Meta.Lines() {
+			if _, err := io.WriteString(w, "# "+line+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	header := []string{
+		"commit", "time", "author", "email", "subject", "file_count", "files",
+		"added_lines", "deleted_lines", "aig_ratio", "aig_policy",
+		"ai_added_lines", "ai_deleted_lines", "is_fix", "fix_severity",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	var total CommitDetail
+	fixes := 0
+	for i, d := range CommitDetails(report.Commits) {
+		c := &report.Commits[i]
+		record := []string{
+			d.ID, d.Time, c.Author, c.Email, d.Subject, strconv.Itoa(len(d.Files)), strings.Join(d.Files, ";"),
+			strconv.Itoa(d.Added), strconv.Itoa(d.Deleted), strconv.FormatFloat(d.AIGRatio, 'f', -1, 64), d.AIGPolicy,
+			strconv.Itoa(d.AIAdded), strconv.Itoa(d.AIDeleted), strconv.FormatBool(d.IsFix), d.FixSeverity,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		total.Added += d.Added
+		total.Deleted += d.Deleted
+		total.AIAdded += d.AIAdded
+		total.AIDeleted += d.AIDeleted
+		if d.IsFix {
+			fixes++
+		}
+	}
+	// 合计行：提交列为 total，修复列为修复提交数
+	record := []string{
+		"total", "", "", "", "", "", "",
+		strconv.Itoa(total.Added), strconv.Itoa(total.Deleted), "", "",
+		strconv.Itoa(total.AIAdded), strconv.Itoa(total.AIDeleted), strconv.Itoa(fixes), "",
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,259 @@
+package stat
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 发送个人周报的 SMTP 密码从该环境变量读取
+const EnvSMTPPassword = "AISTAT_SMTP_PASSWORD"
+
+// 个人周报默认的发送时间(周一 9 点)及趋势图的统计周期数
+const (
+	DefaultDigestHour    = 9
+	DefaultDigestPeriods = 6
+)
+
+// 趋势图中 100% 对应的字符数
+const digestBarWidth = 20
+
+// DigestConfig 每周发送给名单中开发者本人的统计邮件或 IM 消息
+type DigestConfig struct {
+	// 发送的星期(monday ~ sunday)和小时，默认周一 9 点
+	Weekday string `yaml:"weekday"`
+	Hour    *int   `yaml:"hour"`
+	// 趋势图包含的统计周期数(含当前周期)，默认 6
+	Periods int         `yaml:"periods"`
+	SMTP    *SMTPConfig `yaml:"smtp"`
+	// IM 机器人的中转地址，以 JSON POST 发送 email、name、subject、text
+	IMURL string `yaml:"im_url"`
+}
+
+// SMTPConfig 发送邮件的 SMTP 服务器，密码从环境变量 AISTAT_SMTP_PASSWORD 读取
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	From     string `yaml:"from"`
+	Username string `yaml:"username"`
+}
+
+// 检查周报配置
+func (c *DigestConfig) Validate() error {
+	if c.SMTP == nil && c.IMURL == "" {
+		return errors.New("错误：weekly_digest 需要配置 smtp 或 im_url")
+	}
+	if c.SMTP != nil && (c.SMTP.Host == "" || c.SMTP.From == "") {
+		return errors.New("错误：weekly_digest.smtp 需要配置 host 和 from")
+	}
+	if _, err := c.weekday(); err != nil {
+		return err
+	}
+	if c.Hour != nil && (*c.Hour < 0 || *c.Hour > 23) {
+		return fmt.Errorf("错误：weekly_digest.hour 必须是 0~23，当前为 %d", *c.Hour)
+	}
+	if c.Periods < 0 {
+		return errors.New("错误：weekly_digest.periods 必须大于 0")
+	}
+	return nil
+}
+
+func (c *DigestConfig) weekday() (time.Weekday, error) {
+	if c.Weekday == "" {
+		return time.Monday, nil
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(c.Weekday, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("错误：weekly_digest.weekday '%s' 不正确，应为 monday ~ sunday", c.Weekday)
+}
+
+// 趋势图的统计周期数
+func (c *DigestConfig) periods() int {
+	if c.Periods > 0 {
+		return c.Periods
+	}
+	return DefaultDigestPeriods
+}
+
+// now 之后的下一个发送时间
+func (c *DigestConfig) Next(now time.Time) time.Time {
+	weekday, _ := c.weekday()
+	hour := DefaultDigestHour
+	if c.Hour != nil {
+		hour = *c.Hour
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	next = next.AddDate(0, 0, (int(weekday)-int(next.Weekday())+7)%7)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// 统计周期 [since, until] 内该邮箱在各仓库的提交：全部仓库的合计及有提交的仓库各一行(Name 为仓库名)
+func PersonalPeriod(store Store, repos []string, email, since, until string) (*AuthorStats, []*AuthorStats, error) {
+	total := &AuthorStats{Email: email}
+	var rows []*AuthorStats
+	end, _ := time.ParseInLocation(DateLayout, until, time.Local)
+	to := end.AddDate(0, 0, 1).Format(DateLayout)
+	for _, repo := range repos {
+		commits, err := store.Commits(repo, since, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		row := &AuthorStats{Name: repo}
+		for i := range commits {
+			if strings.EqualFold(commits[i].Email, email) {
+				row.Add(&commits[i])
+				total.Add(&commits[i])
+				if total.Name == "" {
+					total.Name = commits[i].Author
+				}
+			}
+		}
+		if row.CommitCount > 0 {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	return total, rows, nil
+}
+
+// DigestPeriod 个人周报中一个统计周期的统计
+type DigestPeriod struct {
+	Since string       `json:"since"`
+	Until string       `json:"until"`
+	Stats *AuthorStats `json:"stats"`
+}
+
+// Digest 发给开发者本人的周报：最近几个统计周期的统计，从旧到新
+type Digest struct {
+	Name    string         `json:"name"`
+	Email   string         `json:"email"`
+	Periods []DigestPeriod `json:"periods"`
+}
+
+// 生成开发者的周报，最近几个统计周期都没有提交时返回 nil
+func BuildDigest(store Store, repos []string, member RosterMember, cfg *DigestConfig, now time.Time) (*Digest, error) {
+	d := &Digest{Name: member.Name, Email: member.Email}
+	active := false
+	for i := 0; i < cfg.periods(); i++ {
+		since, until := CurrentDateRange(now)
+		stats, _, err := PersonalPeriod(store, repos, member.Email, since, until)
+		if err != nil {
+			return nil, err
+		}
+		active = active || stats.CommitCount > 0
+		d.Periods = append([]DigestPeriod{{Since: since, Until: until, Stats: stats}}, d.Periods...)
+		start, _ := time.ParseInLocation(DateLayout, since, time.Local)
+		now = start.AddDate(0, 0, -1)
+	}
+	if !active {
+		return nil, nil
+	}
+	if d.Name == "" {
+		d.Name = d.Periods[len(d.Periods)-1].Stats.Name
+	}
+	return d, nil
+}
+
+// 邮件标题
+func (d *Digest) Subject() string {
+	current := d.Periods[len(d.Periods)-1]
+	return fmt.Sprintf("AI 统计周报 %s ~ %s: AI 添加占比 %s", current.Since, current.Until, FormatSampleRatio(current.Stats.TotalAIAddedLines, current.Stats.RatioAddedLines(), 0))
+}
+
+// 周报正文：本期至今的统计及各周期 AI 添加占比的趋势图
+func (d *Digest) Text() string {
+	var b strings.Builder
+	name := d.Name
+	if name == "" {
+		name = d.Email
+	}
+	current := d.Periods[len(d.Periods)-1].Stats
+	fmt.Fprintf(&b, "%s，你好：\n\n", name)
+	fmt.Fprintf(&b, "本期至今(%s ~ %s)你的统计:\n", d.Periods[len(d.Periods)-1].Since, d.Periods[len(d.Periods)-1].Until)
+	fmt.Fprintf(&b, "  提交: %d 次，添加 %d 行，删除 %d 行\n", current.CommitCount, current.TotalAddedLines, current.TotalDeletedLines)
+	fmt.Fprintf(&b, "  AI 添加: %d 行 (%s)，按提交平均 %s\n", current.TotalAIAddedLines,
+		FormatSampleRatio(current.TotalAIAddedLines, current.RatioAddedLines(), 0), FormatSampleMean(current.CommitAvgRatio(), current.RatioCommits(), 0))
+	if untagged := current.UntaggedCommits(); untagged > 0 {
+		fmt.Fprintf(&b, "  未标记 AIG 的提交: %d 次\n", untagged)
+	}
+	fmt.Fprintf(&b, "\nAI 添加占比趋势:\n")
+	for _, p := range d.Periods {
+		s := p.Stats
+		if s.CommitCount == 0 {
+			fmt.Fprintf(&b, "  %s ~ %s  %s  无提交\n", p.Since, p.Until, strings.Repeat("·", digestBarWidth))
+			continue
+		}
+		ratio := s.AddedRatio()
+		filled := int(ratio/100*digestBarWidth + 0.5)
+		fmt.Fprintf(&b, "  %s ~ %s  %s%s  %6.2f%% (%d 次提交)\n", p.Since, p.Until,
+			strings.Repeat("█", filled), strings.Repeat("░", digestBarWidth-filled), ratio, s.CommitCount)
+	}
+	fmt.Fprintf(&b, "\n这封邮件只发给你本人，用于了解自己的 AI 使用情况，不会用于考核。不想再收到时，请联系管理员在名单中为你设置 digest_opt_out: true\n")
+	return b.String()
+}
+
+// DigestSender 通过 SMTP 或 IM 中转地址发送周报
+type DigestSender struct {
+	SMTP     *SMTPConfig
+	Password string
+	IMURL    string
+	Client   *http.Client
+}
+
+// 发送一份周报，邮件和 IM 都配置时都发送
+func (s *DigestSender) Send(d *Digest) error {
+	var errs []string
+	if s.SMTP != nil {
+		if err := s.sendMail(d); err != nil {
+			errs = append(errs, fmt.Sprintf("发送邮件失败: %v", err))
+		}
+	}
+	if s.IMURL != "" {
+		client := s.Client
+		if client == nil {
+			client = &http.Client{Timeout: 10 * time.Second}
+		}
+		api := &APIClient{Client: client}
+		message := map[string]string{"email": d.Email, "name": d.Name, "subject": d.Subject(), "text": d.Text()}
+		if _, err := api.PostJSON(s.IMURL, message); err != nil {
+			errs = append(errs, fmt.Sprintf("发送 IM 消息失败: %s", strings.TrimPrefix(err.Error(), "错误：")))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("错误：向 %s 发送周报: %s", d.Email, strings.Join(errs, "；"))
+	}
+	return nil
+}
+
+func (s *DigestSender) sendMail(d *Digest) error {
+	port := s.SMTP.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(s.SMTP.Host, strconv.Itoa(port))
+	var auth smtp.Auth
+	if s.SMTP.Username != "" {
+		auth = smtp.PlainAuth("", s.SMTP.Username, s.Password, s.SMTP.Host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.SMTP.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", d.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", d.Subject()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(d.Text(), "\n", "\r\n"))
+	return smtp.SendMail(addr, auth, s.SMTP.From, []string{d.Email}, []byte(msg.String()))
+}
@@ -0,0 +1,183 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// 默认参数：同一开发者相邻提交间隔超过 2 小时视为新的工作时段，每个工作时段的第一个提交计 30 分钟
+const (
+	DefaultSessionGapMinutes  = 120
+	DefaultFirstCommitMinutes = 30
+)
+
+// 编码时长估算的说明，随结果一起输出
+const DurationCaveat = "时长由同一开发者相邻提交的时间间隔推算，未提交的工作、变基或 squash 改写的提交时间都会影响结果，只能比较大致趋势，不能作为个人工时或绩效依据"
+
+// DurationConfig 配置文件中按提交间隔估算编码时长的参数
+type DurationConfig struct {
+	// 相邻提交间隔超过该分钟数时视为新的工作时段，默认 120
+	SessionGapMinutes float64 `yaml:"session_gap_minutes"`
+	// 工作时段第一个提交计入的分钟数，默认 30
+	FirstCommitMinutes float64 `yaml:"first_commit_minutes"`
+}
+
+// 补全默认参数并检查取值
+func (c *DurationConfig) resolve() (*DurationConfig, error) {
+	resolved := DurationConfig{}
+	if c != nil {
+		resolved = *c
+	}
+	if resolved.SessionGapMinutes == 0 {
+		resolved.SessionGapMinutes = DefaultSessionGapMinutes
+	}
+	if resolved.FirstCommitMinutes == 0 {
+		resolved.FirstCommitMinutes = DefaultFirstCommitMinutes
+	}
+	if resolved.SessionGapMinutes < 0 || resolved.FirstCommitMinutes < 0 {
+		return nil, fmt.Errorf("错误：duration 的 session_gap_minutes、first_commit_minutes 必须大于 0")
+	}
+	return &resolved, nil
+}
+
+// DurationStats 一个开发者或一组提交的估算时长及每小时添加的行数
+type DurationStats struct {
+	Name        string  `json:"name" yaml:"name"`
+	Email       string  `json:"email,omitempty" yaml:"email,omitempty"`
+	Commits     int     `json:"commits" yaml:"commits"`
+	Sessions    int     `json:"sessions" yaml:"sessions"`
+	Hours       float64 `json:"hours" yaml:"hours"`
+	AddedLines  int     `json:"added_lines" yaml:"added_lines"`
+	AIAdded     int     `json:"ai_added_lines" yaml:"ai_added_lines"`
+	MedianHours float64 `json:"median_hours" yaml:"median_hours"`
+	// 每个提交的估算时长，计算中位数用
+	durations []float64
+}
+
+// 每小时添加的行数，没有时长时为 0
+func (s *DurationStats) LinesPerHour() float64 {
+	if s.Hours <= 0 {
+		return 0
+	}
+	return float64(s.AddedLines) / s.Hours
+}
+
+func (s *DurationStats) add(c *CommitStats, hours float64, session bool) {
+	s.Commits++
+	s.Hours += hours
+	s.AddedLines += c.AddedLines
+	s.AIAdded += c.AIAddedLines()
+	s.durations = append(s.durations, hours)
+	if session {
+		s.Sessions++
+	}
+}
+
+// DurationEstimate 按提交间隔估算的编码时长，按开发者及 AIG 标记分组比较每小时添加的行数
+type DurationEstimate struct {
+	SessionGapMinutes  float64          `json:"session_gap_minutes" yaml:"session_gap_minutes"`
+	FirstCommitMinutes float64          `json:"first_commit_minutes" yaml:"first_commit_minutes"`
+	Caveat             string           `json:"caveat" yaml:"caveat"`
+	Total              *DurationStats   `json:"total" yaml:"total"`
+	Authors            []*DurationStats `json:"authors" yaml:"authors"`
+	// 按 AIG 标记分组(标记 AIG>0、标记 AIG=0、未标记等)，没有提交的分组不列出
+	Cohorts []*DurationStats `json:"cohorts" yaml:"cohorts"`
+}
+
+// 按开发者(按 identity 识别)把提交按时间排序，相邻提交间隔不超过工作时段间隔时，
+// 后一个提交的时长为该间隔，否则为新工作时段的开始，计 first_commit_minutes
+func EstimateDuration(commits []CommitStats, cfg *DurationConfig, identity string) (*DurationEstimate, error) {
+	cfg, err := cfg.resolve()
+	if err != nil {
+		return nil, err
+	}
+	type timed struct {
+		c *CommitStats
+		t time.Time
+	}
+	byAuthor := make(map[string][]timed)
+	var keys []string
+	for i := range commits {
+		t, err := CommitTime(&commits[i])
+		if err != nil {
+			continue
+		}
+		key := IdentityKey(identity, commits[i].Author, commits[i].Email)
+		if _, ok := byAuthor[key]; !ok {
+			keys = append(keys, key)
+		}
+		byAuthor[key] = append(byAuthor[key], timed{&commits[i], t})
+	}
+
+	e := &DurationEstimate{SessionGapMinutes: cfg.SessionGapMinutes, FirstCommitMinutes: cfg.FirstCommitMinutes,
+		Caveat: DurationCaveat, Total: &DurationStats{Name: "全体"}, Authors: []*DurationStats{}, Cohorts: []*DurationStats{}}
+	cohorts := make(map[string]*DurationStats)
+	for _, l := range cohortLabels {
+		cohorts[l.Cohort] = &DurationStats{Name: l.Label}
+	}
+	gap := time.Duration(cfg.SessionGapMinutes * float64(time.Minute))
+	first := cfg.FirstCommitMinutes / 60
+	for _, key := range keys {
+		list := byAuthor[key]
+		sort.SliceStable(list, func(i, j int) bool { return list[i].t.Before(list[j].t) })
+		author := &DurationStats{Name: list[0].c.Author, Email: list[0].c.Email}
+		for i, item := range list {
+			hours, session := first, true
+			if i > 0 {
+				if d := item.t.Sub(list[i-1].t); d <= gap {
+					hours, session = d.Hours(), false
+				}
+			}
+			for _, s := range []*DurationStats{e.Total, author, cohorts[CommitCohort(item.c)]} {
+				s.add(item.c, hours, session)
+			}
+		}
+		e.Authors = append(e.Authors, author)
+	}
+	sort.SliceStable(e.Authors, func(i, j int) bool { return e.Authors[i].Hours > e.Authors[j].Hours })
+	for _, l := range cohortLabels {
+		if c := cohorts[l.Cohort]; c.Commits > 0 {
+			e.Cohorts = append(e.Cohorts, c)
+		}
+	}
+	for _, s := range append(append([]*DurationStats{e.Total}, e.Authors...), e.Cohorts...) {
+		s.MedianHours = median(s.durations)
+	}
+	return e, nil
+}
+
+// 重新估算时使用的参数
+func (e *DurationEstimate) config() *DurationConfig {
+	return &DurationConfig{SessionGapMinutes: e.SessionGapMinutes, FirstCommitMinutes: e.FirstCommitMinutes}
+}
+
+// 打印编码时长估算及说明
+func PrintDuration(w io.Writer, e *DurationEstimate, nf NumberFormat) {
+	fmt.Fprintf(w, "\n  编码时长估算 (提交间隔超过 %g 分钟为新的工作时段，时段第一个提交计 %g 分钟):\n", e.SessionGapMinutes, e.FirstCommitMinutes)
+	// 工作时段按开发者划分，分组中只列出提交数
+	line := func(indent string, s *DurationStats, sessions bool) {
+		count := nf.Int(s.Commits) + " 个提交"
+		if sessions {
+			count += "，" + nf.Int(s.Sessions) + " 个工作时段"
+		}
+		perHour := "-"
+		if s.Hours > 0 && s.AddedLines > 0 {
+			perHour = nf.Float(s.LinesPerHour(), 1)
+		}
+		fmt.Fprintf(w, "%s%s: %s，约 %s 小时，添加 %s，每小时 %s 行，单个提交中位数 %s\n",
+			indent, s.Name, count, nf.Float(s.Hours, 1), nf.Lines(s.AddedLines), perHour, FormatHours(s.MedianHours, nf))
+	}
+	line("    ", e.Total, true)
+	for _, a := range e.Authors {
+		line("    ", a, true)
+	}
+	if len(e.Cohorts) > 0 {
+		fmt.Fprintf(w, "    按 AIG 标记分组:\n")
+		for _, c := range e.Cohorts {
+			line("      ", c, false)
+		}
+	}
+	fmt.Fprintf(w, "    注意: %s\n", e.Caveat)
+}
@@ -0,0 +1,240 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 工作量模型
+const (
+	// 按每小时编写的行数折算，可按文件扩展名分别设置
+	EffortLines = "lines"
+	// 基本 COCOMO：工作量(人月) = a × KLoC^b，AI 节省的工时为全部代码与去掉 AI 代码后的工作量之差
+	EffortCOCOMO = "cocomo"
+)
+
+// 默认参数：每小时 20 行；COCOMO 取 organic 模式的系数，每人月 152 小时
+const (
+	DefaultLinesPerHour  = 20
+	DefaultCOCOMOA       = 2.4
+	DefaultCOCOMOB       = 1.05
+	DefaultHoursPerMonth = 152
+)
+
+// 工时估算的说明，随结果一起输出
+const EffortCaveat = "工时为按代码行数推算的粗略估计，未考虑需求、设计、评审和返工，AI 比例来自开发者自报，不能作为个人绩效或精确的投资回报依据"
+
+// EffortConfig 配置文件中的工作量模型
+type EffortConfig struct {
+	// lines 或 cocomo，默认 lines
+	Model string `yaml:"model"`
+	// lines 模型每小时编写的行数，languages 按扩展名(如 .go)覆盖
+	LinesPerHour float64            `yaml:"lines_per_hour"`
+	Languages    map[string]float64 `yaml:"languages"`
+	// cocomo 模型的系数及每人月工时
+	A             float64 `yaml:"a"`
+	B             float64 `yaml:"b"`
+	HoursPerMonth float64 `yaml:"hours_per_month"`
+}
+
+// EffortModel 把代码行数折算为工时，lines 为按文件扩展名统计的行数
+type EffortModel interface {
+	Name() string
+	// AI 添加的行数节省的工时，total 为全部添加行数
+	SavedHours(ai, total map[string]float64) float64
+	// 参数说明，写入报告
+	Describe() string
+}
+
+// 按配置创建工作量模型，未配置时使用 lines 模型的默认参数
+func NewEffortModel(cfg *EffortConfig) (EffortModel, error) {
+	if cfg == nil {
+		cfg = &EffortConfig{}
+	}
+	switch cfg.Model {
+	case "", EffortLines:
+		m := &linesModel{perHour: cfg.LinesPerHour, languages: make(map[string]float64)}
+		if m.perHour == 0 {
+			m.perHour = DefaultLinesPerHour
+		}
+		if m.perHour < 0 {
+			return nil, fmt.Errorf("错误：工作量模型的 lines_per_hour 必须大于 0")
+		}
+		for ext, v := range cfg.Languages {
+			if v <= 0 {
+				return nil, fmt.Errorf("错误：工作量模型中 '%s' 的每小时行数必须大于 0", ext)
+			}
+			m.languages[normalizeExt(ext)] = v
+		}
+		return m, nil
+	case EffortCOCOMO:
+		m := &cocomoModel{a: cfg.A, b: cfg.B, hoursPerMonth: cfg.HoursPerMonth}
+		if m.a == 0 {
+			m.a = DefaultCOCOMOA
+		}
+		if m.b == 0 {
+			m.b = DefaultCOCOMOB
+		}
+		if m.hoursPerMonth == 0 {
+			m.hoursPerMonth = DefaultHoursPerMonth
+		}
+		if m.a < 0 || m.b < 0 || m.hoursPerMonth < 0 {
+			return nil, fmt.Errorf("错误：COCOMO 模型的参数必须大于 0")
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("错误：不支持的工作量模型 '%s'，可选: %s, %s", cfg.Model, EffortLines, EffortCOCOMO)
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+type linesModel struct {
+	perHour   float64
+	languages map[string]float64
+}
+
+func (m *linesModel) Name() string { return EffortLines }
+
+func (m *linesModel) SavedHours(ai, _ map[string]float64) float64 {
+	hours := 0.0
+	for ext, lines := range ai {
+		perHour, ok := m.languages[ext]
+		if !ok {
+			perHour = m.perHour
+		}
+		hours += lines / perHour
+	}
+	return hours
+}
+
+func (m *linesModel) Describe() string {
+	desc := fmt.Sprintf("每小时 %g 行", m.perHour)
+	if len(m.languages) > 0 {
+		exts := make([]string, 0, len(m.languages))
+		for ext := range m.languages {
+			exts = append(exts, ext)
+		}
+		sort.Strings(exts)
+		parts := make([]string, len(exts))
+		for i, ext := range exts {
+			parts[i] = fmt.Sprintf("%s %g 行", ext, m.languages[ext])
+		}
+		desc += "(" + strings.Join(parts, "，") + ")"
+	}
+	return desc
+}
+
+type cocomoModel struct {
+	a, b, hoursPerMonth float64
+}
+
+func (m *cocomoModel) Name() string { return EffortCOCOMO }
+
+func (m *cocomoModel) SavedHours(ai, total map[string]float64) float64 {
+	all, human := 0.0, 0.0
+	for ext, lines := range total {
+		all += lines
+		human += lines - ai[ext]
+	}
+	effort := func(lines float64) float64 {
+		if lines <= 0 {
+			return 0
+		}
+		return m.a * math.Pow(lines/1000, m.b) * m.hoursPerMonth
+	}
+	return effort(all) - effort(human)
+}
+
+func (m *cocomoModel) Describe() string {
+	return fmt.Sprintf("COCOMO 工作量 = %g × KLoC^%g 人月，每人月 %g 小时", m.a, m.b, m.hoursPerMonth)
+}
+
+// AuthorEffort 一个开发者的 AI 添加行数及估算节省的工时
+type AuthorEffort struct {
+	Name       string  `json:"name" yaml:"name"`
+	Email      string  `json:"email,omitempty" yaml:"email,omitempty"`
+	AILines    int     `json:"ai_lines" yaml:"ai_lines"`
+	SavedHours float64 `json:"saved_hours" yaml:"saved_hours"`
+}
+
+// EffortEstimate 按工作量模型估算的 AI 节省工时
+type EffortEstimate struct {
+	Model      string         `json:"model" yaml:"model"`
+	Parameters string         `json:"parameters" yaml:"parameters"`
+	Caveat     string         `json:"caveat" yaml:"caveat"`
+	AILines    int            `json:"ai_lines" yaml:"ai_lines"`
+	SavedHours float64        `json:"saved_hours" yaml:"saved_hours"`
+	Authors    []AuthorEffort `json:"authors" yaml:"authors"`
+}
+
+// 按计入统计的文件扩展名汇总 AI 添加行数(添加行数 × AIG 比例)，估算全体及各开发者节省的工时
+func EstimateEffort(model EffortModel, commits []CommitStats, identity string) *EffortEstimate {
+	type lines struct {
+		name, email string
+		ai, total   map[string]float64
+	}
+	newLines := func(name, email string) *lines {
+		return &lines{name: name, email: email, ai: make(map[string]float64), total: make(map[string]float64)}
+	}
+	all := newLines("", "")
+	byAuthor := make(map[string]*lines)
+	var keys []string
+	for i := range commits {
+		c := &commits[i]
+		key := IdentityKey(identity, c.Author, c.Email)
+		a, ok := byAuthor[key]
+		if !ok {
+			a = newLines(c.Author, c.Email)
+			byAuthor[key] = a
+			keys = append(keys, key)
+		}
+		for _, f := range c.Files {
+			if f.Skipped || f.Moved {
+				continue
+			}
+			ext := strings.ToLower(filepath.Ext(f.Path))
+			for _, l := range []*lines{all, a} {
+				l.total[ext] += float64(f.Added)
+				l.ai[ext] += float64(f.Added) * c.AIGRatio
+			}
+		}
+	}
+
+	sum := func(m map[string]float64) int {
+		total := 0.0
+		for _, v := range m {
+			total += v
+		}
+		return int(math.Round(total))
+	}
+	e := &EffortEstimate{Model: model.Name(), Parameters: model.Describe(), Caveat: EffortCaveat,
+		AILines: sum(all.ai), SavedHours: model.SavedHours(all.ai, all.total), Authors: []AuthorEffort{}}
+	for _, key := range keys {
+		a := byAuthor[key]
+		if ai := sum(a.ai); ai > 0 {
+			e.Authors = append(e.Authors, AuthorEffort{Name: a.name, Email: a.email, AILines: ai, SavedHours: model.SavedHours(a.ai, a.total)})
+		}
+	}
+	sort.SliceStable(e.Authors, func(i, j int) bool { return e.Authors[i].SavedHours > e.Authors[j].SavedHours })
+	return e
+}
+
+// 打印工时估算及说明
+func PrintEffort(w io.Writer, e *EffortEstimate, nf NumberFormat) {
+	fmt.Fprintf(w, "\n  AI 节省工时估算 (%s 模型，%s):\n", e.Model, e.Parameters)
+	fmt.Fprintf(w, "    全体: AI 添加 %s，约 %s 小时\n", nf.Lines(e.AILines), nf.Float(e.SavedHours, 1))
+	for _, a := range e.Authors {
+		fmt.Fprintf(w, "    %s: AI 添加 %s，约 %s 小时\n", a.Name, nf.Lines(a.AILines), nf.Float(a.SavedHours, 1))
+	}
+	fmt.Fprintf(w, "    注意: %s\n", e.Caveat)
+}
@@ -0,0 +1,101 @@
+package stat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 可以用 errors.Is 判断的错误类型
+var (
+	// ErrNotARepo 目录不是 git 仓库
+	ErrNotARepo = errors.New("不是 git 仓库")
+	// ErrBadDate 日期参数格式不正确
+	ErrBadDate = errors.New("格式不正确")
+	// ErrGitTimeout git 命令超过 --git-timeout 仍未结束
+	ErrGitTimeout = errors.New("git 命令超时")
+	// ErrParse git 输出无法解析
+	ErrParse = errors.New("无法解析 git 输出")
+	// ErrInterrupted 收到 SIGINT/SIGTERM 后中止
+	ErrInterrupted = errors.New("已中断")
+)
+
+// AnalysisError 分析某个仓库(或子模块)的某一步骤失败
+type AnalysisError struct {
+	// 仓库或子模块，为空时为当前仓库
+	Repo string
+	// 失败的步骤，如 git log、SZZ 分析
+	Stage string
+	Err   error
+}
+
+func (e *AnalysisError) Error() string {
+	msg := strings.TrimPrefix(e.Err.Error(), "错误：")
+	if e.Repo != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Repo, e.Stage, msg)
+	}
+	return e.Stage + ": " + msg
+}
+
+func (e *AnalysisError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError 分析未全部完成：返回的提交可以使用，但部分步骤或子模块失败
+type PartialError struct {
+	Errors []*AnalysisError
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "错误：部分分析失败: " + strings.Join(msgs, "；")
+}
+
+// 支持 errors.Is(err, ErrGitTimeout) 等判断其中任意一个错误
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// 错误是否只是部分分析失败，此时结果仍可使用
+func IsPartial(err error) bool {
+	var partial *PartialError
+	return errors.As(err, &partial)
+}
+
+// 记录失败的步骤，分析继续进行；中断后只记录第一个被中断的步骤
+func (a *Analyzer) fail(stage string, err error) {
+	if errors.Is(err, ErrInterrupted) && a.interrupted() {
+		return
+	}
+	var analysisErr *AnalysisError
+	if errors.As(err, &analysisErr) {
+		a.errors = append(a.errors, analysisErr)
+		return
+	}
+	a.errors = append(a.errors, &AnalysisError{Stage: stage, Err: err})
+}
+
+// 分析是否已被中断，之后的步骤不再执行
+func (a *Analyzer) interrupted() bool {
+	for _, err := range a.errors {
+		if errors.Is(err, ErrInterrupted) {
+			return true
+		}
+	}
+	return false
+}
+
+// 有失败的步骤时返回 PartialError
+func (a *Analyzer) partialError() error {
+	if len(a.errors) == 0 {
+		return nil
+	}
+	return &PartialError{Errors: a.errors}
+}
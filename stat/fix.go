@@ -0,0 +1,174 @@
+package stat
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// 未匹配任何严重级别的修复提交
+const FixNormal = "normal"
+
+// FixSeverity 配置文件中的修复严重级别，按配置顺序取第一个与提交信息匹配的级别
+type FixSeverity struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+// 默认的严重级别：P0、critical 等为 critical，hotfix、P1 等为 high
+var DefaultFixSeverities = []FixSeverity{
+	{Name: "critical", Pattern: `(?i)\b(p0|critical|blocker|sev[-_ ]?[01])\b`},
+	{Name: "high", Pattern: `(?i)\b(hotfix|p1|urgent)\b`},
+}
+
+// FixCounts 某个严重级别的修复提交数及 AI 参与的提交数
+type FixCounts struct {
+	Fixes   int `json:"fixes" yaml:"fixes"`
+	AIFixes int `json:"ai_fixes" yaml:"ai_fixes"`
+	// 策略标记(n/a、exempt)的修复提交数，不计入 AI 参与占比的分母
+	PolicyFixes int `json:"policy_fixes,omitempty" yaml:"policy_fixes,omitempty"`
+}
+
+// AI 参与修复的提交占比（百分比）
+func (f FixCounts) AIRatio() float64 {
+	return percent(f.AIFixes, f.Fixes-f.PolicyFixes)
+}
+
+// FixRules 修复提交的识别规则及严重级别
+type FixRules struct {
+	// 识别修复提交的提交标题正则，为 nil 时使用默认规则(标题以 fix 或 hotfix 开头)
+	Pattern    *regexp.Regexp
+	severities []fixSeverityRule
+}
+
+type fixSeverityRule struct {
+	name  string
+	regex *regexp.Regexp
+}
+
+var defaultFixRules = mustCompileFixRules("", DefaultFixSeverities)
+
+// 编译修复提交的识别规则，severities 为空时使用默认的严重级别
+func CompileFixRules(pattern string, severities []FixSeverity) (*FixRules, error) {
+	rules := &FixRules{}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("错误：修复提交的识别规则 '%s' 不是有效的正则表达式: %v", pattern, err)
+		}
+		rules.Pattern = re
+	}
+	if len(severities) == 0 {
+		severities = DefaultFixSeverities
+	}
+	seen := make(map[string]bool)
+	for _, s := range severities {
+		if s.Name == "" || s.Name == FixNormal || seen[s.Name] {
+			return nil, fmt.Errorf("错误：修复严重级别的名称 '%s' 为空、重复或与默认级别 %s 相同", s.Name, FixNormal)
+		}
+		seen[s.Name] = true
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("错误：修复严重级别 '%s' 的规则 '%s' 不是有效的正则表达式: %v", s.Name, s.Pattern, err)
+		}
+		rules.severities = append(rules.severities, fixSeverityRule{s.Name, re})
+	}
+	return rules, nil
+}
+
+func mustCompileFixRules(pattern string, severities []FixSeverity) *FixRules {
+	rules, err := CompileFixRules(pattern, severities)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// 提交标题是否为修复提交
+func (r *FixRules) IsFix(subject string) bool {
+	if r == nil || r.Pattern == nil {
+		return fixRegex.MatchString(subject)
+	}
+	return r.Pattern.MatchString(subject)
+}
+
+// 严重级别的名称，按匹配顺序排列，最后为 normal
+func (r *FixRules) Severities() []string {
+	if r == nil {
+		r = defaultFixRules
+	}
+	var names []string
+	for _, s := range r.severities {
+		names = append(names, s.name)
+	}
+	return append(names, FixNormal)
+}
+
+// 按规则重新识别修复提交(LLM 估算为修复的提交保留)，并为修复提交标注严重级别
+func (r *FixRules) apply(commits []CommitStats) {
+	if r == nil {
+		r = defaultFixRules
+	}
+	for i := range commits {
+		c := &commits[i]
+		if r.Pattern != nil {
+			c.IsFix = r.Pattern.MatchString(c.Subject) || c.EstimatedType == "fix"
+		}
+		c.FixSeverity = ""
+		if !c.IsFix {
+			continue
+		}
+		c.FixSeverity = FixNormal
+		for _, s := range r.severities {
+			if s.regex.MatchString(c.Message) || s.regex.MatchString(c.Subject) {
+				c.FixSeverity = s.name
+				break
+			}
+		}
+	}
+}
+
+// 按严重级别统计修复提交
+func CountFixSeverities(commits []CommitStats) map[string]FixCounts {
+	counts := make(map[string]FixCounts)
+	for i := range commits {
+		addFixSeverity(counts, &commits[i])
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	return counts
+}
+
+func addFixSeverity(counts map[string]FixCounts, c *CommitStats) {
+	if !c.IsFix || c.FixSeverity == "" {
+		return
+	}
+	f := counts[c.FixSeverity]
+	f.Fixes++
+	if c.AIGRatio > 0 {
+		f.AIFixes++
+	}
+	if c.AIGPolicy != "" {
+		f.PolicyFixes++
+	}
+	counts[c.FixSeverity] = f
+}
+
+// 按严重级别显示修复提交数，如 "critical 2 次 (AI 参与 1 次，50.00%)"，只有 normal 级别时返回 nil
+func FixSeverityLines(counts map[string]FixCounts, severities []string, nf NumberFormat) []string {
+	if len(counts) == 0 || (len(counts) == 1 && counts[FixNormal].Fixes > 0) {
+		return nil
+	}
+	if len(severities) == 0 {
+		severities = defaultFixRules.Severities()
+	}
+	var lines []string
+	for _, name := range severities {
+		f, ok := counts[name]
+		if !ok {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s %s 次 (AI 参与 %s 次，%.2f%%)", name, nf.Int(f.Fixes), nf.Int(f.AIFixes), f.AIRatio()))
+	}
+	return lines
+}
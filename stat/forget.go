@@ -0,0 +1,476 @@
+package stat
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 匿名化后的邮箱域名，保留域名不会被解析
+const anonymousDomain = "anonymized.invalid"
+
+// Forgetter 从运行记录中删除或匿名化一名开发者的数据
+// 匿名化把姓名和邮箱替换为随机的代号，各项汇总不变，同一次操作中的全部记录使用同一代号；
+// 删除则去掉该开发者的提交、个人统计行及热力图，并按剩余的提交重新生成按周、按标记分组、汇总行等各项汇总
+type Forgetter struct {
+	Email  string
+	Delete bool
+	// 替换用的姓名和邮箱
+	Name      string
+	Anonymous string
+	// 删除时重新计算组织汇总和目标所用的名单、组织架构和目标，来自当前配置
+	Roster  []RosterMember
+	Org     []OrgUnit
+	Targets []Target
+}
+
+// ForgetResult 删除或匿名化一名开发者数据的结果，预览时为将要修改的数量
+type ForgetResult struct {
+	Email   string `json:"email"`
+	Runs    int    `json:"runs"`
+	Commits int    `json:"commits"`
+	Frozen  int    `json:"frozen"`
+}
+
+func (r *ForgetResult) String() string {
+	return fmt.Sprintf("%s 的运行记录 %d 条，提交 %d 个，冻结记录 %d 条", r.Email, r.Runs, r.Commits, r.Frozen)
+}
+
+// 创建 Forgetter，生成随机代号，无法从代号反推邮箱
+func NewForgetter(email string, remove bool) (*Forgetter, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("错误：邮箱 '%s' 格式不正确", email)
+	}
+	f := &Forgetter{Email: email, Delete: remove}
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	id := "anonymous-" + hex.EncodeToString(buf)
+	f.Name, f.Anonymous = id, id+"@"+anonymousDomain
+	return f, nil
+}
+
+// 新的结果，不包含代号，避免在输出中留下邮箱与代号的对应关系
+func (f *Forgetter) result() *ForgetResult {
+	return &ForgetResult{Email: f.Email}
+}
+
+func (f *Forgetter) matches(email string) bool {
+	return strings.EqualFold(strings.TrimSpace(email), f.Email)
+}
+
+// 处理一组提交，返回处理后的提交及涉及的提交数
+func (f *Forgetter) Commits(commits []CommitStats) ([]CommitStats, int) {
+	n := 0
+	kept := commits[:0:0]
+	for _, c := range commits {
+		if !f.matches(c.Email) {
+			kept = append(kept, c)
+			continue
+		}
+		n++
+		if !f.Delete {
+			c.Author, c.Email = f.Name, f.Anonymous
+			c.Subject = replaceFold(c.Subject, f.Email, f.Anonymous)
+			c.Message = replaceFold(c.Message, f.Email, f.Anonymous)
+			kept = append(kept, c)
+		}
+	}
+	if n == 0 {
+		return commits, 0
+	}
+	return kept, n
+}
+
+// 处理一份报告，没有该开发者的数据时返回 false，报告不变
+func (f *Forgetter) Report(report *Report) (bool, error) {
+	names := f.names(report)
+	changed := false
+	if f.Delete {
+		removed := make(map[string]bool)
+		for i := range report.Commits {
+			if f.matches(report.Commits[i].Email) {
+				removed[report.Commits[i].ID] = true
+			}
+		}
+		var n int
+		report.Commits, n = f.Commits(report.Commits)
+		listed := false
+		authors := report.Authors[:0:0]
+		for _, a := range report.Authors {
+			if f.matches(a.Email) {
+				listed = true
+				continue
+			}
+			authors = append(authors, a)
+		}
+		report.Authors = authors
+		heatmaps := report.Heatmaps[:0:0]
+		for _, h := range report.Heatmaps {
+			if f.matches(h.Email) {
+				changed = true
+				continue
+			}
+			heatmaps = append(heatmaps, h)
+		}
+		report.Heatmaps = heatmaps
+		external := f.dropExternal(report)
+		if n > 0 || listed || external {
+			changed = true
+			// 有提交却没有个人统计行的开发者计入了“其他”汇总行
+			f.rebuild(report, removed, n > 0 && !listed)
+		}
+	}
+	// 其余位置(评审、修复耗时等)出现的姓名和邮箱一律替换为代号
+	scrubbed, err := f.scrub(report, names)
+	if err != nil {
+		return false, err
+	}
+	if err := f.verify(report); err != nil {
+		return false, err
+	}
+	return changed || scrubbed, nil
+}
+
+// 删除提交后按剩余的提交重新生成汇总：可以从提交得到的各项重新计算，
+// 汇总行、组织汇总、目标、费用、异常、提交图及节省工时去掉该开发者的部分；
+// 组织汇总和目标按当前配置重新计算，未配置时从报告中去掉
+func (f *Forgetter) rebuild(report *Report, removed map[string]bool, inOthers bool) {
+	identity := ""
+	if report.Meta != nil {
+		identity = report.Meta.Filters.Identity
+	}
+	if report.Cohorts != nil {
+		report.Cohorts = BuildCohorts(report.Commits, identity)
+	}
+	if report.Weeks != nil {
+		report.Weeks = BuildWeeks(report.Commits, report.Since, report.Until, report.WeekScheme, identity)
+	}
+	if len(report.Heatmaps) > 0 {
+		report.Heatmaps = BuildHeatmaps(report.Commits, report.Authors, identity)
+	}
+	if report.Duration != nil {
+		if duration, err := EstimateDuration(report.Commits, report.Duration.config(), identity); err == nil {
+			report.Duration = duration
+		}
+	}
+	if report.Lifecycle != nil {
+		report.Lifecycle = BuildFileLifecycle(report.Commits)
+	}
+	if report.Ownership != nil {
+		report.Ownership = BuildOwnership(report.Commits, report.Ownership.Depth, identity)
+	}
+	if report.BugIntroduction != nil && report.Meta != nil {
+		report.BugIntroduction = SummarizeBugs(report.Commits, report.Meta.BugFixCommits)
+	}
+	if inOthers {
+		f.rebuildOthers(report, identity)
+	}
+	if report.Org != nil {
+		report.Org = nil
+		if len(f.Org) > 0 {
+			rows := append(append([]*AuthorStats{}, report.Authors...), report.Groups...)
+			report.Org = RollupOrg(f.Org, f.Roster, rows)
+		}
+	}
+	if report.Targets != nil {
+		report.Targets = nil
+		if len(f.Targets) > 0 {
+			rows := append(append([]*AuthorStats{}, report.Authors...), report.Groups...)
+			report.Targets = EvaluateTargets(f.Targets, rows, f.Roster, reportAsOf(report))
+		}
+	}
+	if report.Costs != nil {
+		f.rebuildCosts(report.Costs, report.Commits)
+	}
+	if report.Effort != nil {
+		rows := report.Effort.Authors[:0:0]
+		for _, a := range report.Effort.Authors {
+			if f.matches(a.Email) {
+				report.Effort.AILines -= a.AILines
+				report.Effort.SavedHours -= a.SavedHours
+				continue
+			}
+			rows = append(rows, a)
+		}
+		report.Effort.Authors = rows
+	}
+	// 与上期的占比变化和 AIG 为 100% 的提交按开发者去掉，无提交的工作日按剩余的提交重新计算
+	anomalies := report.Anomalies[:0:0]
+	for _, a := range report.Anomalies {
+		if a.Kind != AnomalyIdleDays && !f.matches(a.Email) {
+			anomalies = append(anomalies, a)
+		}
+	}
+	report.Anomalies = append(anomalies, idleDays(report.Commits)...)
+	if report.CommitGraph != nil {
+		nodes := report.CommitGraph.Nodes[:0:0]
+		for _, node := range report.CommitGraph.Nodes {
+			if removed[node.ID] {
+				continue
+			}
+			var parents []string
+			for _, p := range node.Parents {
+				if !removed[p] {
+					parents = append(parents, p)
+				}
+			}
+			node.Parents = parents
+			nodes = append(nodes, node)
+		}
+		report.CommitGraph.Nodes = nodes
+	}
+}
+
+// 按剩余的提交重新生成“其他”汇总行：没有个人统计行的开发者合并为一行
+func (f *Forgetter) rebuildOthers(report *Report, identity string) {
+	listed := make(map[string]bool, len(report.Authors))
+	for _, a := range report.Authors {
+		listed[IdentityKey(identity, a.Name, a.Email)] = true
+	}
+	var others *AuthorStats
+	for key, stats := range AggregateByIdentity(report.Commits, identity) {
+		if listed[key] {
+			continue
+		}
+		if others == nil {
+			others = &AuthorStats{Name: GroupOthers}
+		}
+		others.Merge(stats)
+	}
+	groups := report.Groups[:0:0]
+	for _, g := range report.Groups {
+		if g.Name != GroupOthers {
+			groups = append(groups, g)
+		} else if others != nil {
+			groups = append(groups, others)
+		}
+	}
+	report.Groups = groups
+}
+
+// 外部开发者的提交不保存在报告中，无法从“外部贡献者”汇总行中单独扣除，
+// 该开发者可能属于外部开发者时去掉整行
+func (f *Forgetter) dropExternal(report *Report) bool {
+	if report.Meta == nil || MatchEmailDomain(f.Email, report.Meta.Filters.EmailDomains) {
+		return false
+	}
+	for _, m := range f.Roster {
+		if f.matches(m.Email) {
+			return false
+		}
+	}
+	groups := report.Groups[:0:0]
+	for _, g := range report.Groups {
+		if g.Name != GroupExternal {
+			groups = append(groups, g)
+		}
+	}
+	if len(groups) == len(report.Groups) {
+		return false
+	}
+	report.Groups = groups
+	return true
+}
+
+// 去掉该开发者的费用，按剩余的提交重新计算 AI 添加行数、修复次数及单位费用
+func (f *Forgetter) rebuildCosts(r *CostReport, commits []CommitStats) {
+	users := r.Users[:0:0]
+	r.Cost = 0
+	for _, u := range r.Users {
+		if f.matches(u.Email) {
+			continue
+		}
+		r.Cost += u.Cost
+		users = append(users, u)
+	}
+	r.Users = users
+	r.AILines, r.AIFixes = 0, 0
+	for i := range commits {
+		r.AILines += commits[i].AIAddedLines()
+		if commits[i].IsFix && commits[i].AIGRatio > 0 {
+			r.AIFixes++
+		}
+	}
+	r.PerAILine, r.PerAIFix = costPer(r.Cost, r.AILines), costPer(r.Cost, r.AIFixes)
+}
+
+// 判断目标截止日期所用的日期：统计周期的结束日期，按提交范围统计时为报告生成时间
+func reportAsOf(report *Report) time.Time {
+	if until, err := time.ParseInLocation(DateLayout, PeriodDay(report.Until), time.Local); err == nil {
+		return until
+	}
+	if report.Meta != nil {
+		if generated, err := time.Parse(time.RFC3339, report.Meta.GeneratedAt); err == nil {
+			return generated
+		}
+	}
+	return time.Now()
+}
+
+// 处理后的报告中不应再出现该开发者的邮箱，出现时说明有遗漏的位置，不保存修改
+func (f *Forgetter) verify(report *Report) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(strings.ToLower(string(data)), f.Email) {
+		return fmt.Errorf("错误：处理后的报告中仍有 %s 的邮箱", f.Email)
+	}
+	return nil
+}
+
+// 处理冻结记录，没有该开发者的数据时返回 false；修改后重新计算校验和(原记录完好时)
+func (f *Forgetter) Frozen(period *FrozenPeriod) bool {
+	intact := period.Intact()
+	n := 0
+	commits := period.Commits[:0:0]
+	for _, c := range period.Commits {
+		if !f.matches(c.Email) {
+			commits = append(commits, c)
+			continue
+		}
+		n++
+		if !f.Delete {
+			c.Author, c.Email = f.Name, f.Anonymous
+			commits = append(commits, c)
+		}
+	}
+	authors := period.Authors[:0:0]
+	found := false
+	for _, a := range period.Authors {
+		if !f.matches(a.Email) {
+			authors = append(authors, a)
+			continue
+		}
+		found = true
+		if !f.Delete {
+			a.Name, a.Email = f.Name, f.Anonymous
+			authors = append(authors, a)
+		}
+	}
+	if n == 0 && !found {
+		return false
+	}
+	period.Commits, period.Authors = commits, authors
+	if intact {
+		period.Checksum = frozenChecksum(period.Commits, period.Authors)
+	}
+	return true
+}
+
+// 报告中该开发者使用过的姓名
+func (f *Forgetter) names(report *Report) map[string]bool {
+	names := make(map[string]bool)
+	for i := range report.Commits {
+		if f.matches(report.Commits[i].Email) && report.Commits[i].Author != "" {
+			names[report.Commits[i].Author] = true
+		}
+	}
+	for _, a := range report.Authors {
+		if f.matches(a.Email) && a.Name != "" {
+			names[a.Name] = true
+		}
+	}
+	return names
+}
+
+// 按 JSON 结构替换报告中的邮箱(含出现在文字中的)和姓名，姓名只替换完全相同的值，
+// 且跳过邮箱属于其他开发者的对象，避免误改同名的开发者
+func (f *Forgetter) scrub(report *Report, names map[string]bool) (bool, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return false, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return false, err
+	}
+	changed := false
+	tree = f.scrubValue(tree, names, true, &changed)
+	if !changed {
+		return false, nil
+	}
+	if data, err = json.Marshal(tree); err != nil {
+		return false, err
+	}
+	scrubbed := &Report{}
+	if err := json.Unmarshal(data, scrubbed); err != nil {
+		return false, err
+	}
+	*report = *scrubbed
+	return true, nil
+}
+
+func (f *Forgetter) scrubValue(v interface{}, names map[string]bool, replaceNames bool, changed *bool) interface{} {
+	switch v := v.(type) {
+	case string:
+		s := replaceFold(v, f.Email, f.Anonymous)
+		if replaceNames && names[s] {
+			s = f.Name
+		}
+		if s != v {
+			*changed = true
+		}
+		return s
+	case []interface{}:
+		for i := range v {
+			v[i] = f.scrubValue(v[i], names, replaceNames, changed)
+		}
+		return v
+	case map[string]interface{}:
+		// 有邮箱的对象按邮箱判断是否属于该开发者，没有邮箱的(如 PR、提交图节点)按作者姓名判断
+		own, owner := true, false
+		if email, ok := v["email"].(string); ok && email != "" {
+			own = f.matches(email)
+			owner = own
+		} else if author, ok := v["author"].(string); ok {
+			owner = names[author]
+		}
+		scrubbed := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			newKey := f.scrubValue(key, names, own, changed).(string)
+			value = f.scrubValue(value, names, own, changed)
+			// 属于该开发者的对象中，说明文字等包含姓名的字符串也替换
+			if text, ok := value.(string); ok && owner {
+				for name := range names {
+					if replaced := strings.ReplaceAll(text, name, f.Name); replaced != text {
+						text, *changed = replaced, true
+					}
+				}
+				value = text
+			}
+			scrubbed[newKey] = value
+		}
+		return scrubbed
+	}
+	return v
+}
+
+// 不区分大小写地替换 s 中出现的 old
+func replaceFold(s, old, replacement string) string {
+	lower := strings.ToLower(s)
+	if !strings.Contains(lower, old) {
+		return s
+	}
+	if len(lower) != len(s) {
+		// 转为小写后长度变化(少数非 ASCII 字符)，无法按位置对应，只替换大小写相同的
+		return strings.ReplaceAll(s, old, replacement)
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, old)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		b.WriteString(replacement)
+		s, lower = s[i+len(old):], lower[i+len(old):]
+	}
+}
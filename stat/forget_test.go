@@ -0,0 +1,162 @@
+package stat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var forgetRoster = []RosterMember{
+	{Name: "Alice Leaver", Email: "alice@corp.com", Team: "payments"},
+	{Name: "Bob", Email: "bob@corp.com", Team: "web"},
+}
+
+var forgetOrg = []OrgUnit{{Name: "研发", Children: []OrgUnit{{Name: "payments"}, {Name: "web"}}}}
+
+var forgetTargets = []Target{{Name: "全员", Metric: "ai_added_ratio", Value: 30}}
+
+// 每一部分都包含各开发者数据的报告：Alice、Bob 有个人统计行，Carol 在“其他”汇总行，
+// 另有一名外部开发者合并在“外部贡献者”汇总行
+func newForgetReport(t *testing.T) *Report {
+	t.Helper()
+	commits := []CommitStats{
+		{ID: "a1", Author: "Alice Leaver", Email: "alice@corp.com", Time: "2024-05-02 10:00:00", Subject: "feat: pay by alice@corp.com", AddedLines: 100, AIGRatio: 1},
+		{ID: "a2", Author: "Alice Leaver", Email: "alice@corp.com", Time: "2024-05-03 10:00:00", Subject: "fix: refund", AddedLines: 20, AIGRatio: 0.5, IsFix: true},
+		{ID: "b1", Author: "Bob", Email: "bob@corp.com", Time: "2024-05-06 10:00:00", Subject: "feat: web", AddedLines: 50, AIGRatio: 0.2},
+		{ID: "b2", Author: "Bob", Email: "bob@corp.com", Time: "2024-05-13 10:00:00", Subject: "fix: css", AddedLines: 10, IsFix: true},
+		{ID: "c1", Author: "Carol", Email: "carol@corp.com", Time: "2024-05-14 10:00:00", Subject: "docs", AddedLines: 2},
+	}
+	byAuthor := AggregateByIdentity(commits, IdentityEmail)
+	others := &AuthorStats{Name: GroupOthers}
+	others.Merge(byAuthor["carol@corp.com"])
+	external := &AuthorStats{Name: GroupExternal, CommitCount: 1, TotalAddedLines: 7, MemberCount: 1}
+	report := &Report{
+		Meta:    &Metadata{GeneratedAt: "2024-05-16T00:00:00Z", Filters: FilterRules{EmailDomains: []string{"corp.com"}}},
+		Since:   "2024-05-01",
+		Until:   "2024-05-15",
+		Authors: []*AuthorStats{byAuthor["alice@corp.com"], byAuthor["bob@corp.com"]},
+		Groups:  []*AuthorStats{others, external},
+		Commits: commits,
+	}
+	report.Cohorts = BuildCohorts(commits, IdentityEmail)
+	report.Weeks = BuildWeeks(commits, report.Since, report.Until, "", IdentityEmail)
+	report.Heatmaps = BuildHeatmaps(commits, report.Authors, IdentityEmail)
+	rows := append(append([]*AuthorStats{}, report.Authors...), report.Groups...)
+	report.Org = RollupOrg(forgetOrg, forgetRoster, rows)
+	report.Targets = EvaluateTargets(forgetTargets, rows, forgetRoster, reportAsOf(report))
+	costs, err := AccountCosts([]CostRecord{
+		{Email: "alice@corp.com", Month: "2024-05", Cost: 31},
+		{Email: "bob@corp.com", Month: "2024-05", Cost: 62},
+	}, commits, report.Since, report.Until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report.Costs = costs
+	previous := &Report{Since: "2024-04-16", Until: "2024-04-30", Authors: []*AuthorStats{
+		{Name: "Alice Leaver", Email: "alice@corp.com", CommitCount: 1, TotalAddedLines: 100},
+	}}
+	report.Anomalies = DetectAnomalies(report, previous, 20, 0, IdentityEmail)
+	report.Effort = &EffortEstimate{AILines: 113, SavedHours: 12, Authors: []AuthorEffort{
+		{Name: "Alice Leaver", Email: "alice@corp.com", AILines: 110, SavedHours: 11},
+		{Name: "Bob", Email: "bob@corp.com", AILines: 3, SavedHours: 1},
+	}}
+	report.CommitGraph = &CommitGraph{Nodes: []GraphNode{
+		{ID: "b1", Parents: []string{"a2"}, Author: "Bob", Counted: true},
+		{ID: "a2", Parents: []string{"a1"}, Author: "Alice Leaver", Counted: true},
+		{ID: "a1", Author: "Alice Leaver", Counted: true},
+	}}
+	report.Reviews = &ReviewStats{PullRequests: []PullRequestReview{{Number: 1, Title: "Alice Leaver's refund", Author: "Alice Leaver"}}}
+	return report
+}
+
+func forgetReport(t *testing.T, email string, remove bool, report *Report) *Forgetter {
+	t.Helper()
+	f, err := NewForgetter(email, remove)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Roster, f.Org, f.Targets = forgetRoster, forgetOrg, forgetTargets
+	changed, err := f.Report(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatalf("%s: 报告没有修改", email)
+	}
+	return f
+}
+
+// 序列化后的报告中不应出现该开发者的邮箱和姓名
+func assertForgotten(t *testing.T, report *Report, email, name string) {
+	t.Helper()
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.ToLower(string(data))
+	for _, s := range []string{email, strings.ToLower(name)} {
+		if strings.Contains(text, s) {
+			t.Errorf("报告中仍有 %q:\n%s", s, data)
+		}
+	}
+}
+
+func TestForgetDeleteRebuildsDerivedSections(t *testing.T) {
+	report := newForgetReport(t)
+	forgetReport(t, "alice@corp.com", true, report)
+	assertForgotten(t, report, "alice@corp.com", "Alice Leaver")
+
+	// 与只用剩余提交生成的报告一致
+	want := newForgetReport(t)
+	want.Commits = want.Commits[2:]
+	want.Authors = want.Authors[1:]
+	rows := append(append([]*AuthorStats{}, want.Authors...), want.Groups...)
+	if got, expected := report.Org[0].Stats.CommitCount, RollupOrg(forgetOrg, forgetRoster, rows)[0].Stats.CommitCount; got != expected {
+		t.Errorf("组织汇总提交数 = %d, want %d", got, expected)
+	}
+	if got, expected := report.Targets[0].Actual, EvaluateTargets(forgetTargets, rows, forgetRoster, reportAsOf(want))[0].Actual; got != expected {
+		t.Errorf("目标实际值 = %v, want %v", got, expected)
+	}
+	if report.Costs.Cost != 30 || len(report.Costs.Users) != 1 || report.Costs.AILines != 10 {
+		t.Errorf("费用 = %+v", report.Costs)
+	}
+	for _, a := range report.Anomalies {
+		if a.Kind != AnomalyIdleDays {
+			t.Errorf("该开发者的异常没有去掉: %+v", a)
+		}
+	}
+	if report.Effort.AILines != 3 || len(report.Effort.Authors) != 1 {
+		t.Errorf("节省工时 = %+v", report.Effort)
+	}
+	if len(report.CommitGraph.Nodes) != 1 || len(report.CommitGraph.Nodes[0].Parents) != 0 {
+		t.Errorf("提交图 = %+v", report.CommitGraph.Nodes)
+	}
+}
+
+func TestForgetDeleteRebuildsGroups(t *testing.T) {
+	// Carol 在“其他”汇总行中，删除后没有其他成员，整行去掉
+	report := newForgetReport(t)
+	forgetReport(t, "carol@corp.com", true, report)
+	assertForgotten(t, report, "carol@corp.com", "Carol")
+	if len(report.Groups) != 1 || report.Groups[0].Name != GroupExternal {
+		t.Errorf("汇总行 = %+v", report.Groups)
+	}
+
+	// 外部开发者无法从汇总行中单独扣除，整行去掉
+	report = newForgetReport(t)
+	forgetReport(t, "dave@outside.com", true, report)
+	for _, g := range report.Groups {
+		if g.Name == GroupExternal {
+			t.Errorf("外部贡献者汇总行没有去掉")
+		}
+	}
+}
+
+func TestForgetAnonymize(t *testing.T) {
+	report := newForgetReport(t)
+	f := forgetReport(t, "alice@corp.com", false, report)
+	assertForgotten(t, report, "alice@corp.com", "Alice Leaver")
+	if report.Authors[0].Email != f.Anonymous || report.Authors[0].CommitCount != 2 {
+		t.Errorf("匿名化后的个人统计行 = %+v", report.Authors[0])
+	}
+}
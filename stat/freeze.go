@@ -0,0 +1,178 @@
+package stat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FrozenAuthor 冻结时各开发者(按邮箱)的核心数据
+type FrozenAuthor struct {
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	Commits   int    `json:"commits"`
+	Added     int    `json:"added"`
+	Deleted   int    `json:"deleted"`
+	AIAdded   int    `json:"ai_added"`
+	AIDeleted int    `json:"ai_deleted"`
+	Fixes     int    `json:"fixes"`
+}
+
+// FrozenCommit 冻结时的一个提交
+type FrozenCommit struct {
+	ID      string `json:"id"`
+	Author  string `json:"author"`
+	Email   string `json:"email"`
+	Time    string `json:"time"`
+	Subject string `json:"subject"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+	AIAdded int    `json:"ai_added"`
+}
+
+// FrozenPeriod 已发布的统计周期的冻结记录，之后对同一周期的统计与之比较
+type FrozenPeriod struct {
+	Repo     string `json:"repo"`
+	Since    string `json:"since"`
+	Until    string `json:"until"`
+	RevRange string `json:"rev_range,omitempty"`
+	FrozenAt string `json:"frozen_at"`
+	Head     string `json:"head,omitempty"`
+	// 冻结时的提交(按哈希排序)及各开发者数据，Checksum 为二者的摘要
+	Commits  []FrozenCommit `json:"commits"`
+	Authors  []FrozenAuthor `json:"authors"`
+	Checksum string         `json:"checksum"`
+}
+
+// 统计周期的标识，用作冻结记录的文件名或主键
+func (f *FrozenPeriod) Key() string {
+	if f.RevRange != "" {
+		return f.RevRange
+	}
+	return f.Since + "_" + f.Until
+}
+
+// 冻结报告：只记录提交哈希和按邮箱汇总的核心数据，不受开发者过滤、分组等显示选项影响
+func FreezeReport(report *Report) *FrozenPeriod {
+	f := &FrozenPeriod{Since: report.Since, Until: report.Until, RevRange: report.RevRange, FrozenAt: time.Now().Format(time.RFC3339)}
+	if report.Meta != nil {
+		f.Repo, f.Head = report.Meta.Repo, report.Meta.Head
+	}
+	f.Commits, f.Authors = frozenContent(report.Commits)
+	f.Checksum = frozenChecksum(f.Commits, f.Authors)
+	return f
+}
+
+func frozenContent(commits []CommitStats) ([]FrozenCommit, []FrozenAuthor) {
+	frozen := make([]FrozenCommit, 0, len(commits))
+	byEmail := make(map[string]*FrozenAuthor)
+	for i := range commits {
+		c := &commits[i]
+		email := strings.ToLower(c.Email)
+		frozen = append(frozen, FrozenCommit{ID: c.ID, Author: c.Author, Email: email, Time: c.Time, Subject: c.Subject,
+			Added: c.AddedLines, Deleted: c.DeletedLines, AIAdded: c.AIAddedLines()})
+		a, ok := byEmail[email]
+		if !ok {
+			a = &FrozenAuthor{Email: email, Name: c.Author}
+			byEmail[email] = a
+		}
+		a.Commits++
+		a.Added += c.AddedLines
+		a.Deleted += c.DeletedLines
+		a.AIAdded += c.AIAddedLines()
+		a.AIDeleted += c.AIDeletedLines()
+		if c.IsFix {
+			a.Fixes++
+		}
+	}
+	sort.Slice(frozen, func(i, j int) bool { return frozen[i].ID < frozen[j].ID })
+	authors := make([]FrozenAuthor, 0, len(byEmail))
+	for _, a := range byEmail {
+		authors = append(authors, *a)
+	}
+	sort.Slice(authors, func(i, j int) bool { return authors[i].Email < authors[j].Email })
+	return frozen, authors
+}
+
+func frozenChecksum(commits []FrozenCommit, authors []FrozenAuthor) string {
+	return ReportDigest(struct {
+		Commits []FrozenCommit
+		Authors []FrozenAuthor
+	}{commits, authors})
+}
+
+// 冻结记录本身是否被改动过
+func (f *FrozenPeriod) Intact() bool {
+	return f.Checksum == frozenChecksum(f.Commits, f.Authors)
+}
+
+// 在冻结记录中查找与报告相同的统计周期
+func FindFrozen(report *Report, frozen []*FrozenPeriod) *FrozenPeriod {
+	for _, f := range frozen {
+		if f.Since == report.Since && f.Until == report.Until && f.RevRange == report.RevRange {
+			return f
+		}
+	}
+	return nil
+}
+
+// 与冻结记录比较，返回差异说明，一致时返回 nil
+func (f *FrozenPeriod) Diff(report *Report) []string {
+	var notes []string
+	if !f.Intact() {
+		notes = append(notes, "冻结记录的校验和不匹配，记录可能被改动")
+	}
+	commits, authors := frozenContent(report.Commits)
+	if frozenChecksum(commits, authors) == f.Checksum && len(notes) == 0 {
+		return nil
+	}
+
+	late, disappeared := compareFrozenCommits(f.Commits, commits)
+	if len(late) > 0 {
+		notes = append(notes, fmt.Sprintf("新增 %d 个提交(可能是晚推送或改写历史后的新哈希)", len(late)))
+	}
+	if len(disappeared) > 0 {
+		notes = append(notes, fmt.Sprintf("%d 个已冻结的提交不再出现(可能改写了历史或调整了忽略规则)", len(disappeared)))
+	}
+
+	frozenByEmail := make(map[string]FrozenAuthor, len(f.Authors))
+	for _, a := range f.Authors {
+		frozenByEmail[a.Email] = a
+	}
+	for _, a := range authors {
+		old, ok := frozenByEmail[a.Email]
+		delete(frozenByEmail, a.Email)
+		if ok && old == a {
+			continue
+		}
+		notes = append(notes, fmt.Sprintf("%s <%s>: 提交 %d→%d，添加 %d→%d，AI 添加 %d→%d", a.Name, a.Email, old.Commits, a.Commits, old.Added, a.Added, old.AIAdded, a.AIAdded))
+	}
+	for _, old := range f.Authors {
+		if _, ok := frozenByEmail[old.Email]; ok {
+			notes = append(notes, fmt.Sprintf("%s <%s>: 冻结时有 %d 次提交，现在没有", old.Name, old.Email, old.Commits))
+		}
+	}
+	return notes
+}
+
+// 比较冻结时与当前的提交，返回冻结后新出现的和不再出现的提交
+func compareFrozenCommits(frozen, current []FrozenCommit) (late, disappeared []FrozenCommit) {
+	was := make(map[string]bool, len(frozen))
+	for _, c := range frozen {
+		was[c.ID] = true
+	}
+	now := make(map[string]bool, len(current))
+	for _, c := range current {
+		now[c.ID] = true
+		if !was[c.ID] {
+			late = append(late, c)
+		}
+	}
+	for _, c := range frozen {
+		if !now[c.ID] {
+			disappeared = append(disappeared, c)
+		}
+	}
+	return late, disappeared
+}
@@ -0,0 +1,119 @@
+package stat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 名单中是否配置了出勤信息(FTE、入职或离职日期、休假)，配置后报告按出勤天数折算各项指标
+func HasAvailability(roster []RosterMember) bool {
+	for _, m := range roster {
+		if m.FTE != 0 || m.Joined != "" || m.Left != "" || len(m.Leave) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 校验名单中的出勤信息
+func ValidateAvailability(roster []RosterMember) error {
+	for _, m := range roster {
+		if m.FTE < 0 || m.FTE > 1 {
+			return fmt.Errorf("错误：名单中 '%s' 的 fte 应在 0~1 之间", m.Name)
+		}
+		for _, date := range []string{m.Joined, m.Left} {
+			if date == "" {
+				continue
+			}
+			if _, err := time.ParseInLocation(DateLayout, date, time.Local); err != nil {
+				return fmt.Errorf("错误：名单中 '%s' 的日期 '%s' 格式不正确，请使用 2006-01-02 格式", m.Name, date)
+			}
+		}
+		for _, leave := range m.Leave {
+			if _, _, err := parseLeave(leave); err != nil {
+				return fmt.Errorf("错误：名单中 '%s' 的休假 '%s' 格式不正确，请使用 2006-01-02 或 2006-01-02~2006-01-05 格式", m.Name, leave)
+			}
+		}
+	}
+	return nil
+}
+
+// 解析休假日期，单日或以 ~ 分隔的起止日期(含两端)
+func parseLeave(s string) (time.Time, time.Time, error) {
+	from, to, ok := strings.Cut(s, "~")
+	start, err := time.ParseInLocation(DateLayout, strings.TrimSpace(from), time.Local)
+	if err != nil || !ok {
+		return start, start, err
+	}
+	end, err := time.ParseInLocation(DateLayout, strings.TrimSpace(to), time.Local)
+	if err == nil && end.Before(start) {
+		err = fmt.Errorf("结束日期早于开始日期")
+	}
+	return start, end, err
+}
+
+// 统计周期内(含起止日期)的出勤天数：工作日中去掉入职前、离职后及休假的日期，再乘以 FTE(未配置时为 1)
+func ActiveDays(m RosterMember, since, until time.Time) float64 {
+	if m.Joined != "" {
+		if joined, err := time.ParseInLocation(DateLayout, m.Joined, time.Local); err == nil && joined.After(since) {
+			since = joined
+		}
+	}
+	if m.Left != "" {
+		if left, err := time.ParseInLocation(DateLayout, m.Left, time.Local); err == nil && left.Before(until) {
+			until = left
+		}
+	}
+	onLeave := func(day time.Time) bool {
+		for _, leave := range m.Leave {
+			start, end, err := parseLeave(leave)
+			if err == nil && !day.Before(start) && !day.After(end) {
+				return true
+			}
+		}
+		return false
+	}
+	days := 0
+	for day := since; !day.After(until); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday && !onLeave(day) {
+			days++
+		}
+	}
+	fte := m.FTE
+	if fte == 0 {
+		fte = 1
+	}
+	return float64(days) * fte
+}
+
+// 按名单中的出勤信息设置各开发者本期的出勤天数，不在名单中的开发者按全职计算；
+// 按提交范围统计(没有起止日期)时不设置
+func ApplyActiveDays(rows []*AuthorStats, roster []RosterMember, since, until string) {
+	start, err := time.ParseInLocation(DateLayout, PeriodDay(since), time.Local)
+	if err != nil {
+		return
+	}
+	end, err := time.ParseInLocation(DateLayout, PeriodDay(until), time.Local)
+	if err != nil {
+		return
+	}
+	members := make(map[string]RosterMember, len(roster))
+	for _, m := range roster {
+		members[strings.ToLower(m.Email)] = m
+	}
+	for _, s := range rows {
+		if s.MemberCount > 0 {
+			continue
+		}
+		s.ActiveDays = ActiveDays(members[strings.ToLower(s.Email)], start, end)
+	}
+}
+
+// 按出勤天数折算的每天平均值，没有出勤天数时为 0
+func (s *AuthorStats) PerActiveDay(n int) float64 {
+	if s.ActiveDays <= 0 {
+		return 0
+	}
+	return float64(n) / s.ActiveDays
+}
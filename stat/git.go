@@ -0,0 +1,77 @@
+package stat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// GitRunner 执行 git 命令并返回标准输出，便于在测试或下游代码中替换为假实现
+type GitRunner interface {
+	Run(args []string) (io.Reader, error)
+}
+
+// ExecGitRunner 调用本地 git 可执行文件
+type ExecGitRunner struct {
+	// 仓库目录，为空时使用当前目录
+	Dir string
+	// 单个 git 命令的超时时间，0 表示不限制
+	Timeout time.Duration
+	// 取消时中止正在执行的 git 命令，为 nil 时不取消
+	Context context.Context
+}
+
+// 运行 Git 命令
+func (r *ExecGitRunner) Run(args []string) (io.Reader, error) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("执行 git %s 时出错: %w", args[0], ErrInterrupted)
+	}
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.Dir
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, fmt.Errorf("执行 git %s 时出错: %w", args[0], ErrInterrupted)
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("执行 git %s 时出错: %w (%s)", args[0], ErrGitTimeout, r.Timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "not a git repository") {
+			return nil, fmt.Errorf("执行 git 命令时出错: %w: %s", ErrNotARepo, msg)
+		}
+		if msg != "" {
+			return nil, fmt.Errorf("执行 git 命令时出错: %v: %s", err, msg)
+		}
+		return nil, fmt.Errorf("执行 git 命令时出错: %v", err)
+	}
+	return &out, nil
+}
+
+// 中断后仍需执行的少量 git 命令(如读取仓库名称)使用不会被取消的副本，
+// 使部分结果的报告仍能说明数据来源
+func uncancelled(git GitRunner) GitRunner {
+	if r, ok := git.(*ExecGitRunner); ok && r.Context != nil {
+		c := *r
+		c.Context = nil
+		return &c
+	}
+	return git
+}
@@ -0,0 +1,260 @@
+package stat
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// HTML 报告中内嵌提交图的最大提交数，超过时只提供导出的 DOT/Mermaid 文件
+const graphHTMLLimit = 300
+
+// 提交图的导出格式
+const (
+	GraphDOT     = "dot"
+	GraphMermaid = "mermaid"
+)
+
+// GraphNode 提交图中的一个提交
+type GraphNode struct {
+	ID      string   `json:"id" yaml:"id"`
+	Parents []string `json:"parents,omitempty" yaml:"parents,omitempty"`
+	Subject string   `json:"subject" yaml:"subject"`
+	Author  string   `json:"author" yaml:"author"`
+	// 计入统计的提交的 AIG 比例及策略标记，合并提交及被排除的提交 Counted 为假
+	AIGRatio  float64 `json:"aig_ratio" yaml:"aig_ratio"`
+	AIGPolicy string  `json:"aig_policy,omitempty" yaml:"aig_policy,omitempty"`
+	Merge     bool    `json:"merge,omitempty" yaml:"merge,omitempty"`
+	Counted   bool    `json:"counted" yaml:"counted"`
+}
+
+// CommitGraph 统计周期内的提交拓扑(含合并提交)，按 git log --topo-order 从新到旧排列，
+// 只保留两端都在周期内的父子关系
+type CommitGraph struct {
+	Nodes []GraphNode `json:"nodes" yaml:"nodes"`
+}
+
+// 读取与统计相同范围内的提交拓扑，用已分析的提交标注 AIG 比例
+func BuildCommitGraph(git GitRunner, q LogQuery, commits []CommitStats) (*CommitGraph, error) {
+	args := []string{"log", "--topo-order", "--format=%H%x1f%P%x1f%an%x1f%s"}
+	for _, arg := range revisionArgs(q) {
+		if arg != "--no-merges" {
+			args = append(args, arg)
+		}
+	}
+	out, err := git.Run(args)
+	if err != nil {
+		return nil, err
+	}
+	analyzed := make(map[string]*CommitStats, len(commits))
+	for i := range commits {
+		analyzed[commits[i].ID] = &commits[i]
+	}
+
+	g := &CommitGraph{Nodes: []GraphNode{}}
+	inGraph := make(map[string]bool)
+	var parents [][]string
+	for _, line := range strings.Split(gitText(out), "\n") {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		n := GraphNode{ID: fields[0], Author: fields[2], Subject: fields[3]}
+		ps := strings.Fields(fields[1])
+		n.Merge = len(ps) > 1
+		if c, ok := analyzed[n.ID]; ok {
+			n.Counted, n.AIGRatio, n.AIGPolicy = true, c.AIGRatio, c.AIGPolicy
+		}
+		g.Nodes = append(g.Nodes, n)
+		parents = append(parents, ps)
+		inGraph[n.ID] = true
+	}
+	for i, ps := range parents {
+		for _, p := range ps {
+			if inGraph[p] {
+				g.Nodes[i].Parents = append(g.Nodes[i].Parents, p)
+			}
+		}
+	}
+	return g, nil
+}
+
+// 按 AIG 比例分档的颜色，未计入统计的提交为灰色
+func graphColor(n *GraphNode) string {
+	switch {
+	case !n.Counted || n.AIGPolicy != "":
+		return "#bdbdbd"
+	case n.AIGRatio >= 0.75:
+		return "#0d47a1"
+	case n.AIGRatio >= 0.5:
+		return "#1976d2"
+	case n.AIGRatio >= 0.25:
+		return "#64b5f6"
+	case n.AIGRatio > 0:
+		return "#bbdefb"
+	}
+	return "#ffffff"
+}
+
+// 深色节点使用白色文字
+func graphFontColor(n *GraphNode) string {
+	if n.Counted && n.AIGPolicy == "" && n.AIGRatio >= 0.5 {
+		return "#ffffff"
+	}
+	return "#000000"
+}
+
+// 节点的说明文字
+func graphLabel(n *GraphNode) string {
+	label := shortID(n.ID) + " " + n.Subject
+	switch {
+	case n.Merge:
+		label += " (合并)"
+	case !n.Counted:
+		label += " (未计入)"
+	case n.AIGPolicy != "":
+		label += " (AIG " + n.AIGPolicy + ")"
+	default:
+		label += fmt.Sprintf(" (AIG %.2f)", n.AIGRatio)
+	}
+	return label
+}
+
+// 按格式导出提交图
+func (g *CommitGraph) Write(w io.Writer, format string) error {
+	switch format {
+	case GraphDOT:
+		return g.WriteDOT(w)
+	case GraphMermaid:
+		return g.WriteMermaid(w)
+	}
+	return fmt.Errorf("错误：不支持的提交图格式 '%s'，可选: %s, %s", format, GraphDOT, GraphMermaid)
+}
+
+// 导出 Graphviz DOT，子提交指向父提交，颜色越深 AIG 比例越高
+func (g *CommitGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph commits {\n")
+	b.WriteString("  rankdir=BT;\n  node [shape=box, style=\"rounded,filled\", fontname=\"sans-serif\", fontsize=10];\n")
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		fmt.Fprintf(&b, "  %q [label=%q, fillcolor=%q, fontcolor=%q, tooltip=%q];\n", shortID(n.ID), graphLabel(n), graphColor(n), graphFontColor(n), n.Author)
+	}
+	for _, n := range g.Nodes {
+		for _, p := range n.Parents {
+			fmt.Fprintf(&b, "  %q -> %q;\n", shortID(p), shortID(n.ID))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// 导出 Mermaid 流程图，可以直接放入支持 Mermaid 的 Markdown 文档
+func (g *CommitGraph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("flowchart BT\n")
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		label := strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(graphLabel(n))
+		fmt.Fprintf(&b, "  c%s[\"%s\"]\n", shortID(n.ID), label)
+		fmt.Fprintf(&b, "  style c%s fill:%s,color:%s\n", shortID(n.ID), graphColor(n), graphFontColor(n))
+	}
+	for _, n := range g.Nodes {
+		for _, p := range n.Parents {
+			fmt.Fprintf(&b, "  c%s --> c%s\n", shortID(p), shortID(n.ID))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// 按 git log --graph 的方式分配泳道：每条泳道等待一个提交，提交占用等待它的泳道，
+// 第一个父提交沿用该泳道，其余父提交另开泳道
+func (g *CommitGraph) lanes() (map[string]int, int) {
+	lanes := make(map[string]int, len(g.Nodes))
+	var active []string
+	index := func(id string) int {
+		for i, v := range active {
+			if v == id {
+				return i
+			}
+		}
+		return -1
+	}
+	place := func(id string) int {
+		if i := index(""); i >= 0 {
+			active[i] = id
+			return i
+		}
+		active = append(active, id)
+		return len(active) - 1
+	}
+	width := 0
+	for _, n := range g.Nodes {
+		lane := index(n.ID)
+		if lane < 0 {
+			lane = place(n.ID)
+		}
+		// 其他泳道也在等待该提交时合并到当前泳道
+		for i, v := range active {
+			if v == n.ID && i != lane {
+				active[i] = ""
+			}
+		}
+		lanes[n.ID] = lane
+		active[lane] = ""
+		for j, p := range n.Parents {
+			i := index(p)
+			switch {
+			case j == 0 && (i < 0 || i > lane):
+				// 第一个父提交收拢到靠左的泳道
+				if i >= 0 {
+					active[i] = ""
+				}
+				active[lane] = p
+			case i < 0:
+				place(p)
+			}
+		}
+		if len(active) > width {
+			width = len(active)
+		}
+	}
+	return lanes, width
+}
+
+// HTML 报告中的提交图(SVG)，提交过多时返回说明文字
+func (g *CommitGraph) SVG() template.HTML {
+	if len(g.Nodes) > graphHTMLLimit {
+		return template.HTML(fmt.Sprintf("<p>本期共 %d 个提交，超过 %d 个时不在报告中绘制，请使用 --commit-graph 导出的 DOT 或 Mermaid 文件</p>", len(g.Nodes), graphHTMLLimit))
+	}
+	const rowHeight, laneWidth = 20.0, 14.0
+	lanes, width := g.lanes()
+	rows := make(map[string]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		rows[n.ID] = i
+	}
+	pos := func(id string) (float64, float64) {
+		return float64(lanes[id])*laneWidth + 10, float64(rows[id])*rowHeight + 12
+	}
+	textX := float64(width)*laneWidth + 16
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg width="%.0f" height="%.0f" xmlns="http://www.w3.org/2000/svg" font-size="12">`, textX+640, float64(len(g.Nodes))*rowHeight+8)
+	for _, n := range g.Nodes {
+		x1, y1 := pos(n.ID)
+		for _, p := range n.Parents {
+			x2, y2 := pos(p)
+			fmt.Fprintf(&b, `<path d="M%.0f %.0f L%.0f %.0f L%.0f %.0f" stroke="#90a4ae" fill="none"/>`, x1, y1, x2, y1+rowHeight/2, x2, y2)
+		}
+	}
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		x, y := pos(n.ID)
+		fmt.Fprintf(&b, `<circle cx="%.0f" cy="%.0f" r="5" fill="%s" stroke="#455a64"><title>%s</title></circle>`, x, y, graphColor(n), template.HTMLEscapeString(n.Author))
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.0f">%s</text>`, textX, y+4, template.HTMLEscapeString(graphLabel(n)))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
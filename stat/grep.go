@@ -0,0 +1,48 @@
+package stat
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// 编译提交信息过滤的正则表达式
+func compileGrep(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("错误：提交信息过滤的正则表达式 '%s' 无效: %v", pattern, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// 按解析后的完整提交信息再次过滤。git 的 --grep 只作用于提交信息原文，
+// 再次过滤保证 git 与 Go 正则语法的细微差异不会让不符合条件的提交混入统计
+func filterGrep(commits []CommitStats, q LogQuery) ([]CommitStats, error) {
+	if len(q.Grep) == 0 {
+		return commits, nil
+	}
+	res, err := compileGrep(q.Grep)
+	if err != nil {
+		return nil, err
+	}
+	kept := commits[:0]
+	for _, c := range commits {
+		if matchAny(res, c.Message) != q.InvertGrep {
+			kept = append(kept, c)
+		}
+	}
+	return kept, nil
+}
+
+// 判断文本是否匹配任意一个正则
+func matchAny(res []*regexp.Regexp, text string) bool {
+	for _, re := range res {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
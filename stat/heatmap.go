@@ -0,0 +1,127 @@
+package stat
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// 热力图的行标题，周一开始
+var heatmapWeekdays = [7]string{"周一", "周二", "周三", "周四", "周五", "周六", "周日"}
+
+// 终端中按提交数由少到多显示的字符
+var heatmapBlocks = []string{"·", "░", "▒", "▓", "█"}
+
+// Heatmap 按星期和小时统计的提交次数，时间为提交者本地时间
+type Heatmap struct {
+	Name  string `json:"name" yaml:"name"`
+	Email string `json:"email,omitempty" yaml:"email,omitempty"`
+	// Counts[星期][小时]，星期从周一开始
+	Counts [7][24]int `json:"counts" yaml:"counts"`
+	Total  int        `json:"total" yaml:"total"`
+}
+
+// 生成全体及各开发者的提交时间热力图，开发者按 authors 的顺序排列，按 identity 对应提交
+func BuildHeatmaps(commits []CommitStats, authors []*AuthorStats, identity string) []*Heatmap {
+	team := &Heatmap{Name: "全体"}
+	byKey := make(map[string]*Heatmap)
+	heatmaps := []*Heatmap{team}
+	for _, a := range authors {
+		if a.MemberCount > 0 {
+			continue
+		}
+		h := &Heatmap{Name: a.Name, Email: a.Email}
+		byKey[IdentityKey(identity, a.Name, a.Email)] = h
+		heatmaps = append(heatmaps, h)
+	}
+
+	for i := range commits {
+		t, err := CommitTime(&commits[i])
+		if err != nil {
+			continue
+		}
+		day := (int(t.Weekday()) + 6) % 7
+		team.add(day, t.Hour())
+		if h, ok := byKey[IdentityKey(identity, commits[i].Author, commits[i].Email)]; ok {
+			h.add(day, t.Hour())
+		}
+	}
+	return heatmaps
+}
+
+// 记录一次提交
+func (h *Heatmap) add(day, hour int) {
+	h.Counts[day][hour]++
+	h.Total++
+}
+
+// 最大的单格提交数
+func (h *Heatmap) Max() int {
+	most := 0
+	for _, row := range h.Counts {
+		for _, n := range row {
+			if n > most {
+				most = n
+			}
+		}
+	}
+	return most
+}
+
+// 提交数相对最大值的等级，0 表示没有提交
+func (h *Heatmap) level(n, levels int) int {
+	if n == 0 {
+		return 0
+	}
+	level := 1 + (n*(levels-1)-1)/h.Max()
+	if level >= levels {
+		level = levels - 1
+	}
+	return level
+}
+
+// 在终端中以字符块输出热力图
+func PrintHeatmap(w io.Writer, h *Heatmap) {
+	title := h.Name
+	if h.Email != "" {
+		title += " <" + h.Email + ">"
+	}
+	fmt.Fprintf(w, "\n  提交时间分布 %s (共 %d 次，最多 %d 次/格):\n", title, h.Total, h.Max())
+	fmt.Fprintf(w, "        ")
+	for hour := 0; hour < 24; hour += 3 {
+		fmt.Fprintf(w, "%-6d", hour)
+	}
+	fmt.Fprintln(w)
+	for day, row := range h.Counts {
+		fmt.Fprintf(w, "    %s", heatmapWeekdays[day])
+		for _, n := range row {
+			block := heatmapBlocks[h.level(n, len(heatmapBlocks))]
+			fmt.Fprintf(w, "%s%s", block, block)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// 以 HTML 表格输出热力图，颜色深浅表示提交数
+func (h *Heatmap) HTML() template.HTML {
+	var b strings.Builder
+	b.WriteString(`<table class="heatmap"><tr><th></th>`)
+	for hour := 0; hour < 24; hour++ {
+		fmt.Fprintf(&b, "<th>%d</th>", hour)
+	}
+	b.WriteString("</tr>\n")
+	for day, row := range h.Counts {
+		fmt.Fprintf(&b, "<tr><th>%s</th>", heatmapWeekdays[day])
+		for hour, n := range row {
+			alpha := 0.0
+			if most := h.Max(); most > 0 {
+				alpha = float64(n) / float64(most)
+			}
+			fmt.Fprintf(&b, `<td style="background: rgba(25, 118, 210, %.2f)" title="%s %d 时: %d 次"></td>`, alpha, heatmapWeekdays[day], hour, n)
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</table>")
+	return template.HTML(b.String())
+}
@@ -0,0 +1,97 @@
+package stat
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 少于该行数的提交不做启发式评分，样本太小没有参考意义
+const heuristicMinLines = 5
+
+var (
+	// 单行注释或块注释中的行
+	commentLineRegex = regexp.MustCompile(`^\s*(//|#|/\*|\*|<!--|--)`)
+	// 文档注释风格：参数、返回值说明等
+	docStyleRegex = regexp.MustCompile(`(?i)^\s*(//|#|\*|/\*\*|""")\s*(@param|@returns?|@throws|args:|returns:|raises:|parameters:|参数|返回值?[:：])`)
+	// 函数定义
+	funcDeclRegex = regexp.MustCompile(`^\s*(func |def |function |(public|private|protected|static|async)\s+[\w<>\[\]]+\s*\w*\s*\(|(export\s+)?(const|let)\s+\w+\s*=\s*(async\s*)?\()`)
+	// AI 生成代码中常见的注释措辞
+	aiPhraseRegex = regexp.MustCompile(`(?i)(this function|helper function|example usage|in a real (app|application|implementation)|you (can|may|might) (also )?want to|note that|for simplicity|make sure to|here we|let's |✅|🚀|首先|然后我们|这个函数用于|示例用法)`)
+)
+
+// 对提交的 diff 做 AI 风格的启发式评分，结果仅供参考
+// 不访问网络，只根据新增代码的注释密度、文档注释风格、重复的样板代码和常见措辞估算 AI 生成的可能性
+func (a *Analyzer) scoreHeuristics(patches map[string]string, commits []CommitStats) {
+	for i := range commits {
+		commits[i].HeuristicScore = HeuristicScore(a.addedLines(patches[commits[i].ID]))
+	}
+}
+
+// 取出 diff 中符合统计条件的文件的新增行
+func (a *Analyzer) addedLines(patch string) []string {
+	var lines []string
+	valid := false
+	for _, line := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			valid = false
+		case strings.HasPrefix(line, "+++ "):
+			valid = a.countsFile(diffHeaderPath(line))
+		case strings.HasPrefix(line, "+") && valid:
+			lines = append(lines, line[1:])
+		}
+	}
+	return lines
+}
+
+// 根据新增代码行估算 AI 生成的可能性(0~1)
+func HeuristicScore(lines []string) float64 {
+	total, comments, docs, funcs, phrases, duplicates := 0, 0, 0, 0, 0, 0
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		total++
+		if commentLineRegex.MatchString(line) {
+			comments++
+		}
+		if docStyleRegex.MatchString(line) {
+			docs++
+		}
+		if funcDeclRegex.MatchString(line) {
+			funcs++
+		}
+		if aiPhraseRegex.MatchString(line) {
+			phrases++
+		}
+		// 只统计有实际内容的重复行，忽略括号等
+		if len(trimmed) > 3 {
+			if seen[trimmed] {
+				duplicates++
+			}
+			seen[trimmed] = true
+		}
+	}
+	if total < heuristicMinLines {
+		return 0
+	}
+
+	commentScore := capScore(float64(comments) / float64(total) / 0.3)
+	docScore := 0.0
+	if funcs > 0 {
+		docScore = capScore(float64(docs) / float64(funcs))
+	}
+	boilerplateScore := capScore(float64(duplicates) / float64(total) / 0.3)
+	phraseScore := capScore(float64(phrases) / 3)
+	return 0.3*commentScore + 0.25*docScore + 0.25*boilerplateScore + 0.2*phraseScore
+}
+
+// 限制在 0~1 之间
+func capScore(v float64) float64 {
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,263 @@
+package stat
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"withSample": func(s *AuthorStats, meta *Metadata) htmlRow {
+		row := htmlRow{AuthorStats: s, Format: meta.NumberFormat()}
+		if meta != nil {
+			row.MinSample, row.MinSampleLines = meta.Filters.MinSample, meta.Filters.MinSampleLines
+			row.SeverityOrder = meta.Filters.FixSeverities
+		}
+		return row
+	},
+	"withLevel": func(r *OrgRollup, row htmlRow) htmlRow {
+		if r.Name != OrgUnassigned {
+			row.Level = OrgLevelLabel(r.Level)
+		}
+		return row
+	},
+	"ratio":       FormatSampleRatio,
+	"fixNote":     FixRatioNote,
+	"formatHours": FormatHours,
+	"ownershipGroups": func(o *Ownership) []OwnershipGroup {
+		return []OwnershipGroup{o.AIHeavy, o.Other}
+	},
+	"mean":         FormatSampleMean,
+	"weekScheme":   weekSchemeName,
+	"targetMetric": TargetMetricLabel,
+	"targetStatus": TargetStatusLabel,
+	"chart":        authorChart,
+	"fixSeverities": func(row htmlRow) string {
+		return strings.Join(FixSeverityLines(row.FixSeverities, row.SeverityOrder, row.Format), "\n")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Format.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>AI 代码贡献统计 {{with .RevRange}}{{.}}{{else}}{{.Since}} ~ {{.Until}}{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; margin: 24px; color: #222; }
+table { border-collapse: collapse; margin-top: 12px; }
+th, td { border: 1px solid #ccc; padding: 6px 10px; text-align: right; }
+th { background: #f3f3f3; }
+td.name { text-align: left; }
+tr.group td { background: #fafafa; font-style: italic; }
+td.warn { background: #fdecea; color: #b71c1c; }
+table.heatmap th, table.heatmap td { padding: 2px; font-size: 11px; font-weight: normal; }
+table.heatmap td { width: 18px; height: 18px; border-color: #eee; }
+div.progress { width: 200px; height: 12px; background: #eee; display: inline-block; vertical-align: middle; }
+div.progress div { height: 100%; background: #1976d2; }
+div.progress.achieved div { background: #2e7d32; }
+div.progress.missed div { background: #c62828; }
+@page { size: A4 landscape; margin: 12mm; }
+@media print {
+  body { margin: 0; font-size: 10px; }
+  tr { page-break-inside: avoid; }
+  thead { display: table-header-group; }
+}
+</style>
+</head>
+<body>
+<h1>AI 代码贡献统计</h1>
+<p>{{with .RevRange}}提交范围: {{.}}{{else}}统计周期: {{.Since}} ~ {{.Until}}{{end}}，共 {{.Format.Int (len .Commits)}} 次提交</p>
+{{with .Meta}}<details{{if $.Print}} open{{end}}>
+<summary>运行信息</summary>
+<ul>
+{{range .Lines}}<li>{{.}}</li>
+{{end}}</ul>
+</details>{{end}}
+{{with .Targets}}<h2>目标进度</h2>
+<table>
+<thead><tr><th>目标</th><th>范围</th><th>指标</th><th>截止日期</th><th>进度</th><th>实际 / 目标</th><th>差距</th><th>状态</th></tr></thead>
+{{range .}}<tr>
+<td class="name">{{.Name}}</td><td class="name">{{with .Team}}团队 {{.}}{{else}}全体{{end}}</td><td class="name">{{targetMetric .Metric}}</td><td>{{.Deadline}}</td>
+<td><div class="progress {{.Status}}"><div style="width: {{printf "%.0f" .Progress}}%"></div></div></td>
+<td>{{printf "%.2f%%" .Actual}} / {{printf "%.2f%%" .Target}}</td><td>{{if .Gap}}{{printf "%.2f" .Gap}} 个百分点{{else}}-{{end}}</td><td>{{targetStatus .Status}}</td>
+</tr>
+{{end}}</table>
+{{end}}{{with .Org}}<h2>组织汇总</h2>
+<table>
+<thead><tr>
+<th>单位</th><th>层级</th><th>提交次数</th>
+<th>总代码添加</th><th>总代码删除</th>
+<th>AI贡献添加</th><th>AI添加占比</th><th title="每个提交权重相同，不按行数加权">按提交平均AI占比</th>
+<th>AI贡献删除</th><th>AI删除占比</th>
+<th>总修复提交</th><th>AI参与修复</th><th>AI修复贡献率</th>
+<th>工具采纳行数</th><th>工具采纳占比</th>
+<th>往期每期添加</th><th>往期AI添加占比</th>
+<th title="按 FTE、入职离职及休假折算">出勤天数</th><th>每天添加</th><th>每天AI添加</th>
+</tr></thead>
+{{range .}}{{template "row" withSample .Stats $.Meta | withLevel .}}{{end}}
+</table>
+{{end}}{{with .BugIntroduction}}<h2>缺陷引入 (SZZ)</h2>
+<p>扫描本期开始至今的 {{$.Format.Int .FixCommits}} 个修复提交，本期有 {{$.Format.Int .BuggyCommits}} 个提交的代码之后被修复提交修改</p>
+<table>
+<thead><tr><th></th><th>引入缺陷</th><th>添加行数</th><th>每千行缺陷数</th></tr></thead>
+<tr><td class="name">AI 代码</td><td>{{$.Format.Float .AIBugs 1}}</td><td>{{$.Format.LineValue .AILines}}</td><td>{{$.Format.Float .AIPerKLoC 2}}</td></tr>
+<tr><td class="name">人工代码</td><td>{{$.Format.Float .HumanBugs 1}}</td><td>{{$.Format.LineValue .HumanLines}}</td><td>{{$.Format.Float .HumanPerKLoC 2}}</td></tr>
+</table>
+{{end}}{{with .TimeToFix}}<h2>修复耗时</h2>
+<p>本期 {{$.Format.Int .Fixes}} 个修复提交中 {{$.Format.Int .Measured}} 个引用了更早的提交或 issue，以被引用提交的作者时间或 issue 的创建时间为起点{{with .IssueError}}；无法读取 issue 创建时间: {{.}}{{end}}</p>
+<table>
+<thead><tr><th></th><th>修复提交</th><th>耗时中位数</th></tr></thead>
+<tr><td class="name">AI 参与修复</td><td>{{$.Format.Int .AI.Fixes}}</td><td>{{if .AI.Fixes}}{{formatHours .AI.MedianHours $.Format}}{{else}}-{{end}}</td></tr>
+<tr><td class="name">人工修复</td><td>{{$.Format.Int .Human.Fixes}}</td><td>{{if .Human.Fixes}}{{formatHours .Human.MedianHours $.Format}}{{else}}-{{end}}</td></tr>
+</table>
+{{end}}{{with .Effort}}<h2>AI 节省工时估算</h2>
+<p>{{.Model}} 模型，{{.Parameters}}</p>
+<table>
+<thead><tr><th>开发者</th><th>AI 添加行数</th><th>节省工时(小时)</th></tr></thead>
+<tr class="group"><td class="name">全体</td><td>{{$.Format.LineValue .AILines}}</td><td>{{$.Format.Float .SavedHours 1}}</td></tr>
+{{range .Authors}}<tr><td class="name">{{.Name}}</td><td>{{$.Format.LineValue .AILines}}</td><td>{{$.Format.Float .SavedHours 1}}</td></tr>
+{{end}}</table>
+<p class="caveat">注意: {{.Caveat}}</p>
+{{end}}{{with .Duration}}<h2>编码时长估算</h2>
+<p>提交间隔超过 {{.SessionGapMinutes}} 分钟为新的工作时段，时段第一个提交计 {{.FirstCommitMinutes}} 分钟</p>
+<table>
+<thead><tr><th></th><th>提交</th><th>工作时段</th><th>时长(小时)</th><th>添加行数</th><th>每小时行数</th><th>单个提交中位数</th></tr></thead>
+{{with .Total}}<tr class="group"><td class="name">{{.Name}}</td><td>{{$.Format.Int .Commits}}</td><td>{{$.Format.Int .Sessions}}</td><td>{{$.Format.Float .Hours 1}}</td><td>{{$.Format.LineValue .AddedLines}}</td><td>{{if .AddedLines}}{{$.Format.Float .LinesPerHour 1}}{{else}}-{{end}}</td><td>{{formatHours .MedianHours $.Format}}</td></tr>
+{{end}}{{range .Authors}}<tr><td class="name">{{.Name}}</td><td>{{$.Format.Int .Commits}}</td><td>{{$.Format.Int .Sessions}}</td><td>{{$.Format.Float .Hours 1}}</td><td>{{$.Format.LineValue .AddedLines}}</td><td>{{if .AddedLines}}{{$.Format.Float .LinesPerHour 1}}{{else}}-{{end}}</td><td>{{formatHours .MedianHours $.Format}}</td></tr>
+{{end}}{{range .Cohorts}}<tr class="group"><td class="name">{{.Name}}</td><td>{{$.Format.Int .Commits}}</td><td>-</td><td>{{$.Format.Float .Hours 1}}</td><td>{{$.Format.LineValue .AddedLines}}</td><td>{{if .AddedLines}}{{$.Format.Float .LinesPerHour 1}}{{else}}-{{end}}</td><td>{{formatHours .MedianHours $.Format}}</td></tr>
+{{end}}</table>
+<p class="caveat">注意: {{.Caveat}}</p>
+{{end}}{{with .Costs}}<h2>AI 工具费用</h2>
+<p>{{.From}} ~ {{.To}}，各月费用按天数分摊{{with .Currency}}，货币 {{.}}{{end}}</p>
+<table>
+<thead><tr><th>用户</th><th>费用</th><th>AI 添加行数</th><th>AI 参与修复</th><th>每行 AI 代码</th><th>每次 AI 参与修复</th></tr></thead>
+<tr class="group"><td class="name">合计</td><td>{{$.Format.Float .Cost 2}}</td><td>{{$.Format.LineValue .AILines}}</td><td>{{$.Format.Int .AIFixes}}</td><td>{{if .AILines}}{{$.Format.Float .PerAILine 4}}{{else}}-{{end}}</td><td>{{if .AIFixes}}{{$.Format.Float .PerAIFix 4}}{{else}}-{{end}}</td></tr>
+{{range .Users}}<tr><td class="name">{{with .Name}}{{.}} {{end}}&lt;{{.Email}}&gt;</td><td>{{$.Format.Float .Cost 2}}</td><td>{{$.Format.LineValue .AILines}}</td><td>{{$.Format.Int .AIFixes}}</td><td>{{if .AILines}}{{$.Format.Float .PerAILine 4}}{{else}}-{{end}}</td><td>{{if .AIFixes}}{{$.Format.Float .PerAIFix 4}}{{else}}-{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{with .Lifecycle}}<h2>文件生命周期</h2>
+<p>新建 {{$.Format.Int .Created}} 个文件，删除 {{$.Format.Int .Deleted}} 个，修改 {{$.Format.Int .Modified}} 次；净新增 {{$.Format.Int .NetNew}} 个，其中新建时有 AI 参与 {{$.Format.Int .NetNewAI}} 个</p>
+{{with .AIFiles}}<table>
+<thead><tr><th>文件</th><th>作者</th><th>新建提交</th><th>AIG</th><th>添加行数</th><th>AI 占比</th></tr></thead>
+{{range .}}<tr><td class="name">{{.Path}}</td><td class="name">{{.Author}}</td><td class="name">{{.Commit}}</td><td>{{printf "%.2f" .AIGRatio}}</td><td>{{$.Format.LineValue .Added}}</td><td>{{printf "%.2f%%" .AIShare}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}{{with .Ownership}}<h2>目录变更集中程度</h2>
+<p>按前 {{.Depth}} 级目录统计，AI 添加占比不低于 {{.Threshold}}% 为 AI 编写为主；巴士因子为变更行数合计超过一半所需的最少开发者人数</p>
+<table>
+<thead><tr><th></th><th>目录数</th><th>巴士因子为 1</th><th>巴士因子中位数</th><th>最大贡献者占比中位数</th></tr></thead>
+{{range (ownershipGroups .)}}<tr class="group"><td class="name">{{.Name}}</td><td>{{$.Format.Int .Dirs}}</td><td>{{$.Format.Int .SingleOwner}}</td><td>{{if .Dirs}}{{$.Format.Float .MedianBusFactor 1}}{{else}}-{{end}}</td><td>{{if .Dirs}}{{printf "%.2f%%" .MedianTopShare}}{{else}}-{{end}}</td></tr>
+{{end}}</table>
+<table>
+<thead><tr><th>目录</th><th>变更行数</th><th>开发者</th><th>巴士因子</th><th>最大贡献者</th><th>占比</th><th>AI 添加占比</th></tr></thead>
+{{range .Dirs}}<tr><td class="name">{{.Dir}}</td><td>{{$.Format.LineValue .ChangedLines}}</td><td>{{$.Format.Int .Authors}}</td><td>{{.BusFactor}}</td><td class="name">{{.TopAuthor}}</td><td>{{printf "%.2f%%" .TopShare}}</td><td>{{if .AddedLines}}{{printf "%.2f%%" .AIRatio}}{{else}}-{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{with .Reviews}}<h2>代码评审</h2>
+<p>{{.Provider}} {{.Project}}，本期合并 {{$.Format.Int (len .PullRequests)}} 个 PR{{with .Unmatched}}，其中 {{$.Format.Int .}} 个未关联到本期提交，不参与比较{{end}}</p>
+<table>
+<thead><tr><th></th><th>PR 数</th><th>平均评论</th><th>平均批准</th><th>合并耗时中位数(小时)</th></tr></thead>
+<tr><td class="name">有 AI 参与</td><td>{{$.Format.Int .AI.PullRequests}}</td><td>{{$.Format.Float .AI.AvgComments 1}}</td><td>{{$.Format.Float .AI.AvgApprovals 1}}</td><td>{{$.Format.Float .AI.MedianHoursToMerge 1}}</td></tr>
+<tr><td class="name">无 AI 参与</td><td>{{$.Format.Int .Human.PullRequests}}</td><td>{{$.Format.Float .Human.AvgComments 1}}</td><td>{{$.Format.Float .Human.AvgApprovals 1}}</td><td>{{$.Format.Float .Human.MedianHoursToMerge 1}}</td></tr>
+</table>
+{{end}}{{with .Anomalies}}<h2>异常标注</h2>
+<ul class="anomalies">
+{{range .}}<li>{{.Message}}</li>
+{{end}}</ul>
+{{end}}{{with .Authors}}<h2>AI 添加占比</h2>
+{{chart .}}{{end}}
+<table>
+<thead><tr>
+<th>开发者</th><th>邮箱</th><th>提交次数</th>
+<th>总代码添加</th><th>总代码删除</th>
+<th>AI贡献添加</th><th>AI添加占比</th><th title="每个提交权重相同，不按行数加权">按提交平均AI占比</th>
+<th>AI贡献删除</th><th>AI删除占比</th>
+<th>总修复提交</th><th>AI参与修复</th><th>AI修复贡献率</th>
+<th>工具采纳行数</th><th>工具采纳占比</th>
+<th>往期每期添加</th><th>往期AI添加占比</th>
+<th title="按 FTE、入职离职及休假折算">出勤天数</th><th>每天添加</th><th>每天AI添加</th>
+</tr></thead>
+{{range .Authors}}{{template "row" withSample . $.Meta}}{{end}}
+{{range .Groups}}{{template "row" withSample . $.Meta}}{{end}}
+</table>
+{{with .Cohorts}}<h2>按 AIG 标记分组</h2>
+<table>
+<thead><tr>
+<th>分组</th><th></th><th>提交次数</th>
+<th>总代码添加</th><th>总代码删除</th>
+<th>AI贡献添加</th><th>AI添加占比</th><th title="每个提交权重相同，不按行数加权">按提交平均AI占比</th>
+<th>AI贡献删除</th><th>AI删除占比</th>
+<th>总修复提交</th><th>AI参与修复</th><th>AI修复贡献率</th>
+<th>工具采纳行数</th><th>工具采纳占比</th>
+<th>往期每期添加</th><th>往期AI添加占比</th>
+<th title="按 FTE、入职离职及休假折算">出勤天数</th><th>每天添加</th><th>每天AI添加</th>
+</tr></thead>
+{{range .}}{{template "row" withSample . $.Meta}}{{end}}
+</table>
+{{end}}{{with .Weeks}}<h2>按周统计</h2>
+<p>{{weekScheme $.WeekScheme}}</p>
+<table>
+<thead><tr><th>周</th><th>日期</th><th>提交次数</th><th>开发者</th><th>添加行数</th><th>AI添加行数</th><th>AI添加占比</th><th>修复提交</th></tr></thead>
+{{range .}}<tr><td class="name">{{.Week}}</td><td class="name">{{.Start}} ~ {{.End}}</td><td>{{$.Format.Int .Stats.CommitCount}}</td><td>{{$.Format.Int .Contributors}}</td><td>{{$.Format.LineValue .Stats.TotalAddedLines}}</td><td>{{$.Format.LineValue .Stats.TotalAIAddedLines}}</td><td>{{ratio .Stats.TotalAIAddedLines .Stats.RatioAddedLines $.Meta.Filters.MinSampleLines}}</td><td>{{$.Format.Int .Stats.FixCount}}</td></tr>
+{{end}}</table>
+{{end}}{{with .CommitGraph}}<h2>提交图</h2>
+<p>按 AIG 比例着色，颜色越深比例越高，灰色为合并提交或未计入统计的提交</p>
+{{.SVG}}
+{{end}}{{with .Heatmaps}}<h2>提交时间分布</h2>
+{{range .}}<h3>{{.Name}}{{with .Email}} &lt;{{.}}&gt;{{end}} (共 {{$.Format.Int .Total}} 次)</h3>
+{{.HTML}}
+{{end}}{{end}}</body>
+</html>
+{{define "row"}}<tr{{if .MemberCount}} class="group"{{end}}>
+<td class="name">{{.Name}}{{if .MemberCount}} ({{.Format.Int .MemberCount}} 人){{end}}</td><td class="name">{{with .Level}}{{.}}{{else}}{{$.Email}}{{end}}</td><td title="标记 AIG>0: {{.AITaggedCommits}}，标记 AIG=0: {{.NoAITaggedCommits}}，未标记: {{.UntaggedCommits}}{{if .NACommits}}，n/a: {{.NACommits}}{{end}}{{if .ExemptCommits}}，exempt: {{.ExemptCommits}}{{end}}">{{.Format.Int .CommitCount}}</td>
+<td>{{.Format.LineValue .TotalAddedLines}}</td><td>{{.Format.LineValue .TotalDeletedLines}}</td>
+<td>{{.Format.LineValue .TotalAIAddedLines}}</td><td>{{ratio .TotalAIAddedLines .RatioAddedLines .MinSampleLines}}</td><td>{{mean .CommitAvgRatio .RatioCommits .MinSample}}</td>
+<td>{{.Format.LineValue .TotalAIDeletedLines}}</td><td>{{ratio .TotalAIDeletedLines .RatioDeletedLines .MinSampleLines}}</td>
+<td{{with fixSeverities .}} title="{{.}}"{{end}}>{{.Format.Int .FixCount}}</td><td>{{.Format.Int .FixAndAIGCount}}</td><td{{if .RatioFixCount}} title="{{fixNote .FixAndAIGCount .RatioFixCount}}"{{end}}>{{ratio .FixAndAIGCount .RatioFixCount .MinSample}}</td>
+{{if .ToolUsage}}<td>{{.Format.LineValue .ToolAcceptedLines}}</td><td{{if .UsageDiscrepancy}} class="warn" title="与自报 AI 占比差异较大"{{end}}>{{printf "%.2f%%" .ToolRatio}}</td>{{else}}<td>-</td><td>-</td>{{end}}
+{{with .Rolling}}<td title="近 {{.Days}} 天">{{$.Format.Float .AddedPerPeriod 1}}</td><td title="近 {{.Days}} 天">{{ratio .TotalAIAddedLines .RatioAddedLines $.MinSampleLines}}</td>{{else}}<td>-</td><td>-</td>{{end}}
+{{if .ActiveDays}}<td>{{.Format.Float .ActiveDays 1}}</td><td>{{.Format.Float (.PerActiveDay .TotalAddedLines) 1}}</td><td>{{.Format.Float (.PerActiveDay .TotalAIAddedLines) 1}}</td>{{else}}<td>-</td><td>-</td><td>-</td>{{end}}
+</tr>
+{{end}}`))
+
+// htmlPage 页面数据，Print 为真时用于打印(PDF)，展开折叠的内容
+type htmlPage struct {
+	*Report
+	Print bool
+}
+
+// 页面中数字的显示方式
+func (p htmlPage) Format() NumberFormat {
+	return p.Meta.NumberFormat()
+}
+
+// htmlRow 表格中的一行，带上比例的最小样本设置
+type htmlRow struct {
+	*AuthorStats
+	MinSample      int
+	MinSampleLines int
+	Format         NumberFormat
+	// 修复严重级别的显示顺序
+	SeverityOrder []string
+	// 组织汇总行的层级名称，显示在邮箱列
+	Level string
+}
+
+// 以 HTML 页面输出统计汇总表
+func WriteHTML(w io.Writer, report *Report) error {
+	return htmlTemplate.Execute(w, htmlPage{Report: report})
+}
+
+// 各开发者 AI 添加占比的横向条形图(SVG)
+func authorChart(authors []*AuthorStats) template.HTML {
+	const labelWidth, barWidth, rowHeight = 220.0, 400.0, 22.0
+	height := rowHeight*float64(len(authors)) + 4
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg width="%.0f" height="%.0f" xmlns="http://www.w3.org/2000/svg" font-size="12">`, labelWidth+barWidth+70, height)
+	for i, s := range authors {
+		y := float64(i)*rowHeight + 2
+		ratio := s.AddedRatio()
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.1f" text-anchor="end">%s</text>`, labelWidth-8, y+15, template.HTMLEscapeString(s.Name))
+		fmt.Fprintf(&b, `<rect x="%.0f" y="%.1f" width="%.0f" height="16" fill="#eee"/>`, labelWidth, y+2, barWidth)
+		fmt.Fprintf(&b, `<rect x="%.0f" y="%.1f" width="%.1f" height="16" fill="#1976d2"/>`, labelWidth, y+2, ratio/100*barWidth)
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.1f">%.2f%%</text>`, labelWidth+barWidth+6, y+15, ratio)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
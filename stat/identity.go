@@ -0,0 +1,89 @@
+package stat
+
+import "strings"
+
+// 汇总开发者统计时识别同一开发者的依据
+const (
+	// 按邮箱，邮箱为空时按姓名
+	IdentityEmail = "email"
+	// 按姓名，同一开发者使用多个邮箱时合并
+	IdentityName = "name"
+	// 按姓名和邮箱的组合
+	IdentityNameEmail = "name+email"
+)
+
+// 判断开发者识别方式是否有效
+func ValidIdentity(mode string) bool {
+	switch mode {
+	case IdentityEmail, IdentityName, IdentityNameEmail:
+		return true
+	}
+	return false
+}
+
+// 开发者识别方式的显示名称
+func IdentityLabel(mode string) string {
+	switch mode {
+	case IdentityName:
+		return "按姓名"
+	case IdentityNameEmail:
+		return "按姓名和邮箱"
+	}
+	return "按邮箱"
+}
+
+// 按识别方式生成开发者的汇总键，姓名或邮箱为空时使用另一项，避免没有邮箱的开发者被合并在一起
+func IdentityKey(mode, name, email string) string {
+	switch mode {
+	case IdentityName:
+		if name != "" {
+			return "name:" + name
+		}
+	case IdentityNameEmail:
+		return "name:" + name + "\x00" + email
+	default:
+		if email != "" {
+			return email
+		}
+	}
+	if email != "" {
+		return email
+	}
+	return "name:" + name
+}
+
+// 规范化 git 输出的作者姓名和邮箱：去除首尾空白及邮箱两侧的尖括号，
+// 未配置 user.email 的旧版 git 生成的 "(none)" 邮箱视为空
+func normalizeIdentity(name, email string) (string, string) {
+	name = strings.TrimSpace(name)
+	email = strings.TrimSpace(email)
+	email = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(email, "<"), ">"))
+	if email == "(none)" || strings.HasSuffix(email, ".(none)") {
+		email = ""
+	}
+	if name == "" && email != "" {
+		name = email
+		if at := strings.Index(email, "@"); at > 0 {
+			name = email[:at]
+		}
+	}
+	if name == "" {
+		name = "未知作者"
+	}
+	return name, email
+}
+
+// 判断邮箱是否属于指定域名（含子域名）
+func MatchEmailDomain(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	host := strings.ToLower(email[at+1:])
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
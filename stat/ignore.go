@@ -0,0 +1,123 @@
+package stat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFile 仓库根目录下的忽略列表文件，每行一个提交哈希，可在哈希后写明原因，# 开头为注释
+const IgnoreFile = ".aistat-ignore"
+
+// 哈希至少 7 位，避免过短的前缀误匹配
+var ignoreHashRegex = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// IgnoreRule 忽略列表中的一项
+type IgnoreRule struct {
+	Hash   string
+	Reason string
+}
+
+// IgnoredCommit 被忽略的提交，行数为原本会计入统计的行数
+type IgnoredCommit struct {
+	ID           string `json:"id" yaml:"id"`
+	Author       string `json:"author" yaml:"author"`
+	Subject      string `json:"subject" yaml:"subject"`
+	Reason       string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	AddedLines   int    `json:"added_lines" yaml:"added_lines"`
+	DeletedLines int    `json:"deleted_lines" yaml:"deleted_lines"`
+}
+
+// 解析忽略列表中的一行，格式为 "<哈希> [原因]"，空行和注释返回 false
+func ParseIgnoreRule(line string) (IgnoreRule, bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return IgnoreRule{}, false, nil
+	}
+	hash, reason, _ := strings.Cut(line, " ")
+	hash = strings.ToLower(hash)
+	if !ignoreHashRegex.MatchString(hash) {
+		return IgnoreRule{}, false, fmt.Errorf("错误：'%s' 不是有效的提交哈希(至少 7 位)", hash)
+	}
+	return IgnoreRule{Hash: hash, Reason: strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(reason), "#"))}, true, nil
+}
+
+// 读取仓库根目录下的忽略列表，文件不存在时返回空
+func loadIgnoreFile(git GitRunner) ([]IgnoreRule, error) {
+	root := gitOutput(git, "rev-parse", "--show-toplevel")
+	if root == "" {
+		return nil, nil
+	}
+	path := filepath.Join(root, IgnoreFile)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("错误：读取忽略列表 '%s' 失败: %v", path, err)
+	}
+	defer f.Close()
+
+	var rules []IgnoreRule
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		rule, ok, err := ParseIgnoreRule(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("错误：读取忽略列表 '%s' 失败: %v", path, err)
+	}
+	return rules, nil
+}
+
+// 去掉忽略列表中的提交，被忽略的提交记录在 ignored 中
+func (a *Analyzer) applyIgnore(commits []CommitStats) ([]CommitStats, error) {
+	rules, err := loadIgnoreFile(a.Git)
+	if err != nil {
+		return nil, err
+	}
+	rules = a.Migration.ApplyIgnore(append(append([]IgnoreRule{}, a.Ignore...), rules...))
+	if len(rules) == 0 {
+		return commits, nil
+	}
+
+	kept := commits[:0]
+	for _, c := range commits {
+		rule, ok := matchIgnore(c.ID, rules)
+		if !ok {
+			kept = append(kept, c)
+			continue
+		}
+		a.ignored = append(a.ignored, IgnoredCommit{
+			ID:           c.ID,
+			Author:       c.Author,
+			Subject:      c.Subject,
+			Reason:       rule.Reason,
+			AddedLines:   c.AddedLines,
+			DeletedLines: c.DeletedLines,
+		})
+		for _, f := range c.Files {
+			a.ignoredLines.Added += f.Added
+			a.ignoredLines.Deleted += f.Deleted
+		}
+	}
+	return kept, nil
+}
+
+// 按哈希前缀匹配忽略规则
+func matchIgnore(id string, rules []IgnoreRule) (IgnoreRule, bool) {
+	for _, rule := range rules {
+		if strings.HasPrefix(id, rule.Hash) {
+			return rule, true
+		}
+	}
+	return IgnoreRule{}, false
+}
@@ -0,0 +1,135 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// 文件变更状态，取自 git log --raw 的状态字母
+const (
+	FileAdded    = "A"
+	FileDeleted  = "D"
+	FileModified = "M"
+	FileRenamed  = "R"
+	FileCopied   = "C"
+)
+
+// 文本报告中列出的 AI 参与新建文件数
+const lifecycleTopFiles = 20
+
+// NewFile 统计周期内新建且到周期结束时仍存在的文件
+type NewFile struct {
+	Path    string `json:"path" yaml:"path"`
+	Author  string `json:"author" yaml:"author"`
+	Email   string `json:"email,omitempty" yaml:"email,omitempty"`
+	Commit  string `json:"commit" yaml:"commit"`
+	Created string `json:"created" yaml:"created"`
+	// 新建提交的 AIG 比例
+	AIGRatio float64 `json:"aig_ratio" yaml:"aig_ratio"`
+	// 本期内该文件的添加行数及 AI 添加行数(含新建后的修改)
+	Added   int `json:"added" yaml:"added"`
+	AIAdded int `json:"ai_added" yaml:"ai_added"`
+}
+
+// 本期添加行数中 AI 添加的占比(百分比)
+func (f *NewFile) AIShare() float64 {
+	return percent(f.AIAdded, f.Added)
+}
+
+// FileLifecycle 统计周期内文件的新建、删除和修改
+type FileLifecycle struct {
+	Created  int `json:"created" yaml:"created"`
+	Deleted  int `json:"deleted" yaml:"deleted"`
+	Modified int `json:"modified" yaml:"modified"`
+	// 新建且到周期结束时仍存在的文件数，及其中新建提交有 AI 参与(AIG>0)的文件数
+	NetNew   int `json:"net_new" yaml:"net_new"`
+	NetNewAI int `json:"net_new_ai" yaml:"net_new_ai"`
+	// 有 AI 参与的净新增文件，按 AI 添加行数从多到少排列
+	AIFiles []NewFile `json:"ai_files" yaml:"ai_files"`
+}
+
+// 文件是否计入生命周期统计：只统计符合统计条件的文件
+func lifecycleFile(f *FileChange) bool {
+	return !f.Skipped && !f.Moved && f.Status != ""
+}
+
+// 按提交时间顺序跟踪文件的新建、重命名和删除，得到本期的文件生命周期
+func BuildFileLifecycle(commits []CommitStats) *FileLifecycle {
+	l := &FileLifecycle{AIFiles: []NewFile{}}
+	order := make([]int, len(commits))
+	for i := range order {
+		order[i] = i
+	}
+	// git log 从新到旧输出，时间相同时保持原有顺序的倒序
+	sort.SliceStable(order, func(i, j int) bool {
+		ti, tj := commits[order[i]].Time, commits[order[j]].Time
+		if ti != tj {
+			return ti < tj
+		}
+		return order[i] > order[j]
+	})
+
+	created := make(map[string]*NewFile)
+	for _, idx := range order {
+		c := &commits[idx]
+		for _, f := range c.Files {
+			if !lifecycleFile(&f) {
+				continue
+			}
+			key := c.Submodule + "\x00" + f.Path
+			switch f.Status {
+			case FileAdded, FileCopied:
+				l.Created++
+				created[key] = &NewFile{Path: f.Path, Author: c.Author, Email: c.Email, Commit: c.ID, Created: c.Time, AIGRatio: c.AIGRatio}
+			case FileDeleted:
+				l.Deleted++
+				delete(created, key)
+				continue
+			default:
+				l.Modified++
+				if f.OldPath != "" {
+					oldKey := c.Submodule + "\x00" + f.OldPath
+					if nf, ok := created[oldKey]; ok {
+						delete(created, oldKey)
+						nf.Path = f.Path
+						created[key] = nf
+					}
+				}
+			}
+			if nf, ok := created[key]; ok {
+				nf.Added += f.Added
+				nf.AIAdded += int(float64(f.Added)*c.AIGRatio + 0.5)
+			}
+		}
+	}
+
+	l.NetNew = len(created)
+	for _, nf := range created {
+		if nf.AIGRatio > 0 {
+			l.AIFiles = append(l.AIFiles, *nf)
+		}
+	}
+	l.NetNewAI = len(l.AIFiles)
+	sort.Slice(l.AIFiles, func(i, j int) bool {
+		if l.AIFiles[i].AIAdded != l.AIFiles[j].AIAdded {
+			return l.AIFiles[i].AIAdded > l.AIFiles[j].AIAdded
+		}
+		return l.AIFiles[i].Path < l.AIFiles[j].Path
+	})
+	return l
+}
+
+// 打印文件生命周期，最多列出 AI 添加行数最多的 20 个净新增文件
+func PrintFileLifecycle(w io.Writer, l *FileLifecycle, nf NumberFormat) {
+	fmt.Fprintf(w, "\n  文件生命周期:\n")
+	fmt.Fprintf(w, "    新建 %s 个，删除 %s 个，修改 %s 次\n", nf.Int(l.Created), nf.Int(l.Deleted), nf.Int(l.Modified))
+	fmt.Fprintf(w, "    净新增文件: %s 个，其中新建时有 AI 参与: %s 个 (%s)\n", nf.Int(l.NetNew), nf.Int(l.NetNewAI), FormatSampleRatio(l.NetNewAI, l.NetNew, 0))
+	for i, f := range l.AIFiles {
+		if i == lifecycleTopFiles {
+			fmt.Fprintf(w, "      ... 另有 %s 个\n", nf.Int(len(l.AIFiles)-lifecycleTopFiles))
+			break
+		}
+		fmt.Fprintf(w, "      %s (%s，%s，AIG %.2f): 添加 %s，AI 占比 %.2f%%\n", f.Path, f.Author, shortID(f.Commit), f.AIGRatio, nf.Lines(f.Added), f.AIShare())
+	}
+}
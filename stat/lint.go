@@ -0,0 +1,244 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AIG 标记的问题类型
+const (
+	LintCasing    = "casing"
+	LintRange     = "range"
+	LintDuplicate = "duplicate"
+)
+
+// 形如 AIG 标记的文本，键名大小写、分隔符不限，如 "aig: 0.5"、"Ai-G = 50"、"AIG-Squash: 0.4"
+var trailerLikeRegex = regexp.MustCompile(`(?i)\b(ai[-_]?g(?:[-_]?squash)?)\s*[:=]\s*([^\s(),;]*)`)
+
+// TrailerProblem 提交信息中一处 AIG 标记的问题
+type TrailerProblem struct {
+	Kind    string `json:"kind"`
+	Text    string `json:"text"`
+	Message string `json:"message"`
+}
+
+// CommitLint 一个提交的 AIG 标记检查结果
+type CommitLint struct {
+	ID       string           `json:"id"`
+	Author   string           `json:"author"`
+	Subject  string           `json:"subject"`
+	Problems []TrailerProblem `json:"problems"`
+	// 能否自动修正：比例超出范围且无法换算时需要作者确认
+	Fixable bool `json:"fixable"`
+}
+
+// 检查提交信息中的 AIG 标记：键名大小写错误(解析时会被忽略)、比例超出 0~1、重复标记，
+// 返回问题列表和修正后的提交信息，无法自动修正时 fixable 为假。message 为 git log %B 格式的完整提交信息，
+// 有重复标记时按 precedence 规则(同 aig_precedence)确定生效的标记，修正时只保留这一个
+func LintTrailers(message, precedence string) (problems []TrailerProblem, fixed string, fixable bool) {
+	fixable = true
+	// squash 合并提交的正文中保留各原始提交的标记，不算重复
+	squash := strings.Contains(strings.ToLower(message), strings.ToLower(SquashTrailer))
+	var texts []string
+	used := -1
+	if !squash {
+		texts, used = effectiveAIGTag(message, precedence)
+	}
+	if precedence == "" {
+		precedence = AIGPrecedenceFirst
+	}
+	n := 0
+	var out []string
+	for _, line := range strings.Split(message, "\n") {
+		matches := trailerLikeRegex.FindAllStringSubmatchIndex(line, -1)
+		if matches == nil {
+			out = append(out, line)
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			text, key, value := line[m[0]:m[1]], line[m[2]:m[3]], line[m[4]:m[5]]
+			b.WriteString(line[last:m[0]])
+			last = m[1]
+			canonical := "AIG"
+			if strings.Contains(strings.ToLower(key), "squash") {
+				canonical = SquashTrailer
+			}
+			if canonical == "AIG" && len(texts) > 1 {
+				n++
+				switch {
+				case used < 0:
+					// 按规则没有生效的标记时无法确定保留哪一个
+					if n > 1 {
+						problems = append(problems, TrailerProblem{LintDuplicate, text, fmt.Sprintf("重复的 AIG 标记，按 %s 规则没有生效的标记，需要确认保留哪一个", precedence)})
+						fixable = false
+					}
+				case n-1 != used:
+					problems = append(problems, TrailerProblem{LintDuplicate, text, fmt.Sprintf("重复的 AIG 标记，按 %s 规则生效的是 '%s'", precedence, texts[used])})
+					continue
+				}
+			}
+			replacement := text
+			if key != canonical {
+				problems = append(problems, TrailerProblem{LintCasing, text, fmt.Sprintf("键名 '%s' 应为 '%s'，否则不会被统计", key, canonical)})
+				replacement = canonical + ": " + value
+			}
+			if v, ok, note := lintRatio(value); note != "" {
+				problems = append(problems, TrailerProblem{LintRange, text, note})
+				if ok {
+					replacement = canonical + ": " + v
+				} else {
+					fixable = false
+				}
+			}
+			b.WriteString(replacement)
+		}
+		b.WriteString(line[last:])
+		// 只有重复标记的行整行删除
+		if rebuilt := strings.TrimRight(b.String(), " \t"); rebuilt != "" || strings.TrimSpace(line) == "" {
+			out = append(out, rebuilt)
+		}
+	}
+	return problems, strings.Join(out, "\n"), fixable
+}
+
+// 按 precedence 规则选出生效的 AIG 标记：先把各处形如 AIG 标记的文本改为规范写法，即修正后统计时采用的标记。
+// 返回各处标记的原文及生效的标记的序号，没有生效的标记时序号为 -1
+func effectiveAIGTag(message, precedence string) ([]string, int) {
+	var texts []string
+	var starts []int
+	var b strings.Builder
+	for i, line := range strings.Split(message, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		last := 0
+		for _, m := range trailerLikeRegex.FindAllStringSubmatchIndex(line, -1) {
+			key, value := line[m[2]:m[3]], line[m[4]:m[5]]
+			if strings.Contains(strings.ToLower(key), "squash") {
+				continue
+			}
+			b.WriteString(line[last:m[0]])
+			last = m[1]
+			if v, ok, _ := lintRatio(value); ok {
+				value = v
+			}
+			texts = append(texts, line[m[0]:m[1]])
+			starts = append(starts, b.Len())
+			b.WriteString("AIG: " + value)
+		}
+		b.WriteString(line[last:])
+	}
+	if len(texts) < 2 {
+		return texts, -1
+	}
+	canonical := b.String()
+	tag, ok := selectAIGTag("", canonical, aigTags(canonical), precedence)
+	if !ok {
+		return texts, -1
+	}
+	// trailer 段中的标记的位置为所在行的起始位置
+	for i, start := range starts {
+		if start >= tag.pos {
+			return texts, i
+		}
+	}
+	return texts, -1
+}
+
+// 检查比例的取值，策略标记(n/a、exempt 及负数)不检查；1~100 之间的值视为百分数换算为比例
+func lintRatio(value string) (string, bool, string) {
+	lower := strings.ToLower(value)
+	if strings.HasPrefix(lower, "n/a") || lower == "na" || lower == "exempt" || strings.HasPrefix(value, "-") {
+		return value, true, ""
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return value, false, fmt.Sprintf("比例 '%s' 不是有效的数值", value)
+	}
+	if v <= 1 && !strings.HasSuffix(value, "%") {
+		return value, true, ""
+	}
+	if v > 100 {
+		return value, false, fmt.Sprintf("比例 %s 超出 0~1 的范围", value)
+	}
+	fixed := strconv.FormatFloat(v/100, 'f', -1, 64)
+	return fixed, true, fmt.Sprintf("比例 %s 超出 0~1 的范围，按百分数应为 %s", value, fixed)
+}
+
+// 检查提交的 AIG 标记，返回有问题的提交，precedence 为 AIG 标记的取值规则
+func LintCommits(commits []CommitStats, precedence string) []CommitLint {
+	lints := []CommitLint{}
+	for i := range commits {
+		c := &commits[i]
+		problems, _, fixable := LintTrailers(rawMessage(c.Subject, c.Message), precedence)
+		if len(problems) > 0 {
+			lints = append(lints, CommitLint{ID: c.ID, Author: c.Author, Subject: c.Subject, Problems: problems, Fixable: fixable})
+		}
+	}
+	return lints
+}
+
+// 打印检查结果
+func PrintLint(w io.Writer, lints []CommitLint, total int) {
+	for _, l := range lints {
+		fmt.Fprintf(w, "%s %s <%s>\n", shortID(l.ID), l.Subject, l.Author)
+		for _, p := range l.Problems {
+			fmt.Fprintf(w, "  [%s] %s: %s\n", p.Kind, p.Text, p.Message)
+		}
+		if !l.Fixable {
+			fmt.Fprintf(w, "  需要作者确认后手动修正\n")
+		}
+	}
+	fmt.Fprintf(w, "共检查 %d 个提交，%d 个提交的 AIG 标记有问题\n", total, len(lints))
+}
+
+// 修正后的提交信息所在的目录：与脚本同名、扩展名为 .messages，每个提交一个 <提交哈希>.msg 文件
+func FixupMessageDir(script string) string {
+	return strings.TrimSuffix(script, filepath.Ext(script)) + ".messages"
+}
+
+// 把修正后的完整提交信息写入 dir 下的 <提交哈希>.msg 文件
+func WriteFixupMessages(dir string, messages map[string]string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("错误：创建目录 '%s' 失败: %v", dir, err)
+	}
+	for id, msg := range messages {
+		if !isCommitHash(id) {
+			return fmt.Errorf("错误：提交哈希 '%s' 格式不正确", id)
+		}
+		if err := os.WriteFile(filepath.Join(dir, id+".msg"), []byte(msg+"\n"), 0o644); err != nil {
+			return fmt.Errorf("错误：写入提交信息失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// 生成修正提交信息的脚本：用 git filter-branch --msg-filter 改写 base 范围内的提交，
+// dir 下有该提交的 .msg 文件(见 WriteFixupMessages)时用文件内容替换提交信息，否则保持不变。
+// 提交信息不写入脚本，以免其中的文本被当作命令执行。
+// 会改写历史，只应在尚未推送或团队约定可以强推的分支上运行
+func WriteFixupScript(w io.Writer, dir, base string) error {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# 由 AIG_repo lint-commits 生成：修正提交信息中的 AIG 标记\n")
+	b.WriteString("# 注意：会改写提交历史，请先备份分支，只在尚未推送或约定可以强推的分支上运行\n")
+	b.WriteString("set -e\n")
+	fmt.Fprintf(&b, "AIG_FIXUP_DIR=%s\n", shellQuote(dir))
+	b.WriteString("export AIG_FIXUP_DIR\n")
+	b.WriteString(`git filter-branch --msg-filter 'if [ -f "$AIG_FIXUP_DIR/$GIT_COMMIT.msg" ]; then cat "$AIG_FIXUP_DIR/$GIT_COMMIT.msg"; else cat; fi'`)
+	fmt.Fprintf(&b, " -- %s\n", shellQuote(base))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// 用单引号引用 shell 参数
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,74 @@
+package stat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintTrailersDuplicatePrecedence(t *testing.T) {
+	tests := []struct {
+		name, message, precedence string
+		// 生效的标记及修正后的提交信息，used 为空时无法自动修正
+		used, fixed string
+	}{
+		{
+			name:       "默认规则下 trailer 段优先于正文",
+			message:    "feat: retry\n\nPorted from upstream, tagged AIG: 1\n\nAIG: 0.3",
+			precedence: AIGPrecedenceFirst,
+			used:       "AIG: 0.3",
+			fixed:      "feat: retry\n\nPorted from upstream, tagged\n\nAIG: 0.3",
+		},
+		{
+			name:       "默认规则下比例标记优先于策略标记",
+			message:    "feat: retry\n\nAIG: n/a\nAIG: 0.4",
+			precedence: "",
+			used:       "AIG: 0.4",
+			fixed:      "feat: retry\n\nAIG: 0.4",
+		},
+		{
+			name:       "last 取最后一个",
+			message:    "feat: retry\n\nAIG: 0.2\nAIG: 0.6",
+			precedence: AIGPrecedenceLast,
+			used:       "AIG: 0.6",
+			fixed:      "feat: retry\n\nAIG: 0.6",
+		},
+		{
+			name:       "按修正键名后的标记取值",
+			message:    "feat: retry\n\nAIG: 0.2\naig: 0.6",
+			precedence: AIGPrecedenceTrailer,
+			used:       "aig: 0.6",
+			fixed:      "feat: retry\n\nAIG: 0.6",
+		},
+		{
+			name:       "按规则没有生效的标记",
+			message:    "feat: retry AIG: 0.2\n\nretry AIG: 0.6 later",
+			precedence: AIGPrecedenceKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, fixed, fixable := LintTrailers(tt.message, tt.precedence)
+			var duplicates []TrailerProblem
+			for _, p := range problems {
+				if p.Kind == LintDuplicate {
+					duplicates = append(duplicates, p)
+				}
+			}
+			if len(duplicates) != 1 {
+				t.Fatalf("problems = %+v", problems)
+			}
+			if tt.used == "" {
+				if fixable {
+					t.Errorf("没有生效的标记时可以自动修正: %+v", duplicates[0])
+				}
+				return
+			}
+			if !strings.Contains(duplicates[0].Message, "'"+tt.used+"'") {
+				t.Errorf("message = %q, want 生效的是 '%s'", duplicates[0].Message, tt.used)
+			}
+			if !fixable || fixed != tt.fixed {
+				t.Errorf("fixed = %q (fixable=%v), want %q", fixed, fixable, tt.fixed)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package stat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LLM 接口密钥只从环境变量读取，避免出现在命令行参数和审计日志中
+const EnvLLMAPIKey = "AISTAT_LLM_API_KEY"
+
+// 提示词版本，修改提示词后递增，使旧的缓存结果失效
+const llmPromptVersion = "1"
+
+// 随提交消息一起发送的 diff 最大字节数
+const llmMaxDiffBytes = 8000
+
+// LLM 估算的提交类型
+var estimateTypes = []string{"feat", "fix", "refactor", "docs", "test", "chore", "other"}
+
+const llmSystemPrompt = `你是代码审查助手。根据提交消息(以及可能附带的 diff)估算该提交中由 AI 生成的代码比例和提交类型。
+只输出一个 JSON 对象，不要输出其他内容，格式为 {"ai_ratio": 0 到 1 之间的小数, "type": "feat|fix|refactor|docs|test|chore|other"}`
+
+// Estimate LLM 对单个提交的估算结果
+type Estimate struct {
+	AIRatio float64 `json:"ai_ratio"`
+	Type    string  `json:"type"`
+}
+
+// LLMClassifier 调用兼容 OpenAI Chat Completions 接口的 LLM 服务，为没有 AIG 标记的提交估算 AI 参与度
+type LLMClassifier struct {
+	Endpoint string
+	Model    string
+	APIKey   string
+	// 同时发送提交的 diff
+	IncludeDiff bool
+	// 估算结果缓存目录，相同提交不会重复请求
+	CacheDir string
+	Client   *http.Client
+}
+
+// 默认缓存目录 ~/.aistat/llm-cache
+func DefaultLLMCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".aistat", "llm-cache")
+	}
+	return filepath.Join(home, ".aistat", "llm-cache")
+}
+
+// 估算单个提交，优先使用缓存
+func (c *LLMClassifier) Classify(commit *CommitStats, diff string) (*Estimate, error) {
+	cachePath := filepath.Join(c.CacheDir, c.cacheKey(commit.ID)+".json")
+	if data, err := os.ReadFile(cachePath); err == nil {
+		est := &Estimate{}
+		if json.Unmarshal(data, est) == nil {
+			return est, nil
+		}
+	}
+
+	est, err := c.request(commit, diff)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(est); err == nil && os.MkdirAll(c.CacheDir, 0o700) == nil {
+		_ = os.WriteFile(cachePath, data, 0o600)
+	}
+	return est, nil
+}
+
+// 缓存键：模型、提示词版本、是否包含 diff 和提交 ID
+func (c *LLMClassifier) cacheKey(id string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{c.Model, llmPromptVersion, fmt.Sprint(c.IncludeDiff), id}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// 发送请求并解析估算结果
+func (c *LLMClassifier) request(commit *CommitStats, diff string) (*Estimate, error) {
+	prompt := "提交消息:\n" + commit.Message
+	if diff != "" {
+		if len(diff) > llmMaxDiffBytes {
+			diff = diff[:llmMaxDiffBytes] + "\n...(已截断)"
+		}
+		prompt += "\n\ndiff:\n" + diff
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model":       c.Model,
+		"temperature": 0,
+		"messages": []map[string]string{
+			{"role": "system", "content": llmSystemPrompt},
+			{"role": "user", "content": prompt},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	api := &APIClient{Client: client}
+	if c.APIKey != "" {
+		api.Header = http.Header{"Authorization": {"Bearer " + c.APIKey}}
+	}
+	resp, err := api.Do(http.MethodPost, c.Endpoint, body, http.Header{"Content-Type": {"application/json"}})
+	if err != nil {
+		return nil, fmt.Errorf("错误：请求 LLM 接口失败: %s", strings.TrimPrefix(err.Error(), "错误："))
+	}
+	data := resp.Body
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &completion); err != nil || len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("错误：无法解析 LLM 响应")
+	}
+	return parseEstimate(completion.Choices[0].Message.Content)
+}
+
+// 从模型回复中提取 JSON 估算结果，容忍代码块等多余内容
+func parseEstimate(content string) (*Estimate, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("错误：LLM 回复中没有估算结果: %s", content)
+	}
+	est := &Estimate{}
+	if err := json.Unmarshal([]byte(content[start:end+1]), est); err != nil {
+		return nil, fmt.Errorf("错误：无法解析 LLM 估算结果: %v", err)
+	}
+
+	if est.AIRatio < 0 {
+		est.AIRatio = 0
+	}
+	if est.AIRatio > 1 {
+		est.AIRatio = 1
+	}
+	est.Type = strings.ToLower(strings.TrimSpace(est.Type))
+	valid := false
+	for _, t := range estimateTypes {
+		valid = valid || est.Type == t
+	}
+	if !valid {
+		est.Type = "other"
+	}
+	return est, nil
+}
+
+// 为没有 AIG 标记的提交补充 LLM 估算结果，估算失败的提交保持原样并记录警告
+func (a *Analyzer) estimate(commits []CommitStats) {
+	if a.Classifier == nil {
+		return
+	}
+
+	failed := 0
+	var lastErr error
+	for i := range commits {
+		c := &commits[i]
+		if c.AIGSource != "" {
+			continue
+		}
+		diff := ""
+		if a.Classifier.IncludeDiff {
+			diff = gitOutput(a.Git, "show", "--format=", "--patch", "--no-color", c.ID)
+		}
+		est, err := a.Classifier.Classify(c, diff)
+		if err != nil {
+			failed++
+			lastErr = err
+			continue
+		}
+		c.AIGRatio = est.AIRatio
+		c.AIGSource = AIGSourceEstimate
+		c.EstimatedType = est.Type
+		c.IsFix = c.IsFix || est.Type == "fix"
+		a.estimated++
+	}
+	if failed > 0 {
+		a.warnf("%d 个提交的 LLM 估算失败，按未标注处理: %s", failed, strings.TrimPrefix(lastErr.Error(), "错误："))
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package stat
+
+import "os"
+
+// 不支持文件锁的平台上不加锁
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package stat
+
+import (
+	"os"
+	"syscall"
+)
+
+// 对文件加排他锁，阻塞到其他进程(或同一进程中另外打开该文件的写入者)释放为止，关闭文件时释放
+func lockFile(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package stat
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+const lockfileExclusiveLock = 0x2
+
+// 对文件加排他锁，阻塞到其他写入者释放为止，关闭文件时释放。
+// 锁定文件末尾之外的一个字节，不影响其他程序读取文件内容
+func lockFile(f *os.File) error {
+	ol := syscall.Overlapped{Offset: 0xFFFFFFFF, OffsetHigh: 0x7FFFFFFF}
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
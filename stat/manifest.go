@@ -0,0 +1,123 @@
+package stat
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 提交中列出由 AI 代理生成的文件的清单，随改动一起提交
+const DefaultAIManifest = ".ai/manifest.yaml"
+
+// AI 清单路径为该值时不读取清单
+const AIManifestOff = "off"
+
+// AIManifest AI 清单，files 的每一项为文件路径、以 / 结尾的目录或 path.Match 通配符，也可以写成 {path: ...}
+type AIManifest struct {
+	Files []manifestFile `yaml:"files"`
+}
+
+type manifestFile struct {
+	Path string `yaml:"path"`
+}
+
+// 清单中的文件既可以是字符串也可以是带 path 字段的对象
+func (f *manifestFile) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&f.Path)
+	}
+	type plain manifestFile
+	return value.Decode((*plain)(f))
+}
+
+// 清单是否列出了该文件
+func (m *AIManifest) Lists(file string) bool {
+	for _, f := range m.Files {
+		pattern := strings.TrimPrefix(strings.TrimSpace(f.Path), "./")
+		if pattern == "" {
+			continue
+		}
+		if pattern == file || strings.HasSuffix(pattern, "/") && strings.HasPrefix(file, pattern) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 清单中的文件占提交添加行数的比例(没有添加行时按删除行数)，清单没有列出计入统计的文件时返回 false
+func (m *AIManifest) ratio(c *CommitStats) (float64, bool) {
+	matched := false
+	added, deleted := 0, 0
+	for _, f := range c.Files {
+		if f.Skipped || !m.Lists(f.Path) {
+			continue
+		}
+		matched = true
+		added += f.Added
+		deleted += f.Deleted
+	}
+	switch {
+	case !matched:
+		return 0, false
+	case c.AddedLines > 0:
+		return float64(added) / float64(c.AddedLines), true
+	case c.DeletedLines > 0:
+		return float64(deleted) / float64(c.DeletedLines), true
+	}
+	return 0, false
+}
+
+// 清单文件本身不计入统计；提交新增或修改了清单且没有 AIG 标记时，
+// 按清单中列出的文件的行数占比作为提交的 AIG 比例，即这些文件的行数计为 AI 贡献
+func (a *Analyzer) applyManifests(commits []CommitStats) {
+	a.manifests = 0
+	if a.AIManifest == "" || a.AIManifest == AIManifestOff {
+		return
+	}
+	// git 输出的路径以 / 分隔
+	manifest := path.Clean(filepath.ToSlash(a.AIManifest))
+	invalid := 0
+	for i := range commits {
+		c := &commits[i]
+		var entry *FileChange
+		for j := range c.Files {
+			if c.Files[j].Path == manifest {
+				entry = &c.Files[j]
+				break
+			}
+		}
+		if entry == nil {
+			continue
+		}
+		if !entry.Skipped {
+			entry.Skipped, entry.SkipReason = true, SkipAIManifest
+			c.AddedLines -= entry.Added
+			c.DeletedLines -= entry.Deleted
+		}
+		if c.AIGSource != "" || c.AIGPolicy != "" || entry.Status == FileDeleted {
+			continue
+		}
+		out, err := a.Git.Run([]string{"show", c.ID + ":" + manifest})
+		if err != nil {
+			invalid++
+			continue
+		}
+		var m AIManifest
+		if err := yaml.Unmarshal([]byte(gitText(out)), &m); err != nil {
+			invalid++
+			continue
+		}
+		if ratio, ok := m.ratio(c); ok {
+			c.AIGRatio, c.AIGSource = ratio, AIGSourceManifest
+			a.manifests++
+		}
+	}
+	if invalid > 0 {
+		a.warnf("%d 个提交中的 AI 清单 %s 无法读取或解析，按未标注处理", invalid, a.AIManifest)
+	}
+}
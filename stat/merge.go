@@ -0,0 +1,136 @@
+package stat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MergeSource 合并报告中的一个来源报告
+type MergeSource struct {
+	Path        string `json:"path" yaml:"path"`
+	Repo        string `json:"repo" yaml:"repo"`
+	GeneratedAt string `json:"generated_at,omitempty" yaml:"generated_at,omitempty"`
+	Commits     int    `json:"commits" yaml:"commits"`
+}
+
+// 读取 JSON 格式的报告
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取报告 '%s' 失败: %v", path, err)
+	}
+	report := &Report{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("错误：解析报告 '%s' 失败，只能合并 JSON 格式的报告: %v", path, err)
+	}
+	return report, nil
+}
+
+// 合并多个报告的提交，同一提交(按哈希)只保留第一次出现，按提交时间从新到旧排列，
+// 返回合并后的提交及重复的提交数
+func MergeCommits(reports []*Report) ([]CommitStats, int) {
+	seen := make(map[string]bool)
+	var commits []CommitStats
+	duplicates := 0
+	for _, r := range reports {
+		for _, c := range r.Commits {
+			if seen[c.ID] {
+				duplicates++
+				continue
+			}
+			seen[c.ID] = true
+			commits = append(commits, c)
+		}
+	}
+	sort.SliceStable(commits, func(i, j int) bool {
+		ti, err1 := CommitTime(&commits[i])
+		tj, err2 := CommitTime(&commits[j])
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		return ti.After(tj)
+	})
+	return commits, duplicates
+}
+
+// 合并报告的统计周期：都按日期统计时取最早的开始日期和最晚的结束日期，
+// 否则为空(按提交范围统计的报告没有统一的日期)
+func MergePeriod(reports []*Report) (since, until string) {
+	for _, r := range reports {
+		if r.RevRange != "" || r.Since == "" || r.Until == "" {
+			return "", ""
+		}
+		if since == "" || PeriodDay(r.Since) < PeriodDay(since) {
+			since = r.Since
+		}
+		if until == "" || PeriodDay(r.Until) > PeriodDay(until) {
+			until = r.Until
+		}
+	}
+	return since, until
+}
+
+// 合并各报告的运行信息：仓库名合并列出，警告和错误注明来自哪个仓库，
+// 过滤规则以第一个报告为准，各报告不一致时给出警告
+func MergeMetadata(reports []*Report, paths []string) *Metadata {
+	meta := &Metadata{ToolVersion: Version, GeneratedAt: time.Now().Format(time.RFC3339)}
+	var repos []string
+	seenRepo := make(map[string]bool)
+	var filters []byte
+	for i, r := range reports {
+		m := r.Meta
+		if m == nil {
+			m = &Metadata{}
+		}
+		repo := firstNonEmpty(m.Repo, paths[i])
+		if !seenRepo[repo] {
+			seenRepo[repo] = true
+			repos = append(repos, repo)
+		}
+		meta.Merged = append(meta.Merged, MergeSource{Path: paths[i], Repo: m.Repo, GeneratedAt: m.GeneratedAt, Commits: len(r.Commits)})
+		for _, w := range m.Warnings {
+			meta.Warnings = append(meta.Warnings, repo+": "+w)
+		}
+		for _, e := range m.Errors {
+			meta.Errors = append(meta.Errors, repo+": "+e)
+		}
+		meta.Interrupted = meta.Interrupted || m.Interrupted
+		meta.Fast = meta.Fast || m.Fast
+		meta.Heuristic = meta.Heuristic || m.Heuristic
+		meta.Submodules = meta.Submodules || m.Submodules
+		meta.SZZ = meta.SZZ || m.SZZ
+		meta.BugFixCommits += m.BugFixCommits
+		meta.CommitCount += m.CommitCount
+		meta.IgnoredCommits = append(meta.IgnoredCommits, m.IgnoredCommits...)
+		current, _ := json.Marshal(m.Filters)
+		if i == 0 {
+			meta.Filters = m.Filters
+			meta.Lang, meta.KLoC = m.Lang, m.KLoC
+			filters = current
+		} else if string(current) != string(filters) {
+			meta.Warnings = append(meta.Warnings, fmt.Sprintf("%s 的过滤规则与 %s 不同，合并后的数据口径不一致", paths[i], paths[0]))
+		}
+		if m.Fast != reports[0].Meta.fast() {
+			meta.Warnings = append(meta.Warnings, fmt.Sprintf("%s 与 %s 中只有一个是快速模式，合并后的行数不完整", paths[i], paths[0]))
+		}
+	}
+	meta.Repo = strings.Join(repos, " + ")
+	return meta
+}
+
+func (m *Metadata) fast() bool {
+	return m != nil && m.Fast
+}
+
+// 取出提交日期在 [since, until] 之间的提交，同一提交只保留一次
+func CommitsInPeriod(commits []CommitStats, since, until string) []CommitStats {
+	to := "9999-12-31"
+	if end, err := time.ParseInLocation(DateLayout, PeriodDay(until), time.Local); err == nil {
+		to = end.AddDate(0, 0, 1).Format(DateLayout)
+	}
+	return commitsBetween(commits, PeriodDay(since), to)
+}
@@ -0,0 +1,88 @@
+package stat
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// Conventional Commits 格式的提交标题，如 "feat(api): ..."、"fix!: ..."
+	conventionalRegex = regexp.MustCompile(`(?i)^(feat|fix|docs|style|refactor|perf|test|build|ci|chore|revert)(\([^()]+\))?!?: \S`)
+	// 提交信息中的 issue 引用："#123"、"group/app#12"、"PROJ-123" 形式的 Jira 编号或 issue 链接
+	messageIssueRegex = regexp.MustCompile(`(?:^|[\s(\[,:])(?:[\w.-]+/[\w.-]+)?#\d+\b|\b[A-Z][A-Z0-9]+-[1-9]\d*\b|/issues/\d+`)
+)
+
+// MessageQuality 一组提交的提交信息质量
+type MessageQuality struct {
+	Commits int
+	// 平均字符数(不含 AIG 标记)
+	AvgLength float64
+	// 标题符合 Conventional Commits 格式、提交信息引用了 issue 的提交占比（百分比）
+	ConventionalRatio float64
+	IssueRefRatio     float64
+}
+
+// 提交信息的字符数，不含 AIG 标记及首尾空白
+func messageLength(message string) int {
+	for _, re := range []*regexp.Regexp{squashRegex, policyRegex, aigRegex} {
+		message = re.ReplaceAllString(message, "")
+	}
+	return utf8.RuneCountInString(strings.TrimSpace(message))
+}
+
+// 提交标题是否符合 Conventional Commits 格式
+func isConventional(subject string) bool {
+	return conventionalRegex.MatchString(strings.TrimSpace(subject))
+}
+
+// 提交信息是否引用了 issue
+func hasIssueRef(message string) bool {
+	return messageIssueRegex.MatchString(message)
+}
+
+// 累加提交信息质量，标记 AIG>0 的提交同时计入 AI 参与的部分
+func (s *AuthorStats) addMessage(c *CommitStats) {
+	length, conventional, issue := messageLength(c.Message), isConventional(c.Subject), hasIssueRef(c.Message)
+	s.MessageChars += length
+	if conventional {
+		s.ConventionalCommits++
+	}
+	if issue {
+		s.IssueRefCommits++
+	}
+	if CommitCohort(c) != CohortAI {
+		return
+	}
+	s.AIMessageChars += length
+	if conventional {
+		s.AIConventionalCommits++
+	}
+	if issue {
+		s.AIIssueRefCommits++
+	}
+}
+
+// 提交信息质量：ai 为真时只含标记 AIG>0 的提交，否则为其余提交
+func (s *AuthorStats) MessageQuality(ai bool) MessageQuality {
+	if ai {
+		return newMessageQuality(s.AITaggedCommits, s.AIMessageChars, s.AIConventionalCommits, s.AIIssueRefCommits)
+	}
+	return newMessageQuality(s.CommitCount-s.AITaggedCommits, s.MessageChars-s.AIMessageChars,
+		s.ConventionalCommits-s.AIConventionalCommits, s.IssueRefCommits-s.AIIssueRefCommits)
+}
+
+// 全部提交的提交信息质量
+func (s *AuthorStats) AllMessageQuality() MessageQuality {
+	return newMessageQuality(s.CommitCount, s.MessageChars, s.ConventionalCommits, s.IssueRefCommits)
+}
+
+func newMessageQuality(commits, chars, conventional, issue int) MessageQuality {
+	q := MessageQuality{Commits: commits}
+	if commits > 0 {
+		q.AvgLength = float64(chars) / float64(commits)
+	}
+	q.ConventionalRatio = percent(conventional, commits)
+	q.IssueRefRatio = percent(issue, commits)
+	return q
+}
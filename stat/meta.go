@@ -0,0 +1,341 @@
+package stat
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Metadata 报告的运行信息，使报告文件本身可以说明数据来源并被复现
+type Metadata struct {
+	Repo   string `json:"repo" yaml:"repo"`
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+	Head   string `json:"head,omitempty" yaml:"head,omitempty"`
+	// 是否在关联工作区中运行
+	Worktree bool `json:"worktree,omitempty" yaml:"worktree,omitempty"`
+	// 是否包含子模块的提交
+	Submodules bool `json:"submodules,omitempty" yaml:"submodules,omitempty"`
+	// 部分克隆的对象过滤规则，如 blob:none
+	PartialClone string      `json:"partial_clone,omitempty" yaml:"partial_clone,omitempty"`
+	ToolVersion  string      `json:"tool_version" yaml:"tool_version"`
+	GeneratedAt  string      `json:"generated_at" yaml:"generated_at"`
+	Filters      FilterRules `json:"filters" yaml:"filters"`
+	// 读取的配置文件：用户级配置、指定的配置及仓库自己的 .aistat.yaml
+	ConfigFiles []string `json:"config_files,omitempty" yaml:"config_files,omitempty"`
+	// 是否启用启发式 AI 风格检测
+	Heuristic bool `json:"heuristic,omitempty" yaml:"heuristic,omitempty"`
+	// 快速模式，报告中没有行数
+	Fast bool `json:"fast,omitempty" yaml:"fast,omitempty"`
+	// 失败的步骤或子模块，非空时报告只是部分结果
+	Errors []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+	// 收到 SIGINT/SIGTERM 后中止，只包含中断前完成的部分
+	Interrupted bool `json:"interrupted,omitempty" yaml:"interrupted,omitempty"`
+	// git rev-list --count 预计的提交数(含被忽略、被过滤的提交)及并行读取的分片数
+	CommitCount int `json:"commit_count" yaml:"commit_count"`
+	Shards      int `json:"shards,omitempty" yaml:"shards,omitempty"`
+	// 是否统计文件生命周期(新建、删除、修改的文件数)
+	Lifecycle bool `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`
+	// 是否按新建文件和已有文件分别统计 AI 添加占比
+	ChangeType bool `json:"change_type,omitempty" yaml:"change_type,omitempty"`
+	// 是否统计提交信息质量
+	MessageQuality bool `json:"message_quality,omitempty" yaml:"message_quality,omitempty"`
+	// LLM 估算使用的模型及估算的提交数
+	Estimator        string `json:"estimator,omitempty" yaml:"estimator,omitempty"`
+	EstimatedCommits int    `json:"estimated_commits,omitempty" yaml:"estimated_commits,omitempty"`
+	// 识别出的大规模移动提交数及处理方式
+	MassMoves    int    `json:"mass_moves,omitempty" yaml:"mass_moves,omitempty"`
+	MassMoveMode string `json:"mass_move_mode,omitempty" yaml:"mass_move_mode,omitempty"`
+	// AIG 标记的取值规则及含有多个取值不同的 AIG 标记的提交
+	AIGPrecedence string         `json:"aig_precedence,omitempty" yaml:"aig_precedence,omitempty"`
+	AmbiguousTags []AmbiguousTag `json:"ambiguous_tags,omitempty" yaml:"ambiguous_tags,omitempty"`
+	// 确定统计周期及提交时间的时间口径
+	DateKind string `json:"date_kind,omitempty" yaml:"date_kind,omitempty"`
+	// AI 清单的路径及按清单计算 AIG 比例的提交数
+	AIManifest      string `json:"ai_manifest,omitempty" yaml:"ai_manifest,omitempty"`
+	ManifestCommits int    `json:"manifest_commits,omitempty" yaml:"manifest_commits,omitempty"`
+	// 统计所有分支时同一改动在多个分支上的副本的处理方式及不计入统计的副本
+	BranchCopies  string       `json:"branch_copies,omitempty" yaml:"branch_copies,omitempty"`
+	DroppedCopies []BranchCopy `json:"dropped_copies,omitempty" yaml:"dropped_copies,omitempty"`
+	// 被 .gitattributes 标记为生成或第三方代码而排除的文件数及行数
+	AttributeFiles int         `json:"attribute_files,omitempty" yaml:"attribute_files,omitempty"`
+	AttributeLines *LineCounts `json:"attribute_lines,omitempty" yaml:"attribute_lines,omitempty"`
+	// 按原因汇总的不计入统计的文件
+	SkippedFiles []SkippedFiles `json:"skipped_files,omitempty" yaml:"skipped_files,omitempty"`
+	// 排除的符号链接变更及只修改文件权限的变更数
+	SymlinkChanges  int `json:"symlink_changes,omitempty" yaml:"symlink_changes,omitempty"`
+	ModeOnlyChanges int `json:"mode_only_changes,omitempty" yaml:"mode_only_changes,omitempty"`
+	// 忽略列表中被排除的提交
+	IgnoredCommits []IgnoredCommit `json:"ignored_commits,omitempty" yaml:"ignored_commits,omitempty"`
+	// 用于对照的 IDE/AI 工具使用日志
+	UsageLogs []string `json:"usage_logs,omitempty" yaml:"usage_logs,omitempty"`
+	// 仓库迁移映射中的邮箱数和提交数
+	EmailRewrites int `json:"email_rewrites,omitempty" yaml:"email_rewrites,omitempty"`
+	CommitRemaps  int `json:"commit_remaps,omitempty" yaml:"commit_remaps,omitempty"`
+	// 是否进行了 SZZ 缺陷引入分析及扫描的修复提交数
+	SZZ           bool `json:"szz,omitempty" yaml:"szz,omitempty"`
+	BugFixCommits int  `json:"bug_fix_commits,omitempty" yaml:"bug_fix_commits,omitempty"`
+	// 统计周期的冻结时间及当前结果是否与冻结记录不一致
+	FrozenAt      string `json:"frozen_at,omitempty" yaml:"frozen_at,omitempty"`
+	FrozenChanged bool   `json:"frozen_changed,omitempty" yaml:"frozen_changed,omitempty"`
+	// 数字的语言格式，为空表示默认的 zh-CN；KLoC 为真时行数以千行为单位显示
+	Lang string `json:"lang,omitempty" yaml:"lang,omitempty"`
+	KLoC bool   `json:"kloc,omitempty" yaml:"kloc,omitempty"`
+	// 分析过程中的警告，如部分克隆缺少对象导致跳过行数统计
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	// merge 合并生成的报告的来源报告
+	Merged []MergeSource `json:"merged,omitempty" yaml:"merged,omitempty"`
+}
+
+// FilterRules 本次统计生效的过滤规则
+type FilterRules struct {
+	// 文件类型预设
+	Profile      string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	IncludeExts  []string `json:"include_exts" yaml:"include_exts"`
+	ExcludeExts  []string `json:"exclude_exts" yaml:"exclude_exts"`
+	Author       string   `json:"author,omitempty" yaml:"author,omitempty"`
+	EmailDomains []string `json:"email_domains,omitempty" yaml:"email_domains,omitempty"`
+	MinCommits   int      `json:"min_commits,omitempty" yaml:"min_commits,omitempty"`
+	MinLines     int      `json:"min_lines,omitempty" yaml:"min_lines,omitempty"`
+	// 识别开发者的依据，为空表示按邮箱
+	Identity string `json:"identity,omitempty" yaml:"identity,omitempty"`
+	// 比例的最小分母，低于该值的比例不显示
+	MinSample      int `json:"min_sample,omitempty" yaml:"min_sample,omitempty"`
+	MinSampleLines int `json:"min_sample_lines,omitempty" yaml:"min_sample_lines,omitempty"`
+	// 新增的空行不计入统计
+	IgnoreBlankLines bool `json:"ignore_blank_lines,omitempty" yaml:"ignore_blank_lines,omitempty"`
+	// 不按 .gitattributes 排除生成代码和第三方代码
+	IgnoreGitattributes bool `json:"ignore_gitattributes,omitempty" yaml:"ignore_gitattributes,omitempty"`
+	// 提交信息过滤的正则，InvertGrep 为真时为排除
+	Grep       []string `json:"grep,omitempty" yaml:"grep,omitempty"`
+	InvertGrep bool     `json:"invert_grep,omitempty" yaml:"invert_grep,omitempty"`
+	// 自定义的修复提交识别规则，为空表示默认规则；修复严重级别按匹配顺序排列
+	FixPattern    string   `json:"fix_pattern,omitempty" yaml:"fix_pattern,omitempty"`
+	FixSeverities []string `json:"fix_severities,omitempty" yaml:"fix_severities,omitempty"`
+}
+
+// 收集仓库与分析器的运行信息
+func (a *Analyzer) Metadata() *Metadata {
+	meta := &Metadata{
+		ToolVersion: Version,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		Filters: FilterRules{
+			Profile:             a.Profile,
+			IncludeExts:         a.IncludeExts,
+			ExcludeExts:         a.ExcludeExts,
+			IgnoreBlankLines:    a.IgnoreBlankLines,
+			IgnoreGitattributes: a.IgnoreAttributes,
+			FixSeverities:       a.Fixes.Severities(),
+		},
+	}
+	if a.Fixes != nil && a.Fixes.Pattern != nil {
+		meta.Filters.FixPattern = a.Fixes.Pattern.String()
+	}
+	git := uncancelled(a.Git)
+	if info, err := DetectRepo(git); err == nil {
+		meta.Repo = info.Name()
+		meta.Worktree = info.IsWorktree
+	}
+	meta.Submodules = a.RecurseSubmodules
+	meta.PartialClone = a.partialClone
+	meta.Warnings = a.Warnings
+	meta.Heuristic = a.Heuristic
+	meta.Fast = a.Fast
+	for _, err := range a.errors {
+		meta.Errors = append(meta.Errors, err.Error())
+	}
+	meta.Interrupted = a.interrupted()
+	meta.CommitCount, meta.Shards = a.commitCount, a.shards
+	meta.IgnoredCommits = a.ignored
+	meta.MassMoves = a.massMoves
+	if a.attrFiles > 0 {
+		lines := a.attrLines
+		meta.AttributeFiles, meta.AttributeLines = a.attrFiles, &lines
+	}
+	meta.SymlinkChanges, meta.ModeOnlyChanges = a.symlinkChanges, a.modeChanges
+	meta.SkippedFiles = a.skipped
+	meta.MassMoveMode = a.MassMove
+	meta.AIGPrecedence = a.AIGPrecedence
+	meta.AmbiguousTags = a.ambiguous
+	meta.DateKind = a.DateKind
+	meta.AIManifest, meta.ManifestCommits = a.AIManifest, a.manifests
+	meta.BranchCopies = a.BranchCopies
+	meta.DroppedCopies = a.copies
+	meta.SZZ = a.SZZ
+	meta.BugFixCommits = a.bugFixes
+	if !a.Migration.Empty() {
+		meta.EmailRewrites = len(a.Migration.Emails)
+		meta.CommitRemaps = len(a.Migration.Commits)
+	}
+	if a.Classifier != nil {
+		meta.Estimator = a.Classifier.Model
+		meta.EstimatedCommits = a.estimated
+	}
+	meta.Remote = redactURL(gitOutput(git, "config", "--get", "remote.origin.url"))
+	meta.Head = gitOutput(git, "rev-parse", "HEAD")
+	return meta
+}
+
+// 去掉远程地址中的用户名和密码，避免凭据写入报告
+func redactURL(remote string) string {
+	if !strings.Contains(remote, "://") {
+		return remote
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
+
+// 报告中数字的显示方式，没有运行信息时使用默认格式
+func (m *Metadata) NumberFormat() NumberFormat {
+	if m == nil {
+		return NumberFormat{Lang: DefaultLang}
+	}
+	return NumberFormat{Lang: firstNonEmpty(m.Lang, DefaultLang), KLoC: m.KLoC}
+}
+
+// 以 key: value 形式列出运行信息，供文本和 CSV 输出使用
+func (m *Metadata) Lines() []string {
+	lines := []string{
+		"仓库: " + m.Repo,
+		"远程地址: " + m.Remote,
+		"HEAD: " + m.Head,
+		"工具版本: " + m.ToolVersion,
+		"生成时间: " + m.GeneratedAt,
+		"统计文件类型: " + includeExtsLabel(m.Filters.IncludeExts) + profileLabel(m.Filters.Profile),
+		"排除文件类型: " + strings.Join(m.Filters.ExcludeExts, ","),
+	}
+	if len(m.ConfigFiles) > 0 {
+		lines = append(lines, "配置文件: "+strings.Join(m.ConfigFiles, ", "))
+	}
+	for _, src := range m.Merged {
+		lines = append(lines, fmt.Sprintf("合并来源: %s (%s，%d 个提交，生成于 %s)", src.Path, firstNonEmpty(src.Repo, "未知仓库"), src.Commits, src.GeneratedAt))
+	}
+	if m.Filters.IgnoreBlankLines {
+		lines = append(lines, "新增空行: 不计入")
+	}
+	if m.Filters.IgnoreGitattributes {
+		lines = append(lines, ".gitattributes: 不按 linguist-generated/linguist-vendored 排除")
+	} else if m.AttributeFiles > 0 {
+		lines = append(lines, fmt.Sprintf(".gitattributes 排除: %d 个生成或第三方代码文件 (%s 行)", m.AttributeFiles, m.AttributeLines))
+	}
+	if m.SymlinkChanges+m.ModeOnlyChanges > 0 {
+		lines = append(lines, fmt.Sprintf("不计入的特殊变更: 符号链接 %d 处，只修改文件权限 %d 处", m.SymlinkChanges, m.ModeOnlyChanges))
+	}
+	lines = append(lines, skippedLines(m.SkippedFiles)...)
+	if m.Worktree {
+		lines = append(lines, "关联工作区: 是")
+	}
+	if m.Submodules {
+		lines = append(lines, "包含子模块: 是")
+	}
+	if m.Filters.MinSample > 0 || m.Filters.MinSampleLines > 0 {
+		lines = append(lines, fmt.Sprintf("比例最小样本: %d 次修复提交, %d 行", m.Filters.MinSample, m.Filters.MinSampleLines))
+	}
+	if (m.Lang != "" && m.Lang != DefaultLang) || m.KLoC {
+		line := "数字格式: " + firstNonEmpty(m.Lang, DefaultLang)
+		if m.KLoC {
+			line += "，行数以 KLoC (千行) 显示"
+		}
+		lines = append(lines, line)
+	}
+	if m.SZZ {
+		lines = append(lines, fmt.Sprintf("缺陷引入分析(SZZ): 扫描本期开始至今的 %d 个修复提交", m.BugFixCommits))
+	}
+	if m.FrozenAt != "" {
+		if m.FrozenChanged {
+			lines = append(lines, "已冻结: "+m.FrozenAt+"，当前结果与冻结记录不一致(见警告)")
+		} else {
+			lines = append(lines, "已冻结: "+m.FrozenAt+"，当前结果与冻结记录一致")
+		}
+	}
+	if m.PartialClone != "" {
+		lines = append(lines, "部分克隆: "+m.PartialClone)
+	}
+	if m.Filters.FixPattern != "" {
+		lines = append(lines, "修复提交识别: "+m.Filters.FixPattern)
+	}
+	if severities := strings.Join(m.Filters.FixSeverities, ", "); severities != "" && severities != strings.Join(defaultFixRules.Severities(), ", ") {
+		lines = append(lines, "修复严重级别: "+severities)
+	}
+	if len(m.Filters.Grep) > 0 {
+		label := "提交信息过滤: "
+		if m.Filters.InvertGrep {
+			label = "提交信息排除: "
+		}
+		lines = append(lines, label+strings.Join(m.Filters.Grep, ", "))
+	}
+	if m.DateKind == DateKindAuthor {
+		lines = append(lines, "时间口径: "+dateKindLabel(m.DateKind))
+	}
+	if m.Filters.Author != "" {
+		lines = append(lines, "作者过滤: "+m.Filters.Author)
+	}
+	if m.Filters.Identity != "" && m.Filters.Identity != IdentityEmail {
+		lines = append(lines, "开发者识别: "+IdentityLabel(m.Filters.Identity))
+	}
+	if len(m.Filters.EmailDomains) > 0 {
+		lines = append(lines, "邮箱域名: "+strings.Join(m.Filters.EmailDomains, ","))
+	}
+	if m.Filters.MinCommits > 0 || m.Filters.MinLines > 0 {
+		lines = append(lines, fmt.Sprintf("最小活跃度: %d 次提交, %d 行", m.Filters.MinCommits, m.Filters.MinLines))
+	}
+	if m.Heuristic {
+		lines = append(lines, "启发式检测: 已启用 (实验性，结果仅供参考)")
+	}
+	if m.Interrupted {
+		lines = append(lines, "已中断，报告只包含中断前完成的部分")
+	}
+	for _, e := range m.Errors {
+		lines = append(lines, "部分结果，分析未完成: "+e)
+	}
+	if m.Shards > 1 {
+		lines = append(lines, fmt.Sprintf("预计提交数: %d，按时间分 %d 片并行读取", m.CommitCount, m.Shards))
+	}
+	if m.Fast {
+		lines = append(lines, "快速模式: 未统计行数，只有提交次数、修复提交数和按提交平均的 AI 占比")
+	}
+	if m.Estimator != "" {
+		lines = append(lines, fmt.Sprintf("LLM 估算: %d 个未标注的提交由模型 %s 估算，估算值不是开发者标注", m.EstimatedCommits, m.Estimator))
+	}
+	if m.ManifestCommits > 0 {
+		lines = append(lines, fmt.Sprintf("AI 清单: %d 个没有 AIG 标记的提交按 %s 中列出的文件计算 AI 贡献", m.ManifestCommits, m.AIManifest))
+	}
+	if m.MassMoves > 0 {
+		if m.MassMoveMode == MassMoveExclude {
+			lines = append(lines, fmt.Sprintf("大规模移动: %d 次提交，已排除", m.MassMoves))
+		} else {
+			lines = append(lines, fmt.Sprintf("大规模移动: %d 次提交，已扣除未识别为重命名的移动文件行数", m.MassMoves))
+		}
+	}
+	lines = append(lines, ambiguousLines(m.AmbiguousTags, m.AIGPrecedence)...)
+	lines = append(lines, branchCopyLines(m.DroppedCopies, m.BranchCopies)...)
+	if len(m.IgnoredCommits) > 0 {
+		added, deleted := 0, 0
+		for _, c := range m.IgnoredCommits {
+			added += c.AddedLines
+			deleted += c.DeletedLines
+		}
+		lines = append(lines, fmt.Sprintf("忽略提交: %d 次 (共 +%d/-%d 行)", len(m.IgnoredCommits), added, deleted))
+		for _, c := range m.IgnoredCommits {
+			line := fmt.Sprintf("  %.10s %s <%s>", c.ID, c.Subject, c.Author)
+			if c.Reason != "" {
+				line += " 原因: " + c.Reason
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(m.UsageLogs) > 0 {
+		lines = append(lines, "使用日志: "+strings.Join(m.UsageLogs, ","))
+	}
+	if m.EmailRewrites > 0 || m.CommitRemaps > 0 {
+		lines = append(lines, fmt.Sprintf("迁移映射: %d 个旧邮箱, %d 个旧提交", m.EmailRewrites, m.CommitRemaps))
+	}
+	for _, warning := range m.Warnings {
+		lines = append(lines, "警告: "+warning)
+	}
+	return lines
+}
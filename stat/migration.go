@@ -0,0 +1,170 @@
+package stat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// 提交哈希映射文件中的哈希，至少 7 位
+var commitMapHashRegex = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+
+// 读取 "旧值 新值" 形式的映射文件，# 开头为注释，空行忽略
+// 首行为 git filter-repo 生成的 commit-map 表头 "old new" 时跳过
+func loadMappingFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取映射文件 '%s' 失败: %v", path, err)
+	}
+	defer f.Close()
+
+	mapping := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("错误：%s:%d: 格式不正确，每行应为 \"旧值 新值\"", path, n)
+		}
+		if n == 1 && fields[0] == "old" && fields[1] == "new" {
+			continue
+		}
+		mapping[strings.ToLower(fields[0])] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("错误：读取映射文件 '%s' 失败: %v", path, err)
+	}
+	return mapping, nil
+}
+
+// 读取旧邮箱到新邮箱的映射文件，旧邮箱不区分大小写
+func LoadEmailMap(path string) (map[string]string, error) {
+	mapping, err := loadMappingFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for old, email := range mapping {
+		if !strings.Contains(old, "@") || !strings.Contains(email, "@") {
+			return nil, fmt.Errorf("错误：邮箱映射文件 '%s' 中 '%s %s' 不是有效的邮箱", path, old, email)
+		}
+	}
+	return mapping, nil
+}
+
+// 读取旧提交哈希到新提交哈希的映射文件(如 git filter-repo 的 commit-map)
+// 旧哈希为全零的行(迁移中被删除的提交)忽略
+func LoadCommitMap(path string) (map[string]string, error) {
+	mapping, err := loadMappingFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for old, hash := range mapping {
+		hash = strings.ToLower(hash)
+		if !commitMapHashRegex.MatchString(old) || !commitMapHashRegex.MatchString(hash) {
+			return nil, fmt.Errorf("错误：提交映射文件 '%s' 中 '%s %s' 不是有效的提交哈希", path, old, hash)
+		}
+		if strings.Trim(hash, "0") == "" {
+			delete(mapping, old)
+			continue
+		}
+		mapping[old] = hash
+	}
+	return mapping, nil
+}
+
+// Migration 仓库迁移(如从 SVN 转换、改写历史)前后的身份和提交对应关系，
+// 使迁移前后的提交、运行记录和忽略列表能够对应起来
+type Migration struct {
+	// 旧邮箱(小写)到新邮箱
+	Emails map[string]string
+	// 旧提交哈希到新提交哈希
+	Commits map[string]string
+}
+
+// 没有任何映射
+func (m *Migration) Empty() bool {
+	return m == nil || (len(m.Emails) == 0 && len(m.Commits) == 0)
+}
+
+// 改写提交的邮箱和提交 ID，在汇总之前调用
+func (m *Migration) Apply(commits []CommitStats) {
+	m.rewriteEmails(commits)
+	m.remapCommits(commits)
+}
+
+func (m *Migration) rewriteEmails(commits []CommitStats) {
+	if m.Empty() {
+		return
+	}
+	for i := range commits {
+		if email, ok := m.Emails[strings.ToLower(commits[i].Email)]; ok {
+			commits[i].Email = email
+		}
+	}
+}
+
+// 分析迁移前的旧仓库时，读取 diff 等操作仍需要旧哈希，因此在分析完成后再改写提交 ID
+func (m *Migration) remapCommits(commits []CommitStats) {
+	if m.Empty() {
+		return
+	}
+	for i := range commits {
+		if id, ok := m.commitID(commits[i].ID); ok {
+			commits[i].ID = id
+		}
+	}
+}
+
+// 把忽略规则中的旧提交哈希换成新哈希，原规则同时保留
+func (m *Migration) ApplyIgnore(rules []IgnoreRule) []IgnoreRule {
+	if m.Empty() || len(m.Commits) == 0 {
+		return rules
+	}
+	result := append([]IgnoreRule{}, rules...)
+	for _, rule := range rules {
+		if id, ok := m.commitID(rule.Hash); ok {
+			result = append(result, IgnoreRule{Hash: id, Reason: rule.Reason})
+		}
+	}
+	return result
+}
+
+// 映射到 email 的旧邮箱，用于按作者查询时同时匹配迁移前的提交
+func (m *Migration) OldEmails(email string) []string {
+	if m.Empty() || email == "" {
+		return nil
+	}
+	var emails []string
+	for old, cur := range m.Emails {
+		if strings.EqualFold(cur, email) {
+			emails = append(emails, old)
+		}
+	}
+	return emails
+}
+
+// 按完整哈希或唯一的前缀(至少 7 位)查找新的提交哈希
+func (m *Migration) commitID(id string) (string, bool) {
+	id = strings.ToLower(id)
+	if cur, ok := m.Commits[id]; ok {
+		return cur, true
+	}
+	if len(id) < 7 || len(id) >= 40 {
+		return "", false
+	}
+	var found string
+	for old, cur := range m.Commits {
+		if strings.HasPrefix(old, id) {
+			if found != "" {
+				return "", false
+			}
+			found = cur
+		}
+	}
+	return found, found != ""
+}
@@ -0,0 +1,121 @@
+package stat
+
+import (
+	"fmt"
+	"path"
+)
+
+// 大规模移动的处理方式
+const (
+	// 扣除未被识别为重命名的移动文件的行数(默认)
+	MassMoveDiscount = "discount"
+	// 整个提交不参与统计
+	MassMoveExclude = "exclude"
+	// 不检测
+	MassMoveOff = "off"
+)
+
+const (
+	// 至少涉及该数量的文件才判断为大规模移动
+	massMoveMinFiles = 10
+	// 移动的文件占比达到该值判断为大规模移动
+	massMoveRatio = 0.8
+)
+
+// 检查大规模移动的处理方式是否有效
+func ValidMassMove(mode string) bool {
+	switch mode {
+	case MassMoveDiscount, MassMoveExclude, MassMoveOff:
+		return true
+	}
+	return false
+}
+
+// 识别以移动文件为主的提交(目录调整等)。git 识别出的重命名只统计内容变化，
+// 超出重命名检测上限等原因未被识别的移动表现为整文件删除加整文件添加，会大幅夸大行数。
+// discount 模式下扣除这部分行数，exclude 模式下整个提交记入忽略列表
+func (a *Analyzer) detectMassMoves(commits []CommitStats) []CommitStats {
+	if a.MassMove == MassMoveOff {
+		return commits
+	}
+
+	kept := commits[:0]
+	for _, c := range commits {
+		moved := pairMovedFiles(c.Files)
+		renamed := 0
+		for _, f := range c.Files {
+			if f.OldPath != "" {
+				renamed++
+			}
+		}
+		if len(c.Files) < massMoveMinFiles || float64(renamed+len(moved)) < massMoveRatio*float64(len(c.Files)) {
+			kept = append(kept, c)
+			continue
+		}
+
+		a.massMoves++
+		if a.MassMove == MassMoveExclude {
+			a.ignored = append(a.ignored, IgnoredCommit{
+				ID:           c.ID,
+				Author:       c.Author,
+				Subject:      c.Subject,
+				Reason:       fmt.Sprintf("大规模文件移动 (%d/%d 个文件)", renamed+len(moved), len(c.Files)),
+				AddedLines:   c.AddedLines,
+				DeletedLines: c.DeletedLines,
+			})
+			for _, f := range c.Files {
+				a.ignoredLines.Added += f.Added
+				a.ignoredLines.Deleted += f.Deleted
+			}
+			continue
+		}
+
+		for i := range moved {
+			f := &c.Files[moved[i]]
+			f.Moved = true
+			if !f.Skipped {
+				c.AddedLines -= f.Added
+				c.DeletedLines -= f.Deleted
+			}
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// 找出未被识别为重命名的移动：文件名相同、行数相近(相差不超过一半)的整文件删除和整文件添加，返回这些文件的下标
+func pairMovedFiles(files []FileChange) []int {
+	deletes := make(map[string][]int)
+	for i, f := range files {
+		if f.OldPath == "" && f.Added == 0 && f.Deleted > 0 {
+			name := path.Base(f.Path)
+			deletes[name] = append(deletes[name], i)
+		}
+	}
+
+	var moved []int
+	for i, f := range files {
+		if f.OldPath != "" || f.Deleted != 0 || f.Added == 0 {
+			continue
+		}
+		name := path.Base(f.Path)
+		candidates := deletes[name]
+		for j, d := range candidates {
+			if similarLines(f.Added, files[d].Deleted) {
+				moved = append(moved, d, i)
+				deletes[name] = append(candidates[:j:j], candidates[j+1:]...)
+				break
+			}
+		}
+	}
+	return moved
+}
+
+// 行数相差不超过较大者的一半
+func similarLines(a, b int) bool {
+	diff, larger := a-b, a
+	if diff < 0 {
+		diff, larger = -diff, b
+	}
+	return diff*2 <= larger
+}
@@ -0,0 +1,41 @@
+package stat
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PushNotification 推送触发的统计更新完成后发送给通知地址的内容
+type PushNotification struct {
+	Event  *PushEvent `json:"event"`
+	Period string     `json:"period"`
+	// 本次推送中新统计的提交
+	NewCommits []CommitStats `json:"new_commits"`
+	// 更新后本期的汇总
+	Summary RepoSummary `json:"summary"`
+}
+
+// Notifier 以 JSON POST 请求通知外部系统(如聊天机器人的中转服务、OKR 系统)
+type Notifier struct {
+	URLs   []string
+	Client *http.Client
+}
+
+// 向所有通知地址发送内容，返回各地址的错误，单个地址失败不影响其他地址
+func (n *Notifier) Notify(v interface{}) []error {
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	api := &APIClient{Client: client}
+
+	var errs []error
+	for _, url := range n.URLs {
+		if _, err := api.PostJSON(url, v); err != nil {
+			errs = append(errs, fmt.Errorf("错误：发送通知到 '%s' 失败: %s", url, strings.TrimPrefix(err.Error(), "错误：")))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,147 @@
+package stat
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 默认的语言设置，决定报告中数字的格式
+const DefaultLang = "zh-CN"
+
+// numberLocale 数字的千位分隔符和小数点
+type numberLocale struct {
+	group   string
+	decimal string
+}
+
+// 支持的语言，先按完整的语言标签查找，找不到时按主语言查找
+var numberLocales = map[string]numberLocale{
+	"zh":    {",", "."},
+	"en":    {",", "."},
+	"ja":    {",", "."},
+	"ko":    {",", "."},
+	"de":    {".", ","},
+	"es":    {".", ","},
+	"it":    {".", ","},
+	"nl":    {".", ","},
+	"pt":    {".", ","},
+	"fr":    {"\u00a0", ","},
+	"ru":    {"\u00a0", ","},
+	"pl":    {"\u00a0", ","},
+	"sv":    {"\u00a0", ","},
+	"de-CH": {"’", "."},
+}
+
+// 规范化语言标签，如 zh_cn、zh-CN.UTF-8 均转为 zh-CN，不支持的语言返回错误
+func NormalizeLang(lang string) (string, error) {
+	tag, _, _ := strings.Cut(strings.TrimSpace(lang), ".")
+	if tag == "" {
+		return DefaultLang, nil
+	}
+	primary, region, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	tag = strings.ToLower(primary)
+	if region != "" {
+		tag += "-" + strings.ToUpper(region)
+	}
+	if _, ok := lookupNumberLocale(tag); !ok {
+		return "", fmt.Errorf("错误：不支持的语言 '%s'，可选: %s", lang, strings.Join(Langs(), ", "))
+	}
+	return tag, nil
+}
+
+// 支持的语言(主语言)，按名称排序
+func Langs() []string {
+	var langs []string
+	for tag := range numberLocales {
+		if !strings.Contains(tag, "-") {
+			langs = append(langs, tag)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func lookupNumberLocale(tag string) (numberLocale, bool) {
+	if l, ok := numberLocales[tag]; ok {
+		return l, true
+	}
+	primary, _, _ := strings.Cut(tag, "-")
+	l, ok := numberLocales[primary]
+	return l, ok
+}
+
+// NumberFormat 报告中数字的显示方式：按语言添加千位分隔符，KLoC 为真时行数以千行为单位
+type NumberFormat struct {
+	Lang string
+	KLoC bool
+}
+
+func (f NumberFormat) locale() numberLocale {
+	if l, ok := lookupNumberLocale(f.Lang); ok {
+		return l
+	}
+	return numberLocales["zh"]
+}
+
+// 带千位分隔符的整数，如 12,345
+func (f NumberFormat) Int(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	return sign + groupDigits(digits, f.locale().group)
+}
+
+// 保留 prec 位小数的数字，整数部分带千位分隔符，小数点按语言显示
+func (f NumberFormat) Float(v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	l := f.locale()
+	whole, frac, ok := strings.Cut(s, ".")
+	whole = groupDigits(whole, l.group)
+	if ok {
+		whole += l.decimal + frac
+	}
+	return sign + whole
+}
+
+// 表格中的行数：默认为带千位分隔符的整数，KLoC 为真时如 12.35 KLoC
+func (f NumberFormat) LineValue(n int) string {
+	if f.KLoC {
+		return f.Float(float64(n)/1000, 2) + " KLoC"
+	}
+	return f.Int(n)
+}
+
+// 文本输出中带单位的行数，如 12,345 行、12.35 KLoC
+func (f NumberFormat) Lines(n int) string {
+	if f.KLoC {
+		return f.LineValue(n)
+	}
+	return f.Int(n) + " 行"
+}
+
+// 每三位数字插入一个分隔符
+func groupDigits(digits, sep string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
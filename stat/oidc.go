@@ -0,0 +1,192 @@
+package stat
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCVerifier 校验 OIDC 身份提供方签发的 RS256 ID 令牌
+type OIDCVerifier struct {
+	Config OIDCConfig
+	Client *http.Client
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+	// 上次获取签名公钥的时间，令牌使用未知公钥时最多每分钟重新获取一次
+	fetched time.Time
+}
+
+// OIDCClaims 令牌中与访问控制相关的信息
+type OIDCClaims struct {
+	Subject string
+	Email   string
+	Groups  []string
+}
+
+// 校验令牌的签名、签发方、受众和有效期，返回其中的用户信息
+func (v *OIDCVerifier) Verify(token string) (*OIDCClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("错误：令牌格式不正确")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("错误：不支持的令牌签名算法 '%s'", header.Alg)
+	}
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("错误：令牌签名格式不正确")
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+		return nil, fmt.Errorf("错误：令牌签名校验失败")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if iss, _ := claims["iss"].(string); iss != v.Config.Issuer {
+		return nil, fmt.Errorf("错误：令牌签发方 '%s' 不受信任", iss)
+	}
+	if !audienceContains(claims["aud"], v.Config.Audience) {
+		return nil, fmt.Errorf("错误：令牌的受众不是 '%s'", v.Config.Audience)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Unix() >= int64(exp) {
+		return nil, fmt.Errorf("错误：令牌已过期")
+	}
+
+	result := &OIDCClaims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	groupsClaim := v.Config.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	switch groups := claims[groupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				result.Groups = append(result.Groups, s)
+			}
+		}
+	case string:
+		result.Groups = strings.Fields(groups)
+	}
+	return result, nil
+}
+
+// 按 kid 查找签名公钥，找不到时重新获取身份提供方的公钥列表
+func (v *OIDCVerifier) key(kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(v.fetched) < time.Minute {
+		return nil, fmt.Errorf("错误：找不到令牌的签名公钥 '%s'", kid)
+	}
+	keys, err := v.fetchKeys()
+	v.fetched = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("错误：找不到令牌的签名公钥 '%s'", kid)
+}
+
+// 通过 OIDC 发现文档获取身份提供方的 RSA 签名公钥
+func (v *OIDCVerifier) fetchKeys() (map[string]*rsa.PublicKey, error) {
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	api := &APIClient{Client: client}
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	url := strings.TrimSuffix(v.Config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := api.GetJSON(url, &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("错误：OIDC 发现文档 '%s' 中缺少 jwks_uri", url)
+	}
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := api.GetJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
+
+// 解码令牌中 base64url 编码的 JSON 部分
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("错误：令牌格式不正确")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("错误：令牌格式不正确")
+	}
+	return nil
+}
+
+// 令牌的 aud 可以是字符串或字符串数组
+func audienceContains(aud interface{}, audience string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == audience
+	case []interface{}:
+		for _, v := range a {
+			if s, ok := v.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,516 @@
+package stat
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// 输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatHTML = "html"
+	FormatPDF  = "pdf"
+	FormatYAML = "yaml"
+)
+
+// RunOptions 各命令共用的运行参数
+// 优先级：命令行参数 > 环境变量 > 配置文件 > 默认值
+type RunOptions struct {
+	Since string
+	Until string
+	// git 提交范围，如 v1.4.0..v1.5.0，指定时代替日期确定统计范围
+	RevRange string
+	// 逗号分隔的输出格式，解析后保存在 Formats 中
+	Format     string
+	Formats    []string
+	Repo       string
+	ConfigPath string
+	// 报告输出目录，为空时输出到标准输出
+	OutDir string
+	// 额外的输出目标，每项为“名称=目标”，如 json=report.json、webhook=https://...、db=sqlite:aistat.db
+	Sinks []string
+	// 指定输出目录时仍同时输出到标准输出
+	Stdout bool
+	// 报告签名方式：hash、gpg、minisign，为空时不签名
+	Sign    string
+	SignKey string
+	// 生成 PDF 使用的转换工具(wkhtmltopdf 或 Chrome/Chromium)，为空时自动查找
+	PDFTool string
+	// 审计日志路径，off 表示不记录
+	AuditLog string
+	// 运行记录保存目录，off 表示不保存
+	Store string
+	// 显示统计周期之前该天数内的平均水平，0 表示不显示
+	RollingDays int
+	// 同时分析子模块，SubmodulePrefix 为真时文件路径带上子模块路径
+	RecurseSubmodules bool
+	SubmodulePrefix   bool
+	// 部分克隆中从远程获取缺失对象
+	FetchMissing bool
+	// IDE/AI 工具的使用日志，与自报的 AIG 比例对照
+	UsageLogs      []string
+	UsageThreshold float64
+	// 为没有 AIG 标记的提交调用 LLM 估算 AI 参与度，LLMEndpoint 为空时不启用
+	LLMEndpoint string
+	LLMModel    string
+	LLMDiff     bool
+	LLMCacheDir string
+	// 启发式 AI 风格检测(实验性)
+	Heuristic bool
+	// 快速模式：跳过 --numstat，只统计提交数、修复提交数和平均 AIG 比例
+	Fast bool
+	// 单个 git 命令的超时时间，0 表示不限制
+	GitTimeout time.Duration
+	// 取消时中止正在执行的 git 命令(如收到 SIGINT/SIGTERM)，不对应命令行参数
+	Context context.Context
+	// 比例的最小分母：修复提交数、变更行数低于该值时不显示比例
+	MinSample      int
+	MinSampleLines int
+	// 数字的语言格式及是否以 KLoC 显示行数
+	Lang string
+	KLoC bool
+	// 配置文件中的忽略列表
+	IgnoreCommits []IgnoreRule
+	// 大规模移动的处理方式
+	MassMove string
+	// 提交信息中有多个 AIG 标记时的取值规则
+	AIGPrecedence string
+	// 确定统计周期的时间口径：committer、author
+	DateKind string
+	// AI 清单的路径，off 表示不读取
+	AIManifest string
+	// 同一改动在多个分支上的副本的处理方式及主分支
+	BranchCopies string
+	MainBranch   string
+	// 不计入新增的空行
+	IgnoreBlankLines bool
+	// 不按 .gitattributes 的 linguist 属性排除文件
+	IgnoreGitattributes bool
+	// 文件类型预设的名称
+	Profile string
+	// 统计及排除的文件扩展名：命令行中 IncludeExts 代替预设的列表(AllExts 表示全部非二进制文件)，
+	// ExcludeExts 追加到预设的排除列表；Resolve 后为最终使用的列表
+	IncludeExts []string
+	ExcludeExts []string
+	// 统计全部非二进制、未被排除的文件
+	AllText bool
+	// 只统计提交信息匹配这些正则的提交，InvertGrep 为真时改为排除
+	Grep       []string
+	InvertGrep bool
+	// 仓库迁移的邮箱映射文件和提交哈希映射文件，解析后保存在 Migration 中
+	EmailMap  string
+	CommitMap string
+	Migration *Migration
+	// 识别修复提交的提交标题正则，编译后与配置中的严重级别一起保存在 Fixes 中
+	FixPattern string
+	Fixes      *FixRules
+}
+
+// 注册共用的命令行选项
+func (o *RunOptions) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.ConfigPath, "config", "", "配置文件路径 (默认读取当前目录下的 "+DefaultConfigFile+")")
+	fs.StringVar(&o.Since, "since", "", "开始日期，也可以作为位置参数传入 (环境变量 "+EnvSince+")")
+	fs.StringVar(&o.Until, "until", "", "结束日期，也可以作为位置参数传入 (环境变量 "+EnvUntil+")")
+	fs.StringVar(&o.RevRange, "rev-range", "", "按 git 提交范围统计，如 v1.4.0..v1.5.0，代替默认的日期范围")
+	fs.StringVar(&o.Format, "format", "", "输出格式: text, json, yaml, csv, html, pdf，多个格式用逗号分隔 (环境变量 "+EnvFormat+"，默认 text)")
+	fs.StringVar(&o.Repo, "repo", "", "要分析的仓库目录 (环境变量 "+EnvRepo+"，默认当前目录)")
+	fs.StringVar(&o.OutDir, "out-dir", "", "将报告写入该目录，文件名按统计周期自动生成")
+	fs.Func("sink", "额外的输出目标 名称=目标，可多次指定: terminal、text、json、yaml、csv、html、pdf 写入指定文件，webhook=URL 以 JSON POST 发送报告，db=存储位置 保存运行记录(sqlite:路径、postgres://...)", func(s string) error {
+		o.Sinks = append(o.Sinks, s)
+		return nil
+	})
+	fs.BoolVar(&o.Stdout, "stdout", false, "配合 --out-dir 使用，同时输出到标准输出")
+	fs.StringVar(&o.Sign, "sign", "", "在报告中嵌入内容摘要及签名: hash, gpg, minisign")
+	fs.StringVar(&o.SignKey, "sign-key", "", "签名密钥：gpg 用户 ID 或 minisign 私钥文件")
+	fs.StringVar(&o.PDFTool, "pdf-tool", "", "生成 PDF 使用的 wkhtmltopdf 或 Chrome/Chromium 程序路径 (默认从 PATH 中查找)")
+	fs.BoolVar(&o.RecurseSubmodules, "recurse-submodules", false, "同时分析已初始化的子模块(含嵌套子模块)")
+	fs.BoolVar(&o.SubmodulePrefix, "submodule-prefix", false, "配合 --recurse-submodules 使用，子模块中的文件路径加上子模块路径前缀")
+	fs.BoolVar(&o.FetchMissing, "fetch-missing", false, "部分克隆中从远程获取统计行数所需的缺失对象 (默认跳过行数统计并给出警告)")
+	fs.Func("usage-log", "IDE/AI 工具导出的使用日志(JSON 或 CSV)，与自报的 AI 占比对照，多个文件用逗号分隔", func(s string) error {
+		o.UsageLogs = append(o.UsageLogs, splitList(s)...)
+		return nil
+	})
+	fs.Float64Var(&o.UsageThreshold, "usage-threshold", 0, "自报 AI 占比与工具采纳占比相差超过该百分点时标记 (默认 30)")
+	fs.StringVar(&o.LLMEndpoint, "llm-endpoint", "", "LLM 接口地址(兼容 OpenAI Chat Completions)，为没有 AIG 标记的提交估算 AI 参与度，密钥从环境变量 "+EnvLLMAPIKey+" 读取")
+	fs.StringVar(&o.LLMModel, "llm-model", "", "LLM 估算使用的模型名称")
+	fs.BoolVar(&o.LLMDiff, "llm-diff", false, "LLM 估算时同时发送提交的 diff")
+	fs.DurationVar(&o.GitTimeout, "git-timeout", 0, "单个 git 命令的超时时间，如 10m，超时的步骤记为失败 (默认不限制)")
+	fs.BoolVar(&o.Fast, "fast", false, "快速模式：不读取文件变更(--numstat)，只统计提交次数、修复提交数和按提交平均的 AI 占比，适合超大仓库")
+	fs.BoolVar(&o.Heuristic, "heuristic", false, "实验性：根据 diff 的注释密度、样板代码等特征估算 AI 生成的可能性，仅供参考")
+	fs.IntVar(&o.MinSample, "min-sample", 0, "修复提交数低于该值时不显示 AI 修复贡献率，避免 1 次提交得出 100% 之类的误导")
+	fs.IntVar(&o.MinSampleLines, "min-sample-lines", 0, "添加/删除行数低于该值时不显示对应的 AI 占比")
+	fs.StringVar(&o.Lang, "lang", "", "报告中数字的语言格式，决定千位分隔符和小数点，如 zh-CN、en-US、de-DE、fr-FR (默认 "+DefaultLang+")")
+	fs.BoolVar(&o.KLoC, "kloc", false, "行数以 KLoC (千行) 为单位显示")
+	fs.StringVar(&o.MassMove, "mass-move", "", "以移动文件为主的提交(目录调整)的处理方式: discount 扣除未识别为重命名的移动行数, exclude 排除整个提交, off 不检测 (默认 discount)")
+	fs.StringVar(&o.AIGPrecedence, "aig-precedence", "", "提交信息中有多个 AIG 标记(如引用了其他提交的信息)时的取值规则: first trailer 段中的 AIG 键优先、没有时取全文第一个, last 全文最后一个, key 只认行首的 AIG 键(取最后一个), trailer 只认末尾 trailer 段中的 AIG 键 (默认 first)")
+	fs.StringVar(&o.DateKind, "date-kind", "", "按哪个时间确定提交属于哪个统计周期: committer 提交时间, author 作者时间(rebase、cherry-pick 后不变) (默认 committer)")
+	fs.StringVar(&o.AIManifest, "ai-manifest", "", "提交中列出 AI 代理生成的文件的清单路径，没有 AIG 标记的提交按清单中文件的行数计为 AI 贡献，off 不读取 (默认 "+DefaultAIManifest+")")
+	fs.StringVar(&o.BranchCopies, "branch-copies", "", "统计所有分支时，同一改动(cherry-pick、rebase)在多个分支上的副本的处理方式: first 只统计提交时间最早的一份, main 优先统计主分支上的一份, keep 每一份都统计 (默认 first)")
+	fs.StringVar(&o.MainBranch, "main-branch", "", "--branch-copies main 使用的主分支 (默认为 origin/HEAD 指向的分支，其次为 main、master)")
+	fs.BoolVar(&o.IgnoreGitattributes, "ignore-gitattributes", false, "不按 .gitattributes 中的 linguist-generated、linguist-vendored 属性排除文件")
+	fs.StringVar(&o.Profile, "profile", "", "文件类型预设: default (Web 前端与 Go), frontend, backend-go, mobile, data，也可以是配置项 profiles 中新增的预设 (默认 default)")
+	fs.Func("include-ext", "统计的文件扩展名，多个用逗号分隔，代替预设的列表，如 .rs,.py,.java；'*' 统计全部非二进制文件", func(s string) error {
+		o.IncludeExts = append(o.IncludeExts, splitList(s)...)
+		return nil
+	})
+	fs.Func("exclude-ext", "追加到预设中排除的文件扩展名(按文件名后缀匹配)，多个用逗号分隔，如 .lock,.min.js", func(s string) error {
+		o.ExcludeExts = append(o.ExcludeExts, splitList(s)...)
+		return nil
+	})
+	fs.BoolVar(&o.AllText, "all-text", false, "统计全部非二进制、未被排除的文件，同 --include-ext '*'，适合使用默认列表之外语言(如 Rust、Python、Java、Kotlin)的仓库")
+	fs.BoolVar(&o.IgnoreBlankLines, "ignore-blank-lines", false, "不计入新增的空行(需要读取 diff，速度较慢)")
+	fs.Func("grep", "只统计提交信息匹配该正则(扩展正则)的提交，如功能代号或工单前缀 PROJ-，可多次指定，匹配任意一个即可", func(s string) error {
+		o.Grep = append(o.Grep, s)
+		return nil
+	})
+	fs.BoolVar(&o.InvertGrep, "invert-grep", false, "配合 --grep 使用，改为排除提交信息匹配的提交")
+	fs.StringVar(&o.FixPattern, "fix-pattern", "", "识别修复提交的提交标题正则，如 (?i)^(fix|bugfix|hotfix) (默认标题以 fix 或 hotfix 开头)")
+	fs.StringVar(&o.EmailMap, "email-map", "", "仓库迁移的邮箱映射文件，每行 \"旧邮箱 新邮箱\"，汇总前把旧邮箱改写为新邮箱")
+	fs.StringVar(&o.CommitMap, "commit-map", "", "仓库迁移的提交哈希映射文件，每行 \"旧哈希 新哈希\" (兼容 git filter-repo 的 commit-map)，使忽略列表、运行记录中的旧哈希对应到新提交")
+	fs.StringVar(&o.AuditLog, "audit-log", "", "审计日志路径，off 表示不记录 (默认 ~/.aistat/audit.log)")
+	fs.StringVar(&o.Store, "store", "", "运行记录保存位置：目录、sqlite:文件路径、postgres://连接串或 memory，off 表示不保存 (默认 ~/.aistat/runs)")
+	fs.IntVar(&o.RollingDays, "rolling-days", 0, fmt.Sprintf("根据已保存的运行记录显示统计周期之前 N 天的平均水平 (如 %d)", DefaultRollingDays))
+}
+
+// 使用配置补全未在命令行指定的参数，并校验结果
+func (o *RunOptions) Resolve(cfg *Config, now time.Time) error {
+	o.Since = firstNonEmpty(o.Since, cfg.Since)
+	o.Until = firstNonEmpty(o.Until, cfg.Until)
+	o.Format = firstNonEmpty(o.Format, cfg.Format, FormatText)
+	o.Repo = firstNonEmpty(o.Repo, cfg.Repo)
+	o.OutDir = firstNonEmpty(o.OutDir, cfg.OutDir)
+	if len(o.Sinks) == 0 {
+		o.Sinks = cfg.Sinks
+	}
+	o.Sign = firstNonEmpty(o.Sign, cfg.Sign)
+	o.SignKey = firstNonEmpty(o.SignKey, cfg.SignKey)
+	o.PDFTool = firstNonEmpty(o.PDFTool, cfg.PDFTool)
+	o.MassMove = firstNonEmpty(o.MassMove, cfg.MassMove, MassMoveDiscount)
+	o.AIGPrecedence = firstNonEmpty(o.AIGPrecedence, cfg.AIGPrecedence, AIGPrecedenceFirst)
+	o.DateKind = firstNonEmpty(o.DateKind, cfg.DateKind, DateKindCommitter)
+	o.AIManifest = firstNonEmpty(o.AIManifest, cfg.AIManifest, DefaultAIManifest)
+	o.BranchCopies = firstNonEmpty(o.BranchCopies, cfg.BranchCopies, BranchCopiesFirst)
+	o.MainBranch = firstNonEmpty(o.MainBranch, cfg.MainBranch)
+	o.AuditLog = firstNonEmpty(o.AuditLog, cfg.AuditLog, DefaultAuditLog())
+	o.Store = firstNonEmpty(o.Store, cfg.Store, DefaultStoreDir())
+	if o.RollingDays == 0 {
+		o.RollingDays = cfg.RollingDays
+	}
+	o.RecurseSubmodules = o.RecurseSubmodules || cfg.RecurseSubmodules
+	o.SubmodulePrefix = o.SubmodulePrefix || cfg.SubmodulePrefix
+	o.IgnoreBlankLines = o.IgnoreBlankLines || cfg.IgnoreBlankLines
+	o.IgnoreGitattributes = o.IgnoreGitattributes || cfg.IgnoreGitattributes
+	if err := o.resolveExts(cfg); err != nil {
+		return err
+	}
+	if len(o.Grep) == 0 {
+		o.Grep = cfg.Grep
+	}
+	o.InvertGrep = o.InvertGrep || cfg.InvertGrep
+	o.FetchMissing = o.FetchMissing || cfg.FetchMissing
+	if len(o.UsageLogs) == 0 {
+		o.UsageLogs = cfg.UsageLogs
+	}
+	if o.UsageThreshold == 0 {
+		o.UsageThreshold = cfg.UsageThreshold
+	}
+	if o.UsageThreshold == 0 {
+		o.UsageThreshold = DefaultUsageThreshold
+	}
+	o.LLMEndpoint = firstNonEmpty(o.LLMEndpoint, cfg.LLMEndpoint)
+	o.LLMModel = firstNonEmpty(o.LLMModel, cfg.LLMModel)
+	o.LLMDiff = o.LLMDiff || cfg.LLMDiff
+	o.Heuristic = o.Heuristic || cfg.Heuristic
+	o.Fast = o.Fast || cfg.Fast
+	if o.GitTimeout == 0 {
+		o.GitTimeout = cfg.GitTimeout
+	}
+	if o.MinSample == 0 {
+		o.MinSample = cfg.MinSample
+	}
+	if o.MinSampleLines == 0 {
+		o.MinSampleLines = cfg.MinSampleLines
+	}
+	o.KLoC = o.KLoC || cfg.KLoC
+	o.LLMCacheDir = firstNonEmpty(o.LLMCacheDir, cfg.LLMCacheDir, DefaultLLMCacheDir())
+	o.EmailMap = firstNonEmpty(o.EmailMap, cfg.EmailMap)
+	o.CommitMap = firstNonEmpty(o.CommitMap, cfg.CommitMap)
+
+	var err error
+	if o.Since != "" {
+		if o.Since, err = NormalizeDate("起始日期", o.Since); err != nil {
+			return err
+		}
+	}
+	if o.Until != "" {
+		if o.Until, err = NormalizeDate("结束日期", o.Until); err != nil {
+			return err
+		}
+	}
+	if o.Since != "" && o.Until != "" && gitDate(o.Until, true) < gitDate(o.Since, false) {
+		return fmt.Errorf("错误：结束日期 '%s' 早于起始日期 '%s'", o.Until, o.Since)
+	}
+
+	o.Formats = nil
+	for _, format := range strings.Split(o.Format, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+		if _, ok := formatExts[format]; !ok {
+			return fmt.Errorf("错误：不支持的输出格式 '%s'", format)
+		}
+		o.Formats = append(o.Formats, format)
+	}
+	if len(o.Formats) == 0 {
+		o.Formats = []string{FormatText}
+	}
+	for _, spec := range o.Sinks {
+		if _, _, err := ParseSinkSpec(spec); err != nil {
+			return err
+		}
+	}
+	if !ValidSignMethod(o.Sign) {
+		return fmt.Errorf("错误：不支持的签名方式 '%s'", o.Sign)
+	}
+	if o.hasFormat(FormatPDF) {
+		if o.Sign != "" {
+			return fmt.Errorf("错误：PDF 格式不支持 --sign，请对 HTML 报告签名")
+		}
+		// 分析之前先确认转换工具可用
+		tool, err := findPDFTool(o.PDFTool)
+		if err != nil {
+			return err
+		}
+		o.PDFTool = tool
+	}
+	lang, err := NormalizeLang(firstNonEmpty(o.Lang, cfg.Lang))
+	if err != nil {
+		return err
+	}
+	o.Lang = lang
+	if o.InvertGrep && len(o.Grep) == 0 {
+		return fmt.Errorf("错误：--invert-grep 需要与 --grep 一起使用")
+	}
+	if _, err := compileGrep(o.Grep); err != nil {
+		return err
+	}
+	if !ValidMassMove(o.MassMove) {
+		return fmt.Errorf("错误：不支持的大规模移动处理方式 '%s'", o.MassMove)
+	}
+	if !ValidAIGPrecedence(o.AIGPrecedence) {
+		return fmt.Errorf("错误：不支持的 AIG 标记取值规则 '%s'，请使用 first、last、key 或 trailer", o.AIGPrecedence)
+	}
+	if !ValidDateKind(o.DateKind) {
+		return fmt.Errorf("错误：不支持的时间口径 '%s'，请使用 committer 或 author", o.DateKind)
+	}
+	if !ValidBranchCopies(o.BranchCopies) {
+		return fmt.Errorf("错误：不支持的跨分支副本处理方式 '%s'，请使用 first、main 或 keep", o.BranchCopies)
+	}
+	o.IgnoreCommits = nil
+	for _, line := range cfg.IgnoreCommits {
+		rule, ok, err := ParseIgnoreRule(line)
+		if err != nil {
+			return fmt.Errorf("错误：配置项 ignore_commits: %s", strings.TrimPrefix(err.Error(), "错误："))
+		}
+		if ok {
+			o.IgnoreCommits = append(o.IgnoreCommits, rule)
+		}
+	}
+	if err := o.loadMigration(cfg); err != nil {
+		return err
+	}
+	o.FixPattern = firstNonEmpty(o.FixPattern, cfg.FixPattern)
+	if o.Fixes, err = CompileFixRules(o.FixPattern, cfg.FixSeverities); err != nil {
+		return err
+	}
+	if o.Fast {
+		// 以下功能都需要逐行的 diff
+		switch {
+		case o.IgnoreBlankLines:
+			return fmt.Errorf("错误：--fast 不能与 --ignore-blank-lines 同时使用")
+		case o.Heuristic:
+			return fmt.Errorf("错误：--fast 不能与 --heuristic 同时使用")
+		case o.LLMDiff:
+			return fmt.Errorf("错误：--fast 不能与 --llm-diff 同时使用")
+		}
+	}
+	if o.LLMEndpoint != "" && o.LLMModel == "" {
+		return fmt.Errorf("错误：启用 LLM 估算时需要通过 --llm-model 指定模型")
+	}
+	if len(o.Formats) > 1 && o.OutDir == "" {
+		return fmt.Errorf("错误：同时输出多种格式时需要通过 --out-dir 指定输出目录")
+	}
+
+	if o.RevRange != "" {
+		// 子模块有各自的提交历史，无法使用主仓库的提交范围
+		if o.RecurseSubmodules {
+			return fmt.Errorf("错误：--rev-range 不能与 --recurse-submodules 同时使用")
+		}
+		// 指定提交范围时只使用显式给出的日期进一步筛选
+		return nil
+	}
+	o.Since, o.Until = DefaultDateRange(o.Since, o.Until, now)
+	return nil
+}
+
+// 读取迁移映射：映射文件与配置项 email_rewrites 合并，配置项优先
+func (o *RunOptions) loadMigration(cfg *Config) error {
+	m := &Migration{Emails: make(map[string]string)}
+	if o.EmailMap != "" {
+		emails, err := LoadEmailMap(o.EmailMap)
+		if err != nil {
+			return err
+		}
+		m.Emails = emails
+	}
+	for old, email := range cfg.EmailRewrites {
+		m.Emails[strings.ToLower(old)] = email
+	}
+	if o.CommitMap != "" {
+		commits, err := LoadCommitMap(o.CommitMap)
+		if err != nil {
+			return err
+		}
+		m.Commits = commits
+	}
+	o.Migration = nil
+	if !m.Empty() {
+		o.Migration = m
+	}
+	return nil
+}
+
+// 解析选项，允许选项与位置参数交替出现，返回位置参数
+func ParseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// 拆分逗号分隔的列表，去掉空白和空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// 按文件类型预设、配置及命令行确定统计和排除的扩展名：include_exts 代替预设的列表，exclude_exts 追加到预设的排除列表
+func (o *RunOptions) resolveExts(cfg *Config) error {
+	o.Profile = firstNonEmpty(o.Profile, cfg.Profile, DefaultProfile)
+	profile, err := ResolveProfile(o.Profile, cfg.Profiles)
+	if err != nil {
+		return err
+	}
+	if len(o.IncludeExts) == 0 {
+		o.IncludeExts = cfg.IncludeExts
+	}
+	if len(o.IncludeExts) == 0 {
+		o.IncludeExts = profile.IncludeExts
+	}
+	o.ExcludeExts = append(append(append([]string{}, profile.ExcludeExts...), cfg.ExcludeExts...), o.ExcludeExts...)
+	if o.AllText || cfg.AllText {
+		o.IncludeExts = []string{AllExts}
+	}
+	o.IncludeExts, o.ExcludeExts = normalizeExts(o.IncludeExts), normalizeExts(o.ExcludeExts)
+	return nil
+}
+
+// 规范扩展名：不含 "." 的扩展名(如 rs)补上开头的 "."，去掉重复项，"*" 及 _mock.go 这类后缀保持不变
+func normalizeExts(exts []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, ext := range exts {
+		if ext != AllExts && !strings.Contains(ext, ".") {
+			ext = "." + ext
+		}
+		if !seen[ext] {
+			seen[ext] = true
+			out = append(out, ext)
+		}
+	}
+	return out
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// 按运行参数创建分析器
+func (o *RunOptions) NewAnalyzer(git GitRunner) *Analyzer {
+	a := NewAnalyzer(git)
+	a.RecurseSubmodules = o.RecurseSubmodules
+	a.SubmodulePrefix = o.SubmodulePrefix
+	a.FetchMissing = o.FetchMissing
+	a.Heuristic = o.Heuristic
+	a.Fast = o.Fast
+	a.Progress = TerminalStderr()
+	a.Ignore = o.IgnoreCommits
+	a.MassMove = o.MassMove
+	a.AIGPrecedence = o.AIGPrecedence
+	a.DateKind = o.DateKind
+	a.AIManifest = o.AIManifest
+	a.BranchCopies, a.MainBranch = o.BranchCopies, o.MainBranch
+	a.IgnoreBlankLines = o.IgnoreBlankLines
+	a.IgnoreAttributes = o.IgnoreGitattributes
+	if len(o.IncludeExts) > 0 {
+		a.Profile = o.Profile
+		a.IncludeExts, a.ExcludeExts = o.IncludeExts, o.ExcludeExts
+	}
+	a.Migration = o.Migration
+	a.Fixes = o.Fixes
+	if o.LLMEndpoint != "" {
+		a.Classifier = &LLMClassifier{
+			Endpoint:    o.LLMEndpoint,
+			Model:       o.LLMModel,
+			APIKey:      os.Getenv(EnvLLMAPIKey),
+			IncludeDiff: o.LLMDiff,
+			CacheDir:    o.LLMCacheDir,
+		}
+	}
+	return a
+}
+
+// 创建在 dir 中执行 git 命令的 GitRunner，使用 --git-timeout 的超时时间
+func (o *RunOptions) GitRunner(dir string) *ExecGitRunner {
+	return &ExecGitRunner{Dir: dir, Timeout: o.GitTimeout, Context: o.Context}
+}
+
+// 是否输出该格式(含 --sink 中的格式)
+func (o *RunOptions) hasFormat(format string) bool {
+	for _, f := range o.Formats {
+		if f == format {
+			return true
+		}
+	}
+	for _, spec := range o.Sinks {
+		if name, _, err := ParseSinkSpec(spec); err == nil && name == format {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 组织架构的层级，由上到下依次为部门、小组、团队
+const (
+	OrgDepartment = "department"
+	OrgGroup      = "group"
+	OrgTeam       = "team"
+)
+
+// 各层级的显示名称，按深度排列
+var orgLevels = []struct{ Level, Label string }{
+	{OrgDepartment, "部门"},
+	{OrgGroup, "小组"},
+	{OrgTeam, "团队"},
+}
+
+// 未归属到组织架构的开发者汇总到该单位
+const OrgUnassigned = "未归属"
+
+// OrgUnit 配置文件中组织架构的一个单位，第一层为部门，其下为小组、团队；
+// 没有下级的单位名称对应名单中成员的 team
+type OrgUnit struct {
+	Name     string    `yaml:"name"`
+	Children []OrgUnit `yaml:"children"`
+}
+
+// OrgRollup 一个单位汇总的统计，Stats.Name 为从部门开始的完整路径，MemberCount 为人数
+type OrgRollup struct {
+	Level string       `json:"level" yaml:"level"`
+	Name  string       `json:"name" yaml:"name"`
+	Path  string       `json:"path" yaml:"path"`
+	Stats *AuthorStats `json:"stats" yaml:"stats"`
+}
+
+// 校验组织架构：最多三层，同级名称不重复，末级单位在名单中有成员且不重复
+func ValidateOrg(org []OrgUnit, roster []RosterMember) error {
+	teams := make(map[string]bool)
+	for _, m := range roster {
+		teams[m.Team] = true
+	}
+	leaves := make(map[string]bool)
+	var walk func(units []OrgUnit, depth int, parent string) error
+	walk = func(units []OrgUnit, depth int, parent string) error {
+		if depth >= len(orgLevels) {
+			return fmt.Errorf("错误：组织架构 '%s' 的层级超过 %d 层(部门、小组、团队)", parent, len(orgLevels))
+		}
+		siblings := make(map[string]bool)
+		for _, u := range units {
+			path := orgPath(parent, u.Name)
+			if u.Name == "" || u.Name == OrgUnassigned {
+				return fmt.Errorf("错误：组织架构 '%s' 下有单位的名称为空或为保留名称 '%s'", parent, OrgUnassigned)
+			}
+			if siblings[u.Name] {
+				return fmt.Errorf("错误：组织架构中 '%s' 重复", path)
+			}
+			siblings[u.Name] = true
+			if len(u.Children) > 0 {
+				if err := walk(u.Children, depth+1, path); err != nil {
+					return err
+				}
+				continue
+			}
+			if leaves[u.Name] {
+				return fmt.Errorf("错误：组织架构中团队 '%s' 出现了多次", u.Name)
+			}
+			leaves[u.Name] = true
+			if !teams[u.Name] {
+				return fmt.Errorf("错误：组织架构中团队 '%s' 在名单中没有成员", u.Name)
+			}
+		}
+		return nil
+	}
+	return walk(org, 0, "")
+}
+
+func orgPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
+
+// 按组织架构逐级汇总开发者及汇总行，按名单中成员的 team 归属到末级单位，
+// 结果按部门、其下小组、团队的顺序排列，不在组织架构中的开发者汇总为“未归属”
+func RollupOrg(org []OrgUnit, roster []RosterMember, rows []*AuthorStats) []*OrgRollup {
+	teamOf := make(map[string]string, len(roster))
+	for _, m := range roster {
+		teamOf[strings.ToLower(m.Email)] = m.Team
+	}
+	byTeam := make(map[string][]*AuthorStats)
+	for _, s := range rows {
+		team := ""
+		if s.MemberCount == 0 {
+			team = teamOf[strings.ToLower(s.Email)]
+		}
+		byTeam[team] = append(byTeam[team], s)
+	}
+
+	var result []*OrgRollup
+	assigned := make(map[string]bool)
+	var walk func(units []OrgUnit, depth int, parent string) []*AuthorStats
+	walk = func(units []OrgUnit, depth int, parent string) []*AuthorStats {
+		var all []*AuthorStats
+		for _, u := range units {
+			r := &OrgRollup{Level: orgLevels[depth].Level, Name: u.Name, Path: orgPath(parent, u.Name)}
+			r.Stats = &AuthorStats{Name: r.Path}
+			result = append(result, r)
+			members := byTeam[u.Name]
+			if len(u.Children) > 0 {
+				members = walk(u.Children, depth+1, r.Path)
+			} else {
+				assigned[u.Name] = true
+			}
+			for _, s := range members {
+				r.Stats.Merge(s)
+			}
+			all = append(all, members...)
+		}
+		return all
+	}
+	walk(org, 0, "")
+
+	unassigned := &AuthorStats{Name: OrgUnassigned}
+	for team, members := range byTeam {
+		if assigned[team] {
+			continue
+		}
+		for _, s := range members {
+			unassigned.Merge(s)
+		}
+	}
+	if unassigned.MemberCount > 0 {
+		result = append(result, &OrgRollup{Level: OrgDepartment, Name: OrgUnassigned, Path: OrgUnassigned, Stats: unassigned})
+	}
+	return result
+}
+
+// 层级的显示名称
+func OrgLevelLabel(level string) string {
+	for _, l := range orgLevels {
+		if l.Level == level {
+			return l.Label
+		}
+	}
+	return level
+}
+
+// 按层级缩进打印组织汇总，比例按最小样本设置显示
+func PrintOrg(w io.Writer, org []*OrgRollup, nf NumberFormat, minSample, minLines int) {
+	fmt.Fprintf(w, "\n  组织汇总:\n")
+	for _, r := range org {
+		indent := strings.Repeat("  ", strings.Count(r.Path, "/"))
+		s := r.Stats
+		level := OrgLevelLabel(r.Level) + "，"
+		if r.Name == OrgUnassigned {
+			level = ""
+		}
+		fmt.Fprintf(w, "    %s%s (%s%s 人): 提交 %s 次，添加 %s，AI 添加占比 %s，AI 修复贡献率 %s\n", indent, r.Name, level, nf.Int(s.MemberCount),
+			nf.Int(s.CommitCount), nf.Lines(s.TotalAddedLines), FormatSampleRatio(s.TotalAIAddedLines, s.RatioAddedLines(), minLines),
+			FormatSampleRatio(s.FixAndAIGCount, s.RatioFixCount(), minSample))
+	}
+}
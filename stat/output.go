@@ -0,0 +1,112 @@
+package stat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// 各输出格式对应的文件扩展名
+var formatExts = map[string]string{
+	FormatText: ".txt",
+	FormatJSON: ".json",
+	FormatCSV:  ".csv",
+	FormatHTML: ".html",
+	FormatPDF:  ".pdf",
+	FormatYAML: ".yaml",
+}
+
+// 生成自动命名的报告文件名，如 aistat_2024-06-01_2024-06-15.json
+// label 非空时加在统计周期前，用于区分不同作者的报告
+func ReportFileName(label, format string, period ...string) string {
+	parts := []string{"aistat"}
+	if label != "" {
+		parts = append(parts, SafeFileName(label))
+	}
+	for _, p := range period {
+		parts = append(parts, SafeFileName(p))
+	}
+	return strings.Join(parts, "_") + formatExts[format]
+}
+
+// 文件名中的统计周期：指定提交范围时为范围本身，否则为起止日期
+func (o *RunOptions) period() []string {
+	if o.RevRange != "" {
+		return []string{o.RevRange}
+	}
+	return []string{o.Since, o.Until}
+}
+
+// 按每种输出格式输出报告，分析只执行一次，各格式共用同一份结果。
+// 未指定输出目录时写到 stdout；指定时写入自动命名的文件，并在 Stdout 为真时同时写到 stdout。
+// 返回已写入的文件路径
+func (o *RunOptions) WriteOutputs(stdout io.Writer, label string, render func(w io.Writer, format string) error) ([]string, error) {
+	var paths []string
+	for _, format := range o.Formats {
+		if o.OutDir == "" {
+			if err := o.render(stdout, format, render); err != nil {
+				return paths, err
+			}
+			continue
+		}
+
+		path, err := o.writeFile(stdout, label, format, render)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// 将单个格式的报告写入输出目录
+func (o *RunOptions) writeFile(stdout io.Writer, label, format string, render func(w io.Writer, format string) error) (string, error) {
+	if err := os.MkdirAll(o.OutDir, 0o755); err != nil {
+		return "", fmt.Errorf("错误：创建输出目录 '%s' 失败: %v", o.OutDir, err)
+	}
+	path := filepath.Join(o.OutDir, ReportFileName(label, format, o.period()...))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("错误：创建报告文件 '%s' 失败: %v", path, err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	if o.Stdout {
+		w = io.MultiWriter(f, stdout)
+	}
+	if err := o.render(w, format, render); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("错误：写入报告文件 '%s' 失败: %v", path, err)
+	}
+	return path, nil
+}
+
+// 渲染单个格式的报告，开启签名时先渲染到内存再连同签名写出
+func (o *RunOptions) render(w io.Writer, format string, render func(w io.Writer, format string) error) error {
+	if o.Sign == "" {
+		return render(w, format)
+	}
+
+	var buf bytes.Buffer
+	if err := render(&buf, format); err != nil {
+		return err
+	}
+	return WriteSigned(w, buf.Bytes(), format, o.Sign, o.SignKey)
+}
+
+// 将文件名中的路径分隔符、空白等字符替换为下划线
+func SafeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,186 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// 默认按前两级目录统计
+const DefaultOwnershipDepth = 2
+
+// AI 添加占比达到该百分比的目录视为 AI 编写为主的目录
+const OwnershipAIHeavy = 50
+
+// 文本报告中列出的目录数
+const ownershipTopDirs = 20
+
+// DirOwnership 一个目录的变更在开发者之间的集中程度
+type DirOwnership struct {
+	Dir string `json:"dir" yaml:"dir"`
+	// 修改过该目录的开发者人数及变更行数(添加+删除)
+	Authors      int `json:"authors" yaml:"authors"`
+	ChangedLines int `json:"changed_lines" yaml:"changed_lines"`
+	// 计入比例的提交的添加行数及 AI 添加行数
+	AddedLines   int `json:"added_lines" yaml:"added_lines"`
+	AIAddedLines int `json:"ai_added_lines" yaml:"ai_added_lines"`
+	// 变更行数最多的开发者及其占比(百分比)
+	TopAuthor string  `json:"top_author" yaml:"top_author"`
+	TopShare  float64 `json:"top_share" yaml:"top_share"`
+	// 巴士因子：变更行数合计超过一半所需的最少开发者人数
+	BusFactor int `json:"bus_factor" yaml:"bus_factor"`
+}
+
+// 目录的 AI 添加占比(百分比)
+func (d *DirOwnership) AIRatio() float64 {
+	return percent(d.AIAddedLines, d.AddedLines)
+}
+
+// OwnershipGroup 一组目录(AI 编写为主或其他)集中程度的汇总
+type OwnershipGroup struct {
+	Name string `json:"name" yaml:"name"`
+	Dirs int    `json:"dirs" yaml:"dirs"`
+	// 巴士因子为 1(一个人贡献了一半以上变更)的目录数
+	SingleOwner     int     `json:"single_owner" yaml:"single_owner"`
+	MedianBusFactor float64 `json:"median_bus_factor" yaml:"median_bus_factor"`
+	MedianTopShare  float64 `json:"median_top_share" yaml:"median_top_share"`
+}
+
+// Ownership 按目录统计的变更集中程度，比较 AI 编写为主的目录与其他目录
+type Ownership struct {
+	Depth     int            `json:"depth" yaml:"depth"`
+	Threshold float64        `json:"ai_heavy_threshold" yaml:"ai_heavy_threshold"`
+	Dirs      []DirOwnership `json:"dirs" yaml:"dirs"`
+	AIHeavy   OwnershipGroup `json:"ai_heavy" yaml:"ai_heavy"`
+	Other     OwnershipGroup `json:"other" yaml:"other"`
+}
+
+// 提交中文件所属的目录，取前 depth 级，根目录下的文件为 "."
+func ownershipDir(path string, depth int) string {
+	parts := strings.Split(path, "/")
+	parts = parts[:len(parts)-1]
+	if len(parts) == 0 {
+		return "."
+	}
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// 按目录汇总计入统计的文件的变更行数，开发者按 identity 识别，目录按变更行数从多到少排列
+func BuildOwnership(commits []CommitStats, depth int, identity string) *Ownership {
+	if depth <= 0 {
+		depth = DefaultOwnershipDepth
+	}
+	type dirLines struct {
+		DirOwnership
+		byAuthor map[string]int
+		names    map[string]string
+	}
+	dirs := make(map[string]*dirLines)
+	for i := range commits {
+		c := &commits[i]
+		key := IdentityKey(identity, c.Author, c.Email)
+		for _, f := range c.Files {
+			if f.Skipped || f.Moved {
+				continue
+			}
+			dir := ownershipDir(f.Path, depth)
+			d, ok := dirs[dir]
+			if !ok {
+				d = &dirLines{DirOwnership: DirOwnership{Dir: dir}, byAuthor: make(map[string]int), names: make(map[string]string)}
+				dirs[dir] = d
+			}
+			d.byAuthor[key] += f.Added + f.Deleted
+			d.names[key] = c.Author
+			d.ChangedLines += f.Added + f.Deleted
+			if c.AIGPolicy == "" {
+				d.AddedLines += f.Added
+				d.AIAddedLines += int(math.Round(float64(f.Added) * c.AIGRatio))
+			}
+		}
+	}
+
+	o := &Ownership{Depth: depth, Threshold: OwnershipAIHeavy, Dirs: []DirOwnership{}}
+	for _, d := range dirs {
+		if d.ChangedLines == 0 {
+			continue
+		}
+		keys := make([]string, 0, len(d.byAuthor))
+		for key := range d.byAuthor {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if d.byAuthor[keys[i]] != d.byAuthor[keys[j]] {
+				return d.byAuthor[keys[i]] > d.byAuthor[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		d.Authors = len(keys)
+		d.TopAuthor = d.names[keys[0]]
+		d.TopShare = percent(d.byAuthor[keys[0]], d.ChangedLines)
+		covered := 0
+		for _, key := range keys {
+			covered += d.byAuthor[key]
+			d.BusFactor++
+			if covered*2 > d.ChangedLines {
+				break
+			}
+		}
+		o.Dirs = append(o.Dirs, d.DirOwnership)
+	}
+	sort.Slice(o.Dirs, func(i, j int) bool {
+		if o.Dirs[i].ChangedLines != o.Dirs[j].ChangedLines {
+			return o.Dirs[i].ChangedLines > o.Dirs[j].ChangedLines
+		}
+		return o.Dirs[i].Dir < o.Dirs[j].Dir
+	})
+	o.AIHeavy = summarizeOwnership("AI 编写为主", o.Dirs, func(d *DirOwnership) bool { return d.AddedLines > 0 && d.AIRatio() >= OwnershipAIHeavy })
+	o.Other = summarizeOwnership("其他目录", o.Dirs, func(d *DirOwnership) bool { return d.AddedLines == 0 || d.AIRatio() < OwnershipAIHeavy })
+	return o
+}
+
+// 汇总满足条件的目录
+func summarizeOwnership(name string, dirs []DirOwnership, match func(*DirOwnership) bool) OwnershipGroup {
+	g := OwnershipGroup{Name: name}
+	var busFactors, topShares []float64
+	for i := range dirs {
+		d := &dirs[i]
+		if !match(d) {
+			continue
+		}
+		g.Dirs++
+		if d.BusFactor == 1 {
+			g.SingleOwner++
+		}
+		busFactors = append(busFactors, float64(d.BusFactor))
+		topShares = append(topShares, d.TopShare)
+	}
+	g.MedianBusFactor = median(busFactors)
+	g.MedianTopShare = median(topShares)
+	return g
+}
+
+// 打印各目录的集中程度及 AI 编写为主的目录与其他目录的比较
+func PrintOwnership(w io.Writer, o *Ownership, nf NumberFormat) {
+	fmt.Fprintf(w, "\n  目录变更集中程度 (按前 %d 级目录，AI 添加占比不低于 %g%% 为 AI 编写为主):\n", o.Depth, o.Threshold)
+	for _, g := range []OwnershipGroup{o.AIHeavy, o.Other} {
+		if g.Dirs == 0 {
+			fmt.Fprintf(w, "    %s: 无\n", g.Name)
+			continue
+		}
+		fmt.Fprintf(w, "    %s: %s 个目录，巴士因子为 1 的 %s 个 (%s)，巴士因子中位数 %s，最大贡献者占比中位数 %.2f%%\n",
+			g.Name, nf.Int(g.Dirs), nf.Int(g.SingleOwner), FormatSampleRatio(g.SingleOwner, g.Dirs, 0), nf.Float(g.MedianBusFactor, 1), g.MedianTopShare)
+	}
+	for i, d := range o.Dirs {
+		if i == ownershipTopDirs {
+			fmt.Fprintf(w, "      ... 另有 %s 个目录\n", nf.Int(len(o.Dirs)-ownershipTopDirs))
+			break
+		}
+		fmt.Fprintf(w, "      %s: 变更 %s，%s 人，巴士因子 %d，%s 占 %.2f%%，AI 添加占比 %s\n",
+			d.Dir, nf.Lines(d.ChangedLines), nf.Int(d.Authors), d.BusFactor, d.TopAuthor, d.TopShare, FormatSampleRatio(d.AIAddedLines, d.AddedLines, 0))
+	}
+}
@@ -0,0 +1,325 @@
+package stat
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 定义正则表达式模式常量，避免重复编译
+const (
+	aigPattern = `AIG:(\s*([0-9.]+))`
+	// 策略标记：n/a 表示无法使用 AI 工具，exempt 表示豁免(如生成代码)，负数同样视为策略标记
+	policyPattern = `(?i)AIG:\s*(n/?a\b|exempt\b|-[0-9.]+)`
+	// squash 合并提交的汇总标记，优先于正文中各原始提交的 AIG 标记
+	squashPattern = `(?m)^` + SquashTrailer + `:\s*([0-9.]+)`
+	// 提交标题以 fix 或 hotfix 开头视为修复提交
+	fixPattern = `^(fix|hotfix)`
+	// 每个提交以 \x1e 开头作为唯一的提交边界标记
+	commitSep = "\x1e"
+	// 提交头部各字段以 \x1f 分隔，正文以 \x1f 结尾，避免作者名、邮箱或提交信息中的引号、空格干扰解析
+	fieldSep     = "\x1f"
+	prettyFormat = "%x1e%H%x1f%an%x1f%ae%x1f%ad%x1f%s%x1f%b%x1f"
+	// 头部字段数：哈希、作者、邮箱、时间、标题、正文，其后为文件变更列表
+	headerFields = 6
+	// 添加文件扩展名常量
+	includeFileExts = ".html,.vue,.js,.ts,.tsx,.css,.scss,.cjs,.go,.php,.yaml,.proto"
+	excludeFileExts = ".pb.go,.pb.validate.go"
+)
+
+var (
+	aigRegex    = regexp.MustCompile(aigPattern)
+	squashRegex = regexp.MustCompile(squashPattern)
+	policyRegex = regexp.MustCompile(policyPattern)
+	fixRegex    = regexp.MustCompile(fixPattern)
+)
+
+// 分割提交信息，按提交边界标记切分，不受提交正文内容影响
+func splitCommits(output string) []string {
+	var commits []string
+	eachCommit(output, func(commit string) {
+		commits = append(commits, commit)
+	})
+	return commits
+}
+
+// 依次处理每个提交，提交内容是 output 的子串，不复制
+func eachCommit(output string, fn func(commit string)) {
+	for output != "" {
+		var commit string
+		commit, output, _ = strings.Cut(output, commitSep)
+		if commit = strings.TrimSpace(commit); commit != "" {
+			fn(commit)
+		}
+	}
+}
+
+// 取出下一个以 sep 分隔的字段，没有分隔符时返回剩余部分
+func nextField(s string, sep byte) (field, rest string) {
+	if i := strings.IndexByte(s, sep); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// rawChange --raw 输出中一个文件的特殊变更类型及状态
+type rawChange struct {
+	path, kind, status string
+}
+
+// logParser 解析 LogArgs 格式的输出，在提交之间复用临时数据以减少内存分配
+type logParser struct {
+	includeExts, excludeExts []string
+	// AIG 标记的取值规则
+	precedence string
+	raws       []rawChange
+}
+
+// 按文件路径查找 --raw 的记录，numstat 与 --raw 的文件顺序相同，通常第 i 个即为所求
+func (p *logParser) raw(i int, path string) rawChange {
+	if i < len(p.raws) && p.raws[i].path == path {
+		return p.raws[i]
+	}
+	for _, r := range p.raws {
+		if r.path == path {
+			return r
+		}
+	}
+	return rawChange{}
+}
+
+// 解析单个提交，格式不正确时返回 false
+func parseCommit(commit string, includeExts, excludeExts []string) (CommitStats, bool) {
+	p := &logParser{includeExts: includeExts, excludeExts: excludeExts}
+	return p.parse(commit)
+}
+
+func (p *logParser) parse(commit string) (CommitStats, bool) {
+	// 头部字段：哈希、作者、邮箱、时间、标题、正文，其后为文件变更列表
+	var fields [headerFields]string
+	rest := commit
+	for i := range fields {
+		field, tail, ok := strings.Cut(rest, fieldSep)
+		if !ok {
+			return CommitStats{}, false
+		}
+		fields[i], rest = field, tail
+	}
+
+	// 解析提交的基本信息（ID、作者、邮箱、时间、标题、正文）
+	stats := CommitStats{
+		ID:      strings.TrimSpace(fields[0]),
+		Time:    fields[3],
+		Subject: fields[4],
+	}
+	stats.Author, stats.Email = normalizeIdentity(fields[1], fields[2])
+
+	// 合并提交消息
+	stats.Message = stats.Subject
+	if body := strings.TrimSpace(fields[5]); body != "" {
+		stats.Message += "\n" + body
+	}
+
+	if mayHaveAIGTag(stats.Message) {
+		stats.AIGRatio, stats.AIGPolicy, stats.AIGSource, stats.AIGAmbiguous = extractAIG(stats.Subject, stats.Message, p.precedence)
+	}
+	stats.IsFix = fixRegex.MatchString(stats.Subject)
+
+	// 获取文件变更列表，--raw 输出的行在 numstat 之前；每个文件各占一行，按行数预留容量
+	if rest = strings.TrimSpace(rest); rest != "" {
+		stats.Files = make([]FileChange, 0, (strings.Count(rest, "\n")+2)/2)
+	}
+	p.raws = p.raws[:0]
+	numstat := 0
+	for rest != "" {
+		var change string
+		change, rest = nextField(rest, '\n')
+		if strings.HasPrefix(change, ":") {
+			path, kind, status := parseRawChange(change)
+			p.raws = append(p.raws, rawChange{path: path, kind: kind, status: status})
+			continue
+		}
+		added, deleted, rawPath, ok := parseNumstat(change)
+		if !ok {
+			continue
+		}
+
+		fileName, oldName := parseRenamePath(rawPath)
+		raw := p.raw(numstat, fileName)
+		numstat++
+		file := FileChange{
+			Path:    fileName,
+			OldPath: oldName,
+			Added:   added,
+			Deleted: deleted,
+			Change:  raw.kind,
+			Status:  raw.status,
+		}
+		switch {
+		case file.Change != "":
+			file.SkipReason = file.Change
+		case strings.HasPrefix(change, "-\t-\t"):
+			// 二进制文件没有行数
+			if file.SkipReason = extSkipReason(fileName, p.includeExts, p.excludeExts); file.SkipReason != SkipExcludedExt {
+				file.SkipReason = SkipBinary
+			}
+		default:
+			file.SkipReason = extSkipReason(fileName, p.includeExts, p.excludeExts)
+		}
+		file.Skipped = file.SkipReason != ""
+		stats.Files = append(stats.Files, file)
+		if file.Skipped {
+			continue
+		}
+		stats.AddedLines += added
+		stats.DeletedLines += deleted
+	}
+
+	return stats, true
+}
+
+// 解析 --raw 输出的一行(":旧权限 新权限 旧对象 新对象 状态\t路径[\t新路径]")，
+// 返回变更后的路径、特殊变更类型(普通变更为空)及状态字母(去掉重命名、复制的相似度)
+func parseRawChange(line string) (path, kind, status string) {
+	info, paths, ok := strings.Cut(line, "\t")
+	if !ok {
+		return "", "", ""
+	}
+	var fields [5]string
+	rest := strings.TrimPrefix(info, ":")
+	for i := range fields {
+		rest = strings.TrimLeft(rest, " ")
+		fields[i], rest = nextField(rest, ' ')
+	}
+	if fields[4] == "" {
+		return "", "", ""
+	}
+	if i := strings.LastIndexByte(paths, '\t'); i >= 0 {
+		paths = paths[i+1:]
+	}
+	path = unquotePath(paths)
+	oldMode, newMode, oldBlob, newBlob := fields[0], fields[1], fields[2], fields[3]
+	status = fields[4][:1]
+	const symlinkMode = "120000"
+	switch {
+	case newMode == symlinkMode || (status == FileDeleted && oldMode == symlinkMode):
+		// 新增、修改、删除符号链接及普通文件改为符号链接；符号链接改为普通文件时按普通文件统计
+		kind = FileSymlink
+	case oldMode != newMode && oldBlob == newBlob && status != FileAdded && status != FileDeleted:
+		kind = FileModeOnly
+	}
+	return path, kind, status
+}
+
+// 解析 numstat 的一行("添加\t删除\t路径"，二进制文件的行数为 "-")，不是文件变更记录时返回 false
+func parseNumstat(line string) (added, deleted int, path string, ok bool) {
+	first, rest, tab := strings.Cut(line, "\t")
+	var second string
+	if tab {
+		second, path, tab = strings.Cut(rest, "\t")
+	}
+	if !tab {
+		// 兼容以空白分隔的输出，文件名中可能包含空格
+		parts := strings.Fields(line)
+		if len(parts) < 3 {
+			return 0, 0, "", false
+		}
+		first, second, path = parts[0], parts[1], strings.Join(parts[2:], " ")
+	}
+	if added, ok = parseLineCount(first); !ok {
+		return 0, 0, "", false
+	}
+	if deleted, ok = parseLineCount(second); !ok {
+		return 0, 0, "", false
+	}
+	return added, deleted, path, true
+}
+
+// 解析 numstat 中的行数，"-" 为 0
+func parseLineCount(s string) (int, bool) {
+	if s == "-" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// 还原 git 加引号转义的路径：core.quotepath 开启(默认)时非 ASCII 字符输出为 "\346\226\207" 形式的八进制转义，
+// 含制表符、引号等特殊字符的路径无论设置如何都会加引号；没有引号的路径原样返回
+func unquotePath(p string) string {
+	if len(p) < 2 || p[0] != '"' || p[len(p)-1] != '"' {
+		return p
+	}
+	if s, err := strconv.Unquote(p); err == nil {
+		return s
+	}
+	return p
+}
+
+// 取出 diff 文件头(--- a/x、+++ b/x)中的路径，去掉引号转义及 a/、b/ 前缀
+func diffHeaderPath(line string) string {
+	p := unquotePath(line[4:])
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		return p[2:]
+	}
+	return p
+}
+
+// 解析重命名的路径，支持 "old => new" 和 "dir/{old => new}/file" 两种形式，
+// 路径含需要转义的字符时 git 对两侧路径分别加引号
+func parseRenamePath(p string) (newPath, oldPath string) {
+	if unquoted := unquotePath(p); unquoted != p {
+		p = unquoted
+		if !strings.Contains(p, " => ") {
+			return p, ""
+		}
+	}
+	if !strings.Contains(p, " => ") {
+		return p, ""
+	}
+	left, right := strings.Index(p, "{"), strings.LastIndex(p, "}")
+	if left < 0 || right < left {
+		oldPath, newPath, _ = strings.Cut(p, " => ")
+		return unquotePath(newPath), unquotePath(oldPath)
+	}
+	prefix, suffix := p[:left], p[right+1:]
+	from, to, _ := strings.Cut(p[left+1:right], " => ")
+	// {old => } 等形式会产生连续的路径分隔符
+	join := func(middle string) string {
+		return strings.Replace(prefix+middle+suffix, "//", "/", 1)
+	}
+	return join(to), join(from)
+}
+
+// 检查文件是否应该被统计
+func isValidFile(fileName string, includeExts, excludeExts []string) bool {
+	return extSkipReason(fileName, includeExts, excludeExts) == ""
+}
+
+// 消息中是否含有 AIG(不区分大小写)，没有时不可能有任何 AIG 标记，跳过正则匹配
+func mayHaveAIGTag(message string) bool {
+	for i := 0; i+3 <= len(message); i++ {
+		if (message[i]|0x20) == 'a' && (message[i+1]|0x20) == 'i' && (message[i+2]|0x20) == 'g' {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析数值，无效时返回默认值
+func parseFloatOr(s string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+// 解析比例数值，无效或为负时返回 0
+func parseRatio(s string) float64 {
+	ratio, err := strconv.ParseFloat(s, 64)
+	if err != nil || ratio < 0 {
+		return 0
+	}
+	return ratio
+}
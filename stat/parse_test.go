@@ -0,0 +1,173 @@
+package stat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeGit 按子命令返回预设的输出，没有预设的命令返回空输出
+type fakeGit struct {
+	outputs map[string]string
+	calls   [][]string
+}
+
+func (g *fakeGit) Run(args []string) (io.Reader, error) {
+	g.calls = append(g.calls, args)
+	if len(args) == 0 {
+		return nil, errors.New("没有参数")
+	}
+	return strings.NewReader(g.outputs[args[0]]), nil
+}
+
+// 按 LogArgs 的格式拼接一个提交，files 为 numstat 行
+func logEntry(id, name, email, subject, body string, files ...string) string {
+	header := strings.Join([]string{id, name, email, "2024-05-02 10:00:00", subject, body}, fieldSep)
+	return commitSep + header + fieldSep + "\n\n" + strings.Join(files, "\n") + "\n"
+}
+
+func TestParseLogAdversarialInput(t *testing.T) {
+	const (
+		id1 = "1111111111111111111111111111111111111111"
+		id2 = "2222222222222222222222222222222222222222"
+		id3 = "3333333333333333333333333333333333333333"
+		id4 = "4444444444444444444444444444444444444444"
+		// 标题、正文中与提交哈希形式相同的文本
+		hexText = "0123456789abcdef0123456789abcdef01234567"
+	)
+	tests := []struct {
+		name, entry                string
+		id, author, email, subject string
+		message                    string
+		files, added, deleted      int
+		aig                        float64
+	}{
+		{
+			name:    "姓名中的撇号",
+			entry:   logEntry(id1, "Conan O'Brien", "conan@example.com", "fix: handle 'quoted' paths AIG: 0.5", "", "3\t1\tmain.go"),
+			id:      id1,
+			author:  "Conan O'Brien",
+			email:   "conan@example.com",
+			subject: "fix: handle 'quoted' paths AIG: 0.5",
+			message: "fix: handle 'quoted' paths AIG: 0.5",
+			files:   1, added: 3, deleted: 1, aig: 0.5,
+		},
+		{
+			name:    "姓名中的 emoji 和引号",
+			entry:   logEntry(id2, ` "Zoë 🚀" 'Dev' `, "<zoe@example.com>", "feat: 支持 unicode", "", "2\t0\tapi.go"),
+			id:      id2,
+			author:  `"Zoë 🚀" 'Dev'`,
+			email:   "zoe@example.com",
+			subject: "feat: 支持 unicode",
+			message: "feat: 支持 unicode",
+			files:   1, added: 2,
+		},
+		{
+			name:    "标题为 40 位十六进制",
+			entry:   logEntry(id3, "Hex", "hex@example.com", hexText, "", "1\t1\tx.go"),
+			id:      id3,
+			author:  "Hex",
+			email:   "hex@example.com",
+			subject: hexText,
+			message: hexText,
+			files:   1, added: 1, deleted: 1,
+		},
+		{
+			name:    "多行正文中的哈希、numstat 形式的行和 AIG 标记",
+			entry:   logEntry(id4, "Multi Line", "ml@example.com", "refactor: split parser", hexText+"\n\n5\t5\tnot-a-file.go\n\nAIG: 0.25\n", "4\t2\tparse.go", "1\t0\tparse_helper.go"),
+			id:      id4,
+			author:  "Multi Line",
+			email:   "ml@example.com",
+			subject: "refactor: split parser",
+			message: "refactor: split parser\n" + hexText + "\n\n5\t5\tnot-a-file.go\n\nAIG: 0.25",
+			files:   2, added: 5, deleted: 2, aig: 0.25,
+		},
+	}
+
+	var log strings.Builder
+	for _, tt := range tests {
+		log.WriteString(tt.entry)
+	}
+	git := &fakeGit{outputs: map[string]string{"log": log.String()}}
+	a := NewAnalyzer(git)
+	a.IncludeExts = []string{".go"}
+	commits, err := a.Analyze(LogQuery{RevRange: "HEAD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.malformed != 0 {
+		t.Fatalf("malformed = %d, want 0", a.malformed)
+	}
+	if len(commits) != len(tests) {
+		t.Fatalf("解析出 %d 个提交，want %d", len(commits), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := commits[i]
+			if c.ID != tt.id || c.Author != tt.author || c.Email != tt.email || c.Subject != tt.subject {
+				t.Errorf("got id=%q author=%q email=%q subject=%q", c.ID, c.Author, c.Email, c.Subject)
+			}
+			if c.Message != tt.message {
+				t.Errorf("message = %q, want %q", c.Message, tt.message)
+			}
+			if len(c.Files) != tt.files || c.AddedLines != tt.added || c.DeletedLines != tt.deleted {
+				t.Errorf("files=%d +%d -%d, want files=%d +%d -%d", len(c.Files), c.AddedLines, c.DeletedLines, tt.files, tt.added, tt.deleted)
+			}
+			if c.AIGRatio != tt.aig {
+				t.Errorf("aig = %v, want %v", c.AIGRatio, tt.aig)
+			}
+		})
+	}
+}
+
+func TestParseLogMalformed(t *testing.T) {
+	// 缺少字段分隔符的提交跳过并计数，不影响之后的提交
+	log := commitSep + "deadbeef" + fieldSep + "Broken\n" +
+		logEntry("5555555555555555555555555555555555555555", "Ok", "ok@example.com", "ok", "", "1\t0\ta.go")
+	a := NewAnalyzer(&fakeGit{})
+	a.IncludeExts = []string{".go"}
+	commits, err := a.ParseLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.malformed != 1 || len(commits) != 1 || commits[0].Author != "Ok" {
+		t.Fatalf("malformed=%d commits=%+v", a.malformed, commits)
+	}
+}
+
+// 生成约 lines 行的 git log 输出：每个提交带多行正文、--raw 和 numstat 行
+func benchmarkLog(lines int) string {
+	var b strings.Builder
+	for i, n := 0, 0; n < lines; i++ {
+		id := fmt.Sprintf("%040x", i)
+		var files []string
+		for j := 0; j < 8; j++ {
+			path := fmt.Sprintf("pkg%d/file_%d.go", i%13, j)
+			files = append(files, fmt.Sprintf(":100644 100644 %07x %07x M\t%s", i, j, path))
+		}
+		for j := 0; j < 8; j++ {
+			files = append(files, fmt.Sprintf("%d\t%d\tpkg%d/file_%d.go", i%50+j, j, i%13, j))
+		}
+		body := "Refactor the module.\n\nSigned-off-by: Dev <dev@example.com>\nAIG: 0.5"
+		b.WriteString(logEntry(id, fmt.Sprintf("Dev %d", i%20), fmt.Sprintf("dev%d@example.com", i%20), "feat: change "+id[:8], body, files...))
+		// 标题行及正文共 4 行、空行、16 行文件变更
+		n += 21
+	}
+	return b.String()
+}
+
+func BenchmarkParseLog(b *testing.B) {
+	log := benchmarkLog(100000)
+	a := NewAnalyzer(&fakeGit{})
+	a.IncludeExts = []string{".go"}
+	b.SetBytes(int64(len(log)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := a.ParseLog(strings.NewReader(log)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
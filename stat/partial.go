@@ -0,0 +1,77 @@
+package stat
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// 识别部分克隆(partial clone)，返回对象过滤规则，如 blob:none、tree:0，非部分克隆时返回空字符串
+func PartialCloneFilter(git GitRunner) string {
+	out := gitOutput(git, "config", "--get-regexp", `^remote\..*\.(promisor|partialclonefilter)$`)
+	promisor := false
+	filter := ""
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(fields[0], ".promisor"):
+			promisor = promisor || fields[1] == "true"
+		case strings.HasSuffix(fields[0], ".partialclonefilter"):
+			filter = fields[1]
+		}
+	}
+	if !promisor && gitOutput(git, "config", "--get", "extensions.partialClone") == "" {
+		return ""
+	}
+	if filter == "" {
+		filter = "unknown"
+	}
+	return filter
+}
+
+// 统计查询范围内的提交所引用、但本地缺失的对象数量
+// rev-list --missing=print 只列出缺失对象而不会从远程获取
+func MissingObjects(git GitRunner, q LogQuery) (int, error) {
+	args := append([]string{"rev-list", "--objects", "--missing=print"}, revisionArgs(q)...)
+	out, err := git.Run(args)
+	if err != nil {
+		return 0, err
+	}
+
+	missing := 0
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "?") {
+			missing++
+		}
+	}
+	return missing, scanner.Err()
+}
+
+// 检查部分克隆中是否缺少统计行数所需的对象
+// 返回 true 表示应跳过行数统计，避免 git log --numstat 逐个从远程获取对象或中途失败
+func (a *Analyzer) checkPartialClone(q LogQuery) bool {
+	a.partialClone = PartialCloneFilter(a.Git)
+	if a.partialClone == "" || a.FetchMissing {
+		return false
+	}
+
+	missing, err := MissingObjects(a.Git, q)
+	switch {
+	case err != nil:
+		a.warnf("当前仓库为部分克隆(%s)，无法检查缺失对象 (%v)，已跳过行数统计；使用 --fetch-missing 从远程获取缺失对象", a.partialClone, err)
+	case missing > 0:
+		a.warnf("当前仓库为部分克隆(%s)，统计范围内缺少 %d 个对象，已跳过行数统计；使用 --fetch-missing 从远程获取缺失对象", a.partialClone, missing)
+	default:
+		return false
+	}
+	return true
+}
+
+// 记录一条警告，随报告的运行信息一起输出
+func (a *Analyzer) warnf(format string, args ...interface{}) {
+	a.Warnings = append(a.Warnings, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,77 @@
+package stat
+
+import (
+	"strings"
+)
+
+// 需要读取 diff 内容的分析：启发式评分和空行扣除，共用一次 git log --patch
+func (a *Analyzer) analyzePatches(q LogQuery, commits []CommitStats) error {
+	if !a.Heuristic && !a.IgnoreBlankLines {
+		return nil
+	}
+	if q.NoNumstat {
+		if a.Heuristic {
+			a.warnf("缺少统计行数所需的对象，已跳过启发式评分")
+		}
+		if a.IgnoreBlankLines {
+			a.warnf("缺少统计行数所需的对象，已跳过空行扣除")
+		}
+		return nil
+	}
+
+	patches, err := a.loadPatches(q)
+	if err != nil {
+		return err
+	}
+	if a.IgnoreBlankLines {
+		discountBlankLines(patches, commits)
+	}
+	if a.Heuristic {
+		a.scoreHeuristics(patches, commits)
+	}
+	return nil
+}
+
+// 读取查询范围内各提交的 diff，按提交哈希索引
+func (a *Analyzer) loadPatches(q LogQuery) (map[string]string, error) {
+	args := append([]string{"log", "--format=" + commitSep + "%H", "--patch", "--no-color", "--unified=0", "-M"}, revisionArgs(q)...)
+	out, err := a.Git.Run(args)
+	if err != nil {
+		return nil, err
+	}
+
+	patches := make(map[string]string)
+	for _, commit := range splitCommits(gitText(out)) {
+		id, patch, _ := strings.Cut(commit, "\n")
+		patches[id] = patch
+	}
+	return patches, nil
+}
+
+// 从提交的添加行数中扣除新增的空行，只扣除计入统计的文件
+func discountBlankLines(patches map[string]string, commits []CommitStats) {
+	for i := range commits {
+		c := &commits[i]
+		counted := make(map[string]bool)
+		for _, f := range c.Files {
+			if !f.Skipped && !f.Moved {
+				counted[f.Path] = true
+			}
+		}
+
+		blank := 0
+		valid := false
+		for _, line := range strings.Split(patches[c.ID], "\n") {
+			switch {
+			case strings.HasPrefix(line, "diff --git "):
+				valid = false
+			case strings.HasPrefix(line, "+++ "):
+				valid = counted[diffHeaderPath(line)]
+			case valid && strings.HasPrefix(line, "+") && strings.TrimSpace(line[1:]) == "":
+				blank++
+			}
+		}
+		c.BlankLines = blank
+		c.AddedLines -= blank
+	}
+}
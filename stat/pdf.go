@@ -0,0 +1,82 @@
+package stat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// 未指定转换工具时依次在 PATH 中查找
+var pdfTools = []string{"wkhtmltopdf", "chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "msedge"}
+
+// 以 PDF 输出统计汇总表：先生成适合打印的 HTML 页面，再调用 wkhtmltopdf 或无头 Chrome/Chromium 转换
+// tool 为空时自动查找转换工具
+func WritePDF(w io.Writer, report *Report, tool string) error {
+	path, err := findPDFTool(tool)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "aistat-pdf")
+	if err != nil {
+		return fmt.Errorf("错误：创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	html := filepath.Join(dir, "report.html")
+	pdf := filepath.Join(dir, "report.pdf")
+	f, err := os.Create(html)
+	if err != nil {
+		return fmt.Errorf("错误：创建临时文件失败: %v", err)
+	}
+	if err := htmlTemplate.Execute(f, htmlPage{Report: report, Print: true}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("错误：写入临时文件失败: %v", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, pdfToolArgs(path, html, pdf)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("错误：使用 %s 生成 PDF 失败: %v %s", filepath.Base(path), err, strings.TrimSpace(stderr.String()))
+	}
+
+	data, err := os.ReadFile(pdf)
+	if err != nil {
+		return fmt.Errorf("错误：%s 没有生成 PDF 文件: %v", filepath.Base(path), err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// 查找 PDF 转换工具
+func findPDFTool(tool string) (string, error) {
+	if tool != "" {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			return "", fmt.Errorf("错误：找不到 PDF 转换工具 '%s'", tool)
+		}
+		return path, nil
+	}
+	for _, name := range pdfTools {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("错误：生成 PDF 需要安装 wkhtmltopdf 或 Chrome/Chromium，也可以通过 --pdf-tool 指定程序路径")
+}
+
+// 转换工具的命令行参数，wkhtmltopdf 以外的按 Chrome/Chromium 处理
+func pdfToolArgs(path, html, pdf string) []string {
+	if strings.Contains(strings.ToLower(filepath.Base(path)), "wkhtmltopdf") {
+		return []string{"--quiet", "--encoding", "utf-8", "--orientation", "Landscape", html, pdf}
+	}
+	return []string{"--headless", "--disable-gpu", "--no-pdf-header-footer", "--print-to-pdf=" + pdf, "file://" + filepath.ToSlash(html)}
+}
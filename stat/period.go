@@ -0,0 +1,103 @@
+package stat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 日期参数格式
+const DateLayout = "2006-01-02"
+
+// 带时间的日期参数格式(本地时间)，用于一天之内的统计范围
+const DateTimeLayout = "2006-01-02 15:04:05"
+
+// 可以接受的日期写法，依次尝试
+var dateLayouts = []string{DateLayout, "2006/01/02", "2006/1/2", "20060102", "2006.01.02"}
+
+// 可以接受的带时间的写法，带时区的时间转为本地时间
+var dateTimeLayouts = []string{
+	DateTimeLayout, "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04",
+	"2006/01/02 15:04:05", "2006/01/02 15:04", time.RFC3339, "2006-01-02T15:04:05Z0700", "2006-01-02 15:04:05 -0700",
+}
+
+// 将日期参数转为规范形式：只有日期时为 2006-01-02，带时间时为 2006-01-02 15:04:05；
+// name 为参数名称，用于错误信息
+func NormalizeDate(name, s string) (string, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.Format(DateLayout), nil
+		}
+	}
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.In(time.Local).Format(DateTimeLayout), nil
+		}
+	}
+	return "", fmt.Errorf("错误：%s '%s' %w，可以使用 2006-01-02、2006/01/02、20060102 或带时间的 2006-01-02T15:04:05、2006-01-02 15:04", name, s, ErrBadDate)
+}
+
+// 规范形式的日期参数所在的日期，带时间时去掉时间部分
+func PeriodDay(s string) string {
+	if len(s) > len(DateLayout) && s[len(DateLayout)] == ' ' {
+		return s[:len(DateLayout)]
+	}
+	return s
+}
+
+// 传给 git 的时间：只有日期时开始日期取当天零点、结束日期取当天最后一秒，
+// 避免 git 把日期补上当前的时刻
+func gitDate(s string, end bool) string {
+	if s == "" || s != PeriodDay(s) {
+		return s
+	}
+	if _, err := time.Parse(DateLayout, s); err != nil {
+		return s
+	}
+	if end {
+		return s + " 23:59:59"
+	}
+	return s + " 00:00:00"
+}
+
+// 当前日期所在的半月统计周期(1~15 日或 16 日~月底)，用于持续更新的本期统计
+func CurrentDateRange(now time.Time) (string, string) {
+	year, month, day := now.Date()
+	location := now.Location()
+	if day <= 15 {
+		return time.Date(year, month, 1, 0, 0, 0, 0, location).Format(DateLayout),
+			time.Date(year, month, 15, 0, 0, 0, 0, location).Format(DateLayout)
+	}
+	return time.Date(year, month, 16, 0, 0, 0, 0, location).Format(DateLayout),
+		time.Date(year, month+1, 0, 0, 0, 0, 0, location).Format(DateLayout)
+}
+
+// 获取默认日期范围：当前日期的上一个半月统计周期
+func DefaultDateRange(since, until string, now time.Time) (string, string) {
+	if since != "" && until != "" {
+		return since, until
+	}
+
+	year, month, day := now.Date()
+	location := now.Location()
+
+	var periodStart, periodEnd time.Time
+
+	if day <= 15 {
+		// 当前在上半月，则统计上月16号到月底的数据
+		firstOfThisMonth := time.Date(year, month, 1, 0, 0, 0, 0, location)
+		lastMonth := firstOfThisMonth.AddDate(0, -1, 0)
+		lastMonthYear, lastMonthMonth, _ := lastMonth.Date()
+
+		periodStart = time.Date(lastMonthYear, lastMonthMonth, 16, 0, 0, 0, 0, location)
+		firstOfNextMonth := time.Date(lastMonthYear, lastMonthMonth+1, 1, 0, 0, 0, 0, location)
+		periodEnd = firstOfNextMonth.AddDate(0, 0, -1)
+	} else {
+		// 当前在下半月，则统计本月1号到15号的数据
+		periodStart = time.Date(year, month, 1, 0, 0, 0, 0, location)
+		periodEnd = time.Date(year, month, 15, 0, 0, 0, 0, location)
+	}
+
+	return periodStart.Format(DateLayout), periodEnd.Format(DateLayout)
+}
@@ -0,0 +1,222 @@
+package stat
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AIG 标记的取值规则：提交信息中有多个 AIG 标记(如引用了其他提交的信息)时使用哪一个
+const (
+	// 提交信息末尾 trailer 段中有 AIG 项时只认这些项，否则认全文；取第一个比例标记，
+	// 没有比例标记时取第一个策略标记(默认)
+	AIGPrecedenceFirst = "first"
+	// 全文最后一个标记
+	AIGPrecedenceLast = "last"
+	// 只认位于行首的 AIG 键，有多个时取最后一个
+	AIGPrecedenceKey = "key"
+	// 只认提交信息末尾 trailer 段(按 git interpret-trailers 的规则识别)中的 AIG 项，有多个时取最后一个
+	AIGPrecedenceTrailer = "trailer"
+)
+
+// 运行信息中列出的有歧义的提交数
+const ambiguousListLimit = 10
+
+// 检查 AIG 标记的取值规则是否有效
+func ValidAIGPrecedence(mode string) bool {
+	switch mode {
+	case AIGPrecedenceFirst, AIGPrecedenceLast, AIGPrecedenceKey, AIGPrecedenceTrailer:
+		return true
+	}
+	return false
+}
+
+// AmbiguousTag 含有多个取值不同的 AIG 标记的提交
+type AmbiguousTag struct {
+	ID      string `json:"id" yaml:"id"`
+	Author  string `json:"author" yaml:"author"`
+	Subject string `json:"subject" yaml:"subject"`
+	// 提交信息中的全部取值(按出现顺序)及按规则采用的取值，没有符合规则的标记时 Used 为空
+	Values []string `json:"values" yaml:"values"`
+	Used   string   `json:"used" yaml:"used"`
+}
+
+// 提交信息中的一个 AIG 标记
+type aigTag struct {
+	// 标记在提交信息中的位置及取值(比例或策略标记 n/a、exempt)
+	pos   int
+	value string
+	// 比例标记的比例，策略标记为空时有效
+	ratio  float64
+	policy string
+	// AIG 键位于行首(前面只有空白)
+	lineStart bool
+}
+
+// 按出现顺序找出提交信息中的比例标记和策略标记，不含 squash 汇总标记
+func aigTags(message string) []aigTag {
+	var tags []aigTag
+	for _, m := range aigRegex.FindAllStringSubmatchIndex(message, -1) {
+		value := message[m[4]:m[5]]
+		ratio := parseRatio(value)
+		tags = append(tags, aigTag{pos: m[0], value: strconv.FormatFloat(ratio, 'f', -1, 64), ratio: ratio})
+	}
+	for _, m := range policyRegex.FindAllStringSubmatchIndex(message, -1) {
+		policy := policyValue(message[m[2]:m[3]])
+		tags = append(tags, aigTag{pos: m[0], value: policy, policy: policy})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].pos < tags[j].pos })
+	for i := range tags {
+		lineStart := strings.LastIndexByte(message[:tags[i].pos], '\n') + 1
+		tags[i].lineStart = strings.TrimSpace(message[lineStart:tags[i].pos]) == ""
+	}
+	return tags
+}
+
+// 策略标记的取值：n/a、na 及 -2 以外的负数为 n/a，exempt 及 -2 为 exempt
+func policyValue(value string) string {
+	value = strings.ToLower(value)
+	if value == "exempt" || parseFloatOr(value, 0) == -2 {
+		return AIGPolicyExempt
+	}
+	return AIGPolicyNA
+}
+
+// 按规则选出生效的标记，没有符合规则的标记时返回 false
+func selectAIGTag(subject, message string, tags []aigTag, precedence string) (aigTag, bool) {
+	if len(tags) == 0 {
+		return aigTag{}, false
+	}
+	switch precedence {
+	case AIGPrecedenceLast:
+		if len(tags) > 0 {
+			return tags[len(tags)-1], true
+		}
+	case AIGPrecedenceKey:
+		for i := len(tags) - 1; i >= 0; i-- {
+			if tags[i].lineStart {
+				return tags[i], true
+			}
+		}
+	case AIGPrecedenceTrailer:
+		if trailers := trailerAIGTags(subject, message); len(trailers) > 0 {
+			return trailers[len(trailers)-1], true
+		}
+	default:
+		// trailer 段中的 AIG 项优先，正文中提到的 AIG 不参与；没有时按全文查找。
+		// 只有一个标记时结果相同，不需要解析 trailer 段
+		if len(tags) > 1 {
+			if trailers := trailerAIGTags(subject, message); len(trailers) > 0 {
+				tags = trailers
+			}
+		}
+		// 比例标记优先于策略标记
+		for _, tag := range tags {
+			if tag.policy == "" {
+				return tag, true
+			}
+		}
+		if len(tags) > 0 {
+			return tags[0], true
+		}
+	}
+	return aigTag{}, false
+}
+
+// 按 git interpret-trailers 的规则找出 trailer 段，按出现顺序返回其中的 AIG 项，位置为所在行在 message 中的起始位置
+func trailerAIGTags(subject, message string) []aigTag {
+	raw := rawMessage(subject, message)
+	var tags []aigTag
+	for _, t := range ParseTrailers(raw) {
+		if !strings.EqualFold(t.Key, "AIG") {
+			continue
+		}
+		if found := aigTags(t.Key + ": " + t.Value); len(found) > 0 {
+			tag := found[0]
+			// trailer 段不在标题所在的第一段，rawMessage 补上的空行在其之前
+			tag.pos = lineOffset(raw, t.line) - (len(raw) - len(message))
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// 第 n 行(从 0 开始)在 s 中的起始位置
+func lineOffset(s string, n int) int {
+	pos := 0
+	for ; n > 0; n-- {
+		pos += strings.IndexByte(s[pos:], '\n') + 1
+	}
+	return pos
+}
+
+// 恢复标题与正文之间的空行：CommitStats.Message 由标题和正文直接拼接
+func rawMessage(subject, message string) string {
+	if body := strings.TrimPrefix(message, subject+"\n"); body != message {
+		return subject + "\n\n" + body
+	}
+	return message
+}
+
+// 提取 AIG 比例、策略标记及来源，存在 squash 汇总标记时使用汇总值，没有标记时来源为空；
+// 有多个取值不同的标记时 ambiguous 为真，按 precedence 规则取值
+func extractAIG(subject, message, precedence string) (ratio float64, policy, source string, ambiguous bool) {
+	if matches := squashRegex.FindStringSubmatch(message); len(matches) > 1 {
+		return parseRatio(matches[1]), "", AIGSourceSquash, false
+	}
+	tags := aigTags(message)
+	for i := 1; i < len(tags); i++ {
+		if tags[i].value != tags[0].value {
+			ambiguous = true
+		}
+	}
+	tag, ok := selectAIGTag(subject, message, tags, precedence)
+	if !ok {
+		return 0, "", "", ambiguous
+	}
+	return tag.ratio, tag.policy, AIGSourceTag, ambiguous
+}
+
+// 记录含有多个取值不同的 AIG 标记的提交
+func (a *Analyzer) collectAmbiguous(commits []CommitStats) {
+	a.ambiguous = nil
+	for i := range commits {
+		c := &commits[i]
+		if !c.AIGAmbiguous {
+			continue
+		}
+		t := AmbiguousTag{ID: c.ID, Author: c.Author, Subject: c.Subject}
+		tags := aigTags(c.Message)
+		for _, tag := range tags {
+			t.Values = append(t.Values, tag.value)
+		}
+		if tag, ok := selectAIGTag(c.Subject, c.Message, tags, a.AIGPrecedence); ok {
+			t.Used = tag.value
+		}
+		a.ambiguous = append(a.ambiguous, t)
+	}
+}
+
+// 运行信息中有歧义的 AIG 标记的说明
+func ambiguousLines(tags []AmbiguousTag, precedence string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	if precedence == "" {
+		precedence = AIGPrecedenceFirst
+	}
+	lines := []string{fmt.Sprintf("AIG 标记有歧义: %d 次提交含有多个取值不同的 AIG 标记，按 %s 规则取值 (可通过 aig_precedence 调整)", len(tags), precedence)}
+	for i, t := range tags {
+		if i == ambiguousListLimit {
+			lines = append(lines, fmt.Sprintf("  ... 另有 %d 次提交", len(tags)-ambiguousListLimit))
+			break
+		}
+		used := t.Used
+		if used == "" {
+			used = "无(视为未标记)"
+		}
+		lines = append(lines, fmt.Sprintf("  %.10s %s <%s> 取值 %s，采用 %s", t.ID, t.Subject, t.Author, strings.Join(t.Values, "、"), used))
+	}
+	return lines
+}
@@ -0,0 +1,50 @@
+package stat
+
+import "testing"
+
+func TestExtractAIGDefaultPrecedence(t *testing.T) {
+	tests := []struct {
+		name, subject, message string
+		ratio                  float64
+		policy                 string
+	}{
+		{
+			name:    "正文中提到的 AIG 不覆盖 trailer",
+			subject: "feat: retry",
+			message: "feat: retry\nPorted from a change tagged AIG: 1 upstream.\n\nAIG: 0.3\nSigned-off-by: A <a@example.com>",
+			ratio:   0.3,
+		},
+		{
+			name:    "trailer 段中有多个 AIG 项时取第一个比例标记",
+			subject: "feat: retry",
+			message: "feat: retry\nbody\n\nAIG: n/a\nAIG: 0.4\nAIG: 0.6",
+			ratio:   0.4,
+		},
+		{
+			name:    "没有 trailer 段时按全文查找",
+			subject: "fix: handle empty response AIG: 0.5",
+			message: "fix: handle empty response AIG: 0.5",
+			ratio:   0.5,
+		},
+		{
+			name:    "trailer 段中没有 AIG 项时按全文查找",
+			subject: "fix: timeout AIG: 0.2",
+			message: "fix: timeout AIG: 0.2\nbody\n\nSigned-off-by: A <a@example.com>",
+			ratio:   0.2,
+		},
+		{
+			name:    "trailer 段中的策略标记",
+			subject: "docs: proto",
+			message: "docs: proto\nsee AIG: 0.9 in the old commit\n\nAIG: exempt",
+			policy:  AIGPolicyExempt,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ratio, policy, _, _ := extractAIG(tt.subject, tt.message, AIGPrecedenceFirst)
+			if ratio != tt.ratio || policy != tt.policy {
+				t.Errorf("ratio=%v policy=%q, want ratio=%v policy=%q", ratio, policy, tt.ratio, tt.policy)
+			}
+		})
+	}
+}
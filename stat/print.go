@@ -0,0 +1,79 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 打印单个提交的详细信息
+func PrintCommit(w io.Writer, c *CommitStats) {
+	fmt.Fprintf(w, "\n提交详情:\n")
+	fmt.Fprintf(w, "  提交ID: %s\n", c.ID)
+	fmt.Fprintf(w, "  作者: %s\n", c.Author)
+	fmt.Fprintf(w, "  邮箱: %s\n", c.Email)
+	fmt.Fprintf(w, "  时间: %s\n", c.Time)
+	if c.Submodule != "" {
+		fmt.Fprintf(w, "  子模块: %s\n", c.Submodule)
+	}
+	fmt.Fprintf(w, "  消息:\n")
+	// 打印多行消息，每行前面加缩进
+	for _, line := range strings.Split(c.Message, "\n") {
+		if strings.TrimSpace(line) != "" {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+
+	if c.AIGPolicy != "" {
+		fmt.Fprintf(w, "  AI贡献率: 不适用 (AIG: %s，不计入比例)\n", c.AIGPolicy)
+	} else if c.AIGSource == AIGSourceEstimate {
+		fmt.Fprintf(w, "  AI贡献率: %.2f%% (LLM 估算)\n", c.AIGRatio*100)
+		fmt.Fprintf(w, "  提交类型: %s (LLM 估算)\n", c.EstimatedType)
+	} else if c.AIGSource == AIGSourceManifest {
+		fmt.Fprintf(w, "  AI贡献率: %.2f%% (按 AI 清单中的文件计算)\n", c.AIGRatio*100)
+	} else {
+		fmt.Fprintf(w, "  AI贡献率: %.2f%%\n", c.AIGRatio*100)
+	}
+	if c.HeuristicScore > 0 {
+		fmt.Fprintf(w, "  启发式 AI 可能性: %.2f%% (实验性)\n", c.HeuristicScore*100)
+	}
+	fmt.Fprintf(w, "  是否修复提交: %v\n", c.IsFix)
+	if len(c.BugFixes) > 0 {
+		ids := make([]string, len(c.BugFixes))
+		for i, id := range c.BugFixes {
+			ids[i] = shortID(id)
+		}
+		fmt.Fprintf(w, "  之后被修复: %s\n", strings.Join(ids, ", "))
+	}
+	fmt.Fprintf(w, "  变更文件:\n")
+	for _, file := range c.Files {
+		name := file.Path
+		if file.OldPath != "" {
+			name = file.OldPath + " -> " + file.Path
+		}
+		switch {
+		case file.Change == FileSymlink:
+			fmt.Fprintf(w, "    [链接] %s (符号链接，不计入统计)\n", name)
+		case file.Change == FileModeOnly:
+			fmt.Fprintf(w, "    [权限] %s (只修改了文件权限，不计入统计)\n", name)
+		case file.Attribute != "":
+			fmt.Fprintf(w, "    [跳过] %s (.gitattributes 标记为 %s)\n", name, file.Attribute)
+		case file.Skipped:
+			fmt.Fprintf(w, "    [跳过] %s (%s)\n", name, SkipReasonLabel(file.SkipReason))
+		case file.Moved:
+			fmt.Fprintf(w, "    [移动] %s (添加: %d, 删除: %d, 不计入统计)\n", name, file.Added, file.Deleted)
+		default:
+			fmt.Fprintf(w, "    - %s (添加: %d, 删除: %d)\n", name, file.Added, file.Deleted)
+		}
+	}
+
+	fmt.Fprintf(w, "  本次提交总计:\n")
+	fmt.Fprintf(w, "    总添加行数: %d\n", c.AddedLines)
+	if c.BlankLines > 0 {
+		fmt.Fprintf(w, "    新增空行(不计入): %d\n", c.BlankLines)
+	}
+	fmt.Fprintf(w, "    总删除行数: %d\n", c.DeletedLines)
+	fmt.Fprintf(w, "    AI贡献添加行数: %d\n", c.AIAddedLines())
+	fmt.Fprintf(w, "    AI贡献删除行数: %d\n", c.AIDeletedLines())
+	fmt.Fprintf(w, "  %s\n", strings.Repeat("-", 80))
+}
@@ -0,0 +1,82 @@
+package stat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 默认的文件类型预设：Web 前端与 Go 后端
+const DefaultProfile = "default"
+
+// LanguageProfile 一类技术栈统计及排除的文件扩展名，排除列表按文件名后缀匹配
+type LanguageProfile struct {
+	IncludeExts []string `yaml:"include_exts"`
+	ExcludeExts []string `yaml:"exclude_exts"`
+}
+
+// 内置的文件类型预设，可以在配置项 profiles 中覆盖或新增
+var builtinProfiles = map[string]LanguageProfile{
+	DefaultProfile: {
+		IncludeExts: strings.Split(includeFileExts, ","),
+		ExcludeExts: strings.Split(excludeFileExts, ","),
+	},
+	"frontend": {
+		IncludeExts: []string{".html", ".vue", ".svelte", ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".css", ".scss", ".sass", ".less"},
+		ExcludeExts: []string{".min.js", ".min.css", ".bundle.js", ".map"},
+	},
+	"backend-go": {
+		IncludeExts: []string{".go", ".proto", ".sql", ".yaml", ".yml"},
+		ExcludeExts: []string{".pb.go", ".pb.validate.go", ".pb.gw.go", "_grpc.pb.go", "_mock.go", "_gen.go"},
+	},
+	"mobile": {
+		IncludeExts: []string{".swift", ".m", ".mm", ".h", ".kt", ".kts", ".java", ".dart", ".gradle"},
+		ExcludeExts: []string{".g.dart", ".freezed.dart", ".pb.swift", ".generated.swift"},
+	},
+	"data": {
+		IncludeExts: []string{".py", ".sql", ".r", ".R", ".scala", ".jl", ".yaml", ".yml"},
+		ExcludeExts: []string{"_pb2.py", "_pb2_grpc.py"},
+	},
+}
+
+// 可用的预设名称(含配置中新增的)，按名称排序
+func ProfileNames(custom map[string]LanguageProfile) []string {
+	var names []string
+	for name := range builtinProfiles {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, ok := builtinProfiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 按名称取预设，配置中同名预设的 include_exts、exclude_exts 覆盖内置预设中的对应项
+func ResolveProfile(name string, custom map[string]LanguageProfile) (LanguageProfile, error) {
+	p, builtin := builtinProfiles[name]
+	override, ok := custom[name]
+	if !builtin && !ok {
+		return LanguageProfile{}, fmt.Errorf("错误：未知的文件类型预设 '%s'，可用: %s", name, strings.Join(ProfileNames(custom), ", "))
+	}
+	if override.IncludeExts != nil {
+		p.IncludeExts = override.IncludeExts
+	}
+	if override.ExcludeExts != nil {
+		p.ExcludeExts = override.ExcludeExts
+	}
+	if len(p.IncludeExts) == 0 {
+		return LanguageProfile{}, fmt.Errorf("错误：文件类型预设 '%s' 没有配置 include_exts", name)
+	}
+	return p, nil
+}
+
+// 运行信息中统计文件类型后标注的预设名称，默认预设不标注
+func profileLabel(name string) string {
+	if name == "" || name == DefaultProfile {
+		return ""
+	}
+	return " (预设 " + name + ")"
+}
@@ -0,0 +1,136 @@
+package stat
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 拥有全部项目权限的 API 令牌所在的环境变量
+const EnvAdminToken = "AISTAT_ADMIN_TOKEN"
+
+// Project serve 模式下的一个项目，拥有独立的仓库、团队配置和访问令牌
+type Project struct {
+	Name string `yaml:"name"`
+	// 运行记录中的仓库名称
+	Repos []string `yaml:"repos"`
+	// 项目自己的配置文件(名单、团队目标等)，为空时使用主配置文件
+	Config string `yaml:"config"`
+	// 保存项目 API 令牌的环境变量，多个令牌以逗号分隔，令牌不写入配置文件
+	TokenEnv string `yaml:"token_env"`
+	// OIDC 用户属于其中任一组时可以访问该项目
+	OIDCGroups []string `yaml:"oidc_groups"`
+	// 接收推送 webhook 的仓库全名到本地克隆目录的映射
+	WebhookRepos map[string]string `yaml:"webhook_repos"`
+}
+
+// OIDCConfig 使用 OIDC 身份提供方签发的 ID 令牌访问 serve 的 API
+type OIDCConfig struct {
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// 令牌中保存用户组的字段，默认 groups
+	GroupsClaim string `yaml:"groups_claim"`
+}
+
+// 项目的 API 令牌，环境变量未设置时为空
+func (p *Project) Tokens() []string {
+	var tokens []string
+	if p.TokenEnv == "" {
+		return nil
+	}
+	for _, t := range strings.Split(os.Getenv(p.TokenEnv), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// 校验项目配置：名称唯一，webhook 仓库不能同时属于多个项目
+func ValidateProjects(projects []Project, oidc *OIDCConfig) error {
+	names := make(map[string]bool)
+	hooks := make(map[string]string)
+	for _, p := range projects {
+		if strings.TrimSpace(p.Name) == "" {
+			return fmt.Errorf("错误：项目缺少名称")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("错误：项目 '%s' 重复", p.Name)
+		}
+		names[p.Name] = true
+		if len(p.Repos) == 0 {
+			return fmt.Errorf("错误：项目 '%s' 没有配置仓库", p.Name)
+		}
+		if p.TokenEnv == "" && len(p.OIDCGroups) == 0 {
+			return fmt.Errorf("错误：项目 '%s' 需要配置 token_env 或 oidc_groups", p.Name)
+		}
+		if len(p.OIDCGroups) > 0 && oidc == nil {
+			return fmt.Errorf("错误：项目 '%s' 配置了 oidc_groups，但没有配置 oidc", p.Name)
+		}
+		for repo := range p.WebhookRepos {
+			if other, ok := hooks[repo]; ok {
+				return fmt.Errorf("错误：webhook 仓库 '%s' 同时属于项目 '%s' 和 '%s'", repo, other, p.Name)
+			}
+			hooks[repo] = p.Name
+		}
+	}
+	if oidc != nil && (oidc.Issuer == "" || oidc.Audience == "") {
+		return fmt.Errorf("错误：oidc 需要配置 issuer 和 audience")
+	}
+	return nil
+}
+
+// 按开发者身份访问 serve 时的角色
+const (
+	// 可以查看全部开发者的数据
+	RoleAdmin = "admin"
+	// 可以查看所负责团队成员的数据
+	RoleLead = "lead"
+	// 只能查看本人的数据，未配置角色的开发者默认为该角色
+	RoleDeveloper = "developer"
+)
+
+// RoleBinding 开发者的角色，lead 负责的团队为空时为其在名单中所属的团队
+type RoleBinding struct {
+	Email string   `yaml:"email"`
+	Role  string   `yaml:"role"`
+	Teams []string `yaml:"teams"`
+}
+
+// 校验角色配置：角色有效、邮箱不重复，lead 负责的团队在名单中存在
+func ValidateRoles(roles []RoleBinding, roster []RosterMember) error {
+	teams := make(map[string]bool)
+	teamOf := make(map[string]string)
+	for _, m := range roster {
+		if m.Team != "" {
+			teams[m.Team] = true
+			teamOf[strings.ToLower(m.Email)] = m.Team
+		}
+	}
+	seen := make(map[string]bool)
+	for _, b := range roles {
+		email := strings.ToLower(strings.TrimSpace(b.Email))
+		if email == "" {
+			return fmt.Errorf("错误：角色配置缺少邮箱")
+		}
+		if seen[email] {
+			return fmt.Errorf("错误：开发者 '%s' 的角色重复", b.Email)
+		}
+		seen[email] = true
+		switch b.Role {
+		case RoleAdmin, RoleDeveloper:
+		case RoleLead:
+			if len(b.Teams) == 0 && teamOf[email] == "" {
+				return fmt.Errorf("错误：lead '%s' 没有配置 teams，名单中也没有所属团队", b.Email)
+			}
+			for _, t := range b.Teams {
+				if !teams[t] {
+					return fmt.Errorf("错误：lead '%s' 负责的团队 '%s' 在名单中没有成员", b.Email, t)
+				}
+			}
+		default:
+			return fmt.Errorf("错误：不支持的角色 '%s'，请使用 admin、lead 或 developer", b.Role)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package stat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PeriodPreset 交互选择时提供的统计周期
+type PeriodPreset struct {
+	Label string
+	// 返回起止日期，Custom 为真时由用户输入，不调用
+	Range  func(now time.Time) (string, string)
+	Custom bool
+}
+
+// 交互选择的统计周期，第一项为默认值(与不带参数运行时的默认周期相同)
+var PeriodPresets = []PeriodPreset{
+	{Label: "上一个半月周期", Range: func(now time.Time) (string, string) { return DefaultDateRange("", "", now) }},
+	{Label: "本期至今", Range: func(now time.Time) (string, string) {
+		since, _ := CurrentDateRange(now)
+		return since, now.Format(DateLayout)
+	}},
+	{Label: "上个月", Range: func(now time.Time) (string, string) {
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return first.AddDate(0, -1, 0).Format(DateLayout), first.AddDate(0, 0, -1).Format(DateLayout)
+	}},
+	{Label: "本月至今", Range: func(now time.Time) (string, string) {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format(DateLayout), now.Format(DateLayout)
+	}},
+	{Label: "最近 30 天", Range: func(now time.Time) (string, string) {
+		return now.AddDate(0, 0, -29).Format(DateLayout), now.Format(DateLayout)
+	}},
+	{Label: "自定义起止日期", Custom: true},
+}
+
+// 文件是否为终端
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// 等待输入时收到取消信号
+var ErrPromptCanceled = errors.New("错误：已取消")
+
+// Prompter 在终端中逐项询问，直接回车时使用默认值
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	// 关闭时停止等待输入，返回 ErrPromptCanceled
+	done <-chan struct{}
+}
+
+// 从 in 读取回答，问题写到 out
+func NewPrompter(in io.Reader, out io.Writer, done <-chan struct{}) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out, done: done}
+}
+
+// 读取一行输入，去掉首尾空白
+func (p *Prompter) readLine() (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		line, err := p.in.ReadString('\n')
+		ch <- result{line, err}
+	}()
+	select {
+	case <-p.done:
+		fmt.Fprintln(p.out)
+		return "", ErrPromptCanceled
+	case r := <-ch:
+		if r.err != nil && (r.err != io.EOF || r.line == "") {
+			return "", errors.New("错误：输入已结束")
+		}
+		return strings.TrimSpace(r.line), nil
+	}
+}
+
+// 询问一个文本值，直接回车时返回 def
+func (p *Prompter) Ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	answer, err := p.readLine()
+	if err != nil || answer == "" {
+		return def, err
+	}
+	return answer, nil
+}
+
+// 从编号列出的选项中选择一项，直接回车时选择 def，返回所选下标；输入不正确时重新询问
+func (p *Prompter) Choose(question string, options []string, def int) (int, error) {
+	fmt.Fprintf(p.out, "%s\n", question)
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+	for {
+		answer, err := p.Ask("请输入编号", strconv.Itoa(def+1))
+		if err != nil {
+			return def, err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		fmt.Fprintf(p.out, "请输入 1~%d 的编号\n", len(options))
+	}
+}
+
+// 选择统计周期，自定义时询问起止日期并校验格式
+func (p *Prompter) ChoosePeriod(now time.Time) (string, string, error) {
+	labels := make([]string, len(PeriodPresets))
+	for i, preset := range PeriodPresets {
+		labels[i] = preset.Label
+		if !preset.Custom {
+			since, until := preset.Range(now)
+			labels[i] += fmt.Sprintf(" (%s ~ %s)", since, until)
+		}
+	}
+	i, err := p.Choose("统计周期:", labels, 0)
+	if err != nil {
+		return "", "", err
+	}
+	if !PeriodPresets[i].Custom {
+		since, until := PeriodPresets[i].Range(now)
+		return since, until, nil
+	}
+	since, err := p.askDate("开始日期", "")
+	if err != nil {
+		return "", "", err
+	}
+	until, err := p.askDate("结束日期", now.Format(DateLayout))
+	return since, until, err
+}
+
+// 询问日期，格式不正确时重新询问
+func (p *Prompter) askDate(name, def string) (string, error) {
+	for {
+		answer, err := p.Ask(name+" (如 "+DateLayout+")", def)
+		if err != nil {
+			return "", err
+		}
+		date, err := NormalizeDate(name, answer)
+		if err == nil {
+			return date, nil
+		}
+		fmt.Fprintln(p.out, strings.TrimPrefix(err.Error(), "错误："))
+	}
+}
@@ -0,0 +1,64 @@
+package stat
+
+import (
+	"fmt"
+	"time"
+)
+
+// PurgeResult 按保留期限清理存储的结果，预览时为将要删除的数量
+type PurgeResult struct {
+	// 截止日期，统计周期在此日期之前结束的运行记录和冻结记录、提交时间在此之前的提交被删除
+	Before  string `json:"before"`
+	Runs    int    `json:"runs"`
+	Commits int    `json:"commits"`
+	Frozen  int    `json:"frozen"`
+}
+
+// 是否有需要删除的数据
+func (r *PurgeResult) Empty() bool {
+	return r.Runs == 0 && r.Commits == 0 && r.Frozen == 0
+}
+
+func (r *PurgeResult) String() string {
+	return fmt.Sprintf("%s 之前的运行记录 %d 条，提交 %d 个，冻结记录 %d 条", r.Before, r.Runs, r.Commits, r.Frozen)
+}
+
+// 保留最近 months 个月的数据时的截止日期
+func RetentionCutoff(months int, now time.Time) string {
+	return now.AddDate(0, -months, 0).Format(DateLayout)
+}
+
+// 运行记录的日期：统计周期的结束日期，按提交范围统计时取生成日期，都没有时为空(不清理)
+func runDay(report *Report) string {
+	if report.Until != "" {
+		return PeriodDay(report.Until)
+	}
+	if report.Meta != nil {
+		return commitDay(report.Meta.GeneratedAt)
+	}
+	return ""
+}
+
+// 冻结记录的日期：统计周期的结束日期，按提交范围冻结时取冻结日期
+func frozenDay(period *FrozenPeriod) string {
+	if period.Until != "" {
+		return PeriodDay(period.Until)
+	}
+	return commitDay(period.FrozenAt)
+}
+
+// 日期在截止日期之前，日期未知时保留
+func expired(day, before string) bool {
+	return day != "" && day < before
+}
+
+// 去掉提交时间在截止日期之前的提交，返回保留的提交
+func keepCommits(commits []CommitStats, before string) []CommitStats {
+	var kept []CommitStats
+	for _, c := range commits {
+		if !expired(commitDay(c.Time), before) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
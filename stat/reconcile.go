@@ -0,0 +1,114 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// RewrittenCommit 冻结后被改写(rebase、amend)的提交：作者和标题相同而哈希变化
+type RewrittenCommit struct {
+	Old FrozenCommit `json:"old"`
+	New FrozenCommit `json:"new"`
+}
+
+// ReconcileAuthor 一个开发者在冻结后的提交变化
+type ReconcileAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// 冻结后出现的提交(晚推送)、不再出现的提交(被 rebase 掉或删除)及被改写的提交
+	Late        []FrozenCommit    `json:"late,omitempty"`
+	Disappeared []FrozenCommit    `json:"disappeared,omitempty"`
+	Rewritten   []RewrittenCommit `json:"rewritten,omitempty"`
+	// 与冻结时相比的添加行数及 AI 添加行数的变化
+	AddedDelta   int `json:"added_delta"`
+	AIAddedDelta int `json:"ai_added_delta"`
+}
+
+// Reconciliation 冻结记录与重新统计结果的逐提交核对
+type Reconciliation struct {
+	Since    string `json:"since"`
+	Until    string `json:"until"`
+	RevRange string `json:"rev_range,omitempty"`
+	FrozenAt string `json:"frozen_at"`
+	// 冻结时与当前的提交数
+	FrozenCommits  int                `json:"frozen_commits"`
+	CurrentCommits int                `json:"current_commits"`
+	Authors        []*ReconcileAuthor `json:"authors"`
+}
+
+// 按开发者列出冻结后新出现、不再出现和被改写的提交
+func Reconcile(f *FrozenPeriod, report *Report) *Reconciliation {
+	current, _ := frozenContent(report.Commits)
+	r := &Reconciliation{Since: f.Since, Until: f.Until, RevRange: f.RevRange, FrozenAt: f.FrozenAt,
+		FrozenCommits: len(f.Commits), CurrentCommits: len(current), Authors: []*ReconcileAuthor{}}
+	late, disappeared := compareFrozenCommits(f.Commits, current)
+
+	byEmail := make(map[string]*ReconcileAuthor)
+	author := func(c FrozenCommit) *ReconcileAuthor {
+		a, ok := byEmail[c.Email]
+		if !ok {
+			a = &ReconcileAuthor{Name: c.Author, Email: c.Email}
+			byEmail[c.Email] = a
+			r.Authors = append(r.Authors, a)
+		}
+		return a
+	}
+
+	// 作者和标题相同的一对提交视为改写，每个不再出现的提交最多匹配一个新提交
+	gone := make(map[string][]int)
+	for i, c := range disappeared {
+		key := c.Email + "\x00" + c.Subject
+		gone[key] = append(gone[key], i)
+	}
+	matched := make(map[int]bool)
+	for _, c := range late {
+		a := author(c)
+		a.AddedDelta += c.Added
+		a.AIAddedDelta += c.AIAdded
+		key := c.Email + "\x00" + c.Subject
+		if candidates := gone[key]; len(candidates) > 0 {
+			old := disappeared[candidates[0]]
+			gone[key] = candidates[1:]
+			matched[candidates[0]] = true
+			a.Rewritten = append(a.Rewritten, RewrittenCommit{Old: old, New: c})
+			continue
+		}
+		a.Late = append(a.Late, c)
+	}
+	for i, c := range disappeared {
+		a := author(c)
+		a.AddedDelta -= c.Added
+		a.AIAddedDelta -= c.AIAdded
+		if !matched[i] {
+			a.Disappeared = append(a.Disappeared, c)
+		}
+	}
+	sort.Slice(r.Authors, func(i, j int) bool { return r.Authors[i].Email < r.Authors[j].Email })
+	return r
+}
+
+// 打印核对结果
+func PrintReconciliation(w io.Writer, r *Reconciliation, nf NumberFormat) {
+	period := r.Since + " ~ " + r.Until
+	if r.RevRange != "" {
+		period = r.RevRange
+	}
+	fmt.Fprintf(w, "统计周期 %s (冻结于 %s): 冻结时 %s 个提交，当前 %s 个提交\n", period, r.FrozenAt, nf.Int(r.FrozenCommits), nf.Int(r.CurrentCommits))
+	if len(r.Authors) == 0 {
+		fmt.Fprintf(w, "与冻结记录一致，没有晚推送或消失的提交\n")
+		return
+	}
+	for _, a := range r.Authors {
+		fmt.Fprintf(w, "\n%s <%s>: 添加 %+d 行，AI 添加 %+d 行\n", a.Name, a.Email, a.AddedDelta, a.AIAddedDelta)
+		for _, c := range a.Late {
+			fmt.Fprintf(w, "  [晚推送] %s %s %s (+%d/-%d)\n", shortID(c.ID), c.Time, c.Subject, c.Added, c.Deleted)
+		}
+		for _, c := range a.Disappeared {
+			fmt.Fprintf(w, "  [已消失] %s %s %s (+%d/-%d)\n", shortID(c.ID), c.Time, c.Subject, c.Added, c.Deleted)
+		}
+		for _, c := range a.Rewritten {
+			fmt.Fprintf(w, "  [已改写] %s -> %s %s (+%d/-%d -> +%d/-%d)\n", shortID(c.Old.ID), shortID(c.New.ID), c.New.Subject, c.Old.Added, c.Old.Deleted, c.New.Added, c.New.Deleted)
+		}
+	}
+}
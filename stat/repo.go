@@ -0,0 +1,183 @@
+package stat
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SubdirRunner 可以为仓库内的子目录创建 GitRunner，用于分析子模块
+type SubdirRunner interface {
+	Subdir(dir string) GitRunner
+}
+
+// 为子目录创建新的 GitRunner
+func (r *ExecGitRunner) Subdir(dir string) GitRunner {
+	return &ExecGitRunner{Dir: filepath.Join(r.Dir, filepath.FromSlash(dir)), Timeout: r.Timeout, Context: r.Context}
+}
+
+// RepoInfo 仓库位置信息
+type RepoInfo struct {
+	// 工作区根目录，在关联工作区(linked worktree)中为该工作区的目录
+	TopLevel string
+	// 主仓库的 .git 目录，关联工作区与主工作区共用
+	CommonDir string
+	// 是否为 git worktree add 创建的关联工作区
+	IsWorktree bool
+}
+
+// 仓库名称：关联工作区中取主仓库的目录名，而不是工作区目录名
+func (r *RepoInfo) Name() string {
+	if r.IsWorktree && filepath.Base(r.CommonDir) == ".git" {
+		return filepath.Base(filepath.Dir(r.CommonDir))
+	}
+	return filepath.Base(r.TopLevel)
+}
+
+// 识别当前目录所在的仓库，支持关联工作区
+func DetectRepo(git GitRunner) (*RepoInfo, error) {
+	out := gitOutput(git, "rev-parse", "--path-format=absolute", "--show-toplevel", "--git-dir", "--git-common-dir")
+	lines := strings.Split(out, "\n")
+	if len(lines) != 3 || strings.HasPrefix(lines[0], "--") {
+		// git 2.31 之前不支持 --path-format，无法可靠区分关联工作区，只识别工作区根目录
+		top := gitOutput(git, "rev-parse", "--show-toplevel")
+		if top == "" {
+			return nil, fmt.Errorf("错误：当前目录%w", ErrNotARepo)
+		}
+		return &RepoInfo{TopLevel: top, CommonDir: filepath.Join(top, ".git")}, nil
+	}
+	if lines[0] == "" {
+		return nil, fmt.Errorf("错误：当前目录%w", ErrNotARepo)
+	}
+
+	info := &RepoInfo{TopLevel: lines[0], CommonDir: lines[2]}
+	info.IsWorktree = filepath.Clean(lines[1]) != filepath.Clean(lines[2])
+	return info, nil
+}
+
+// 仓库 .git 目录中文件的路径(git rev-parse --git-path)，支持关联工作区；
+// git 2.31 之前返回相对于 git 命令工作目录的路径，不在仓库中时为空
+func GitPath(git GitRunner, name string) string {
+	path := gitOutput(git, "rev-parse", "--path-format=absolute", "--git-path", name)
+	if path == "" || strings.HasPrefix(path, "--") {
+		path = gitOutput(git, "rev-parse", "--git-path", name)
+	}
+	return path
+}
+
+// 当前 git 用户的邮箱(git config user.email)，未配置时为空
+func GitUserEmail(git GitRunner) string {
+	return gitOutput(git, "config", "user.email")
+}
+
+// 仓库所有引用及 HEAD 指向的提交，有新提交、拉取或切换分支时随之改变
+func RefsSignature(git GitRunner) string {
+	return gitOutput(git, "for-each-ref", "--format=%(objectname) %(refname)") + "\n" + gitOutput(git, "rev-parse", "HEAD")
+}
+
+// 查找 tag 之前最近的标签，没有更早的标签时返回空字符串
+func PreviousTag(git GitRunner, tag string) (string, error) {
+	if gitOutput(git, "rev-parse", "--verify", "--quiet", tag+"^{commit}") == "" {
+		return "", fmt.Errorf("错误：找不到标签 '%s'", tag)
+	}
+	return gitOutput(git, "describe", "--tags", "--abbrev=0", tag+"^"), nil
+}
+
+// 子模块路径（相对仓库根目录），递归包含嵌套子模块，跳过未初始化的子模块
+func ListSubmodules(git GitRunner) ([]string, error) {
+	out, err := git.Run([]string{"submodule", "status", "--recursive"})
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, line := range strings.Split(gitText(out), "\n") {
+		// 格式: [ +-U]<sha> <path> (<describe>)
+		if len(line) < 2 || line[0] == '-' {
+			continue
+		}
+		fields := strings.Fields(line[1:])
+		if len(fields) < 2 {
+			continue
+		}
+		paths = append(paths, fields[1])
+	}
+	return paths, nil
+}
+
+// 分析各子模块的提交，submodule 字段记录来源，prefix 为真时文件路径加上子模块路径前缀；
+// 单个子模块失败时记录错误并继续分析其余子模块
+func (a *Analyzer) analyzeSubmodules(q LogQuery) []CommitStats {
+	subRunner, ok := a.Git.(SubdirRunner)
+	if !ok {
+		a.fail("子模块", fmt.Errorf("错误：当前的 GitRunner 不支持分析子模块"))
+		return nil
+	}
+	submodules, err := ListSubmodules(a.Git)
+	if err != nil {
+		a.fail("子模块", err)
+		return nil
+	}
+
+	var commits []CommitStats
+	for _, dir := range submodules {
+		sub := *a
+		sub.Git = subRunner.Subdir(dir)
+		sub.RecurseSubmodules = false
+		sub.Warnings = nil
+		sub.estimated = 0
+		sub.manifests = 0
+		sub.ignored = nil
+		sub.massMoves = 0
+		sub.ambiguous = nil
+		sub.copies = nil
+		sub.symlinkChanges, sub.modeChanges = 0, 0
+		// 加上子模块路径后由本分析器输出
+		sub.emit, sub.parsed = nil, nil
+		subCommits, err := sub.Analyze(q)
+		var partial *PartialError
+		switch {
+		case errors.As(err, &partial):
+			for _, e := range partial.Errors {
+				a.errors = append(a.errors, &AnalysisError{Repo: dir, Stage: e.Stage, Err: e.Err})
+			}
+		case err != nil:
+			stage := "分析"
+			var analysisErr *AnalysisError
+			if errors.As(err, &analysisErr) {
+				stage, err = analysisErr.Stage, analysisErr.Err
+			}
+			a.errors = append(a.errors, &AnalysisError{Repo: dir, Stage: stage, Err: err})
+			continue
+		}
+		a.estimated += sub.estimated
+		a.manifests += sub.manifests
+		a.ignored = append(a.ignored, sub.ignored...)
+		a.massMoves += sub.massMoves
+		a.ambiguous = append(a.ambiguous, sub.ambiguous...)
+		a.copies = append(a.copies, sub.copies...)
+		a.symlinkChanges += sub.symlinkChanges
+		a.modeChanges += sub.modeChanges
+		for _, warning := range sub.Warnings {
+			a.warnf("子模块 '%s': %s", dir, warning)
+		}
+		for i := range subCommits {
+			subCommits[i].Submodule = dir
+			if a.SubmodulePrefix {
+				for j := range subCommits[i].Files {
+					subCommits[i].Files[j].Path = path.Join(dir, subCommits[i].Files[j].Path)
+					if subCommits[i].Files[j].OldPath != "" {
+						subCommits[i].Files[j].OldPath = path.Join(dir, subCommits[i].Files[j].OldPath)
+					}
+				}
+			}
+		}
+		if a.emit != nil {
+			a.emit(subCommits)
+		}
+		commits = append(commits, subCommits...)
+	}
+	return commits
+}
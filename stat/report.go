@@ -0,0 +1,104 @@
+package stat
+
+import (
+	"encoding/json"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Report 一次统计的完整结果。Report、AuthorStats、CommitStats、FileChange 及 PeriodStats 的
+// JSON、YAML 字段名保持稳定，供下游程序解析
+type Report struct {
+	Meta  *Metadata `json:"meta,omitempty" yaml:"meta,omitempty"`
+	Since string    `json:"since" yaml:"since"`
+	Until string    `json:"until" yaml:"until"`
+	// 按提交范围统计时的范围，如 v1.4.0..v1.5.0
+	RevRange string         `json:"rev_range,omitempty" yaml:"rev_range,omitempty"`
+	Authors  []*AuthorStats `json:"authors" yaml:"authors"`
+	// 汇总行，如“其他”“外部贡献者”
+	Groups  []*AuthorStats `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Commits []CommitStats  `json:"commits" yaml:"commits"`
+	// 按 AIG 标记分组(标记 AIG>0、标记 AIG=0、未标记)的汇总，开启时才有
+	Cohorts []*AuthorStats `json:"cohorts,omitempty" yaml:"cohorts,omitempty"`
+	// 按周汇总的统计及周的编号方式，开启时才有
+	Weeks      []*WeekStats `json:"weeks,omitempty" yaml:"weeks,omitempty"`
+	WeekScheme string       `json:"week_scheme,omitempty" yaml:"week_scheme,omitempty"`
+	// 全体及各开发者的提交时间热力图，开启时才有
+	Heatmaps []*Heatmap `json:"heatmaps,omitempty" yaml:"heatmaps,omitempty"`
+	// 与 git shortstat 交叉核对的结果，开启校验时才有
+	Validation *Validation `json:"validation,omitempty" yaml:"validation,omitempty"`
+	// 按组织架构逐级汇总的统计，配置了组织架构时才有
+	Org []*OrgRollup `json:"org,omitempty" yaml:"org,omitempty"`
+	// 配置文件中各目标的进度
+	Targets []TargetStatus `json:"targets,omitempty" yaml:"targets,omitempty"`
+	// 供评审参考的异常标注
+	Anomalies []Anomaly `json:"anomalies,omitempty" yaml:"anomalies,omitempty"`
+	// SZZ 缺陷引入分析的汇总，开启时才有
+	BugIntroduction *BugIntroduction `json:"bug_introduction,omitempty" yaml:"bug_introduction,omitempty"`
+	// 本期合并的 PR/MR 的评审数据，开启时才有
+	Reviews *ReviewStats `json:"reviews,omitempty" yaml:"reviews,omitempty"`
+	// 修复提交的修复耗时，开启时才有
+	TimeToFix *TimeToFix `json:"time_to_fix,omitempty" yaml:"time_to_fix,omitempty"`
+	// 按工作量模型估算的 AI 节省工时，开启时才有
+	Effort *EffortEstimate `json:"effort,omitempty" yaml:"effort,omitempty"`
+	// 按提交间隔估算的编码时长，开启时才有
+	Duration *DurationEstimate `json:"duration,omitempty" yaml:"duration,omitempty"`
+	// AI 工具费用的分摊及每行 AI 代码、每次 AI 参与修复的费用，指定费用明细时才有
+	Costs *CostReport `json:"costs,omitempty" yaml:"costs,omitempty"`
+	// 统计周期内的提交拓扑，导出提交图时才有
+	CommitGraph *CommitGraph `json:"commit_graph,omitempty" yaml:"commit_graph,omitempty"`
+	// 文件的新建、删除及有 AI 参与的净新增文件，开启时才有
+	Lifecycle *FileLifecycle `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`
+	// 按目录统计的变更集中程度，开启时才有
+	Ownership *Ownership `json:"ownership,omitempty" yaml:"ownership,omitempty"`
+	// 上一统计周期的运行记录，用于文本报告中标注变化，不输出也不保存
+	Previous *Report `json:"-" yaml:"-"`
+}
+
+// 以缩进格式输出 JSON
+func WriteJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// 以 YAML 输出，字段名与 JSON 相同。先编码为 JSON 再转换，没有 yaml 标签的嵌套类型也使用 JSON 的字段名
+func WriteYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// 去掉从 JSON 转换来的流式写法和引号，需要引号的字符串在输出时仍会加上
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// 统计结果的摘要，不包含生成时间等运行信息，相同输入得到相同摘要
+func (r *Report) Digest() string {
+	return ReportDigest(struct {
+		Since    string
+		Until    string
+		RevRange string
+		Authors  []*AuthorStats
+		Groups   []*AuthorStats
+		Commits  []CommitStats
+	}{r.Since, r.Until, r.RevRange, r.Authors, r.Groups, r.Commits})
+}
@@ -0,0 +1,87 @@
+package stat
+
+import (
+	"sort"
+	"strings"
+)
+
+// PeriodChange 相邻两个统计周期之间开发者的变化及团队 AI 占比的变化
+type PeriodChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// 新出现、不再出现、两期都有提交的开发者
+	Joined   []*AuthorStats `json:"joined"`
+	Left     []*AuthorStats `json:"left"`
+	Retained int            `json:"retained"`
+	// 两期全体开发者的 AI 添加占比
+	FromRatio float64 `json:"from_ratio"`
+	ToRatio   float64 `json:"to_ratio"`
+	// 两期都有提交的开发者在两期的 AI 添加占比，排除人员变化的影响
+	RetainedFromRatio float64 `json:"retained_from_ratio"`
+	RetainedToRatio   float64 `json:"retained_to_ratio"`
+	// 新开发者在本期、离开的开发者在上期的 AI 添加占比
+	JoinedRatio float64 `json:"joined_ratio"`
+	LeftRatio   float64 `json:"left_ratio"`
+}
+
+// 对比相邻统计周期的开发者构成，reports 按时间先后排列
+// 只统计有提交的开发者，名单中本期没有提交的成员视为未出现
+func Retention(reports []*Report) []PeriodChange {
+	var changes []PeriodChange
+	for i := 1; i < len(reports); i++ {
+		prev, cur := activeAuthors(reports[i-1]), activeAuthors(reports[i])
+		change := PeriodChange{
+			From: reports[i-1].PeriodLabel(),
+			To:   reports[i].PeriodLabel(),
+		}
+
+		var all [2]AuthorStats
+		var retained [2]AuthorStats
+		var joined, left AuthorStats
+		for key, s := range prev {
+			all[0].Merge(s)
+			if _, ok := cur[key]; ok {
+				retained[0].Merge(s)
+			} else {
+				left.Merge(s)
+				change.Left = append(change.Left, s)
+			}
+		}
+		for key, s := range cur {
+			all[1].Merge(s)
+			if _, ok := prev[key]; ok {
+				retained[1].Merge(s)
+				change.Retained++
+			} else {
+				joined.Merge(s)
+				change.Joined = append(change.Joined, s)
+			}
+		}
+		sortByEmail(change.Joined)
+		sortByEmail(change.Left)
+
+		change.FromRatio, change.ToRatio = all[0].AddedRatio(), all[1].AddedRatio()
+		change.RetainedFromRatio, change.RetainedToRatio = retained[0].AddedRatio(), retained[1].AddedRatio()
+		change.JoinedRatio, change.LeftRatio = joined.AddedRatio(), left.AddedRatio()
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// 报告中有提交的开发者，按小写邮箱索引
+func activeAuthors(report *Report) map[string]*AuthorStats {
+	authors := make(map[string]*AuthorStats)
+	for _, s := range report.Authors {
+		if s.CommitCount > 0 {
+			authors[strings.ToLower(s.Email)] = s
+		}
+	}
+	return authors
+}
+
+// 按邮箱排序
+func sortByEmail(authors []*AuthorStats) {
+	sort.Slice(authors, func(i, j int) bool {
+		return authors[i].Email < authors[j].Email
+	})
+}
@@ -0,0 +1,466 @@
+package stat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// 评审平台的访问令牌默认从该环境变量读取
+const EnvReviewToken = "AISTAT_REVIEW_TOKEN"
+
+// 评审平台
+const (
+	ReviewGitHub = "github"
+	ReviewGitLab = "gitlab"
+)
+
+// ReviewConfig 配置文件中代码评审数据的来源，各项为空时根据仓库的远程地址推断
+type ReviewConfig struct {
+	// github 或 gitlab
+	Provider string `yaml:"provider"`
+	// 接口地址，如 https://api.github.com、https://gitlab.example.com/api/v4
+	APIURL string `yaml:"api_url"`
+	// 仓库全名，如 group/app
+	Project string `yaml:"project"`
+	// 保存访问令牌的环境变量 (默认 AISTAT_REVIEW_TOKEN)
+	TokenEnv string `yaml:"token_env"`
+}
+
+// PullRequestReview 本期合并的一个 PR/MR 的评审数据
+type PullRequestReview struct {
+	Number    int    `json:"number" yaml:"number"`
+	Title     string `json:"title" yaml:"title"`
+	Author    string `json:"author" yaml:"author"`
+	CreatedAt string `json:"created_at" yaml:"created_at"`
+	MergedAt  string `json:"merged_at" yaml:"merged_at"`
+	Approvals int    `json:"approvals" yaml:"approvals"`
+	// 评审意见及讨论的评论数
+	Comments     int     `json:"comments" yaml:"comments"`
+	HoursToMerge float64 `json:"hours_to_merge" yaml:"hours_to_merge"`
+	// 关联到本期统计的提交数及按行数加权的 AIG 比例，没有关联到提交时 Matched 为假
+	Matched  bool    `json:"matched" yaml:"matched"`
+	Commits  int     `json:"commits" yaml:"commits"`
+	AIGRatio float64 `json:"aig_ratio" yaml:"aig_ratio"`
+}
+
+// 有 AI 参与的 PR(关联提交的 AIG 比例大于 0)
+func (p *PullRequestReview) AIAssisted() bool {
+	return p.Matched && p.AIGRatio > 0
+}
+
+// ReviewGroup 一组 PR 的评审汇总
+type ReviewGroup struct {
+	PullRequests int `json:"pull_requests" yaml:"pull_requests"`
+	Approvals    int `json:"approvals" yaml:"approvals"`
+	Comments     int `json:"comments" yaml:"comments"`
+	// 从创建到合并的小时数的中位数
+	MedianHoursToMerge float64 `json:"median_hours_to_merge" yaml:"median_hours_to_merge"`
+}
+
+// 平均每个 PR 的评论数
+func (g ReviewGroup) AvgComments() float64 {
+	return average(g.Comments, g.PullRequests)
+}
+
+// 平均每个 PR 的批准数
+func (g ReviewGroup) AvgApprovals() float64 {
+	return average(g.Approvals, g.PullRequests)
+}
+
+func average(total, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(total) / float64(n)
+}
+
+// ReviewStats 本期合并的 PR/MR 的评审数据，按是否有 AI 参与分组比较
+type ReviewStats struct {
+	Provider string      `json:"provider" yaml:"provider"`
+	Project  string      `json:"project" yaml:"project"`
+	AI       ReviewGroup `json:"ai" yaml:"ai"`
+	Human    ReviewGroup `json:"human" yaml:"human"`
+	// 没有关联到本期提交的 PR 数，不参与比较
+	Unmatched    int                 `json:"unmatched" yaml:"unmatched"`
+	PullRequests []PullRequestReview `json:"pull_requests" yaml:"pull_requests"`
+}
+
+// 从 GitHub/GitLab 读取本期合并的 PR/MR 的批准数、评论数和合并耗时，并通过提交哈希关联到报告中的提交
+func CollectReviews(git GitRunner, cfg *ReviewConfig, report *Report) (*ReviewStats, error) {
+	source, err := resolveReviewSource(git, cfg)
+	if err != nil {
+		return nil, err
+	}
+	prs, err := source.fetch(report)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make(map[string]*CommitStats, len(report.Commits))
+	for i := range report.Commits {
+		commits[report.Commits[i].ID] = &report.Commits[i]
+	}
+	stats := &ReviewStats{Provider: source.provider, Project: source.project}
+	var aiHours, humanHours []float64
+	for _, pr := range prs {
+		review := pr.PullRequestReview
+		added, aiAdded := 0, 0.0
+		for _, sha := range pr.shas {
+			c, ok := commits[sha]
+			if !ok {
+				continue
+			}
+			review.Matched = true
+			review.Commits++
+			added += c.AddedLines
+			aiAdded += float64(c.AddedLines) * c.AIGRatio
+			// 只删除代码的 PR 取提交中最大的 AIG 比例
+			if c.AIGRatio > review.AIGRatio {
+				review.AIGRatio = c.AIGRatio
+			}
+		}
+		if added > 0 {
+			review.AIGRatio = aiAdded / float64(added)
+		}
+		// 按提交范围统计时只保留关联到本期提交的 PR
+		if report.RevRange != "" && !review.Matched {
+			continue
+		}
+		switch {
+		case !review.Matched:
+			stats.Unmatched++
+		case review.AIAssisted():
+			stats.AI.add(&review)
+			aiHours = append(aiHours, review.HoursToMerge)
+		default:
+			stats.Human.add(&review)
+			humanHours = append(humanHours, review.HoursToMerge)
+		}
+		stats.PullRequests = append(stats.PullRequests, review)
+	}
+	stats.AI.MedianHoursToMerge = median(aiHours)
+	stats.Human.MedianHoursToMerge = median(humanHours)
+	return stats, nil
+}
+
+func (g *ReviewGroup) add(p *PullRequestReview) {
+	g.PullRequests++
+	g.Approvals += p.Approvals
+	g.Comments += p.Comments
+}
+
+// reviewSource 评审平台的接口
+type reviewSource struct {
+	provider string
+	apiURL   string
+	project  string
+	api      *APIClient
+}
+
+// 读取到的 PR 及其包含的提交哈希(含合并提交、squash 提交)
+type fetchedPR struct {
+	PullRequestReview
+	shas []string
+}
+
+// 根据配置及远程地址确定评审平台、接口地址和仓库
+func resolveReviewSource(git GitRunner, cfg *ReviewConfig) (*reviewSource, error) {
+	if cfg == nil {
+		cfg = &ReviewConfig{}
+	}
+	host, path := parseRemote(gitOutput(git, "config", "--get", "remote.origin.url"))
+	s := &reviewSource{provider: strings.ToLower(cfg.Provider), apiURL: strings.TrimSuffix(cfg.APIURL, "/"), project: firstNonEmpty(cfg.Project, path)}
+	if s.provider == "" {
+		switch {
+		case strings.Contains(host, "github"):
+			s.provider = ReviewGitHub
+		case strings.Contains(host, "gitlab"):
+			s.provider = ReviewGitLab
+		default:
+			return nil, fmt.Errorf("错误：无法从远程地址判断评审平台，请在配置项 review.provider 中指定 github 或 gitlab")
+		}
+	}
+	if s.provider != ReviewGitHub && s.provider != ReviewGitLab {
+		return nil, fmt.Errorf("错误：不支持的评审平台 '%s'，可选: github, gitlab", cfg.Provider)
+	}
+	if s.project == "" {
+		return nil, fmt.Errorf("错误：无法从远程地址确定仓库，请在配置项 review.project 中指定")
+	}
+	if s.apiURL == "" {
+		switch {
+		case host == "":
+			return nil, fmt.Errorf("错误：无法从远程地址确定接口地址，请在配置项 review.api_url 中指定")
+		case s.provider == ReviewGitHub && host == "github.com":
+			s.apiURL = "https://api.github.com"
+		case s.provider == ReviewGitHub:
+			s.apiURL = "https://" + host + "/api/v3"
+		default:
+			s.apiURL = "https://" + host + "/api/v4"
+		}
+	}
+
+	header := http.Header{}
+	token := os.Getenv(firstNonEmpty(cfg.TokenEnv, EnvReviewToken))
+	if token != "" {
+		if s.provider == ReviewGitHub {
+			header.Set("Authorization", "Bearer "+token)
+		} else {
+			header.Set("PRIVATE-TOKEN", token)
+		}
+	}
+	s.api = &APIClient{Client: &http.Client{Timeout: 30 * time.Second}, Header: header}
+	return s, nil
+}
+
+// 解析远程地址中的主机名和仓库路径，支持 https://host/group/app.git、git@host:group/app.git 等形式
+func parseRemote(remote string) (string, string) {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return "", ""
+	}
+	var host, path string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", ""
+		}
+		host, path = u.Hostname(), u.Path
+	} else if at, rest, ok := strings.Cut(remote, ":"); ok {
+		_, host, _ = strings.Cut(at, "@")
+		if host == "" {
+			host = at
+		}
+		path = rest
+	}
+	return strings.ToLower(host), strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+}
+
+// 读取合并时间在统计周期内的 PR，按提交范围统计时读取最近合并的 PR
+func (s *reviewSource) fetch(report *Report) ([]fetchedPR, error) {
+	if s.provider == ReviewGitHub {
+		return s.fetchGitHub(report)
+	}
+	return s.fetchGitLab(report)
+}
+
+// 停止读取后续分页
+var errStopPages = errors.New("stop")
+
+// 合并时间是否在统计周期内，按提交范围统计时不限制
+func mergedInPeriod(report *Report, mergedAt string) bool {
+	t, err := time.Parse(time.RFC3339, mergedAt)
+	if err != nil {
+		return false
+	}
+	day := t.In(time.Local).Format(DateLayout)
+	return (report.Since == "" || day >= PeriodDay(report.Since)) && (report.Until == "" || day <= PeriodDay(report.Until))
+}
+
+// 最近更新时间早于统计周期时，之后的 PR 都不可能在本期合并
+func updatedBeforePeriod(report *Report, updatedAt string) bool {
+	t, err := time.Parse(time.RFC3339, updatedAt)
+	return err == nil && report.Since != "" && t.In(time.Local).Format(DateLayout) < PeriodDay(report.Since)
+}
+
+func hoursBetween(from, to string) float64 {
+	start, err1 := time.Parse(time.RFC3339, from)
+	end, err2 := time.Parse(time.RFC3339, to)
+	if err1 != nil || err2 != nil || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start).Hours()
+}
+
+func (s *reviewSource) fetchGitHub(report *Report) ([]fetchedPR, error) {
+	base := s.apiURL + "/repos/" + s.project
+	var prs []fetchedPR
+	err := s.api.GetPages(base+"/pulls?state=closed&sort=updated&direction=desc&per_page=100", func(body []byte) error {
+		var page []struct {
+			Number    int    `json:"number"`
+			Title     string `json:"title"`
+			CreatedAt string `json:"created_at"`
+			UpdatedAt string `json:"updated_at"`
+			MergedAt  string `json:"merged_at"`
+			MergeSHA  string `json:"merge_commit_sha"`
+			User      struct {
+				Login string `json:"login"`
+			} `json:"user"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("错误：解析 GitHub PR 列表失败: %v", err)
+		}
+		for _, p := range page {
+			if updatedBeforePeriod(report, p.UpdatedAt) {
+				return errStopPages
+			}
+			if p.MergedAt == "" || (report.RevRange == "" && !mergedInPeriod(report, p.MergedAt)) {
+				continue
+			}
+			prs = append(prs, fetchedPR{
+				PullRequestReview: PullRequestReview{
+					Number: p.Number, Title: p.Title, Author: p.User.Login,
+					CreatedAt: p.CreatedAt, MergedAt: p.MergedAt, HoursToMerge: hoursBetween(p.CreatedAt, p.MergedAt),
+				},
+				shas: []string{p.MergeSHA},
+			})
+		}
+		return nil
+	})
+	if err != nil && err != errStopPages {
+		return nil, err
+	}
+
+	for i := range prs {
+		pr := &prs[i]
+		prURL := fmt.Sprintf("%s/pulls/%d", base, pr.Number)
+		var detail struct {
+			Comments       int `json:"comments"`
+			ReviewComments int `json:"review_comments"`
+		}
+		if err := s.api.GetJSON(prURL, &detail); err != nil {
+			return nil, err
+		}
+		pr.Comments = detail.Comments + detail.ReviewComments
+		err := s.api.GetPages(prURL+"/reviews?per_page=100", func(body []byte) error {
+			var reviews []struct {
+				State string `json:"state"`
+			}
+			if err := json.Unmarshal(body, &reviews); err != nil {
+				return fmt.Errorf("错误：解析 GitHub PR #%d 的评审失败: %v", pr.Number, err)
+			}
+			for _, r := range reviews {
+				switch r.State {
+				case "APPROVED":
+					pr.Approvals++
+				case "COMMENTED", "CHANGES_REQUESTED":
+					// 只有总结意见的评审也计为一条评论
+					pr.Comments++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if pr.shas, err = s.pageSHAs(prURL+"/commits?per_page=100", pr.shas, "sha"); err != nil {
+			return nil, err
+		}
+	}
+	return prs, nil
+}
+
+func (s *reviewSource) fetchGitLab(report *Report) ([]fetchedPR, error) {
+	base := s.apiURL + "/projects/" + url.PathEscape(s.project)
+	list := base + "/merge_requests?state=merged&order_by=updated_at&sort=desc&per_page=100"
+	if report.Since != "" {
+		list += "&updated_after=" + url.QueryEscape(PeriodDay(report.Since)+"T00:00:00Z")
+	}
+	var prs []fetchedPR
+	err := s.api.GetPages(list, func(body []byte) error {
+		var page []struct {
+			IID            int    `json:"iid"`
+			Title          string `json:"title"`
+			CreatedAt      string `json:"created_at"`
+			MergedAt       string `json:"merged_at"`
+			MergeSHA       string `json:"merge_commit_sha"`
+			SquashSHA      string `json:"squash_commit_sha"`
+			UserNotesCount int    `json:"user_notes_count"`
+			Author         struct {
+				Username string `json:"username"`
+			} `json:"author"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("错误：解析 GitLab MR 列表失败: %v", err)
+		}
+		for _, p := range page {
+			if report.RevRange == "" && !mergedInPeriod(report, p.MergedAt) {
+				continue
+			}
+			prs = append(prs, fetchedPR{
+				PullRequestReview: PullRequestReview{
+					Number: p.IID, Title: p.Title, Author: p.Author.Username, Comments: p.UserNotesCount,
+					CreatedAt: p.CreatedAt, MergedAt: p.MergedAt, HoursToMerge: hoursBetween(p.CreatedAt, p.MergedAt),
+				},
+				shas: []string{p.MergeSHA, p.SquashSHA},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range prs {
+		pr := &prs[i]
+		mrURL := fmt.Sprintf("%s/merge_requests/%d", base, pr.Number)
+		var approvals struct {
+			ApprovedBy []json.RawMessage `json:"approved_by"`
+		}
+		if err := s.api.GetJSON(mrURL+"/approvals", &approvals); err != nil {
+			return nil, err
+		}
+		pr.Approvals = len(approvals.ApprovedBy)
+		if pr.shas, err = s.pageSHAs(mrURL+"/commits?per_page=100", pr.shas, "id"); err != nil {
+			return nil, err
+		}
+	}
+	return prs, nil
+}
+
+// issue 的创建时间
+func (s *reviewSource) issueOpened(number int) (time.Time, error) {
+	issueURL := fmt.Sprintf("%s/repos/%s/issues/%d", s.apiURL, s.project, number)
+	if s.provider == ReviewGitLab {
+		issueURL = fmt.Sprintf("%s/projects/%s/issues/%d", s.apiURL, url.PathEscape(s.project), number)
+	}
+	var issue struct {
+		CreatedAt string `json:"created_at"`
+	}
+	if err := s.api.GetJSON(issueURL, &issue); err != nil {
+		return time.Time{}, err
+	}
+	opened, err := time.Parse(time.RFC3339, issue.CreatedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("错误：issue #%d 的创建时间 '%s' 无法解析", number, issue.CreatedAt)
+	}
+	return opened, nil
+}
+
+// 读取 PR/MR 的提交列表，提交哈希的字段名 GitHub 为 sha，GitLab 为 id
+func (s *reviewSource) pageSHAs(pageURL string, shas []string, field string) ([]string, error) {
+	err := s.api.GetPages(pageURL, func(body []byte) error {
+		var commits []map[string]interface{}
+		if err := json.Unmarshal(body, &commits); err != nil {
+			return fmt.Errorf("错误：解析 '%s' 的提交列表失败: %v", pageURL, err)
+		}
+		for _, c := range commits {
+			if sha, ok := c[field].(string); ok {
+				shas = append(shas, sha)
+			}
+		}
+		return nil
+	})
+	return shas, err
+}
+
+// 打印 AI 参与与否的 PR 评审数据对比
+func PrintReviews(w io.Writer, r *ReviewStats, nf NumberFormat) {
+	fmt.Fprintf(w, "\n  代码评审 (%s %s，本期合并 %s 个 PR):\n", r.Provider, r.Project, nf.Int(len(r.PullRequests)))
+	for _, g := range []struct {
+		label string
+		group ReviewGroup
+	}{{"有 AI 参与", r.AI}, {"无 AI 参与", r.Human}} {
+		fmt.Fprintf(w, "    %s: %s 个 PR，平均评论 %s 条，平均批准 %s 个，合并耗时中位数 %s 小时\n", g.label,
+			nf.Int(g.group.PullRequests), nf.Float(g.group.AvgComments(), 1), nf.Float(g.group.AvgApprovals(), 1), nf.Float(g.group.MedianHoursToMerge, 1))
+	}
+	if r.Unmatched > 0 {
+		fmt.Fprintf(w, "    未关联到本期提交: %s 个 PR (不参与比较)\n", nf.Int(r.Unmatched))
+	}
+}
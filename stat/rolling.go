@@ -0,0 +1,76 @@
+package stat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 默认的滚动窗口天数
+const DefaultRollingDays = 90
+
+// RollingStats 开发者在统计周期之前一段时间内的平均水平，用于判断本期数据是否典型
+type RollingStats struct {
+	Days int `json:"days" yaml:"days"`
+	// 窗口内的提交、添加行和 AI 添加行
+	CommitCount       int `json:"commit_count" yaml:"commit_count"`
+	TotalAddedLines   int `json:"total_added_lines" yaml:"total_added_lines"`
+	TotalAIAddedLines int `json:"total_ai_added_lines" yaml:"total_ai_added_lines"`
+	// 策略标记(n/a、exempt)提交的添加行，不计入占比的分母
+	PolicyAddedLines int `json:"policy_added_lines,omitempty" yaml:"policy_added_lines,omitempty"`
+	// 折算为与本期等长的周期后每期的平均添加行数
+	AddedPerPeriod float64 `json:"added_per_period" yaml:"added_per_period"`
+}
+
+// 窗口内的 AI 添加行占比（百分比）
+func (r *RollingStats) AddedRatio() float64 {
+	return percent(r.TotalAIAddedLines, r.RatioAddedLines())
+}
+
+// 计算 AI 添加占比的分母
+func (r *RollingStats) RatioAddedLines() int {
+	return r.TotalAddedLines - r.PolicyAddedLines
+}
+
+// 滚动窗口的起止日期：统计开始日期之前的 days 天，返回 [from, to)
+func RollingWindow(since string, days int) (string, string, error) {
+	start, err := time.Parse(DateLayout, PeriodDay(since))
+	if err != nil {
+		return "", "", fmt.Errorf("错误：计算滚动窗口需要有效的开始日期")
+	}
+	return start.AddDate(0, 0, -days).Format(DateLayout), PeriodDay(since), nil
+}
+
+// 统计周期的天数(含首尾)，无法解析时返回 0
+func PeriodDays(since, until string) int {
+	start, err1 := time.Parse(DateLayout, PeriodDay(since))
+	end, err2 := time.Parse(DateLayout, PeriodDay(until))
+	if err1 != nil || err2 != nil || end.Before(start) {
+		return 0
+	}
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// 根据窗口内的提交为每个开发者补充滚动统计，periodDays 为本期天数
+func ApplyRolling(authors []*AuthorStats, commits []CommitStats, days, periodDays int) {
+	byEmail := make(map[string]*AuthorStats)
+	for email, stats := range AggregateByAuthor(commits) {
+		byEmail[strings.ToLower(email)] = stats
+	}
+
+	periods := 1.0
+	if periodDays > 0 {
+		periods = float64(days) / float64(periodDays)
+	}
+	for _, stats := range authors {
+		rolling := &RollingStats{Days: days}
+		if past, ok := byEmail[strings.ToLower(stats.Email)]; ok {
+			rolling.CommitCount = past.CommitCount
+			rolling.TotalAddedLines = past.TotalAddedLines
+			rolling.TotalAIAddedLines = past.TotalAIAddedLines
+			rolling.PolicyAddedLines = past.PolicyAddedLines
+			rolling.AddedPerPeriod = float64(past.TotalAddedLines) / periods
+		}
+		stats.Rolling = rolling
+	}
+}
@@ -0,0 +1,82 @@
+package stat
+
+import "context"
+
+// 读取 git log 时每解析该数量的提交输出一批初步结果
+const parsedBatch = 1000
+
+// CommitResult 流式分析输出的一个提交
+type CommitResult struct {
+	// 分析完成的提交，子模块的提交 Submodule 为子模块路径
+	Commit CommitStats
+	// 为真时是读取 git log 过程中刚解析出的初步结果，之后的跨分支去重、忽略列表、
+	// 大规模移动、AI 清单等步骤可能去掉或修改该提交，只用于显示进度；
+	// 同一提交分析完成后会再以 Preliminary 为假输出一次，汇总时只用最终结果
+	Preliminary bool
+	// 本阶段已输出的提交数(含本条)及 git rev-list --count 预计的提交数，
+	// 预计数含被忽略、被过滤的提交，不含子模块，无法统计时为 0
+	Done, Expected int
+}
+
+// 与 Analyze 相同地分析提交：读取 git log 时每解析完一批提交(分片读取时每个分片)即以初步结果逐个调用 fn，
+// 主仓库及每个子模块分析完成后再对其中的提交以最终结果逐个调用 fn，
+// 嵌入方可以据此显示进度或自行汇总，不必等待完整的报告。fn 在调用 Run 的 goroutine 中执行；
+// ctx 取消后中止正在执行的 git 命令且不再调用 fn，返回 PartialError。运行信息仍通过 Metadata 获取
+func (a *Analyzer) Run(ctx context.Context, q LogQuery, fn func(CommitResult)) error {
+	git := a.Git
+	if r, ok := git.(*ExecGitRunner); ok {
+		c := *r
+		c.Context = ctx
+		a.Git = &c
+	}
+	parsed, done := 0, 0
+	a.parsed = func(commits []CommitStats) {
+		for _, c := range commits {
+			if ctx.Err() != nil {
+				return
+			}
+			parsed++
+			fn(CommitResult{Commit: c, Preliminary: true, Done: parsed, Expected: a.commitCount})
+		}
+	}
+	a.emit = func(commits []CommitStats) {
+		for _, c := range commits {
+			if ctx.Err() != nil {
+				return
+			}
+			done++
+			fn(CommitResult{Commit: c, Done: done, Expected: a.commitCount})
+		}
+	}
+	defer func() {
+		a.Git, a.emit, a.parsed = git, nil, nil
+	}()
+
+	_, err := a.Analyze(q)
+	if err != nil && !IsPartial(err) {
+		return err
+	}
+	if ctx.Err() != nil && !a.interrupted() {
+		a.fail("流式输出", ErrInterrupted)
+		return a.partialError()
+	}
+	return err
+}
+
+// 以通道的形式调用 Run：提交逐个发送到 results，分析结束后关闭 results，再向 errc 发送 Run 的返回值。
+// 读取完 errc 之前不要使用该分析器
+func (a *Analyzer) Stream(ctx context.Context, q LogQuery) (results <-chan CommitResult, errc <-chan error) {
+	out := make(chan CommitResult)
+	errs := make(chan error, 1)
+	go func() {
+		err := a.Run(ctx, q, func(r CommitResult) {
+			select {
+			case out <- r:
+			case <-ctx.Done():
+			}
+		})
+		close(out)
+		errs <- err
+	}()
+	return out, errs
+}
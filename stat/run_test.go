@@ -0,0 +1,41 @@
+package stat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunEmitsParsedBatches(t *testing.T) {
+	const total = 2500
+	var log strings.Builder
+	for i := 0; i < total; i++ {
+		log.WriteString(logEntry(fmt.Sprintf("%040x", i+1), "Dev", "dev@example.com", "feat: change", "", "1\t0\ta.go"))
+	}
+	git := &fakeGit{outputs: map[string]string{"log": log.String(), "rev-list": fmt.Sprint(total)}}
+	a := NewAnalyzer(git)
+	a.IncludeExts = []string{".go"}
+
+	var preliminary, final []CommitResult
+	err := a.Run(context.Background(), LogQuery{RevRange: "HEAD"}, func(r CommitResult) {
+		if r.Preliminary {
+			if len(final) > 0 {
+				t.Fatalf("分析完成后又输出了初步结果")
+			}
+			preliminary = append(preliminary, r)
+			return
+		}
+		final = append(final, r)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preliminary) != total || len(final) != total {
+		t.Fatalf("初步结果 %d 个、最终结果 %d 个，want 各 %d 个", len(preliminary), len(final), total)
+	}
+	last := preliminary[len(preliminary)-1]
+	if last.Done != total || last.Expected != total {
+		t.Errorf("最后一个初步结果 Done=%d Expected=%d", last.Done, last.Expected)
+	}
+}
@@ -0,0 +1,74 @@
+package stat
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 95% 置信水平对应的 z 值
+const confidenceZ = 1.96
+
+// 分母(样本量)低于 minSample 时比例没有参考意义，返回 false
+func SampleRatio(part, total, minSample int) (float64, bool) {
+	if total == 0 || total < minSample {
+		return 0, false
+	}
+	return percent(part, total), true
+}
+
+// 格式化比例，样本不足时显示样本量而不是比例，如 "样本不足(n=2)"
+func FormatSampleRatio(part, total, minSample int) string {
+	ratio, ok := SampleRatio(part, total, minSample)
+	if !ok && total > 0 {
+		return fmt.Sprintf("样本不足(n=%d)", total)
+	}
+	return fmt.Sprintf("%.2f%%", ratio)
+}
+
+// 格式化按提交平均的比例，提交数低于 minSample 时显示样本量
+func FormatSampleMean(mean float64, n, minSample int) string {
+	if n == 0 || n < minSample {
+		if n > 0 {
+			return fmt.Sprintf("样本不足(n=%d)", n)
+		}
+		return "0.00%"
+	}
+	return fmt.Sprintf("%.2f%%", mean)
+}
+
+// 比例的 Wilson 95% 置信区间（百分比），样本量为 0 时返回 0, 0
+func WilsonInterval(k, n int) (float64, float64) {
+	if n == 0 {
+		return 0, 0
+	}
+	p := float64(k) / float64(n)
+	z2 := confidenceZ * confidenceZ
+	denominator := 1 + z2/float64(n)
+	center := (p + z2/(2*float64(n))) / denominator
+	margin := confidenceZ * math.Sqrt(p*(1-p)/float64(n)+z2/(4*float64(n)*float64(n))) / denominator
+	return math.Max(0, center-margin) * 100, math.Min(1, center+margin) * 100
+}
+
+// 修复提交占比的样本说明，如 "n=3, 95% 置信区间 20.77%~100.00%"
+func FixRatioNote(k, n int) string {
+	if n == 0 {
+		return "n=0"
+	}
+	lo, hi := WilsonInterval(k, n)
+	return fmt.Sprintf("n=%d, 95%% 置信区间 %.2f%%~%.2f%%", n, lo, hi)
+}
+
+// 中位数，没有数据时为 0
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,105 @@
+package stat
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 时间序列支持的指标
+var SeriesMetrics = []string{
+	"commits", "added_lines", "deleted_lines",
+	"ai_added_lines", "ai_deleted_lines", "ai_added_ratio",
+	"fix_count", "ai_fix_ratio",
+}
+
+// Point 时间序列中的一个点
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series 一条时间序列
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+// 提交时间，按本地时区解析 LogArgs 输出的时间格式
+func CommitTime(c *CommitStats) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02 15:04:05", c.Time, time.Local)
+}
+
+// 将 [from, to) 内的提交按 step 分桶汇总为时间序列，group 返回提交所属序列的名称
+// 计数类指标没有提交的桶取 0，比例类指标没有分母的桶不输出
+func BuildSeries(commits []CommitStats, metric string, from, to time.Time, step time.Duration, group func(*CommitStats) string) ([]Series, error) {
+	if !validMetric(metric) {
+		return nil, fmt.Errorf("错误：不支持的指标 '%s'", metric)
+	}
+	if step <= 0 || !to.After(from) {
+		return nil, nil
+	}
+
+	buckets := int((to.Sub(from)-1)/step) + 1
+	groups := make(map[string][]AuthorStats)
+	for i := range commits {
+		t, err := CommitTime(&commits[i])
+		if err != nil || t.Before(from) || !t.Before(to) {
+			continue
+		}
+		name := group(&commits[i])
+		if groups[name] == nil {
+			groups[name] = make([]AuthorStats, buckets)
+		}
+		groups[name][int(t.Sub(from)/step)].Add(&commits[i])
+	}
+
+	var series []Series
+	for name, stats := range groups {
+		s := Series{Name: name}
+		for i := range stats {
+			value, ok := metricValue(&stats[i], metric)
+			if !ok {
+				continue
+			}
+			s.Points = append(s.Points, Point{Time: from.Add(time.Duration(i) * step), Value: value})
+		}
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].Name < series[j].Name
+	})
+	return series, nil
+}
+
+func validMetric(metric string) bool {
+	for _, m := range SeriesMetrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// 取统计中的指标值，比例没有分母时返回 false
+func metricValue(s *AuthorStats, metric string) (float64, bool) {
+	switch metric {
+	case "commits":
+		return float64(s.CommitCount), true
+	case "added_lines":
+		return float64(s.TotalAddedLines), true
+	case "deleted_lines":
+		return float64(s.TotalDeletedLines), true
+	case "ai_added_lines":
+		return float64(s.TotalAIAddedLines), true
+	case "ai_deleted_lines":
+		return float64(s.TotalAIDeletedLines), true
+	case "ai_added_ratio":
+		return s.AddedRatio(), s.RatioAddedLines() > 0
+	case "fix_count":
+		return float64(s.FixCount), true
+	case "ai_fix_ratio":
+		return s.AIFixRatio(), s.RatioFixCount() > 0
+	}
+	return 0, false
+}
@@ -0,0 +1,183 @@
+package stat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 预计提交数达到该值时按时间把日期范围分片，并行执行 git log
+const ShardThreshold = 20000
+
+// 每个分片的目标提交数
+const shardCommits = 10000
+
+// 用 git rev-list --count 预先统计查询范围内的提交数，只遍历提交，不读取文件变更
+func CountCommits(git GitRunner, q LogQuery) (int, error) {
+	out, err := git.Run(append([]string{"rev-list", "--count"}, revisionArgs(q)...))
+	if err != nil {
+		return 0, err
+	}
+	count, err := strconv.Atoi(gitText(out))
+	if err != nil {
+		return 0, fmt.Errorf("错误：无法解析 git rev-list --count 的输出: %v", err)
+	}
+	return count, nil
+}
+
+// 按预计提交数把日期范围等分为若干时间片，从新到旧排列，与 git log 的输出顺序一致；
+// 按提交范围统计或提交数低于阈值时不分片
+func shardQueries(q LogQuery, count, workers int) []LogQuery {
+	if q.RevRange != "" || count < ShardThreshold {
+		return []LogQuery{q}
+	}
+	start, err1 := time.ParseInLocation(DateTimeLayout, gitDate(q.Since, false), time.Local)
+	end, err2 := time.ParseInLocation(DateTimeLayout, gitDate(q.Until, true), time.Local)
+	if err1 != nil || err2 != nil || !end.After(start) {
+		return []LogQuery{q}
+	}
+	n := (count + shardCommits - 1) / shardCommits
+	if n > workers*4 {
+		n = workers * 4
+	}
+	// git 的 --since、--until 都包含边界，分片之间按秒错开
+	step := end.Sub(start).Truncate(time.Second) / time.Duration(n)
+	if n < 2 || step < time.Second {
+		return []LogQuery{q}
+	}
+	shards := make([]LogQuery, n)
+	for i := 0; i < n; i++ {
+		from := start.Add(step * time.Duration(i))
+		to := from.Add(step - time.Second)
+		if i == n-1 {
+			to = end
+		}
+		shard := q
+		shard.Since, shard.Until = from.Format(DateTimeLayout), to.Format(DateTimeLayout)
+		shards[n-1-i] = shard
+	}
+	return shards
+}
+
+// 执行 git log 并解析：先统计提交数，提交较多时分片并行读取，每个分片读完即解析，
+// 结果按从新到旧的顺序拼接
+func (a *Analyzer) readLog(q LogQuery) ([]CommitStats, error) {
+	a.malformed = 0
+	count, err := CountCommits(a.Git, q)
+	if err != nil {
+		// 只影响分片和进度显示，git log 本身出错时会给出错误
+		return a.readShard(q)
+	}
+	a.commitCount = count
+	workers := runtime.GOMAXPROCS(0)
+	shards := shardQueries(q, count, workers)
+	if len(shards) == 1 {
+		return a.readShard(q)
+	}
+	a.shards = len(shards)
+	if workers > len(shards) {
+		workers = len(shards)
+	}
+
+	outputs := make([][]byte, len(shards))
+	errs := make([]error, len(shards))
+	progress := newProgress(a.Progress, len(shards), count)
+	sem := make(chan struct{}, workers)
+	finished := make(chan int, len(shards))
+	for i := range shards {
+		go func(i int) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			out, err := a.Git.Run(LogArgs(shards[i]))
+			if err == nil {
+				outputs[i], err = io.ReadAll(out)
+			}
+			errs[i] = err
+			progress.done()
+			finished <- i
+		}(i)
+	}
+	// 在当前 goroutine 中按完成的顺序解析，Run 的回调不会被并发调用
+	parsed := make([][]CommitStats, len(shards))
+	for range shards {
+		i := <-finished
+		if errs[i] == nil {
+			parsed[i] = a.parseOutput(string(outputs[i]))
+			outputs[i] = nil
+		}
+	}
+	progress.finish()
+	// 被中断时保留已读完的分片，作为部分结果
+	var commits []CommitStats
+	done := 0
+	for i, err := range errs {
+		if err == nil {
+			commits = append(commits, parsed[i]...)
+			done++
+		} else if !errors.Is(err, ErrInterrupted) {
+			return nil, err
+		}
+	}
+	if done == 0 {
+		return nil, errs[0]
+	}
+	if done < len(shards) {
+		a.fail("git log", fmt.Errorf("%w，只读取了 %d/%d 个分片", ErrInterrupted, done, len(shards)))
+	}
+	return commits, nil
+}
+
+// 不分片地执行 git log 并解析
+func (a *Analyzer) readShard(q LogQuery) ([]CommitStats, error) {
+	out, err := a.Git.Run(LogArgs(q))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		return nil, err
+	}
+	return a.parseOutput(string(data)), nil
+}
+
+// progress 在终端上显示分片读取的进度，w 为 nil 时不显示
+type progress struct {
+	mu       sync.Mutex
+	w        io.Writer
+	total    int
+	commits  int
+	finished int
+}
+
+func newProgress(w io.Writer, total, commits int) *progress {
+	return &progress{w: w, total: total, commits: commits}
+}
+
+// 完成一个分片
+func (p *progress) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finished++
+	if p.w != nil {
+		fmt.Fprintf(p.w, "\r读取提交: %d/%d 个分片 (共约 %d 个提交)", p.finished, p.total, p.commits)
+	}
+}
+
+func (p *progress) finish() {
+	if p.w != nil {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// 标准错误输出是终端时返回它，用于显示进度，否则返回 nil
+func TerminalStderr() io.Writer {
+	if !IsTerminal(os.Stderr) {
+		return nil
+	}
+	return os.Stderr
+}
@@ -0,0 +1,306 @@
+package stat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// 报告签名方式
+const (
+	SignHash     = "hash"
+	SignGPG      = "gpg"
+	SignMinisign = "minisign"
+)
+
+const (
+	signatureBegin = "-----BEGIN AISTAT SIGNATURE-----"
+	signatureEnd   = "-----END AISTAT SIGNATURE-----"
+)
+
+// Signature 嵌入报告的内容摘要与可选签名
+type Signature struct {
+	Method string `json:"method"`
+	SHA256 string `json:"sha256"`
+	// gpg 的 ASCII armor 签名或 minisign 签名文件内容
+	Signature string `json:"signature,omitempty"`
+}
+
+// JSON 报告签名后的外层结构，report 字段保留原始字节以便校验
+type signedJSON struct {
+	Report    json.RawMessage `json:"report"`
+	Signature *Signature      `json:"signature"`
+}
+
+// 校验签名方式是否受支持
+func ValidSignMethod(method string) bool {
+	switch method {
+	case "", SignHash, SignGPG, SignMinisign:
+		return true
+	}
+	return false
+}
+
+// 计算报告内容的摘要，并按需调用 gpg/minisign 签名
+// key 为 gpg 的用户 ID 或 minisign 私钥路径，可为空
+func Sign(content []byte, method, key string) (*Signature, error) {
+	sum := sha256.Sum256(content)
+	sig := &Signature{Method: method, SHA256: hex.EncodeToString(sum[:])}
+
+	switch method {
+	case SignHash:
+		return sig, nil
+	case SignGPG:
+		args := []string{"--batch", "--yes", "--armor", "--detach-sign"}
+		if key != "" {
+			args = append(args, "--local-user", key)
+		}
+		cmd := exec.Command("gpg", args...)
+		cmd.Stdin = bytes.NewReader(content)
+		var out, stderr bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("错误：gpg 签名失败: %v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		sig.Signature = out.String()
+		return sig, nil
+	case SignMinisign:
+		if key == "" {
+			return nil, errors.New("错误：minisign 签名需要通过 --sign-key 指定私钥文件")
+		}
+		signature, err := withTempFiles(content, func(dataFile, sigFile string) ([]byte, error) {
+			cmd := exec.Command("minisign", "-S", "-s", key, "-m", dataFile, "-x", sigFile)
+			cmd.Stdin = os.Stdin
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				return nil, fmt.Errorf("错误：minisign 签名失败: %v", err)
+			}
+			return os.ReadFile(sigFile)
+		})
+		if err != nil {
+			return nil, err
+		}
+		sig.Signature = string(signature)
+		return sig, nil
+	}
+	return nil, fmt.Errorf("错误：不支持的签名方式 '%s'", method)
+}
+
+// 对报告内容签名后连同签名一起写出
+// JSON 报告按紧凑格式计算摘要并包装为 {"report": ..., "signature": ...}，
+// 其他格式在末尾追加注释形式的签名块
+func WriteSigned(w io.Writer, content []byte, format, method, key string) error {
+	if format == FormatJSON {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, content); err != nil {
+			return err
+		}
+		content = compact.Bytes()
+	}
+
+	sig, err := Sign(content, method, key)
+	if err != nil {
+		return err
+	}
+	if format == FormatJSON {
+		return WriteJSON(w, signedJSON{Report: json.RawMessage(content), Signature: sig})
+	}
+
+	var block []string
+	block = append(block, signatureBegin, "Method: "+sig.Method, "SHA256: "+sig.SHA256)
+	if sig.Signature != "" {
+		block = append(block, "")
+		block = append(block, strings.Split(strings.TrimRight(sig.Signature, "\n"), "\n")...)
+	}
+	block = append(block, signatureEnd)
+
+	var buf bytes.Buffer
+	buf.Write(content)
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	switch format {
+	case FormatHTML:
+		buf.WriteString("<!--\n" + strings.Join(block, "\n") + "\n-->\n")
+	case FormatCSV, FormatYAML:
+		for _, line := range block {
+			buf.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+		}
+	default:
+		buf.WriteString(strings.Join(block, "\n") + "\n")
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// 从已签名的报告中拆分出原始内容与签名
+func ExtractSignature(data []byte) ([]byte, *Signature, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var signed signedJSON
+		if err := json.Unmarshal(trimmed, &signed); err == nil && signed.Signature != nil {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, signed.Report); err != nil {
+				return nil, nil, err
+			}
+			return compact.Bytes(), signed.Signature, nil
+		}
+	}
+
+	begin := bytes.LastIndex(data, []byte(signatureBegin))
+	if begin < 0 {
+		return nil, nil, errors.New("错误：报告中没有找到签名信息")
+	}
+	lineStart := bytes.LastIndexByte(data[:begin], '\n') + 1
+	prefix := string(data[lineStart:begin])
+	content := data[:lineStart]
+	content = bytes.TrimSuffix(content, []byte("<!--\n"))
+
+	sig := &Signature{}
+	var sigLines []string
+	inBody := false
+	for _, line := range strings.Split(string(data[begin:]), "\n")[1:] {
+		line = strings.TrimPrefix(line, strings.TrimRight(prefix, " "))
+		line = strings.TrimPrefix(line, " ")
+		if line == signatureEnd {
+			sig.Signature = strings.Join(sigLines, "\n")
+			if sig.Signature != "" {
+				sig.Signature += "\n"
+			}
+			return content, sig, nil
+		}
+		switch {
+		case inBody:
+			sigLines = append(sigLines, line)
+		case line == "":
+			inBody = true
+		case strings.HasPrefix(line, "Method: "):
+			sig.Method = strings.TrimPrefix(line, "Method: ")
+		case strings.HasPrefix(line, "SHA256: "):
+			sig.SHA256 = strings.TrimPrefix(line, "SHA256: ")
+		}
+	}
+	return nil, nil, errors.New("错误：签名块不完整")
+}
+
+// VerifyOptions 校验报告时对签名的要求。报告中记录的签名方式可以被随意改写，
+// 因此只有摘要(hash)的报告需要显式接受，gpg 签名需要指定签名者
+type VerifyOptions struct {
+	// 要求的签名方式：gpg、minisign 或 hash；为空时接受 gpg 和 minisign，不接受只有摘要的报告
+	Require string
+	// 期望的 gpg 签名者：密钥指纹或长 key ID
+	Signer string
+	// minisign 公钥文件路径
+	PubKey string
+}
+
+// 校验签名方式是否可以作为 --require 的取值
+func ValidRequiredSign(method string) bool {
+	return method == "" || method == SignHash || method == SignGPG || method == SignMinisign
+}
+
+// 校验内容摘要和签名
+func VerifySignature(content []byte, sig *Signature, opts VerifyOptions) error {
+	if opts.Require != "" && sig.Method != opts.Require {
+		return fmt.Errorf("错误：报告的签名方式为 '%s'，要求为 '%s'", sig.Method, opts.Require)
+	}
+	if sig.Method == SignHash && opts.Require != SignHash {
+		return errors.New("错误：报告只有内容摘要，没有签名，无法确认未被篡改(确认只需校验摘要时使用 --require hash)")
+	}
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != sig.SHA256 {
+		return errors.New("错误：报告内容摘要不匹配，报告可能已被修改")
+	}
+
+	switch sig.Method {
+	case SignHash:
+		return nil
+	case SignGPG:
+		if opts.Signer == "" {
+			return errors.New("错误：校验 gpg 签名需要通过 --signer 指定签名者的密钥指纹")
+		}
+		_, err := withTempFiles(content, func(dataFile, sigFile string) ([]byte, error) {
+			if err := os.WriteFile(sigFile, []byte(sig.Signature), 0o600); err != nil {
+				return nil, err
+			}
+			var status, stderr bytes.Buffer
+			cmd := exec.Command("gpg", "--batch", "--status-fd", "1", "--verify", sigFile, dataFile)
+			cmd.Stdout = &status
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err != nil {
+				return nil, fmt.Errorf("错误：gpg 签名校验失败: %s", strings.TrimSpace(stderr.String()))
+			}
+			if !gpgSignedBy(status.String(), opts.Signer) {
+				return nil, fmt.Errorf("错误：报告不是由 %s 签名的", opts.Signer)
+			}
+			return nil, nil
+		})
+		return err
+	case SignMinisign:
+		if opts.PubKey == "" {
+			return errors.New("错误：校验 minisign 签名需要通过 --pubkey 指定公钥文件")
+		}
+		_, err := withTempFiles(content, func(dataFile, sigFile string) ([]byte, error) {
+			if err := os.WriteFile(sigFile, []byte(sig.Signature), 0o600); err != nil {
+				return nil, err
+			}
+			out, err := exec.Command("minisign", "-V", "-p", opts.PubKey, "-m", dataFile, "-x", sigFile).CombinedOutput()
+			if err != nil {
+				return nil, fmt.Errorf("错误：minisign 签名校验失败: %s", strings.TrimSpace(string(out)))
+			}
+			return nil, nil
+		})
+		return err
+	}
+	return fmt.Errorf("错误：不支持的签名方式 '%s'", sig.Method)
+}
+
+// gpg --status-fd 输出的 VALIDSIG 行中签名密钥或其主密钥的指纹以 signer 结尾。
+// signer 可以是指纹或长 key ID，忽略空格、大小写及 0x 前缀
+func gpgSignedBy(status, signer string) bool {
+	signer = strings.ToUpper(strings.ReplaceAll(strings.TrimPrefix(strings.TrimPrefix(signer, "0x"), "0X"), " ", ""))
+	// 短 key ID 容易伪造，至少要求 16 位的长 key ID
+	if len(signer) < 16 {
+		return false
+	}
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "[GNUPG:]" || fields[1] != "VALIDSIG" {
+			continue
+		}
+		fingerprints := []string{fields[2]}
+		if len(fields) > 11 {
+			fingerprints = append(fingerprints, fields[11])
+		}
+		for _, fpr := range fingerprints {
+			if strings.HasSuffix(strings.ToUpper(fpr), signer) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 将内容写入临时文件，供外部签名工具使用
+func withTempFiles(content []byte, fn func(dataFile, sigFile string) ([]byte, error)) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "aistat-sign")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	dataFile := filepath.Join(dir, "report")
+	if err := os.WriteFile(dataFile, content, 0o600); err != nil {
+		return nil, err
+	}
+	return fn(dataFile, filepath.Join(dir, "report.sig"))
+}
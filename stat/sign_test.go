@@ -0,0 +1,124 @@
+package stat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+var signFormats = map[string]string{
+	FormatJSON: "{\n  \"since\": \"2024-05-01\",\n  \"authors\": []\n}\n",
+	FormatText: "统计结果汇总:\n  开始时间: 2024-05-01\n",
+	FormatCSV:  "since,until\n2024-05-01,2024-05-15\n",
+	FormatYAML: "since: \"2024-05-01\"\nauthors: []\n",
+	FormatHTML: "<html><body><h1>AI 贡献统计</h1></body></html>\n",
+}
+
+// 签名后拆分出的内容与签名，校验通过
+func signRoundTrip(t *testing.T, format, method, key string, opts VerifyOptions) ([]byte, *Signature) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteSigned(&buf, []byte(signFormats[format]), format, method, key); err != nil {
+		t.Fatal(err)
+	}
+	content, sig, err := ExtractSignature(buf.Bytes())
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", format, err, buf.Bytes())
+	}
+	if err := VerifySignature(content, sig, opts); err != nil {
+		t.Fatalf("%s: %v", format, err)
+	}
+	return content, sig
+}
+
+// 修改内容并重新计算摘要
+func rehash(content []byte, sig *Signature, edit string) ([]byte, *Signature) {
+	content = append(append([]byte{}, content...), edit...)
+	sum := sha256.Sum256(content)
+	forged := *sig
+	forged.SHA256 = hex.EncodeToString(sum[:])
+	return content, &forged
+}
+
+func TestSignHashRoundTrip(t *testing.T) {
+	for format := range signFormats {
+		content, sig := signRoundTrip(t, format, SignHash, "", VerifyOptions{Require: SignHash})
+		if format != FormatJSON && string(content) != signFormats[format] {
+			t.Errorf("%s: 拆分出的内容 = %q", format, content)
+		}
+		// 只有摘要的报告默认不通过
+		if err := VerifySignature(content, sig, VerifyOptions{}); err == nil {
+			t.Errorf("%s: 未指定 --require hash 时只有摘要的报告通过了校验", format)
+		}
+		if err := VerifySignature(append(content, " "...), sig, VerifyOptions{Require: SignHash}); err == nil {
+			t.Errorf("%s: 修改后的内容通过了校验", format)
+		}
+	}
+}
+
+// 把 gpg 签名的报告改为只有摘要并重新计算摘要，不能通过校验
+func TestVerifyRejectsDowngradedMethod(t *testing.T) {
+	content, sig := []byte(signFormats[FormatText]), &Signature{Method: SignGPG, Signature: "-----BEGIN PGP SIGNATURE-----\n"}
+	content, forged := rehash(content, sig, "篡改\n")
+	forged.Method, forged.Signature = SignHash, ""
+	if err := VerifySignature(content, forged, VerifyOptions{}); err == nil {
+		t.Error("降级为 hash 的报告通过了校验")
+	}
+	if err := VerifySignature(content, forged, VerifyOptions{Require: SignGPG, Signer: "0123456789ABCDEF"}); err == nil {
+		t.Error("要求 gpg 签名时降级为 hash 的报告通过了校验")
+	}
+}
+
+// 在临时的 GNUPGHOME 中生成签名密钥，返回指纹
+func gpgKey(t *testing.T, uid string) string {
+	t.Helper()
+	if out, err := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", uid, "ed25519", "sign", "never").CombinedOutput(); err != nil {
+		t.Skipf("无法生成 gpg 密钥: %v\n%s", err, out)
+	}
+	out, err := exec.Command("gpg", "--batch", "--with-colons", "--fingerprint", uid).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" && len(fields) > 9 {
+			return fields[9]
+		}
+	}
+	t.Fatalf("没有找到 %s 的指纹", uid)
+	return ""
+}
+
+func TestSignGPGRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("没有安装 gpg")
+	}
+	t.Setenv("GNUPGHOME", t.TempDir())
+	t.Cleanup(func() { exec.Command("gpgconf", "--kill", "gpg-agent").Run() })
+	signer := gpgKey(t, "Signer <signer@example.com>")
+	other := gpgKey(t, "Other <other@example.com>")
+
+	for format := range signFormats {
+		content, sig := signRoundTrip(t, format, SignGPG, signer, VerifyOptions{Require: SignGPG, Signer: signer})
+		// 长 key ID 也可以
+		if err := VerifySignature(content, sig, VerifyOptions{Signer: signer[len(signer)-16:]}); err != nil {
+			t.Errorf("%s: %v", format, err)
+		}
+		// 本地密钥环中的其他密钥签名不能通过
+		if err := VerifySignature(content, sig, VerifyOptions{Signer: other}); err == nil {
+			t.Errorf("%s: 签名者不符时通过了校验", format)
+		}
+		if err := VerifySignature(content, sig, VerifyOptions{}); err == nil {
+			t.Errorf("%s: 未指定签名者时通过了校验", format)
+		}
+		if err := VerifySignature(content, sig, VerifyOptions{Require: SignMinisign}); err == nil {
+			t.Errorf("%s: 要求 minisign 时 gpg 签名通过了校验", format)
+		}
+		tampered, forged := rehash(content, sig, " ")
+		if err := VerifySignature(tampered, forged, VerifyOptions{Signer: signer}); err == nil {
+			t.Errorf("%s: 修改内容并重新计算摘要后通过了校验", format)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package stat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// 收到 SIGINT 或 SIGTERM 时取消的 context：正在执行的 git 命令随之中止，
+// 已完成的部分照常输出并标记为部分结果。再次收到信号时立即退出
+func SignalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Fprintf(os.Stderr, "\n收到信号 %s，正在停止并输出已完成的部分(再次按 Ctrl+C 立即退出)\n", sig)
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+		if _, ok := <-signals; ok {
+			os.Exit(130)
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
@@ -0,0 +1,229 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 输出目标名称，格式类的目标与输出格式同名
+const (
+	SinkTerminal = "terminal"
+	SinkWebhook  = "webhook"
+	SinkDB       = "db"
+)
+
+// Sink 报告的输出目标，分析完成后每个目标各写一次
+type Sink interface {
+	Write(report *Report) error
+}
+
+// SinkContext 创建输出目标所需的参数
+type SinkContext struct {
+	Options *RunOptions
+	// 未指定输出文件和输出目录时写到该 Writer
+	Stdout io.Writer
+	// 自动命名的报告文件名中区分报告的标签，如开发者
+	Label string
+	// --sink 中等号之后的目标：文件路径、URL 或存储位置，可以为空
+	Target string
+}
+
+// SinkFactory 按参数创建输出目标
+type SinkFactory func(ctx SinkContext) (Sink, error)
+
+// Renderer 把报告渲染为一种输出格式
+type Renderer func(w io.Writer, report *Report, o *RunOptions) error
+
+// 已注册的输出目标
+var sinkFactories = map[string]SinkFactory{
+	SinkTerminal: formatSinkFactory(FormatText),
+	FormatText:   formatSinkFactory(FormatText),
+	FormatJSON:   formatSinkFactory(FormatJSON),
+	FormatCSV:    formatSinkFactory(FormatCSV),
+	FormatHTML:   formatSinkFactory(FormatHTML),
+	FormatPDF:    formatSinkFactory(FormatPDF),
+	FormatYAML:   formatSinkFactory(FormatYAML),
+	SinkWebhook:  newWebhookSink,
+	SinkDB:       newDBSink,
+}
+
+// 各输出格式的渲染方式，文本报告因命令而异，由各命令注册
+var renderers = map[string]Renderer{
+	FormatJSON: func(w io.Writer, report *Report, o *RunOptions) error { return WriteJSON(w, report) },
+	FormatCSV:  func(w io.Writer, report *Report, o *RunOptions) error { return WriteCSV(w, report) },
+	FormatHTML: func(w io.Writer, report *Report, o *RunOptions) error { return WriteHTML(w, report) },
+	FormatPDF:  func(w io.Writer, report *Report, o *RunOptions) error { return WritePDF(w, report, o.PDFTool) },
+	FormatYAML: func(w io.Writer, report *Report, o *RunOptions) error { return WriteYAML(w, report) },
+}
+
+// 注册输出目标，同名时覆盖
+func RegisterSink(name string, factory SinkFactory) {
+	sinkFactories[name] = factory
+}
+
+// 注册或替换一种输出格式的渲染方式
+func RegisterRenderer(format string, render Renderer) {
+	renderers[format] = render
+}
+
+// 已注册的输出目标名称
+func SinkNames() []string {
+	names := make([]string, 0, len(sinkFactories))
+	for name := range sinkFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 解析 --sink 的值“名称=目标”，目标可以省略
+func ParseSinkSpec(spec string) (name, target string, err error) {
+	name, target, _ = strings.Cut(strings.TrimSpace(spec), "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, ok := sinkFactories[name]; !ok {
+		return "", "", fmt.Errorf("错误：不支持的输出目标 '%s'，可选: %s", name, strings.Join(SinkNames(), ", "))
+	}
+	return name, strings.TrimSpace(target), nil
+}
+
+// 按输出格式和 --sink 创建所有输出目标
+func (o *RunOptions) NewSinks(stdout io.Writer, label string) ([]Sink, error) {
+	ctx := SinkContext{Options: o, Stdout: stdout, Label: label}
+	var sinks []Sink
+	for _, format := range o.Formats {
+		sink, err := sinkFactories[format](ctx)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	for _, spec := range o.Sinks {
+		name, target, err := ParseSinkSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		ctx.Target = target
+		sink, err := sinkFactories[name](ctx)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// 把报告写到所有输出目标，单个目标失败时继续写其余目标，返回第一个错误
+func (o *RunOptions) WriteSinks(stdout io.Writer, label string, report *Report) error {
+	sinks, err := o.NewSinks(stdout, label)
+	if err != nil {
+		return err
+	}
+	var first error
+	for _, sink := range sinks {
+		if err := sink.Write(report); err != nil {
+			if first != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			first = err
+		}
+	}
+	return first
+}
+
+// formatSink 按输出格式写到标准输出、输出目录或指定文件
+type formatSink struct {
+	ctx    SinkContext
+	format string
+}
+
+func formatSinkFactory(format string) SinkFactory {
+	return func(ctx SinkContext) (Sink, error) {
+		return &formatSink{ctx: ctx, format: format}, nil
+	}
+}
+
+func (s *formatSink) Write(report *Report) error {
+	render, ok := renderers[s.format]
+	if !ok {
+		return fmt.Errorf("错误：当前命令不支持输出格式 '%s'", s.format)
+	}
+	o := s.ctx.Options
+	write := func(w io.Writer, format string) error { return render(w, report, o) }
+	if s.ctx.Target == "" && o.OutDir == "" {
+		return o.render(s.ctx.Stdout, s.format, write)
+	}
+
+	var path string
+	var err error
+	if s.ctx.Target == "" {
+		path, err = o.writeFile(s.ctx.Stdout, s.ctx.Label, s.format, write)
+	} else {
+		path, err = o.writeTarget(s.ctx.Target, s.format, write)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "报告已写入: %s\n", path)
+	return nil
+}
+
+// 将单个格式的报告写入指定文件
+func (o *RunOptions) writeTarget(path, format string, render func(w io.Writer, format string) error) (string, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("错误：创建报告文件 '%s' 失败: %v", path, err)
+	}
+	defer f.Close()
+	if err := o.render(f, format, render); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("错误：写入报告文件 '%s' 失败: %v", path, err)
+	}
+	return path, nil
+}
+
+// webhookSink 以 JSON POST 请求把完整报告发送到指定地址
+type webhookSink struct {
+	notifier *Notifier
+}
+
+func newWebhookSink(ctx SinkContext) (Sink, error) {
+	if !strings.HasPrefix(ctx.Target, "http://") && !strings.HasPrefix(ctx.Target, "https://") {
+		return nil, fmt.Errorf("错误：输出目标 webhook 需要指定 http(s) 地址，如 webhook=https://example.com/aistat")
+	}
+	return &webhookSink{notifier: &Notifier{URLs: []string{ctx.Target}}}, nil
+}
+
+func (s *webhookSink) Write(report *Report) error {
+	if errs := s.notifier.Notify(report); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// dbSink 把报告作为一次运行记录保存到指定的存储，与 --store 保存的历史记录相互独立
+type dbSink struct {
+	location string
+}
+
+func newDBSink(ctx SinkContext) (Sink, error) {
+	if ctx.Target == "" {
+		return nil, fmt.Errorf("错误：输出目标 db 需要指定存储位置，如 db=sqlite:aistat.db 或 db=postgres://...")
+	}
+	return &dbSink{location: ctx.Target}, nil
+}
+
+func (s *dbSink) Write(report *Report) error {
+	store, err := OpenStore(s.location)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	_, err = store.SaveRun(report)
+	return err
+}
@@ -0,0 +1,154 @@
+package stat
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 文件不计入统计的原因，另有 .gitattributes 的 linguist-generated、linguist-vendored 及符号链接、权限变更
+const (
+	// 扩展名在排除列表中，如 .pb.go
+	SkipExcludedExt = "excluded_ext"
+	// 扩展名不在统计列表中
+	SkipUnlistedExt = "unlisted_ext"
+	// 二进制文件，numstat 中没有行数
+	SkipBinary = "binary"
+	// AI 清单文件本身
+	SkipAIManifest = "ai_manifest"
+)
+
+// 统计文件类型为该值时统计全部非二进制文件
+const AllExts = "*"
+
+// 各原因在报告中的名称及列出顺序
+var skipReasons = []struct{ reason, label string }{
+	{SkipExcludedExt, "排除的文件类型"},
+	{SkipUnlistedExt, "不在统计范围的文件类型"},
+	{SkipBinary, "二进制文件"},
+	{SkipAIManifest, "AI 清单"},
+	{AttrGenerated, "生成代码 (.gitattributes)"},
+	{AttrVendored, "第三方代码 (.gitattributes)"},
+	{FileSymlink, "符号链接"},
+	{FileModeOnly, "只修改文件权限"},
+}
+
+// 每个原因列出的示例路径数
+const skipExamples = 3
+
+// 原因的名称
+func SkipReasonLabel(reason string) string {
+	for _, r := range skipReasons {
+		if r.reason == reason {
+			return r.label
+		}
+	}
+	return "不符合统计条件"
+}
+
+// 按文件名判断是否计入统计，不计入时返回原因。排除列表按后缀匹配，以便排除 .pb.go 这类多级扩展名
+func extSkipReason(fileName string, includeExts, excludeExts []string) string {
+	for _, excludeExt := range excludeExts {
+		if strings.HasSuffix(fileName, excludeExt) {
+			return SkipExcludedExt
+		}
+	}
+	ext := filepath.Ext(fileName)
+	for _, includeExt := range includeExts {
+		if includeExt == AllExts || ext == includeExt {
+			return ""
+		}
+	}
+	return SkipUnlistedExt
+}
+
+// SkippedFiles 一种原因下不计入统计的文件
+type SkippedFiles struct {
+	Reason string `json:"reason" yaml:"reason"`
+	// 不同的文件路径数、文件变更次数及行数
+	Files   int        `json:"files" yaml:"files"`
+	Changes int        `json:"changes" yaml:"changes"`
+	Lines   LineCounts `json:"lines" yaml:"lines"`
+	// 按路径排序的前几个文件
+	Examples []string `json:"examples,omitempty" yaml:"examples,omitempty"`
+}
+
+// 按原因汇总提交中跳过的文件
+func summarizeSkipped(commits []CommitStats) []SkippedFiles {
+	byReason := make(map[string]*SkippedFiles)
+	paths := make(map[string]map[string]bool)
+	for _, c := range commits {
+		for _, f := range c.Files {
+			if !f.Skipped {
+				continue
+			}
+			s, ok := byReason[f.SkipReason]
+			if !ok {
+				s = &SkippedFiles{Reason: f.SkipReason}
+				byReason[f.SkipReason] = s
+				paths[f.SkipReason] = make(map[string]bool)
+			}
+			s.Changes++
+			s.Lines.Added += f.Added
+			s.Lines.Deleted += f.Deleted
+			paths[f.SkipReason][f.Path] = true
+		}
+	}
+	var out []SkippedFiles
+	add := func(reason string) {
+		s, ok := byReason[reason]
+		if !ok {
+			return
+		}
+		delete(byReason, reason)
+		for path := range paths[reason] {
+			s.Examples = append(s.Examples, path)
+		}
+		sort.Strings(s.Examples)
+		s.Files = len(s.Examples)
+		if len(s.Examples) > skipExamples {
+			s.Examples = s.Examples[:skipExamples]
+		}
+		out = append(out, *s)
+	}
+	for _, r := range skipReasons {
+		add(r.reason)
+	}
+	// 旧版本保存的提交没有记录原因
+	add("")
+	return out
+}
+
+// 运行信息中按原因列出跳过的文件
+func skippedLines(skipped []SkippedFiles) []string {
+	if len(skipped) == 0 {
+		return nil
+	}
+	files := 0
+	for _, s := range skipped {
+		files += s.Files
+	}
+	lines := []string{fmt.Sprintf("跳过的文件: %d 个，不计入统计", files)}
+	for _, s := range skipped {
+		line := fmt.Sprintf("  %s: %d 个文件，%d 处变更 (%s 行)", SkipReasonLabel(s.Reason), s.Files, s.Changes, s.Lines)
+		if len(s.Examples) > 0 {
+			line += "，如 " + strings.Join(s.Examples, "、")
+			if s.Files > len(s.Examples) {
+				line += " 等"
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// 运行信息中的统计文件类型
+func includeExtsLabel(exts []string) string {
+	for _, ext := range exts {
+		if ext == AllExts {
+			return "全部非二进制文件 (*)"
+		}
+	}
+	return strings.Join(exts, ",")
+}
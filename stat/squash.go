@@ -0,0 +1,36 @@
+package stat
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// squash 合并提交消息中的 AIG 汇总标记
+const SquashTrailer = "AIG-Squash"
+
+// 按变更行数加权合并多个提交的 AIG 比例，返回合并后的比例和总行数
+// 所有提交都没有统计行数时取算术平均
+func CombineAIG(commits []CommitStats) (float64, int) {
+	if len(commits) == 0 {
+		return 0, 0
+	}
+
+	var weighted, sum float64
+	lines := 0
+	for i := range commits {
+		n := commits[i].AddedLines + commits[i].DeletedLines
+		weighted += commits[i].AIGRatio * float64(n)
+		sum += commits[i].AIGRatio
+		lines += n
+	}
+	if lines == 0 {
+		return sum / float64(len(commits)), 0
+	}
+	return weighted / float64(lines), lines
+}
+
+// 生成 squash 合并提交使用的汇总标记，如 AIG-Squash: 0.42 (commits=5, lines=120)
+func SquashTrailerLine(commits []CommitStats) string {
+	ratio, lines := CombineAIG(commits)
+	return fmt.Sprintf("%s: %s (commits=%d, lines=%d)", SquashTrailer, strconv.FormatFloat(ratio, 'f', 2, 64), len(commits), lines)
+}
@@ -0,0 +1,505 @@
+package stat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StoreOff 表示不保存运行结果
+const StoreOff = "off"
+
+// Store 运行记录的存储后端，serve、滚动平均、历史对比等功能通过它读写运行记录
+type Store interface {
+	// 保存一次运行的报告及其中的提交，返回记录的位置
+	SaveRun(report *Report) (string, error)
+	// 仓库的全部运行记录，按生成时间排序
+	LoadRuns(repo string) ([]*Report, error)
+	// 保存单独统计的提交，如 webhook 增量分析的提交
+	SaveCommits(repo string, commits []CommitStats) error
+	// 提交时间在 [from, to) 之间的提交，同一提交只保留一次
+	Commits(repo, from, to string) ([]CommitStats, error)
+	// 按开发者汇总提交时间在 [from, to) 之间的提交，repo 为空时汇总全部仓库
+	QueryAuthors(repo, from, to, identity string) ([]*AuthorStats, error)
+	// 已保存运行记录的仓库名称
+	Repos() ([]string, error)
+	// 保存统计周期的冻结记录，同一周期再次冻结时覆盖
+	Freeze(period *FrozenPeriod) error
+	// 仓库已冻结的统计周期
+	FrozenPeriods(repo string) ([]*FrozenPeriod, error)
+	// 删除截止日期(YYYY-MM-DD)之前的运行记录、提交和冻结记录，dryRun 时只统计不删除
+	Purge(before string, dryRun bool) (*PurgeResult, error)
+	// 删除或匿名化一名开发者在运行记录、提交和冻结记录中的数据，dryRun 时只统计不修改
+	Forget(f *Forgetter, dryRun bool) (*ForgetResult, error)
+	// 存储位置的显示名称，不含密码
+	String() string
+	Close() error
+}
+
+// 按位置打开存储后端：
+// memory 或 memory:名称 为进程内存储，sqlite:路径 为 SQLite 数据库，
+// postgres:// 开头为 PostgreSQL 数据库，其他为保存 JSON 文件的目录
+func OpenStore(location string) (Store, error) {
+	switch {
+	case location == "memory" || strings.HasPrefix(location, "memory:"):
+		return memoryStoreNamed(strings.TrimPrefix(strings.TrimPrefix(location, "memory"), ":")), nil
+	case strings.HasPrefix(location, "sqlite:"):
+		return openSQLStore("sqlite3", strings.TrimPrefix(strings.TrimPrefix(location, "sqlite:"), "//"))
+	case strings.HasPrefix(location, "postgres://") || strings.HasPrefix(location, "postgresql://"):
+		return openSQLStore("postgres", location)
+	}
+	return &RunStore{Dir: location}, nil
+}
+
+// RunStore 将每次运行的报告按仓库保存为 JSON 文件，供趋势、历史对比等功能使用
+// 目录结构: <Dir>/<仓库名>/<生成时间>_<统计周期>.json，单独保存的提交在 <Dir>/<仓库名>/commits/ 下
+type RunStore struct {
+	Dir string
+}
+
+// 默认保存目录 ~/.aistat/runs
+func DefaultStoreDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".aistat", "runs")
+	}
+	return filepath.Join(home, ".aistat", "runs")
+}
+
+// 仓库对应的目录名
+func storeRepoKey(report *Report) string {
+	if report.Meta == nil || report.Meta.Repo == "" {
+		return "unknown"
+	}
+	return SafeFileName(report.Meta.Repo)
+}
+
+// 保存一次运行的报告，返回文件路径
+func (s *RunStore) SaveRun(report *Report) (string, error) {
+	dir := filepath.Join(s.Dir, storeRepoKey(report))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("错误：创建运行记录目录 '%s' 失败: %v", dir, err)
+	}
+
+	period := []string{report.Since, report.Until}
+	if report.RevRange != "" {
+		period = []string{report.RevRange}
+	}
+	name := ReportFileName(time.Now().Format("20060102T150405.000000"), FormatJSON, period...)
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("错误：保存运行记录失败: %v", err)
+	}
+	defer f.Close()
+	if err := WriteJSON(f, report); err != nil {
+		return "", fmt.Errorf("错误：保存运行记录失败: %v", err)
+	}
+	return path, f.Close()
+}
+
+// 读取仓库的全部运行记录，按文件名(生成时间)排序
+func (s *RunStore) LoadRuns(repo string) ([]*Report, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, SafeFileName(repo), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var reports []*Report
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("错误：读取运行记录 '%s' 失败: %v", path, err)
+		}
+		report := &Report{}
+		if err := json.Unmarshal(data, report); err != nil {
+			return nil, fmt.Errorf("错误：解析运行记录 '%s' 失败: %v", path, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// 保存单独统计的提交，文件名为保存时间
+func (s *RunStore) SaveCommits(repo string, commits []CommitStats) error {
+	dir := filepath.Join(s.Dir, SafeFileName(repo), "commits")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("错误：创建运行记录目录 '%s' 失败: %v", dir, err)
+	}
+	data, err := json.Marshal(commits)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, time.Now().Format("20060102T150405.000000")+".json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("错误：保存提交失败: %v", err)
+	}
+	return nil
+}
+
+// 从运行记录及单独保存的提交中取出提交时间在 [from, to) 之间的提交，同一提交只保留一次
+func (s *RunStore) Commits(repo, from, to string) ([]CommitStats, error) {
+	reports, err := s.LoadRuns(repo)
+	if err != nil {
+		return nil, err
+	}
+	var commits []CommitStats
+	for _, report := range reports {
+		commits = append(commits, report.Commits...)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(s.Dir, SafeFileName(repo), "commits", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("错误：读取提交记录 '%s' 失败: %v", path, err)
+		}
+		var saved []CommitStats
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("错误：解析提交记录 '%s' 失败: %v", path, err)
+		}
+		commits = append(commits, saved...)
+	}
+	return commitsBetween(commits, from, to), nil
+}
+
+// 按开发者汇总提交
+func (s *RunStore) QueryAuthors(repo, from, to, identity string) ([]*AuthorStats, error) {
+	return queryAuthors(s, repo, from, to, identity)
+}
+
+// 已保存运行记录的仓库名称
+func (s *RunStore) Repos() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("错误：读取运行记录目录 '%s' 失败: %v", s.Dir, err)
+	}
+	var repos []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			repos = append(repos, entry.Name())
+		}
+	}
+	return repos, nil
+}
+
+// 冻结记录保存在 <Dir>/<仓库名>/frozen/<统计周期>.json
+func (s *RunStore) Freeze(period *FrozenPeriod) error {
+	dir := filepath.Join(s.Dir, SafeFileName(firstNonEmpty(period.Repo, "unknown")), "frozen")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("错误：创建冻结记录目录 '%s' 失败: %v", dir, err)
+	}
+	data, err := json.MarshalIndent(period, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, SafeFileName(period.Key())+".json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("错误：保存冻结记录失败: %v", err)
+	}
+	return nil
+}
+
+// 读取仓库的全部冻结记录
+func (s *RunStore) FrozenPeriods(repo string) ([]*FrozenPeriod, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, SafeFileName(repo), "frozen", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var periods []*FrozenPeriod
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("错误：读取冻结记录 '%s' 失败: %v", path, err)
+		}
+		period := &FrozenPeriod{}
+		if err := json.Unmarshal(data, period); err != nil {
+			return nil, fmt.Errorf("错误：解析冻结记录 '%s' 失败: %v", path, err)
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// 删除统计周期在截止日期之前结束的运行记录文件及冻结记录文件，
+// 单独保存的提交文件去掉过期的提交后重写，全部过期时删除
+func (s *RunStore) Purge(before string, dryRun bool) (*PurgeResult, error) {
+	result := &PurgeResult{Before: before}
+	repos, err := s.Repos()
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		dir := filepath.Join(s.Dir, repo)
+		paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			report := &Report{}
+			if err := readStoreFile(path, report); err != nil {
+				return nil, err
+			}
+			if expired(runDay(report), before) {
+				result.Runs++
+				if err := removeStoreFile(path, dryRun); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		paths, err = filepath.Glob(filepath.Join(dir, "commits", "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			var commits []CommitStats
+			if err := readStoreFile(path, &commits); err != nil {
+				return nil, err
+			}
+			kept := keepCommits(commits, before)
+			if len(kept) == len(commits) {
+				continue
+			}
+			result.Commits += len(commits) - len(kept)
+			if len(kept) == 0 {
+				err = removeStoreFile(path, dryRun)
+			} else if !dryRun {
+				err = writeStoreFile(path, kept)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		paths, err = filepath.Glob(filepath.Join(dir, "frozen", "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			period := &FrozenPeriod{}
+			if err := readStoreFile(path, period); err != nil {
+				return nil, err
+			}
+			if expired(frozenDay(period), before) {
+				result.Frozen++
+				if err := removeStoreFile(path, dryRun); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// 逐个改写包含该开发者数据的运行记录、提交文件和冻结记录文件
+func (s *RunStore) Forget(f *Forgetter, dryRun bool) (*ForgetResult, error) {
+	result := f.result()
+	repos, err := s.Repos()
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		dir := filepath.Join(s.Dir, repo)
+		paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			report := &Report{}
+			if err := readStoreFile(path, report); err != nil {
+				return nil, err
+			}
+			changed, err := f.Report(report)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+			result.Runs++
+			if !dryRun {
+				if err := writeStoreFile(path, report); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		paths, err = filepath.Glob(filepath.Join(dir, "commits", "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			var commits []CommitStats
+			if err := readStoreFile(path, &commits); err != nil {
+				return nil, err
+			}
+			kept, n := f.Commits(commits)
+			if n == 0 {
+				continue
+			}
+			result.Commits += n
+			if len(kept) == 0 {
+				err = removeStoreFile(path, dryRun)
+			} else if !dryRun {
+				err = writeStoreFile(path, kept)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		paths, err = filepath.Glob(filepath.Join(dir, "frozen", "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			period := &FrozenPeriod{}
+			if err := readStoreFile(path, period); err != nil {
+				return nil, err
+			}
+			if !f.Frozen(period) {
+				continue
+			}
+			result.Frozen++
+			if !dryRun {
+				if err := writeStoreFile(path, period); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// 读取并解析存储目录中的 JSON 文件
+func readStoreFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("错误：读取运行记录 '%s' 失败: %v", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("错误：解析运行记录 '%s' 失败: %v", path, err)
+	}
+	return nil
+}
+
+// 先写入临时文件再替换，避免中途失败留下不完整的文件
+func writeStoreFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("错误：写入运行记录 '%s' 失败: %v", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("错误：写入运行记录 '%s' 失败: %v", path, err)
+	}
+	return nil
+}
+
+func removeStoreFile(path string, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("错误：删除运行记录 '%s' 失败: %v", path, err)
+	}
+	return nil
+}
+
+func (s *RunStore) String() string {
+	return s.Dir
+}
+
+func (s *RunStore) Close() error {
+	return nil
+}
+
+// 取出提交时间在 [from, to) 之间的提交，同一提交只保留第一次出现
+func commitsBetween(commits []CommitStats, from, to string) []CommitStats {
+	seen := make(map[string]bool)
+	var result []CommitStats
+	for _, c := range commits {
+		day := commitDay(c.Time)
+		if day < from || day >= to || seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		result = append(result, c)
+	}
+	return result
+}
+
+// 读取一个或全部仓库在 [from, to) 之间的提交并按开发者汇总，各存储后端共用
+func queryAuthors(s Store, repo, from, to, identity string) ([]*AuthorStats, error) {
+	repos := []string{repo}
+	if repo == "" {
+		var err error
+		if repos, err = s.Repos(); err != nil {
+			return nil, err
+		}
+	}
+	var commits []CommitStats
+	for _, name := range repos {
+		repoCommits, err := s.Commits(name, from, to)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, repoCommits...)
+	}
+	var authors []*AuthorStats
+	for _, a := range AggregateByIdentity(commits, identity) {
+		authors = append(authors, a)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].Email != authors[j].Email {
+			return authors[i].Email < authors[j].Email
+		}
+		return authors[i].Name < authors[j].Name
+	})
+	return authors, nil
+}
+
+// 统计周期的显示名称：提交范围或起止日期
+func (r *Report) PeriodLabel() string {
+	if r.RevRange != "" {
+		return r.RevRange
+	}
+	return r.Since + " ~ " + r.Until
+}
+
+// 同一统计周期多次运行时只保留最新的一次，runs 按生成时间排序，结果按开始日期排序
+func LatestByPeriod(runs []*Report) []*Report {
+	index := make(map[string]int)
+	var latest []*Report
+	for _, report := range runs {
+		label := report.PeriodLabel()
+		if i, ok := index[label]; ok {
+			latest[i] = report
+			continue
+		}
+		index[label] = len(latest)
+		latest = append(latest, report)
+	}
+	sort.SliceStable(latest, func(i, j int) bool {
+		return latest[i].Since < latest[j].Since
+	})
+	return latest
+}
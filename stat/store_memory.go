@@ -0,0 +1,239 @@
+package stat
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// 同名的进程内存储共用同一份数据，同一进程中多次打开 memory 存储可以读到之前保存的记录
+var (
+	memoryStoresMu sync.Mutex
+	memoryStores   = make(map[string]*MemoryStore)
+)
+
+// MemoryStore 进程内的运行记录存储，进程退出后数据丢失，适合临时的 serve 实例和演示
+type MemoryStore struct {
+	name    string
+	mu      sync.Mutex
+	runs    map[string][]*Report
+	commits map[string][]CommitStats
+	frozen  map[string]map[string]FrozenPeriod
+}
+
+func memoryStoreNamed(name string) *MemoryStore {
+	memoryStoresMu.Lock()
+	defer memoryStoresMu.Unlock()
+	if s, ok := memoryStores[name]; ok {
+		return s
+	}
+	s := &MemoryStore{name: name, runs: make(map[string][]*Report), commits: make(map[string][]CommitStats), frozen: make(map[string]map[string]FrozenPeriod)}
+	memoryStores[name] = s
+	return s
+}
+
+// 保存报告的副本，返回记录编号
+func (s *MemoryStore) SaveRun(report *Report) (string, error) {
+	saved, err := copyReport(report)
+	if err != nil {
+		return "", err
+	}
+	repo := storeRepoKey(report)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[repo] = append(s.runs[repo], saved)
+	return fmt.Sprintf("%s#%d", repo, len(s.runs[repo])), nil
+}
+
+// 仓库的全部运行记录的副本
+func (s *MemoryStore) LoadRuns(repo string) ([]*Report, error) {
+	s.mu.Lock()
+	runs := append([]*Report{}, s.runs[SafeFileName(repo)]...)
+	s.mu.Unlock()
+	reports := make([]*Report, 0, len(runs))
+	for _, r := range runs {
+		report, err := copyReport(r)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (s *MemoryStore) SaveCommits(repo string, commits []CommitStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := SafeFileName(repo)
+	s.commits[key] = append(s.commits[key], commits...)
+	return nil
+}
+
+func (s *MemoryStore) Commits(repo, from, to string) ([]CommitStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := SafeFileName(repo)
+	var commits []CommitStats
+	for _, r := range s.runs[key] {
+		commits = append(commits, r.Commits...)
+	}
+	return commitsBetween(append(commits, s.commits[key]...), from, to), nil
+}
+
+func (s *MemoryStore) QueryAuthors(repo, from, to, identity string) ([]*AuthorStats, error) {
+	return queryAuthors(s, repo, from, to, identity)
+}
+
+func (s *MemoryStore) Repos() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var repos []string
+	for repo := range s.runs {
+		repos = append(repos, repo)
+	}
+	for repo := range s.commits {
+		if _, ok := s.runs[repo]; !ok {
+			repos = append(repos, repo)
+		}
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// 保存冻结记录的副本
+func (s *MemoryStore) Freeze(period *FrozenPeriod) error {
+	saved := *period
+	saved.Commits = append([]FrozenCommit(nil), period.Commits...)
+	saved.Authors = append([]FrozenAuthor(nil), period.Authors...)
+	repo := SafeFileName(firstNonEmpty(period.Repo, "unknown"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.frozen[repo] == nil {
+		s.frozen[repo] = make(map[string]FrozenPeriod)
+	}
+	s.frozen[repo][period.Key()] = saved
+	return nil
+}
+
+// 仓库的冻结记录，按统计周期排序
+func (s *MemoryStore) FrozenPeriods(repo string) ([]*FrozenPeriod, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var periods []*FrozenPeriod
+	for _, p := range s.frozen[SafeFileName(repo)] {
+		p := p
+		periods = append(periods, &p)
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Key() < periods[j].Key() })
+	return periods, nil
+}
+
+func (s *MemoryStore) Purge(before string, dryRun bool) (*PurgeResult, error) {
+	result := &PurgeResult{Before: before}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for repo, runs := range s.runs {
+		var kept []*Report
+		for _, r := range runs {
+			if expired(runDay(r), before) {
+				result.Runs++
+			} else {
+				kept = append(kept, r)
+			}
+		}
+		if !dryRun && len(kept) == 0 {
+			delete(s.runs, repo)
+		} else if !dryRun {
+			s.runs[repo] = kept
+		}
+	}
+	for repo, commits := range s.commits {
+		kept := keepCommits(commits, before)
+		result.Commits += len(commits) - len(kept)
+		if !dryRun && len(kept) == 0 {
+			delete(s.commits, repo)
+		} else if !dryRun {
+			s.commits[repo] = kept
+		}
+	}
+	for _, periods := range s.frozen {
+		for key, p := range periods {
+			p := p
+			if expired(frozenDay(&p), before) {
+				result.Frozen++
+				if !dryRun {
+					delete(periods, key)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) Forget(f *Forgetter, dryRun bool) (*ForgetResult, error) {
+	result := f.result()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, runs := range s.runs {
+		for i, r := range runs {
+			report, err := copyReport(r)
+			if err != nil {
+				return nil, err
+			}
+			changed, err := f.Report(report)
+			if err != nil {
+				return nil, err
+			}
+			if changed {
+				result.Runs++
+				if !dryRun {
+					runs[i] = report
+				}
+			}
+		}
+	}
+	for repo, commits := range s.commits {
+		kept, n := f.Commits(commits)
+		result.Commits += n
+		if !dryRun && n > 0 {
+			s.commits[repo] = kept
+		}
+	}
+	for _, periods := range s.frozen {
+		for key, p := range periods {
+			p := p
+			if f.Frozen(&p) {
+				result.Frozen++
+				if !dryRun {
+					periods[key] = p
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) String() string {
+	if s.name == "" {
+		return "memory"
+	}
+	return "memory:" + s.name
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// 通过 JSON 复制报告，避免调用方修改已保存的记录
+func copyReport(report *Report) (*Report, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	saved := &Report{}
+	if err := json.Unmarshal(data, saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
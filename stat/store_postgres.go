@@ -0,0 +1,4 @@
+package stat
+
+// 注册 PostgreSQL 驱动，用于 postgres:// 形式的运行记录位置
+import _ "github.com/lib/pq"
@@ -0,0 +1,516 @@
+package stat
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 运行记录表和提交表，SQLite 和 PostgreSQL 共用
+var sqlStoreSchema = []string{
+	`CREATE TABLE IF NOT EXISTS aistat_runs (
+		id TEXT PRIMARY KEY,
+		repo TEXT NOT NULL,
+		created TEXT NOT NULL,
+		period_since TEXT NOT NULL,
+		period_until TEXT NOT NULL,
+		rev_range TEXT NOT NULL,
+		report TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS aistat_runs_repo ON aistat_runs (repo, created)`,
+	`CREATE TABLE IF NOT EXISTS aistat_commits (
+		repo TEXT NOT NULL,
+		id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		author TEXT NOT NULL,
+		email TEXT NOT NULL,
+		commit_data TEXT NOT NULL,
+		PRIMARY KEY (repo, id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS aistat_commits_day ON aistat_commits (repo, day)`,
+	`CREATE TABLE IF NOT EXISTS aistat_frozen (
+		repo TEXT NOT NULL,
+		period TEXT NOT NULL,
+		frozen_data TEXT NOT NULL,
+		PRIMARY KEY (repo, period)
+	)`,
+}
+
+// SQLStore 把运行记录和提交保存在 SQLite 或 PostgreSQL 数据库中
+// 报告整体以 JSON 保存，提交按仓库和提交 ID 去重单独保存，按日期查询不需要读取全部报告
+type SQLStore struct {
+	driver   string
+	location string
+	db       *sql.DB
+}
+
+func openSQLStore(driver, dsn string) (*SQLStore, error) {
+	if !driverRegistered(driver) {
+		return nil, fmt.Errorf("错误：当前程序没有编译 %s 支持 (SQLite 需要启用 cgo 编译)", driver)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("错误：打开运行记录数据库失败: %v", err)
+	}
+	s := &SQLStore{driver: driver, location: redactDSN(driver, dsn), db: db}
+	for _, stmt := range sqlStoreSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("错误：初始化运行记录数据库 '%s' 失败: %v", s.location, err)
+		}
+	}
+	return s, nil
+}
+
+// 保存报告并更新其中的提交，返回记录 ID
+func (s *SQLStore) SaveRun(report *Report) (string, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	repo := storeRepoKey(report)
+	created := time.Now().Format("20060102T150405.000000")
+	id := repo + "/" + created
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("错误：保存运行记录失败: %v", err)
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(s.rebind(`INSERT INTO aistat_runs (id, repo, created, period_since, period_until, rev_range, report)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`), id, repo, created, report.Since, report.Until, report.RevRange, string(data))
+	if err != nil {
+		return "", fmt.Errorf("错误：保存运行记录失败: %v", err)
+	}
+	if err := s.saveCommits(tx, repo, report.Commits); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("错误：保存运行记录失败: %v", err)
+	}
+	return id, nil
+}
+
+// 仓库的全部运行记录，按生成时间排序
+func (s *SQLStore) LoadRuns(repo string) ([]*Report, error) {
+	rows, err := s.db.Query(s.rebind(`SELECT id, report FROM aistat_runs WHERE repo = $1 ORDER BY created`), SafeFileName(repo))
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取运行记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	var reports []*Report
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("错误：读取运行记录失败: %v", err)
+		}
+		report := &Report{}
+		if err := json.Unmarshal([]byte(data), report); err != nil {
+			return nil, fmt.Errorf("错误：解析运行记录 '%s' 失败: %v", id, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+func (s *SQLStore) SaveCommits(repo string, commits []CommitStats) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("错误：保存提交失败: %v", err)
+	}
+	defer tx.Rollback()
+	if err := s.saveCommits(tx, SafeFileName(repo), commits); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 写入提交，已有的提交以新的统计结果为准
+func (s *SQLStore) saveCommits(tx *sql.Tx, repo string, commits []CommitStats) error {
+	stmt, err := tx.Prepare(s.rebind(`INSERT INTO aistat_commits (repo, id, day, author, email, commit_data)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (repo, id) DO UPDATE SET day = excluded.day, author = excluded.author,
+			email = excluded.email, commit_data = excluded.commit_data`))
+	if err != nil {
+		return fmt.Errorf("错误：保存提交失败: %v", err)
+	}
+	defer stmt.Close()
+	for _, c := range commits {
+		data, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(repo, c.ID, commitDay(c.Time), c.Author, c.Email, string(data)); err != nil {
+			return fmt.Errorf("错误：保存提交 %s 失败: %v", c.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) Commits(repo, from, to string) ([]CommitStats, error) {
+	rows, err := s.db.Query(s.rebind(`SELECT commit_data FROM aistat_commits
+		WHERE repo = $1 AND day >= $2 AND day < $3`), SafeFileName(repo), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取提交失败: %v", err)
+	}
+	defer rows.Close()
+
+	var commits []CommitStats
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("错误：读取提交失败: %v", err)
+		}
+		var c CommitStats
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return nil, fmt.Errorf("错误：解析提交记录失败: %v", err)
+		}
+		commits = append(commits, c)
+	}
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Time > commits[j].Time
+	})
+	return commits, rows.Err()
+}
+
+func (s *SQLStore) QueryAuthors(repo, from, to, identity string) ([]*AuthorStats, error) {
+	return queryAuthors(s, repo, from, to, identity)
+}
+
+// 有运行记录或提交的仓库
+func (s *SQLStore) Repos() ([]string, error) {
+	rows, err := s.db.Query(`SELECT repo FROM aistat_runs UNION SELECT repo FROM aistat_commits ORDER BY repo`)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取仓库列表失败: %v", err)
+	}
+	defer rows.Close()
+	var repos []string
+	for rows.Next() {
+		var repo string
+		if err := rows.Scan(&repo); err != nil {
+			return nil, fmt.Errorf("错误：读取仓库列表失败: %v", err)
+		}
+		repos = append(repos, repo)
+	}
+	return repos, rows.Err()
+}
+
+// 保存冻结记录，同一周期覆盖
+func (s *SQLStore) Freeze(period *FrozenPeriod) error {
+	data, err := json.Marshal(period)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(s.rebind(`INSERT INTO aistat_frozen (repo, period, frozen_data) VALUES ($1, $2, $3)
+		ON CONFLICT (repo, period) DO UPDATE SET frozen_data = excluded.frozen_data`),
+		SafeFileName(firstNonEmpty(period.Repo, "unknown")), period.Key(), string(data))
+	if err != nil {
+		return fmt.Errorf("错误：保存冻结记录失败: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) FrozenPeriods(repo string) ([]*FrozenPeriod, error) {
+	rows, err := s.db.Query(s.rebind(`SELECT period, frozen_data FROM aistat_frozen WHERE repo = $1 ORDER BY period`), SafeFileName(repo))
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取冻结记录失败: %v", err)
+	}
+	defer rows.Close()
+	var periods []*FrozenPeriod
+	for rows.Next() {
+		var key, data string
+		if err := rows.Scan(&key, &data); err != nil {
+			return nil, fmt.Errorf("错误：读取冻结记录失败: %v", err)
+		}
+		period := &FrozenPeriod{}
+		if err := json.Unmarshal([]byte(data), period); err != nil {
+			return nil, fmt.Errorf("错误：解析冻结记录 '%s' 失败: %v", key, err)
+		}
+		periods = append(periods, period)
+	}
+	return periods, rows.Err()
+}
+
+// 在一个事务中删除过期的运行记录、提交和冻结记录，dryRun 时统计后回滚
+func (s *SQLStore) Purge(before string, dryRun bool) (*PurgeResult, error) {
+	result := &PurgeResult{Before: before}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("错误：清理运行记录失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	// 按提交范围统计的运行记录没有结束日期，按生成时间(20060102T150405 形式)判断
+	var runs []string
+	rows, err := tx.Query(`SELECT id, period_until, created FROM aistat_runs`)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取运行记录失败: %v", err)
+	}
+	for rows.Next() {
+		var id, until, created string
+		if err := rows.Scan(&id, &until, &created); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("错误：读取运行记录失败: %v", err)
+		}
+		day := PeriodDay(until)
+		if day == "" && len(created) >= 8 {
+			day = created[:4] + "-" + created[4:6] + "-" + created[6:8]
+		}
+		if expired(day, before) {
+			runs = append(runs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("错误：读取运行记录失败: %v", err)
+	}
+	for _, id := range runs {
+		if _, err := tx.Exec(s.rebind(`DELETE FROM aistat_runs WHERE id = $1`), id); err != nil {
+			return nil, fmt.Errorf("错误：删除运行记录 '%s' 失败: %v", id, err)
+		}
+	}
+	result.Runs = len(runs)
+
+	res, err := tx.Exec(s.rebind(`DELETE FROM aistat_commits WHERE day < $1`), before)
+	if err != nil {
+		return nil, fmt.Errorf("错误：删除提交失败: %v", err)
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.Commits = int(n)
+	}
+
+	type frozenKey struct{ repo, period string }
+	var frozen []frozenKey
+	rows, err = tx.Query(`SELECT repo, period, frozen_data FROM aistat_frozen`)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取冻结记录失败: %v", err)
+	}
+	for rows.Next() {
+		var key frozenKey
+		var data string
+		if err := rows.Scan(&key.repo, &key.period, &data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("错误：读取冻结记录失败: %v", err)
+		}
+		period := &FrozenPeriod{}
+		if err := json.Unmarshal([]byte(data), period); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("错误：解析冻结记录 '%s' 失败: %v", key.period, err)
+		}
+		if expired(frozenDay(period), before) {
+			frozen = append(frozen, key)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("错误：读取冻结记录失败: %v", err)
+	}
+	for _, key := range frozen {
+		if _, err := tx.Exec(s.rebind(`DELETE FROM aistat_frozen WHERE repo = $1 AND period = $2`), key.repo, key.period); err != nil {
+			return nil, fmt.Errorf("错误：删除冻结记录 '%s' 失败: %v", key.period, err)
+		}
+	}
+	result.Frozen = len(frozen)
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("错误：清理运行记录失败: %v", err)
+	}
+	return result, nil
+}
+
+// 在一个事务中改写包含该开发者数据的运行记录、提交和冻结记录，dryRun 时统计后回滚
+func (s *SQLStore) Forget(f *Forgetter, dryRun bool) (*ForgetResult, error) {
+	result := f.result()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("错误：改写运行记录失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	reports := make(map[string]*Report)
+	rows, err := tx.Query(`SELECT id, report FROM aistat_runs`)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取运行记录失败: %v", err)
+	}
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("错误：读取运行记录失败: %v", err)
+		}
+		report := &Report{}
+		if err := json.Unmarshal([]byte(data), report); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("错误：解析运行记录 '%s' 失败: %v", id, err)
+		}
+		changed, err := f.Report(report)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if changed {
+			reports[id] = report
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("错误：读取运行记录失败: %v", err)
+	}
+	for id, report := range reports {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(s.rebind(`UPDATE aistat_runs SET report = $1 WHERE id = $2`), string(data), id); err != nil {
+			return nil, fmt.Errorf("错误：改写运行记录 '%s' 失败: %v", id, err)
+		}
+	}
+	result.Runs = len(reports)
+
+	// 提交表中的提交按邮箱查找，匿名化时改写姓名、邮箱和提交内容
+	var commits []CommitStats
+	var repos []string
+	rows, err = tx.Query(s.rebind(`SELECT repo, commit_data FROM aistat_commits WHERE LOWER(email) = $1`), f.Email)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取提交失败: %v", err)
+	}
+	for rows.Next() {
+		var repo, data string
+		if err := rows.Scan(&repo, &data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("错误：读取提交失败: %v", err)
+		}
+		var c CommitStats
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("错误：解析提交记录失败: %v", err)
+		}
+		commits = append(commits, c)
+		repos = append(repos, repo)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("错误：读取提交失败: %v", err)
+	}
+	if f.Delete {
+		if _, err := tx.Exec(s.rebind(`DELETE FROM aistat_commits WHERE LOWER(email) = $1`), f.Email); err != nil {
+			return nil, fmt.Errorf("错误：删除提交失败: %v", err)
+		}
+	} else {
+		anonymized, _ := f.Commits(commits)
+		for i, c := range anonymized {
+			data, err := json.Marshal(c)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := tx.Exec(s.rebind(`UPDATE aistat_commits SET author = $1, email = $2, commit_data = $3 WHERE repo = $4 AND id = $5`),
+				c.Author, c.Email, string(data), repos[i], c.ID); err != nil {
+				return nil, fmt.Errorf("错误：改写提交 %s 失败: %v", c.ID, err)
+			}
+		}
+	}
+	result.Commits = len(commits)
+
+	type frozenKey struct{ repo, period string }
+	frozen := make(map[frozenKey]*FrozenPeriod)
+	rows, err = tx.Query(`SELECT repo, period, frozen_data FROM aistat_frozen`)
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取冻结记录失败: %v", err)
+	}
+	for rows.Next() {
+		var key frozenKey
+		var data string
+		if err := rows.Scan(&key.repo, &key.period, &data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("错误：读取冻结记录失败: %v", err)
+		}
+		period := &FrozenPeriod{}
+		if err := json.Unmarshal([]byte(data), period); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("错误：解析冻结记录 '%s' 失败: %v", key.period, err)
+		}
+		if f.Frozen(period) {
+			frozen[key] = period
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("错误：读取冻结记录失败: %v", err)
+	}
+	for key, period := range frozen {
+		data, err := json.Marshal(period)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(s.rebind(`UPDATE aistat_frozen SET frozen_data = $1 WHERE repo = $2 AND period = $3`), string(data), key.repo, key.period); err != nil {
+			return nil, fmt.Errorf("错误：改写冻结记录 '%s' 失败: %v", key.period, err)
+		}
+	}
+	result.Frozen = len(frozen)
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("错误：改写运行记录失败: %v", err)
+	}
+	return result, nil
+}
+
+func (s *SQLStore) String() string {
+	return s.location
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// 查询统一使用 $1 形式的参数，SQLite 改为等价的 ?1 形式
+func (s *SQLStore) rebind(query string) string {
+	if s.driver == "sqlite3" {
+		return strings.ReplaceAll(query, "$", "?")
+	}
+	return query
+}
+
+// 提交时间中的日期部分
+func commitDay(t string) string {
+	if len(t) > len(DateLayout) {
+		return t[:len(DateLayout)]
+	}
+	return t
+}
+
+func driverRegistered(name string) bool {
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 显示用的数据库位置，隐去连接串中的密码
+func redactDSN(driver, dsn string) string {
+	if driver == "sqlite3" {
+		return "sqlite:" + dsn
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return driver
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+	}
+	return u.String()
+}
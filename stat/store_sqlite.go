@@ -0,0 +1,6 @@
+//go:build cgo
+
+package stat
+
+// 注册 SQLite 驱动(需要 cgo)，用于 sqlite: 形式的运行记录位置
+import _ "github.com/mattn/go-sqlite3"
@@ -0,0 +1,196 @@
+package stat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// unified diff 的块头，取修改前的起始行和行数
+	hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+`)
+	// git blame --porcelain 中每行的提交信息行
+	blameLineRegex = regexp.MustCompile(`^([0-9a-f]{40}) \d+ \d+`)
+)
+
+// BugIntroduction SZZ 分析的汇总：本期提交的代码之后被修复提交修改的次数，按 AIG 比例拆分为 AI 与人工代码
+type BugIntroduction struct {
+	// 扫描的修复提交数(本期开始至今)
+	FixCommits int `json:"fix_commits" yaml:"fix_commits"`
+	// 代码被修复提交修改过的本期提交数
+	BuggyCommits int `json:"buggy_commits" yaml:"buggy_commits"`
+	// 按 AIG 比例拆分的引入缺陷数及对应的添加行数，不含策略标记的提交
+	AIBugs     float64 `json:"ai_bugs" yaml:"ai_bugs"`
+	HumanBugs  float64 `json:"human_bugs" yaml:"human_bugs"`
+	AILines    int     `json:"ai_lines" yaml:"ai_lines"`
+	HumanLines int     `json:"human_lines" yaml:"human_lines"`
+}
+
+// AI 代码每千行引入的缺陷数
+func (b *BugIntroduction) AIPerKLoC() float64 {
+	return perKLoC(b.AIBugs, b.AILines)
+}
+
+// 人工代码每千行引入的缺陷数
+func (b *BugIntroduction) HumanPerKLoC() float64 {
+	return perKLoC(b.HumanBugs, b.HumanLines)
+}
+
+func perKLoC(bugs float64, lines int) float64 {
+	if lines == 0 {
+		return 0
+	}
+	return bugs / float64(lines) * 1000
+}
+
+// 汇总 attributeBugs 的结果，一个提交被 n 个修复提交修改计为 n 个缺陷，按提交的 AIG 比例拆分
+func SummarizeBugs(commits []CommitStats, fixCommits int) *BugIntroduction {
+	b := &BugIntroduction{FixCommits: fixCommits}
+	for i := range commits {
+		c := &commits[i]
+		if len(c.BugFixes) > 0 {
+			b.BuggyCommits++
+		}
+		if c.AIGPolicy != "" {
+			continue
+		}
+		ai := c.AIAddedLines()
+		b.AILines += ai
+		b.HumanLines += c.AddedLines - ai
+		bugs := float64(len(c.BugFixes))
+		b.AIBugs += bugs * c.AIGRatio
+		b.HumanBugs += bugs * (1 - c.AIGRatio)
+	}
+	return b
+}
+
+// SZZ 分析：扫描本期最早的提交之后的全部修复提交，用 git blame 找出修复提交删除或修改的行最后由哪个提交引入，
+// 引入的提交属于本期时记录到该提交的 BugFixes 中。只分析计入统计的文件，修复提交只新增代码时无法追溯
+func (a *Analyzer) attributeBugs(commits []CommitStats) error {
+	if !a.SZZ || len(commits) == 0 {
+		return nil
+	}
+	if a.noNumstat {
+		a.warnf("缺少统计行数所需的对象，已跳过缺陷引入分析")
+		return nil
+	}
+	index := make(map[string]int, len(commits))
+	earliest := commits[0].Time
+	for i, c := range commits {
+		index[c.ID] = i
+		if c.Time < earliest {
+			earliest = c.Time
+		}
+	}
+
+	out, err := a.Git.Run([]string{"log", "--all", "--no-merges", "--format=%H%x1f%P%x1f%s", "--since=" + earliest})
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(gitText(out), "\n") {
+		fields := strings.SplitN(line, fieldSep, 3)
+		// 根提交没有可以追溯的父提交
+		if len(fields) < 3 || fields[1] == "" || !a.Fixes.IsFix(fields[2]) {
+			continue
+		}
+		fix := fields[0]
+		a.bugFixes++
+		introducers, err := a.blameFix(fix)
+		if err != nil {
+			return err
+		}
+		for _, id := range introducers {
+			if i, ok := index[id]; ok && id != fix {
+				commits[i].BugFixes = append(commits[i].BugFixes, fix)
+			}
+		}
+	}
+	return nil
+}
+
+// 修复提交删除或修改的行最后由哪些提交引入，按提交哈希排序
+func (a *Analyzer) blameFix(fix string) ([]string, error) {
+	out, err := a.Git.Run([]string{"diff", "--unified=0", "--no-color", "--no-ext-diff", "-M", fix + "^", fix})
+	if err != nil {
+		return nil, err
+	}
+	ranges := make(map[string][]string)
+	var paths []string
+	path := ""
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			path = ""
+		case strings.HasPrefix(line, "--- "):
+			path = diffHeaderPath(line)
+			if path == "/dev/null" || !a.countsFile(path) {
+				path = ""
+			}
+		case path != "" && strings.HasPrefix(line, "@@ "):
+			m := hunkHeaderRegex.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			// 只新增代码的块没有可以追溯的行
+			if count == 0 {
+				continue
+			}
+			if _, ok := ranges[path]; !ok {
+				paths = append(paths, path)
+			}
+			ranges[path] = append(ranges[path], "-L", m[1]+",+"+strconv.Itoa(count))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("错误：读取修复提交 %s 的 diff 失败: %v", shortID(fix), err)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		args := append([]string{"blame", "--porcelain", "-w"}, ranges[p]...)
+		out, err := a.Git.Run(append(args, fix+"^", "--", p))
+		if err != nil {
+			return nil, err
+		}
+		if err := collectBlame(out, seen); err != nil {
+			return nil, err
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// 收集 git blame --porcelain 输出中的提交哈希
+func collectBlame(r io.Reader, seen map[string]bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if m := blameLineRegex.FindStringSubmatch(scanner.Text()); m != nil {
+			seen[m[1]] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// 打印缺陷引入分析的汇总
+func PrintBugIntroduction(w io.Writer, b *BugIntroduction, nf NumberFormat) {
+	fmt.Fprintf(w, "\n  缺陷引入 (SZZ，扫描 %s 个修复提交):\n", nf.Int(b.FixCommits))
+	fmt.Fprintf(w, "    被修复提交修改过的本期提交: %s 个\n", nf.Int(b.BuggyCommits))
+	fmt.Fprintf(w, "    AI 代码: %s 个缺陷 / %s，每千行 %s 个\n", nf.Float(b.AIBugs, 1), nf.Lines(b.AILines), nf.Float(b.AIPerKLoC(), 2))
+	fmt.Fprintf(w, "    人工代码: %s 个缺陷 / %s，每千行 %s 个\n", nf.Float(b.HumanBugs, 1), nf.Lines(b.HumanLines), nf.Float(b.HumanPerKLoC(), 2))
+}
@@ -0,0 +1,108 @@
+package stat
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 记录所用 AI 工具的标记，如 AIG-Tool: cursor
+const ToolTrailer = "AIG-Tool"
+
+var (
+	// trailer 段中已有的 AIG 及 AIG-Tool 标记行(键名大小写、分隔符不限)，写入新标记时替换，AIG-Squash 保留
+	aigLineRegex = regexp.MustCompile(`(?i)^\s*ai[-_]?g\s*[:=]`)
+	toolRegex    = regexp.MustCompile(`(?i)^\s*ai[-_]?g[-_]?tool\s*[:=]`)
+)
+
+// git 在提交信息模板中插入的剪刀线，其后(含 diff)都不属于提交信息
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+// 校验并规范 AIG 比例：0~1 的小数、带 % 的百分数(换算为小数)或策略标记 n/a、exempt
+func NormalizeTagRatio(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "":
+		return "", errors.New("错误：请通过 --ratio 指定 AI 生成比例")
+	case "n/a", "na":
+		return "n/a", nil
+	case "exempt":
+		return "exempt", nil
+	}
+	if strings.HasPrefix(value, "-") {
+		return "", fmt.Errorf("错误：比例 '%s' 不能为负数，无法使用 AI 工具时请使用 n/a，豁免时使用 exempt", value)
+	}
+	fixed, ok, note := lintRatio(value)
+	// 不带 % 的 1~100 可能是百分数也可能是笔误，不自动换算
+	if !ok || (note != "" && !strings.HasSuffix(value, "%")) {
+		return "", fmt.Errorf("错误：比例 '%s' 不正确，应为 0~1 的小数或 0%%~100%% 的百分数", value)
+	}
+	return fixed, nil
+}
+
+// 在提交信息末尾的 trailer 段写入 AIG 标记(tool 不为空时同时写入 AIG-Tool)，替换 trailer 段中已有的 AIG、AIG-Tool 标记；
+// 末尾的注释行及剪刀线之后的内容保持不变
+func AddAIGTrailer(message, ratio, tool string) string {
+	lines := strings.Split(strings.TrimSuffix(message, "\n"), "\n")
+	// 提交信息正文与末尾的注释部分(注释行、空行及剪刀线之后的全部内容)
+	end := len(lines)
+	for i, line := range lines {
+		if line == scissorsLine {
+			end = i
+			break
+		}
+	}
+	for end > 0 && (strings.HasPrefix(lines[end-1], "#") || strings.TrimSpace(lines[end-1]) == "") {
+		end--
+	}
+	body := trimBlankTail(append([]string{}, lines[:end]...))
+	// 只替换最后的 trailer 段中的标记：正文中提到的 AIG 及 squash 合并提交中保留的各提交的标记不动
+	if start, ok := trailerBlock(body); ok {
+		kept := append([]string{}, body[:start]...)
+		for _, line := range body[start:] {
+			if !strings.HasPrefix(line, "#") && (aigLineRegex.MatchString(line) || toolRegex.MatchString(line)) {
+				continue
+			}
+			kept = append(kept, line)
+		}
+		body = trimBlankTail(kept)
+	}
+
+	trailers := []string{"AIG: " + ratio}
+	if tool != "" {
+		trailers = append(trailers, ToolTrailer+": "+tool)
+	}
+	// 最后一段已是 trailer(如 Signed-off-by)时接在其后，否则与正文之间空一行；
+	// 没有正文时保留空的标题行
+	switch {
+	case len(body) == 0:
+		body = []string{"", ""}
+	case !hasTrailerBlock(body):
+		body = append(body, "")
+	}
+	body = append(body, trailers...)
+
+	out := strings.Join(body, "\n") + "\n"
+	if rest := lines[end:]; len(rest) > 0 {
+		if strings.TrimSpace(rest[0]) != "" {
+			out += "\n"
+		}
+		out += strings.Join(rest, "\n") + "\n"
+	}
+	return out
+}
+
+// 去掉末尾的空行
+func trimBlankTail(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// 按 git interpret-trailers 的规则判断最后一段是否为 trailer 段
+func hasTrailerBlock(lines []string) bool {
+	_, ok := trailerBlock(lines)
+	return ok
+}
@@ -0,0 +1,39 @@
+package stat
+
+import "testing"
+
+func TestAddAIGTrailer(t *testing.T) {
+	tests := []struct {
+		name, message, want string
+	}{
+		{
+			name:    "替换 trailer 段中的标记",
+			message: "feat: retry\n\nbody\n\naig: 0.2\nAIG-Tool: copilot\nSigned-off-by: A <a@example.com>\n",
+			want:    "feat: retry\n\nbody\n\nSigned-off-by: A <a@example.com>\nAIG: 0.5\nAIG-Tool: cursor\n",
+		},
+		{
+			name:    "正文中提到的 AIG 不动",
+			message: "docs: explain tags\n\nAIG: 0.3 means 30% of the lines came from AI.\nThe parser also accepts AIG=0.3 in the subject.\n",
+			want:    "docs: explain tags\n\nAIG: 0.3 means 30% of the lines came from AI.\nThe parser also accepts AIG=0.3 in the subject.\n\nAIG: 0.5\nAIG-Tool: cursor\n",
+		},
+		{
+			name: "squash 合并提交中各提交的标记保留",
+			message: "feat: checkout\n\n# This is a combination of 2 commits.\nfeat: cart\n\nAIG: 0.3\n\nfix: total\n\nAIG: 0.8\n\n" +
+				"AIG-Squash: 0.42 (commits=2, lines=10)\n",
+			want: "feat: checkout\n\n# This is a combination of 2 commits.\nfeat: cart\n\nAIG: 0.3\n\nfix: total\n\nAIG: 0.8\n\n" +
+				"AIG-Squash: 0.42 (commits=2, lines=10)\nAIG: 0.5\nAIG-Tool: cursor\n",
+		},
+		{
+			name:    "trailer 段只有 AIG 标记",
+			message: "fix: typo\n\nAIG: 1\n\n# Please enter the commit message\n",
+			want:    "fix: typo\n\nAIG: 0.5\nAIG-Tool: cursor\n\n# Please enter the commit message\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AddAIGTrailer(tt.message, "0.5", "cursor"); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
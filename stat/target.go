@@ -0,0 +1,188 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 目标的完成状态
+const (
+	TargetAchieved   = "achieved"
+	TargetInProgress = "in_progress"
+	TargetMissed     = "missed"
+)
+
+// 可以设定目标的指标及显示名称，值均为百分比
+var targetMetrics = map[string]struct {
+	Label string
+	Value func(s *AuthorStats) float64
+}{
+	"ai_added_ratio":      {"AI 添加占比", (*AuthorStats).AddedRatio},
+	"ai_deleted_ratio":    {"AI 删除占比", (*AuthorStats).DeletedRatio},
+	"ai_fix_ratio":        {"AI 修复贡献率", (*AuthorStats).AIFixRatio},
+	"ai_commit_avg_ratio": {"按提交平均 AI 占比", (*AuthorStats).CommitAvgRatio},
+	"tagged_ratio": {"AIG 标记率", func(s *AuthorStats) float64 {
+		return percent(s.AITaggedCommits+s.NoAITaggedCommits, s.RatioCommits())
+	}},
+}
+
+// Target 配置文件中的目标，如“第三季度 AI 添加占比达到 30%”
+type Target struct {
+	Name   string `yaml:"name"`
+	Metric string `yaml:"metric"`
+	// 目标值(百分比)，实际值不低于该值即达成
+	Value float64 `yaml:"value"`
+	// 截止日期，2006-01-02 或 2024-Q3 形式，为空表示没有截止日期
+	Deadline string `yaml:"deadline"`
+	// 名单中 team 为该值的成员，为空时为全体开发者
+	Team string `yaml:"team"`
+}
+
+// TargetStatus 目标在本期报告中的进度
+type TargetStatus struct {
+	Name     string  `json:"name" yaml:"name"`
+	Team     string  `json:"team,omitempty" yaml:"team,omitempty"`
+	Metric   string  `json:"metric" yaml:"metric"`
+	Target   float64 `json:"target" yaml:"target"`
+	Actual   float64 `json:"actual" yaml:"actual"`
+	Deadline string  `json:"deadline,omitempty" yaml:"deadline,omitempty"`
+	// 完成进度(0~100)及距离目标的差值(百分点，已达成时为 0)
+	Progress float64 `json:"progress" yaml:"progress"`
+	Gap      float64 `json:"gap" yaml:"gap"`
+	// achieved、in_progress、missed
+	Status string `json:"status" yaml:"status"`
+	// 计入的有提交的开发者人数
+	Members int `json:"members" yaml:"members"`
+}
+
+// 校验配置中的目标，team 需要在名单中有成员
+func ValidateTargets(targets []Target, roster []RosterMember) error {
+	teams := make(map[string]bool)
+	for _, m := range roster {
+		teams[m.Team] = true
+	}
+	for _, t := range targets {
+		if _, ok := targetMetrics[t.Metric]; !ok {
+			return fmt.Errorf("错误：目标 '%s' 的指标 '%s' 不支持，可选: %s", t.Name, t.Metric, strings.Join(TargetMetrics(), ", "))
+		}
+		if t.Value <= 0 || t.Value > 100 {
+			return fmt.Errorf("错误：目标 '%s' 的目标值应在 0~100 之间", t.Name)
+		}
+		if t.Deadline != "" {
+			if _, err := parseDeadline(t.Deadline); err != nil {
+				return fmt.Errorf("错误：目标 '%s' 的截止日期 '%s' 格式不正确，请使用 2006-01-02 或 2024-Q3 格式", t.Name, t.Deadline)
+			}
+		}
+		if t.Team != "" && !teams[t.Team] {
+			return fmt.Errorf("错误：目标 '%s' 的团队 '%s' 在名单中没有成员", t.Name, t.Team)
+		}
+	}
+	return nil
+}
+
+// 支持设定目标的指标，按名称排序
+func TargetMetrics() []string {
+	return []string{"ai_added_ratio", "ai_commit_avg_ratio", "ai_deleted_ratio", "ai_fix_ratio", "tagged_ratio"}
+}
+
+// 解析截止日期，季度形式取季度最后一天
+func parseDeadline(s string) (time.Time, error) {
+	if year, quarter, ok := strings.Cut(strings.ToUpper(s), "-Q"); ok {
+		y, err := strconv.Atoi(year)
+		if err != nil {
+			return time.Time{}, err
+		}
+		q, err := strconv.Atoi(quarter)
+		if err != nil || q < 1 || q > 4 {
+			return time.Time{}, fmt.Errorf("季度 '%s' 无效", quarter)
+		}
+		return time.Date(y, time.Month(q*3+1), 0, 0, 0, 0, 0, time.Local), nil
+	}
+	return time.ParseInLocation(DateLayout, s, time.Local)
+}
+
+// 按本期各开发者及汇总行计算目标进度，asOf 为统计周期的结束日期，用于判断是否已过截止日期
+func EvaluateTargets(targets []Target, rows []*AuthorStats, roster []RosterMember, asOf time.Time) []TargetStatus {
+	var statuses []TargetStatus
+	for _, t := range targets {
+		members := make(map[string]bool)
+		for _, m := range roster {
+			if m.Team == t.Team {
+				members[strings.ToLower(m.Email)] = true
+			}
+		}
+		total := &AuthorStats{}
+		for _, s := range rows {
+			if s.CommitCount == 0 || (t.Team != "" && (s.MemberCount > 0 || !members[strings.ToLower(s.Email)])) {
+				continue
+			}
+			total.Merge(s)
+		}
+
+		status := TargetStatus{
+			Name:     t.Name,
+			Team:     t.Team,
+			Metric:   t.Metric,
+			Target:   t.Value,
+			Actual:   targetMetrics[t.Metric].Value(total),
+			Deadline: t.Deadline,
+			Members:  total.MemberCount,
+			Status:   TargetInProgress,
+		}
+		status.Progress = status.Actual / status.Target * 100
+		if status.Actual >= status.Target {
+			status.Progress, status.Status = 100, TargetAchieved
+		} else {
+			status.Gap = status.Target - status.Actual
+			if deadline, err := parseDeadline(t.Deadline); err == nil && asOf.After(deadline) {
+				status.Status = TargetMissed
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// 目标状态的显示名称
+func TargetStatusLabel(status string) string {
+	switch status {
+	case TargetAchieved:
+		return "已达成"
+	case TargetMissed:
+		return "未达成(已过截止日期)"
+	}
+	return "进行中"
+}
+
+// 指标的显示名称
+func TargetMetricLabel(metric string) string {
+	if m, ok := targetMetrics[metric]; ok {
+		return m.Label
+	}
+	return metric
+}
+
+// 以进度条形式打印目标进度
+func PrintTargets(w io.Writer, statuses []TargetStatus) {
+	fmt.Fprintf(w, "\n  目标进度:\n")
+	for _, s := range statuses {
+		scope := "全体"
+		if s.Team != "" {
+			scope = "团队 " + s.Team
+		}
+		fmt.Fprintf(w, "    %s (%s，%s ≥ %.2f%%", s.Name, scope, TargetMetricLabel(s.Metric), s.Target)
+		if s.Deadline != "" {
+			fmt.Fprintf(w, "，截止 %s", s.Deadline)
+		}
+		fmt.Fprintf(w, ")\n")
+		filled := int(s.Progress / 5)
+		fmt.Fprintf(w, "      [%s%s] %.2f%% / %.2f%%", strings.Repeat("█", filled), strings.Repeat("░", 20-filled), s.Actual, s.Target)
+		if s.Gap > 0 {
+			fmt.Fprintf(w, "，差 %.2f 个百分点", s.Gap)
+		}
+		fmt.Fprintf(w, "，%s\n", TargetStatusLabel(s.Status))
+	}
+}
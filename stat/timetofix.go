@@ -0,0 +1,198 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// 提交信息中引用的提交哈希，如 "Fixes: 3f9c2a1d"、"This reverts commit ..."
+	commitRefRegex = regexp.MustCompile(`\b[0-9a-f]{7,40}\b`)
+	// 提交信息中引用的 issue 编号，如 "fix #123"、"(#45)"，不含 group/app#12 形式的跨仓库引用
+	issueRefRegex = regexp.MustCompile(`(?:^|[\s(\[,:])#(\d+)\b`)
+)
+
+// 修复耗时的起点
+const (
+	FixRefCommit = "commit"
+	FixRefIssue  = "issue"
+)
+
+// FixDuration 一个修复提交从缺陷引入(被引用的提交)或 issue 创建到修复的耗时
+type FixDuration struct {
+	Commit string `json:"commit" yaml:"commit"`
+	// 起点类型 commit 或 issue 及被引用的提交哈希或 issue 编号
+	RefKind    string  `json:"ref_kind" yaml:"ref_kind"`
+	Ref        string  `json:"ref" yaml:"ref"`
+	Hours      float64 `json:"hours" yaml:"hours"`
+	AIAssisted bool    `json:"ai_assisted" yaml:"ai_assisted"`
+}
+
+// TimeToFixGroup 一组修复提交的耗时汇总
+type TimeToFixGroup struct {
+	Fixes       int     `json:"fixes" yaml:"fixes"`
+	MedianHours float64 `json:"median_hours" yaml:"median_hours"`
+}
+
+// TimeToFix 本期修复提交的修复耗时，按是否有 AI 参与分组比较
+type TimeToFix struct {
+	// 本期修复提交数(不含策略标记的提交)及其中能确定起点的提交数
+	Fixes    int            `json:"fixes" yaml:"fixes"`
+	Measured int            `json:"measured" yaml:"measured"`
+	AI       TimeToFixGroup `json:"ai" yaml:"ai"`
+	Human    TimeToFixGroup `json:"human" yaml:"human"`
+	// 引用了 issue 但无法读取 issue 创建时间的原因
+	IssueError string        `json:"issue_error,omitempty" yaml:"issue_error,omitempty"`
+	Durations  []FixDuration `json:"durations" yaml:"durations"`
+}
+
+// 统计本期修复提交的修复耗时：提交信息引用了更早的提交时以该提交的作者时间为起点，
+// 否则引用了 issue 时以 issue 的创建时间为起点(从 GitHub/GitLab 读取)，都没有时不计入
+func MeasureTimeToFix(git GitRunner, review *ReviewConfig, commits []CommitStats) *TimeToFix {
+	t := &TimeToFix{}
+	var source *reviewSource
+	var aiHours, humanHours []float64
+	for i := range commits {
+		c := &commits[i]
+		if !c.IsFix || c.AIGPolicy != "" {
+			continue
+		}
+		t.Fixes++
+		fixed, ok := authorTime(git, c.ID)
+		if !ok {
+			continue
+		}
+		d, ok := commitFixDuration(git, c, fixed)
+		if !ok {
+			issues := issueRefs(c.Message)
+			if len(issues) == 0 {
+				continue
+			}
+			if source == nil && t.IssueError == "" {
+				var err error
+				if source, err = resolveReviewSource(git, review); err != nil {
+					t.IssueError = strings.TrimPrefix(err.Error(), "错误：")
+				}
+			}
+			if source == nil {
+				continue
+			}
+			if d, ok = issueFixDuration(source, t, issues, fixed); !ok {
+				continue
+			}
+		}
+		d.Commit = c.ID
+		d.AIAssisted = c.AIGRatio > 0
+		t.Measured++
+		if d.AIAssisted {
+			t.AI.Fixes++
+			aiHours = append(aiHours, d.Hours)
+		} else {
+			t.Human.Fixes++
+			humanHours = append(humanHours, d.Hours)
+		}
+		t.Durations = append(t.Durations, d)
+	}
+	t.AI.MedianHours = median(aiHours)
+	t.Human.MedianHours = median(humanHours)
+	return t
+}
+
+// 提交的作者时间
+func authorTime(git GitRunner, rev string) (time.Time, bool) {
+	sec, err := strconv.ParseInt(gitOutput(git, "show", "-s", "--format=%at", rev+"^{commit}"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// 以引用的提交中最早的一个为起点，引用自身或不早于修复提交的引用不计入
+func commitFixDuration(git GitRunner, c *CommitStats, fixed time.Time) (FixDuration, bool) {
+	var best FixDuration
+	found := false
+	for _, ref := range commitRefRegex.FindAllString(c.Message, -1) {
+		if strings.HasPrefix(c.ID, ref) {
+			continue
+		}
+		introduced, ok := authorTime(git, ref)
+		if !ok || !introduced.Before(fixed) {
+			continue
+		}
+		hours := fixed.Sub(introduced).Hours()
+		if !found || hours > best.Hours {
+			best = FixDuration{RefKind: FixRefCommit, Ref: ref, Hours: hours}
+			found = true
+		}
+	}
+	return best, found
+}
+
+// 以引用的 issue 中最早创建的一个为起点
+func issueFixDuration(source *reviewSource, t *TimeToFix, issues []int, fixed time.Time) (FixDuration, bool) {
+	var best FixDuration
+	found := false
+	for _, number := range issues {
+		opened, err := source.issueOpened(number)
+		if err != nil {
+			if t.IssueError == "" {
+				t.IssueError = strings.TrimPrefix(err.Error(), "错误：")
+			}
+			continue
+		}
+		if !opened.Before(fixed) {
+			continue
+		}
+		hours := fixed.Sub(opened).Hours()
+		if !found || hours > best.Hours {
+			best = FixDuration{RefKind: FixRefIssue, Ref: "#" + strconv.Itoa(number), Hours: hours}
+			found = true
+		}
+	}
+	return best, found
+}
+
+// 提交信息中引用的 issue 编号，去除重复
+func issueRefs(message string) []int {
+	var numbers []int
+	seen := make(map[int]bool)
+	for _, m := range issueRefRegex.FindAllStringSubmatch(message, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n == 0 || seen[n] {
+			continue
+		}
+		seen[n] = true
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// 格式化耗时，不足两天时以小时显示，否则以天显示
+func FormatHours(hours float64, nf NumberFormat) string {
+	if hours < 48 {
+		return nf.Float(hours, 1) + " 小时"
+	}
+	return nf.Float(hours/24, 1) + " 天"
+}
+
+// 打印修复耗时的汇总
+func PrintTimeToFix(w io.Writer, t *TimeToFix, nf NumberFormat) {
+	fmt.Fprintf(w, "\n  修复耗时 (%s 个修复提交中 %s 个引用了更早的提交或 issue):\n", nf.Int(t.Fixes), nf.Int(t.Measured))
+	for _, g := range []struct {
+		label string
+		group TimeToFixGroup
+	}{{"AI 参与修复", t.AI}, {"人工修复", t.Human}} {
+		if g.group.Fixes == 0 {
+			fmt.Fprintf(w, "    %s: 0 次\n", g.label)
+			continue
+		}
+		fmt.Fprintf(w, "    %s: %s 次，中位数 %s\n", g.label, nf.Int(g.group.Fixes), FormatHours(g.group.MedianHours, nf))
+	}
+	if t.IssueError != "" {
+		fmt.Fprintf(w, "    无法读取 issue 创建时间: %s\n", t.IssueError)
+	}
+}
@@ -0,0 +1,126 @@
+package stat
+
+import "strings"
+
+// Trailer 提交信息末尾 trailer 段中的一项，如 Signed-off-by: A <a@example.com>
+type Trailer struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// 所在行(从 0 开始)，有续行时为第一行
+	line int
+}
+
+// git 自动生成的 trailer 前缀，trailer 段含有这些行时允许混有普通文本
+var gitGeneratedPrefixes = []string{"Signed-off-by: ", "(cherry picked from commit "}
+
+// 按 git interpret-trailers 的规则解析提交信息末尾的 trailer 段，按出现顺序返回各项，
+// 同名的多项都保留，由调用方决定取哪一个。规则：
+//   - 剪刀线及 "---" 开头的补丁分隔行之后的内容、末尾的注释行和空行不属于提交信息
+//   - 标题所在的第一段不是 trailer 段，trailer 段只能是最后一段
+//   - 最后一段全部为 "键: 值" 形式的行，或含有 git 自动生成的行(如 Signed-off-by)且 trailer 行不少于其余行的 25%
+//   - 以空白开头的行是上一项的续行，键由字母、数字和 "-" 组成，键与冒号之间可以有空白
+func ParseTrailers(message string) []Trailer {
+	lines := messageLines(message)
+	start, ok := trailerBlock(lines)
+	if !ok {
+		return nil
+	}
+	var trailers []Trailer
+	for n, line := range lines[start:] {
+		switch {
+		case strings.HasPrefix(line, "#"):
+		case line != "" && (line[0] == ' ' || line[0] == '\t'):
+			if len(trailers) > 0 {
+				trailers[len(trailers)-1].Value = strings.TrimSpace(trailers[len(trailers)-1].Value + " " + strings.TrimSpace(line))
+			}
+		default:
+			// 混在 trailer 段中的普通文本(如 cherry-pick 的说明)不是 trailer
+			if i := trailerSeparator(line); i > 0 {
+				trailers = append(trailers, Trailer{Key: strings.TrimSpace(line[:i]), Value: strings.TrimSpace(line[i+1:]), line: start + n})
+			}
+		}
+	}
+	return trailers
+}
+
+// 提交信息的各行，去掉剪刀线或补丁分隔行之后的内容及末尾的注释行和空行
+func messageLines(message string) []string {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if line == scissorsLine || (strings.HasPrefix(line, "---") && (len(line) == 3 || line[3] == ' ' || line[3] == '\t')) {
+			lines = lines[:i]
+			break
+		}
+	}
+	end := len(lines)
+	for end > 0 && (strings.HasPrefix(lines[end-1], "#") || strings.TrimSpace(lines[end-1]) == "") {
+		end--
+	}
+	return lines[:end]
+}
+
+// trailer 段在 lines 中的起始行，lines 为已去掉末尾注释和空行的提交信息，没有 trailer 段时返回 false
+func trailerBlock(lines []string) (int, bool) {
+	// 标题所在的第一段不参与判断
+	title := 0
+	for title < len(lines) && (strings.HasPrefix(lines[title], "#") || strings.TrimSpace(lines[title]) != "") {
+		title++
+	}
+	trailerLines, nonTrailerLines, continuation := 0, 0, 0
+	recognized := false
+	for i := len(lines) - 1; i >= title; i-- {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "#"):
+			nonTrailerLines += continuation
+			continuation = 0
+		case strings.TrimSpace(line) == "":
+			nonTrailerLines += continuation
+			if (trailerLines > 0 && nonTrailerLines == 0) || (recognized && trailerLines*3 >= nonTrailerLines) {
+				return i + 1, true
+			}
+			return 0, false
+		case hasGitGeneratedPrefix(line):
+			trailerLines++
+			continuation = 0
+			recognized = true
+		case line[0] == ' ' || line[0] == '\t':
+			continuation++
+		case trailerSeparator(line) > 0:
+			trailerLines++
+			continuation = 0
+		default:
+			nonTrailerLines += 1 + continuation
+			continuation = 0
+		}
+	}
+	return 0, false
+}
+
+// 是否为 git 自动生成的 trailer 行
+func hasGitGeneratedPrefix(line string) bool {
+	for _, prefix := range gitGeneratedPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// "键: 值" 形式的行中冒号的位置：键由字母、数字和 "-" 组成，其后可以有空白；不是这种形式时返回 -1
+func trailerSeparator(line string) int {
+	space := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case !space && (c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'):
+		case i > 0 && (c == ' ' || c == '\t'):
+			space = true
+		case c == ':' && i > 0:
+			return i
+		default:
+			return -1
+		}
+	}
+	return -1
+}
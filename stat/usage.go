@@ -0,0 +1,199 @@
+package stat
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 自报 AI 占比与工具采纳占比相差超过该百分点时标记为差异较大
+const DefaultUsageThreshold = 30
+
+// UsageRecord IDE/AI 工具导出的使用记录，每条为某个用户某天采纳的建议行数
+type UsageRecord struct {
+	Email         string
+	Date          string
+	AcceptedLines int
+}
+
+// 使用日志中各字段可能的列名(统一转为小写下划线形式后比较)，兼容 Cursor、Copilot 等工具的导出格式
+var usageColumns = map[string][]string{
+	"email":    {"email", "user_email", "user", "login", "user_login"},
+	"date":     {"date", "day", "timestamp"},
+	"accepted": {"accepted_lines", "lines_accepted", "accepted_lines_added", "total_lines_accepted", "total_accepted_lines"},
+}
+
+// 读取使用日志，按扩展名识别 JSON(对象数组) 或 CSV(首行为表头)
+func LoadUsage(paths []string) ([]UsageRecord, error) {
+	var records []UsageRecord
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("错误：读取使用日志 '%s' 失败: %v", path, err)
+		}
+
+		var rows []map[string]string
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			rows, err = usageJSONRows(data)
+		} else {
+			rows, err = usageCSVRows(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("错误：解析使用日志 '%s' 失败: %v", path, err)
+		}
+
+		for i, row := range rows {
+			record, err := usageRecord(row)
+			if err != nil {
+				return nil, fmt.Errorf("错误：使用日志 '%s' 第 %d 条记录: %v", path, i+1, err)
+			}
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// 将 JSON 对象数组转换为以规范列名为键的记录
+func usageJSONRows(data []byte) ([]map[string]string, error) {
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		row := make(map[string]string, len(item))
+		for key, value := range item {
+			switch v := value.(type) {
+			case string:
+				row[normalizeColumn(key)] = v
+			case float64:
+				row[normalizeColumn(key)] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// 将 CSV 表格转换为以规范列名为键的记录
+func usageCSVRows(data []byte) ([]map[string]string, error) {
+	table, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(data), "\ufeff"))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(table) == 0 {
+		return nil, nil
+	}
+
+	header := table[0]
+	rows := make([]map[string]string, 0, len(table)-1)
+	for _, line := range table[1:] {
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(line) {
+				row[normalizeColumn(name)] = line[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// 从记录中按候选列名取出字段
+func usageRecord(row map[string]string) (UsageRecord, error) {
+	field := func(name string) string {
+		for _, column := range usageColumns[name] {
+			if v, ok := row[column]; ok {
+				return strings.TrimSpace(v)
+			}
+		}
+		return ""
+	}
+
+	record := UsageRecord{Email: strings.ToLower(field("email"))}
+	if record.Email == "" {
+		return record, fmt.Errorf("缺少用户邮箱")
+	}
+	date, err := parseUsageDate(field("date"))
+	if err != nil {
+		return record, err
+	}
+	record.Date = date
+	if accepted := field("accepted"); accepted != "" {
+		lines, err := strconv.ParseFloat(accepted, 64)
+		if err != nil {
+			return record, fmt.Errorf("采纳行数 '%s' 不是数字", accepted)
+		}
+		record.AcceptedLines = int(lines)
+	}
+	return record, nil
+}
+
+// 将常见的日期格式统一为 2006-01-02
+func parseUsageDate(s string) (string, error) {
+	for _, layout := range []string{DateLayout, time.RFC3339, "2006-01-02 15:04:05", "2006/01/02", "2006/1/2"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(DateLayout), nil
+		}
+	}
+	// 毫秒时间戳
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC().Format(DateLayout), nil
+	}
+	return "", fmt.Errorf("无法识别的日期 '%s'", s)
+}
+
+// 列名统一为小写下划线形式，如 "Accepted Lines Added"、acceptedLinesAdded 均转为 accepted_lines_added
+func normalizeColumn(name string) string {
+	var b strings.Builder
+	prevLower := false
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case r == ' ' || r == '-' || r == '_':
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteRune('_')
+			}
+			prevLower = false
+		case unicode.IsUpper(r):
+			if prevLower {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			prevLower = false
+		default:
+			b.WriteRune(r)
+			prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+		}
+	}
+	return b.String()
+}
+
+// 将统计范围内的工具采纳行数按邮箱对应到开发者，并标记与自报 AI 占比差异较大的开发者
+// since、until 为空时不按日期筛选
+func ApplyUsage(authors []*AuthorStats, records []UsageRecord, since, until string, threshold float64) {
+	accepted := make(map[string]int)
+	for _, r := range records {
+		if (since != "" && r.Date < since) || (until != "" && r.Date > until) {
+			continue
+		}
+		accepted[r.Email] += r.AcceptedLines
+	}
+
+	for _, stats := range authors {
+		lines, ok := accepted[strings.ToLower(stats.Email)]
+		if !ok {
+			continue
+		}
+		stats.ToolUsage = true
+		stats.ToolAcceptedLines = lines
+		stats.UsageDiscrepancy = math.Abs(stats.AddedRatio()-stats.ToolRatio()) > threshold
+	}
+}
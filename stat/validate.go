@@ -0,0 +1,181 @@
+package stat
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	shortstatAddedRegex   = regexp.MustCompile(`(\d+) insertions?\(\+\)`)
+	shortstatDeletedRegex = regexp.MustCompile(`(\d+) deletions?\(-\)`)
+)
+
+// LineCounts 添加、删除行数
+type LineCounts struct {
+	Added   int `json:"added" yaml:"added"`
+	Deleted int `json:"deleted" yaml:"deleted"`
+}
+
+// 格式化为 +添加/-删除
+func (c LineCounts) String() string {
+	return fmt.Sprintf("+%d/-%d", c.Added, c.Deleted)
+}
+
+// Validation 将汇总的行数与 git 自身的统计交叉核对，说明各项差异的来源
+type Validation struct {
+	// git log --shortstat 逐提交累计(所有文件)
+	Shortstat LineCounts `json:"shortstat" yaml:"shortstat"`
+	// 解析 numstat 得到的合计(所有文件)
+	Parsed LineCounts `json:"parsed" yaml:"parsed"`
+	// 忽略列表及大规模移动排除的提交的行数(所有文件)
+	Ignored LineCounts `json:"ignored" yaml:"ignored"`
+	// 大规模移动中扣除的移动文件行数
+	Moved LineCounts `json:"moved" yaml:"moved"`
+	// 文件类型规则排除的行数
+	Skipped LineCounts `json:"skipped" yaml:"skipped"`
+	// .gitattributes 标记为生成或第三方代码而排除的行数
+	Attributes LineCounts `json:"attributes" yaml:"attributes"`
+	// 符号链接变更排除的行数(只修改权限的变更没有行数)
+	Special LineCounts `json:"special" yaml:"special"`
+	// 扣除的新增空行
+	Blank LineCounts `json:"blank" yaml:"blank"`
+	// 计入统计的行数
+	Counted LineCounts `json:"counted" yaml:"counted"`
+	// 报告中开发者及汇总行的合计
+	Reported LineCounts `json:"reported" yaml:"reported"`
+	// git diff --shortstat 提交范围首尾的净变化，只在按提交范围统计时计算
+	Diff *LineCounts `json:"diff,omitempty" yaml:"diff,omitempty"`
+	// 差异说明
+	Notes []string `json:"notes" yaml:"notes"`
+}
+
+// 核对分析结果：重新用 git log --shortstat 统计同一范围，并与解析、过滤后的各级合计比较
+// reported 为报告中的开发者及汇总行
+func (a *Analyzer) Validate(q LogQuery, commits []CommitStats, reported []*AuthorStats) (*Validation, error) {
+	v := &Validation{}
+	if a.noNumstat {
+		v.Notes = append(v.Notes, "缺少统计行数所需的对象，无法校验")
+		return v, nil
+	}
+
+	// 按作者时间统计时与 Analyze 使用相同的查询范围和提交
+	if a.authorQuery != nil {
+		q = *a.authorQuery
+	}
+	out, err := a.Git.Run(append([]string{"log", "--format=" + commitSep + "%H", "--shortstat"}, revisionArgs(q)...))
+	if err != nil {
+		return nil, err
+	}
+	for _, commit := range splitCommits(gitText(out)) {
+		if id, _, _ := strings.Cut(commit, "\n"); a.authorIDs != nil && !a.authorIDs[strings.TrimSpace(id)] {
+			continue
+		}
+		v.Shortstat.add(parseShortstat(commit))
+	}
+
+	v.Ignored = a.ignoredLines
+	v.Parsed = a.ignoredLines
+	submoduleCommits := 0
+	for _, c := range commits {
+		for _, f := range c.Files {
+			switch {
+			case f.Change != "":
+				v.Special.Added += f.Added
+				v.Special.Deleted += f.Deleted
+			case f.Attribute != "":
+				v.Attributes.Added += f.Added
+				v.Attributes.Deleted += f.Deleted
+			case f.Skipped:
+				v.Skipped.Added += f.Added
+				v.Skipped.Deleted += f.Deleted
+			case f.Moved:
+				v.Moved.Added += f.Added
+				v.Moved.Deleted += f.Deleted
+			}
+			if c.Submodule == "" {
+				v.Parsed.Added += f.Added
+				v.Parsed.Deleted += f.Deleted
+			}
+		}
+		v.Blank.Added += c.BlankLines
+		v.Counted.Added += c.AddedLines
+		v.Counted.Deleted += c.DeletedLines
+		if c.Submodule != "" {
+			submoduleCommits++
+		}
+	}
+	for _, s := range reported {
+		v.Reported.Added += s.TotalAddedLines
+		v.Reported.Deleted += s.TotalDeletedLines
+	}
+
+	// 提交范围 A..B 且没有按日期、作者、提交信息筛选时，比较首尾的净变化
+	if strings.Contains(q.RevRange, "..") && !strings.Contains(q.RevRange, "...") && q.Since == "" && q.Until == "" && q.Author == "" && len(q.Grep) == 0 {
+		out, err := a.Git.Run([]string{"diff", "--shortstat", "--end-of-options", q.RevRange})
+		if err != nil {
+			return nil, err
+		}
+		diff := parseShortstat(gitText(out))
+		v.Diff = &diff
+	}
+
+	v.explain(submoduleCommits)
+	return v, nil
+}
+
+// 生成差异说明
+func (v *Validation) explain(submoduleCommits int) {
+	if v.Shortstat != v.Parsed {
+		v.Notes = append(v.Notes, fmt.Sprintf("git log --shortstat 合计 %s 与解析结果 %s 不一致，请检查是否有无法解析的文件名", v.Shortstat, v.Parsed))
+	}
+	if v.Ignored != (LineCounts{}) {
+		v.Notes = append(v.Notes, fmt.Sprintf("忽略的提交(忽略列表、大规模移动)排除 %s 行", v.Ignored))
+	}
+	if v.Moved != (LineCounts{}) {
+		v.Notes = append(v.Notes, fmt.Sprintf("大规模移动扣除移动文件 %s 行", v.Moved))
+	}
+	if v.Skipped != (LineCounts{}) {
+		v.Notes = append(v.Notes, fmt.Sprintf("文件类型规则排除 %s 行", v.Skipped))
+	}
+	if v.Special != (LineCounts{}) {
+		v.Notes = append(v.Notes, fmt.Sprintf("符号链接变更排除 %s 行", v.Special))
+	}
+	if v.Attributes != (LineCounts{}) {
+		v.Notes = append(v.Notes, fmt.Sprintf(".gitattributes 标记的生成或第三方代码排除 %s 行", v.Attributes))
+	}
+	if v.Blank != (LineCounts{}) {
+		v.Notes = append(v.Notes, fmt.Sprintf("新增空行扣除 %s 行", v.Blank))
+	}
+	if submoduleCommits > 0 {
+		v.Notes = append(v.Notes, fmt.Sprintf("%d 次子模块提交不在 shortstat 校验范围内", submoduleCommits))
+	}
+	if excluded := (LineCounts{v.Counted.Added - v.Reported.Added, v.Counted.Deleted - v.Reported.Deleted}); excluded != (LineCounts{}) {
+		v.Notes = append(v.Notes, fmt.Sprintf("开发者过滤规则(邮箱域名等)排除 %s 行", excluded))
+	}
+	if v.Diff != nil && *v.Diff != v.Shortstat {
+		v.Notes = append(v.Notes, fmt.Sprintf("提交范围首尾的净变化为 %s 行，逐提交累计为 %s 行，差异来自范围内被再次修改或删除的代码，以及不计入统计的合并提交", v.Diff, v.Shortstat))
+	}
+	if len(v.Notes) == 0 {
+		v.Notes = append(v.Notes, "各级合计一致")
+	}
+}
+
+// 累加行数
+func (c *LineCounts) add(o LineCounts) {
+	c.Added += o.Added
+	c.Deleted += o.Deleted
+}
+
+// 解析 "3 files changed, 10 insertions(+), 2 deletions(-)" 格式的输出
+func parseShortstat(s string) LineCounts {
+	var c LineCounts
+	if m := shortstatAddedRegex.FindStringSubmatch(s); m != nil {
+		c.Added, _ = strconv.Atoi(m[1])
+	}
+	if m := shortstatDeletedRegex.FindStringSubmatch(s); m != nil {
+		c.Deleted, _ = strconv.Atoi(m[1])
+	}
+	return c
+}
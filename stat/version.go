@@ -0,0 +1,318 @@
+package stat
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 工具版本、源码提交及构建时间，发布构建(go run ./build)时通过
+// -ldflags "-X AIStat/stat.Version=... -X AIStat/stat.Commit=... -X AIStat/stat.BuildDate=..." 注入
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+	// 默认的版本清单位置，发布构建时用 --update-url 指定
+	UpdateURL = ""
+	// 校验版本清单签名的公钥(base64 编码的 ed25519 公钥)，发布构建时由 --sign-key 指定的私钥导出
+	UpdatePublicKey = ""
+)
+
+const (
+	// 发布构建生成的版本清单文件名，与各平台的可执行文件放在同一目录
+	ReleaseManifestFile = "release.json"
+	// 版本清单的签名文件在清单位置后加的后缀，内容为 base64 编码的 ed25519 签名
+	ReleaseSignatureSuffix = ".sig"
+)
+
+// BuildInfo 可执行文件的构建信息
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// 当前可执行文件的构建信息，未通过 ldflags 注入提交时使用 go build 记录的版本控制信息
+func CurrentBuild() BuildInfo {
+	b := BuildInfo{Version: Version, Commit: Commit, BuildDate: BuildDate, GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if info, ok := debug.ReadBuildInfo(); ok && b.Commit == "" {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				b.Commit = s.Value
+			case "vcs.time":
+				b.BuildDate = firstNonEmpty(b.BuildDate, s.Value)
+			}
+		}
+	}
+	return b
+}
+
+// 一行形式的版本说明，如 v1.2.0 (提交 3f2a9c1，构建于 2024-06-01T08:00:00Z，go1.20 windows/amd64)
+func (b BuildInfo) String() string {
+	parts := []string{}
+	if b.Commit != "" {
+		parts = append(parts, "提交 "+shortID(b.Commit))
+	}
+	if b.BuildDate != "" {
+		parts = append(parts, "构建于 "+b.BuildDate)
+	}
+	parts = append(parts, b.GoVersion+" "+b.OS+"/"+b.Arch)
+	return b.Version + " (" + strings.Join(parts, "，") + ")"
+}
+
+// 比较两个版本号(如 v1.2.0、1.10)，按点分隔的各段数字依次比较，a 较新时返回 1，较旧时返回 -1。
+// dev 等无法解析的版本视为最旧
+func CompareVersions(a, b string) int {
+	pa, okA := versionParts(a)
+	pb, okB := versionParts(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// 版本号中的各段数字，忽略前缀 v 及 "-" 之后的预发布标识
+func versionParts(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// ReleaseManifest 发布构建生成的版本清单，self-update 据此查找并校验新版本
+type ReleaseManifest struct {
+	Version   string         `json:"version"`
+	Commit    string         `json:"commit"`
+	BuildDate string         `json:"build_date"`
+	Assets    []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset 一个平台的可执行文件
+type ReleaseAsset struct {
+	// 工具名称，如 AIG_repo、AIG_person
+	Tool string `json:"tool"`
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// 相对于清单文件的路径
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// 查找指定工具及平台的可执行文件
+func (m *ReleaseManifest) Asset(tool, goos, goarch string) (*ReleaseAsset, bool) {
+	for i := range m.Assets {
+		a := &m.Assets[i]
+		if a.Tool == tool && a.OS == goos && a.Arch == goarch {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// 读取版本清单，location 为 https 地址、清单文件路径或其所在目录。publicKey 不为空时
+// 要求清单旁有 .sig 签名文件且签名与公钥匹配：清单中记录了各文件的 SHA256，清单本身须可信
+func LoadReleaseManifest(location, publicKey string) (*ReleaseManifest, string, error) {
+	if err := checkHTTPS(location); err != nil {
+		return nil, "", err
+	}
+	if !isHTTPURL(location) {
+		if info, err := os.Stat(location); err == nil && info.IsDir() {
+			location = filepath.Join(location, ReleaseManifestFile)
+		}
+	}
+	data, err := readLocation(location, "版本清单")
+	if err != nil {
+		return nil, "", err
+	}
+	if publicKey != "" {
+		sig, err := readLocation(location+ReleaseSignatureSuffix, "版本清单签名")
+		if err != nil {
+			return nil, "", err
+		}
+		if err := VerifyReleaseManifest(data, sig, publicKey); err != nil {
+			return nil, "", fmt.Errorf("错误：版本清单 '%s' 校验失败: %v", location, err)
+		}
+	}
+	m := &ReleaseManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, "", fmt.Errorf("错误：解析版本清单 '%s' 失败: %v", location, err)
+	}
+	if m.Version == "" {
+		return nil, "", fmt.Errorf("错误：版本清单 '%s' 中没有版本号", location)
+	}
+	return m, location, nil
+}
+
+// 用发布私钥签名版本清单，返回签名文件的内容
+func SignReleaseManifest(data []byte, key ed25519.PrivateKey) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n")
+}
+
+// 校验版本清单的签名，publicKey 为 base64 编码的 ed25519 公钥
+func VerifyReleaseManifest(data, sig []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("内置的发布公钥无效")
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), data, signature) {
+		return errors.New("签名与发布公钥不符，清单可能被篡改")
+	}
+	return nil
+}
+
+// 把可执行文件下载或复制到 dest 并校验 SHA256，manifest 为清单的实际位置
+func FetchReleaseAsset(manifest string, asset *ReleaseAsset, dest string) error {
+	src := asset.File
+	if isHTTPURL(manifest) {
+		base, err := url.Parse(manifest)
+		if err != nil {
+			return err
+		}
+		ref, err := url.Parse(asset.File)
+		if err != nil {
+			return err
+		}
+		src = base.ResolveReference(ref).String()
+		if err := checkHTTPS(src); err != nil {
+			return err
+		}
+	} else if !filepath.IsAbs(src) {
+		src = filepath.Join(filepath.Dir(manifest), filepath.FromSlash(src))
+	}
+
+	var r io.ReadCloser
+	if isHTTPURL(src) {
+		resp, err := httpsClient(10 * time.Minute).Get(src)
+		if err != nil {
+			return fmt.Errorf("错误：下载 '%s' 失败: %v", src, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("错误：下载 '%s' 失败: %s", src, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return fmt.Errorf("错误：读取 '%s' 失败: %v", src, err)
+		}
+		r = f
+	}
+	defer r.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return fmt.Errorf("错误：写入 '%s' 失败: %v", dest, err)
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return fmt.Errorf("错误：下载 '%s' 失败: %v", src, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, asset.SHA256) {
+		os.Remove(dest)
+		return fmt.Errorf("错误：'%s' 的 SHA256 为 %s，与版本清单中的 %s 不符", src, sum, asset.SHA256)
+	}
+	return nil
+}
+
+// 读取 https 地址或本地文件的内容，what 为错误信息中的文件说明
+func readLocation(location, what string) ([]byte, error) {
+	if !isHTTPURL(location) {
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("错误：读取%s '%s' 失败: %v", what, location, err)
+		}
+		return data, nil
+	}
+	resp, err := (&APIClient{Client: httpsClient(30 * time.Second)}).Do(http.MethodGet, location, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// 是否为 http(s) 地址
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// 版本清单和可执行文件只通过 https 下载，http 传输的内容可能被替换
+func checkHTTPS(location string) error {
+	if strings.HasPrefix(location, "http://") {
+		return fmt.Errorf("错误：'%s' 使用 http，请改用 https 地址", location)
+	}
+	return nil
+}
+
+// 不跟随跳转到 http 地址的客户端
+func httpsClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("错误：'%s' 跳转到了非 https 地址 '%s'", via[0].URL, req.URL)
+			}
+			if len(via) >= 10 {
+				return errors.New("错误：跳转次数过多")
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package stat
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 在 dir 中写入签名的版本清单，返回公钥
+func writeSignedManifest(t *testing.T, dir, manifest string) string {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, ReleaseManifestFile)
+	if err := os.WriteFile(name, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name+ReleaseSignatureSuffix, SignReleaseManifest([]byte(manifest), private), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(public)
+}
+
+func TestLoadReleaseManifestSignature(t *testing.T) {
+	dir := t.TempDir()
+	public := writeSignedManifest(t, dir, `{"version": "v1.2.0", "assets": []}`)
+	m, where, err := LoadReleaseManifest(dir, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != "v1.2.0" || where != filepath.Join(dir, ReleaseManifestFile) {
+		t.Errorf("manifest = %+v, where = %s", m, where)
+	}
+
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, _, err := LoadReleaseManifest(dir, base64.StdEncoding.EncodeToString(other)); err == nil {
+		t.Error("其他公钥校验通过了")
+	}
+	// 修改清单(如替换文件的 SHA256)后签名不符
+	if err := os.WriteFile(where, []byte(`{"version": "v9.0.0", "assets": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadReleaseManifest(dir, public); err == nil {
+		t.Error("修改后的清单校验通过了")
+	}
+	// 没有签名文件
+	if err := os.Remove(where + ReleaseSignatureSuffix); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadReleaseManifest(dir, public); err == nil {
+		t.Error("没有签名文件的清单校验通过了")
+	}
+}
+
+func TestReleaseRequiresHTTPS(t *testing.T) {
+	if _, _, err := LoadReleaseManifest("http://intranet.example.com/aistat/release.json", ""); err == nil || !strings.Contains(err.Error(), "https") {
+		t.Errorf("http 地址的版本清单: err = %v", err)
+	}
+	// https 清单中指向 http 地址的文件
+	asset := &ReleaseAsset{File: "http://mirror.example.com/AIG_repo"}
+	if err := FetchReleaseAsset("https://intranet.example.com/aistat/release.json", asset, filepath.Join(t.TempDir(), "AIG_repo")); err == nil || !strings.Contains(err.Error(), "https") {
+		t.Errorf("http 地址的文件: err = %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package stat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// webhook 密钥只从环境变量读取，避免出现在命令行参数中
+const EnvWebhookSecret = "AISTAT_WEBHOOK_SECRET"
+
+// 推送 webhook 请求体的大小上限
+const maxWebhookBody = 25 << 20
+
+var (
+	// ErrWebhookIgnored 不需要处理的事件，如 GitHub 的 ping、标签推送
+	ErrWebhookIgnored = errors.New("忽略的事件")
+	// ErrWebhookUnauthorized 签名或令牌校验失败
+	ErrWebhookUnauthorized = errors.New("错误：webhook 签名或令牌校验失败")
+)
+
+// PushEvent GitHub/GitLab 推送事件中统计所需的信息
+type PushEvent struct {
+	// github 或 gitlab
+	Provider string `json:"provider"`
+	// 仓库全名，如 group/app
+	Repo   string `json:"repo"`
+	Ref    string `json:"ref"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// 推送新建了分支，没有可用的起始提交
+func (e *PushEvent) NewBranch() bool {
+	return strings.Trim(e.Before, "0") == ""
+}
+
+// 推送删除了分支
+func (e *PushEvent) Deleted() bool {
+	return strings.Trim(e.After, "0") == ""
+}
+
+// 解析并校验 GitHub/GitLab 的推送 webhook，secret 为空时拒绝所有请求
+// 非推送事件返回 ErrWebhookIgnored
+func ParsePushWebhook(r *http.Request, secret string) (*PushEvent, error) {
+	if secret == "" {
+		return nil, ErrWebhookUnauthorized
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		return nil, fmt.Errorf("错误：读取 webhook 请求失败: %v", err)
+	}
+
+	var provider string
+	switch {
+	case r.Header.Get("X-GitHub-Event") != "":
+		provider = "github"
+		if !validGitHubSignature(body, secret, r.Header.Get("X-Hub-Signature-256")) {
+			return nil, ErrWebhookUnauthorized
+		}
+		if r.Header.Get("X-GitHub-Event") != "push" {
+			return nil, ErrWebhookIgnored
+		}
+	case r.Header.Get("X-Gitlab-Event") != "":
+		provider = "gitlab"
+		token := r.Header.Get("X-Gitlab-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return nil, ErrWebhookUnauthorized
+		}
+		if r.Header.Get("X-Gitlab-Event") != "Push Hook" {
+			return nil, ErrWebhookIgnored
+		}
+	default:
+		return nil, fmt.Errorf("错误：无法识别的 webhook，只支持 GitHub 和 GitLab 的推送事件")
+	}
+
+	var payload struct {
+		Ref        string `json:"ref"`
+		Before     string `json:"before"`
+		After      string `json:"after"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("错误：解析 webhook 请求失败: %v", err)
+	}
+	event := &PushEvent{
+		Provider: provider,
+		Repo:     firstNonEmpty(payload.Repository.FullName, payload.Project.PathWithNamespace),
+		Ref:      payload.Ref,
+		Before:   payload.Before,
+		After:    payload.After,
+	}
+	if event.Repo == "" || event.After == "" {
+		return nil, fmt.Errorf("错误：webhook 请求中缺少仓库或提交信息")
+	}
+	// 提交哈希会作为 git log 的提交范围，只接受完整的 SHA-1/SHA-256 哈希
+	if !isCommitHash(event.After) || (event.Before != "" && !isCommitHash(event.Before)) {
+		return nil, fmt.Errorf("错误：webhook 请求中的提交哈希格式不正确")
+	}
+	if !strings.HasPrefix(event.Ref, "refs/heads/") {
+		return nil, ErrWebhookIgnored
+	}
+	return event, nil
+}
+
+// 40 位(SHA-1)或 64 位(SHA-256)的十六进制提交哈希
+func isCommitHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// 校验 GitHub 的 X-Hub-Signature-256 签名
+func validGitHubSignature(body []byte, secret, signature string) bool {
+	hexSum, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	sum, err := hex.DecodeString(hexSum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sum, mac.Sum(nil))
+}
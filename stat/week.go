@@ -0,0 +1,144 @@
+package stat
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// 按周汇总时周的起始日及编号方式
+const (
+	// ISO 8601：周一开始，包含该年第一个周四的周为第 1 周，如 2024-W03
+	WeekISO = "iso"
+	// 周一开始，包含 1 月 1 日的周为第 1 周
+	WeekMonday = "monday"
+	// 周日开始，包含 1 月 1 日的周为第 1 周(美国等地区的习惯)
+	WeekSunday = "sunday"
+)
+
+// 是否为支持的周编号方式
+func ValidWeekScheme(scheme string) bool {
+	return scheme == WeekISO || scheme == WeekMonday || scheme == WeekSunday
+}
+
+// WeekStats 一周内全部提交的汇总
+type WeekStats struct {
+	// 周的编号，如 2024-W03
+	Week string `json:"week" yaml:"week"`
+	// 该周的第一天和最后一天
+	Start string       `json:"start" yaml:"start"`
+	End   string       `json:"end" yaml:"end"`
+	Stats *AuthorStats `json:"stats" yaml:"stats"`
+	// 有提交的开发者人数，按 identity 识别
+	Contributors int `json:"contributors" yaml:"contributors"`
+}
+
+// 按周汇总 [from, to] 日期范围内的提交，没有提交的周也输出一行；
+// from、to 为空(按提交范围统计)时取最早和最晚的提交日期
+func BuildWeeks(commits []CommitStats, from, to, scheme, identity string) []*WeekStats {
+	first, last, ok := weekRange(commits, from, to)
+	if !ok {
+		return nil
+	}
+	var weeks []*WeekStats
+	index := make(map[string]*WeekStats)
+	members := make(map[string]map[string]bool)
+	for start := weekStart(first, scheme); !start.After(last); start = start.AddDate(0, 0, 7) {
+		week := &WeekStats{
+			Week:  weekLabel(start, scheme),
+			Start: start.Format(DateLayout),
+			End:   start.AddDate(0, 0, 6).Format(DateLayout),
+			Stats: &AuthorStats{},
+		}
+		weeks = append(weeks, week)
+		index[week.Start] = week
+		members[week.Start] = make(map[string]bool)
+	}
+	for i := range commits {
+		t, err := CommitTime(&commits[i])
+		if err != nil {
+			continue
+		}
+		key := weekStart(t, scheme).Format(DateLayout)
+		if week, ok := index[key]; ok {
+			week.Stats.Add(&commits[i])
+			members[key][IdentityKey(identity, commits[i].Author, commits[i].Email)] = true
+		}
+	}
+	for _, week := range weeks {
+		week.Stats.Name = week.Week
+		week.Contributors = len(members[week.Start])
+	}
+	return weeks
+}
+
+// 按周汇总的日期范围
+func weekRange(commits []CommitStats, from, to string) (time.Time, time.Time, bool) {
+	first, err1 := time.ParseInLocation(DateLayout, PeriodDay(from), time.Local)
+	last, err2 := time.ParseInLocation(DateLayout, PeriodDay(to), time.Local)
+	if err1 == nil && err2 == nil {
+		return first, last, !last.Before(first)
+	}
+	found := false
+	for i := range commits {
+		t, err := CommitTime(&commits[i])
+		if err != nil {
+			continue
+		}
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		if !found || day.Before(first) {
+			first = day
+		}
+		if !found || day.After(last) {
+			last = day
+		}
+		found = true
+	}
+	return first, last, found
+}
+
+// t 所在周的第一天(0 点)
+func weekStart(t time.Time, scheme string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	offset := int(day.Weekday())
+	if scheme != WeekSunday {
+		offset = (offset + 6) % 7
+	}
+	return day.AddDate(0, 0, -offset)
+}
+
+// 周的编号：ISO 周按 ISO 8601 编号；其他方式以包含 1 月 1 日的周为第 1 周，
+// 跨年的周属于新的一年
+func weekLabel(start time.Time, scheme string) string {
+	if scheme == WeekISO {
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	year := start.AddDate(0, 0, 6).Year()
+	first := weekStart(time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local), scheme)
+	week := int(start.Sub(first).Hours()/24+0.5)/7 + 1
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// 打印按周汇总的统计
+func PrintWeeks(w io.Writer, weeks []*WeekStats, scheme string, nf NumberFormat, minLines int) {
+	fmt.Fprintf(w, "\n  按周统计 (%s):\n", weekSchemeName(scheme))
+	for _, week := range weeks {
+		s := week.Stats
+		fmt.Fprintf(w, "    %s (%s ~ %s): %s 次提交，%s 人，添加 %s，AI 添加 %s (%s)，修复 %s 次\n",
+			week.Week, week.Start[5:], week.End[5:], nf.Int(s.CommitCount), nf.Int(week.Contributors),
+			nf.Lines(s.TotalAddedLines), nf.Lines(s.TotalAIAddedLines),
+			FormatSampleRatio(s.TotalAIAddedLines, s.RatioAddedLines(), minLines), nf.Int(s.FixCount))
+	}
+}
+
+// 周编号方式的说明
+func weekSchemeName(scheme string) string {
+	switch scheme {
+	case WeekMonday:
+		return "周一开始"
+	case WeekSunday:
+		return "周日开始"
+	}
+	return "ISO 周"
+}